@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DailyActiveHoursItem 单日首条与末条消息的时刻，用于观察每天的开工/收工时间
+type DailyActiveHoursItem struct {
+	Date  string `json:"date"`
+	First string `json:"first"` // "08:00"
+	Last  string `json:"last"`  // "22:00"
+	// FirstHour/LastHour 同一时刻的小时数（如 8.5 表示 08:30），便于绘制起止区间
+	FirstHour float64 `json:"first_hour"`
+	LastHour  float64 `json:"last_hour"`
+}
+
+// secondOfDay 时间戳在当天的秒数（与日期 key 同一时区）
+func secondOfDay(t time.Time) int {
+	return t.Hour()*3600 + t.Minute()*60 + t.Second()
+}
+
+// formatSecondOfDay 当天秒数格式化为 HH:MM
+func formatSecondOfDay(second int) string {
+	return fmt.Sprintf("%02d:%02d", second/3600, second%3600/60)
+}
+
+// recordDayBounds 用一条记录的当天秒数扩展该日的 [首条, 末条] 区间
+func recordDayBounds(bounds map[string][2]int, date string, second int) {
+	current, ok := bounds[date]
+	if !ok {
+		bounds[date] = [2]int{second, second}
+		return
+	}
+	if second < current[0] {
+		current[0] = second
+	}
+	if second > current[1] {
+		current[1] = second
+	}
+	bounds[date] = current
+}
+
+// mergeDayBounds 把 src 各日区间并入 dst（取更早的首条与更晚的末条）
+func mergeDayBounds(dst, src map[string][2]int) {
+	for date, span := range src {
+		recordDayBounds(dst, date, span[0])
+		recordDayBounds(dst, date, span[1])
+	}
+}
+
+func copyDayBounds(src map[string][2]int) map[string][2]int {
+	if len(src) == 0 {
+		return nil
+	}
+	out := make(map[string][2]int, len(src))
+	for key, value := range src {
+		out[key] = value
+	}
+	return out
+}
+
+// buildDailyActiveHours 由各日 [首条, 末条] 秒数构建按日期升序的序列，没有数据时返回 nil
+func buildDailyActiveHours(bounds map[string][2]int) []DailyActiveHoursItem {
+	if len(bounds) == 0 {
+		return nil
+	}
+	items := make([]DailyActiveHoursItem, 0, len(bounds))
+	for date, span := range bounds {
+		items = append(items, DailyActiveHoursItem{
+			Date:      date,
+			First:     formatSecondOfDay(span[0]),
+			Last:      formatSecondOfDay(span[1]),
+			FirstHour: float64(span[0]) / 3600,
+			LastHour:  float64(span[1]) / 3600,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Date < items[j].Date })
+	return items
+}
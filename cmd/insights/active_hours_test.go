@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDailyActiveHoursTracksFirstAndLastMessage(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	// 文件内顺序打乱，首末时刻应取最小/最大值而不是首行/末行
+	content := projectRecordJSON("/tmp/demo", "s1", day.Add(13*time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", day.Add(22*time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s2", day.Add(8*time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s3", day.AddDate(0, 0, 1).Add(9*time.Hour+30*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	want := []DailyActiveHoursItem{
+		{Date: "2026-01-05", First: "08:00", Last: "22:00", FirstHour: 8, LastHour: 22},
+		{Date: "2026-01-06", First: "09:30", Last: "09:30", FirstHour: 9.5, LastHour: 9.5},
+	}
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if got := buildDailyActiveHours(agg.DailyBounds); !reflect.DeepEqual(got, want) {
+		t.Fatalf("daily active hours = %+v, want %+v", got, want)
+	}
+	if first := agg.DailyActivityList[0]; first.FirstTime != "08:00" || first.LastTime != "22:00" {
+		t.Fatalf("DailyActivity first/last = %q/%q, want 08:00/22:00", first.FirstTime, first.LastTime)
+	}
+
+	// 缓存路径：按日落盘的首末时刻应得到相同序列
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	bounds := make(map[string][2]int)
+	for date, stats := range cache.DailyStats {
+		if stats.HasBounds {
+			bounds[date] = stats.Bounds
+		}
+	}
+	if got := buildDailyActiveHours(bounds); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached daily active hours = %+v, want %+v", got, want)
+	}
+}
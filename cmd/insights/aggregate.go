@@ -10,10 +10,18 @@ func newProjectAggregate() *ProjectAggregate {
 		ProjectStats:            make(map[string]*ProjectStatItem),
 		DailyActivity:           make(map[string]int),
 		DailySessions:           make(map[string]map[string]bool),
+		DailySessionSpans:       make(map[string]map[string]SessionSpan),
 		DailyProjectCounts:      make(map[string]map[string]int),
 		DailyModelCounts:        make(map[string]map[string]int),
+		DailyVersionCounts:      make(map[string]map[string]int),
+		DailyStopReasonCounts:   make(map[string]map[string]int),
+		DailyOutputTokens:       make(map[string]int),
+		DailyAssistantMessages:  make(map[string]int),
 		DailyModelTokens:        make(map[string]map[string]int),
+		DailyProjectTokens:      make(map[string]map[string]int),
 		DailyHourlyCounts:       make(map[string][24]int),
+		DailyHalfHourCounts:     make(map[string][48]int),
+		DailyBounds:             make(map[string][2]int),
 		DailyRuntime:            make(map[string]*ProjectAggregate),
 		DailyProjectRuntime:     make(map[string]map[string]*ProjectAggregate),
 		DailySessionRuntime:     make(map[string]map[string]*ProjectAggregate),
@@ -70,12 +78,15 @@ func newProjectAggregate() *ProjectAggregate {
 }
 
 func mergeProjectAggregate(dst, src *ProjectAggregate) {
+	dst.ParseStats.Add(src.ParseStats)
 	for project, stat := range src.ProjectStats {
+		project = canonicalProjectName(project)
 		if dst.ProjectStats[project] == nil {
 			dst.ProjectStats[project] = &ProjectStatItem{Project: project}
 		}
 		dst.ProjectStats[project].MessageCount += stat.MessageCount
 		dst.ProjectStats[project].SessionCount += stat.SessionCount
+		dst.ProjectStats[project].Tokens += stat.Tokens
 	}
 	for i := range src.WeekdayData {
 		dst.WeekdayData[i].MessageCount += src.WeekdayData[i].MessageCount
@@ -88,7 +99,7 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 			dst.DailyProjectCounts[date] = make(map[string]int)
 		}
 		for project, count := range projects {
-			dst.DailyProjectCounts[date][project] += count
+			dst.DailyProjectCounts[date][canonicalProjectName(project)] += count
 		}
 	}
 	for date, models := range src.DailyModelCounts {
@@ -99,6 +110,28 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 			dst.DailyModelCounts[date][model] += count
 		}
 	}
+	for date, versions := range src.DailyVersionCounts {
+		if dst.DailyVersionCounts[date] == nil {
+			dst.DailyVersionCounts[date] = make(map[string]int)
+		}
+		for version, count := range versions {
+			dst.DailyVersionCounts[date][version] += count
+		}
+	}
+	for date, reasons := range src.DailyStopReasonCounts {
+		if dst.DailyStopReasonCounts[date] == nil {
+			dst.DailyStopReasonCounts[date] = make(map[string]int)
+		}
+		for reason, count := range reasons {
+			dst.DailyStopReasonCounts[date][reason] += count
+		}
+	}
+	for date, tokens := range src.DailyOutputTokens {
+		dst.DailyOutputTokens[date] += tokens
+	}
+	for date, count := range src.DailyAssistantMessages {
+		dst.DailyAssistantMessages[date] += count
+	}
 	for date, models := range src.DailyModelTokens {
 		if dst.DailyModelTokens[date] == nil {
 			dst.DailyModelTokens[date] = make(map[string]int)
@@ -107,6 +140,14 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 			dst.DailyModelTokens[date][model] += tokens
 		}
 	}
+	for date, projects := range src.DailyProjectTokens {
+		if dst.DailyProjectTokens[date] == nil {
+			dst.DailyProjectTokens[date] = make(map[string]int)
+		}
+		for project, tokens := range projects {
+			dst.DailyProjectTokens[date][canonicalProjectName(project)] += tokens
+		}
+	}
 	for date, counts := range src.DailyHourlyCounts {
 		dstCounts := dst.DailyHourlyCounts[date]
 		for hour, count := range counts {
@@ -114,6 +155,14 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 		}
 		dst.DailyHourlyCounts[date] = dstCounts
 	}
+	for date, counts := range src.DailyHalfHourCounts {
+		dstCounts := dst.DailyHalfHourCounts[date]
+		for bin, count := range counts {
+			dstCounts[bin] += count
+		}
+		dst.DailyHalfHourCounts[date] = dstCounts
+	}
+	mergeDayBounds(dst.DailyBounds, src.DailyBounds)
 	for date, runtimeAgg := range src.DailyRuntime {
 		if runtimeAgg == nil {
 			continue
@@ -126,7 +175,7 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 			if runtimeAgg == nil {
 				continue
 			}
-			dstRuntime := ensureDailyProjectRuntimeAggregate(dst, date, project)
+			dstRuntime := ensureDailyProjectRuntimeAggregate(dst, date, canonicalProjectName(project))
 			mergeProjectAggregate(dstRuntime, runtimeAgg)
 		}
 	}
@@ -147,6 +196,7 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 			dst.DailySessions[date][sessionID] = true
 		}
 	}
+	mergeSessionSpans(dst.DailySessionSpans, src.DailySessionSpans, sessionGap())
 	for hour, count := range src.HourlyCounts {
 		dst.HourlyCounts[hour] += count
 	}
@@ -162,7 +212,7 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 		mergeCostModelStat(dstStat, stat)
 	}
 	for project, stat := range src.CostProjectStats {
-		dstStat := ensureCostProjectStat(dst, project)
+		dstStat := ensureCostProjectStat(dst, canonicalProjectName(project))
 		mergeCostProjectStat(dstStat, stat)
 	}
 	for sessionID, stat := range src.CostSessionStats {
@@ -282,9 +332,12 @@ func mergeProjectAggregate(dst, src *ProjectAggregate) {
 	for name, count := range src.SkillListingStats {
 		dst.SkillListingStats[name] += count
 	}
-	for key, stat := range src.SkillProjectStats {
+	for _, stat := range src.SkillProjectStats {
+		project := canonicalProjectName(stat.Project)
+		key := stat.SkillName + "\x00" + project
 		if dst.SkillProjectStats[key] == nil {
 			statCopy := *stat
+			statCopy.Project = project
 			dst.SkillProjectStats[key] = &statCopy
 			continue
 		}
@@ -621,10 +674,18 @@ func aggregateToProjectFileAggregateWithDaily(src *ProjectAggregate, includeDail
 		WeekdayData:             src.WeekdayData,
 		DailyActivity:           copyIntMap(src.DailyActivity),
 		DailySessions:           boolSetMapToSlices(src.DailySessions),
+		DailySessionSpans:       copySessionSpans(src.DailySessionSpans),
 		DailyProjectCounts:      copyNestedIntMap(src.DailyProjectCounts),
 		DailyModelCounts:        copyNestedIntMap(src.DailyModelCounts),
+		DailyVersionCounts:      copyNestedIntMap(src.DailyVersionCounts),
+		DailyStopReasonCounts:   copyNestedIntMap(src.DailyStopReasonCounts),
+		DailyOutputTokens:       copyIntMap(src.DailyOutputTokens),
+		DailyAssistantMessages:  copyIntMap(src.DailyAssistantMessages),
 		DailyModelTokens:        copyNestedIntMap(src.DailyModelTokens),
+		DailyProjectTokens:      copyNestedIntMap(src.DailyProjectTokens),
 		DailyHourlyCounts:       copyDailyHourlyCounts(src.DailyHourlyCounts),
+		DailyHalfHourCounts:     copyDailyHalfHourCounts(src.DailyHalfHourCounts),
+		DailyBounds:             copyDayBounds(src.DailyBounds),
 		DailyRuntime:            make(map[string]ProjectFileAggregate),
 		DailyProjectRuntime:     make(map[string]map[string]ProjectFileAggregate),
 		DailySessionRuntime:     make(map[string]map[string]ProjectFileAggregate),
@@ -682,6 +743,10 @@ func aggregateToProjectFileAggregateWithDaily(src *ProjectAggregate, includeDail
 	if !includeDaily {
 		out.DailyRuntime = nil
 	}
+	if src.ParseStats != (ParseStats{}) {
+		parseStats := src.ParseStats
+		out.ParseStats = &parseStats
+	}
 	for key, stat := range src.ProjectStats {
 		out.ProjectStats[key] = *stat
 	}
@@ -837,10 +902,25 @@ func projectFileAggregateToAggregate(src ProjectFileAggregate) *ProjectAggregate
 	out.WeekdayData = src.WeekdayData
 	out.DailyActivity = copyIntMap(src.DailyActivity)
 	out.DailySessions = slicesMapToBoolSets(src.DailySessions)
+	if spans := copySessionSpans(src.DailySessionSpans); spans != nil {
+		out.DailySessionSpans = spans
+	}
 	out.DailyProjectCounts = copyNestedIntMap(src.DailyProjectCounts)
 	out.DailyModelCounts = copyNestedIntMap(src.DailyModelCounts)
+	out.DailyVersionCounts = copyNestedIntMap(src.DailyVersionCounts)
+	out.DailyStopReasonCounts = copyNestedIntMap(src.DailyStopReasonCounts)
+	out.DailyOutputTokens = copyIntMap(src.DailyOutputTokens)
+	out.DailyAssistantMessages = copyIntMap(src.DailyAssistantMessages)
 	out.DailyModelTokens = copyNestedIntMap(src.DailyModelTokens)
+	out.DailyProjectTokens = copyNestedIntMap(src.DailyProjectTokens)
+	if src.ParseStats != nil {
+		out.ParseStats = *src.ParseStats
+	}
 	out.DailyHourlyCounts = copyDailyHourlyCounts(src.DailyHourlyCounts)
+	out.DailyHalfHourCounts = copyDailyHalfHourCounts(src.DailyHalfHourCounts)
+	if bounds := copyDayBounds(src.DailyBounds); bounds != nil {
+		out.DailyBounds = bounds
+	}
 	for date, runtimeSnapshot := range src.DailyRuntime {
 		out.DailyRuntime[date] = projectFileAggregateToAggregate(runtimeSnapshot)
 	}
@@ -1105,6 +1185,17 @@ func copyDailyHourlyCounts(src map[string][24]int) map[string][24]int {
 	return out
 }
 
+func copyDailyHalfHourCounts(src map[string][48]int) map[string][48]int {
+	if len(src) == 0 {
+		return nil
+	}
+	out := make(map[string][48]int, len(src))
+	for key, value := range src {
+		out[key] = value
+	}
+	return out
+}
+
 func boolSetMapToSlices(src map[string]map[string]bool) map[string][]string {
 	if len(src) == 0 {
 		return nil
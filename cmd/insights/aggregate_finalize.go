@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 )
 
 // finalize 生成输出格式的数据
@@ -14,9 +15,20 @@ func (agg *ProjectAggregate) finalize() {
 	for _, proj := range agg.ProjectStats {
 		agg.Projects = append(agg.Projects, *proj)
 	}
-	sort.Slice(agg.Projects, func(i, j int) bool {
-		return agg.Projects[i].MessageCount > agg.Projects[j].MessageCount
-	})
+	applyProjectActivity(agg.Projects, agg.DailyProjectCounts)
+	projectModelCounts := make(map[string]map[string]int)
+	for _, projects := range agg.DailyProjectRuntime {
+		for project, runtimeAgg := range projects {
+			if runtimeAgg == nil {
+				continue
+			}
+			for model, usage := range runtimeAgg.ModelUsage {
+				addProjectModelCount(projectModelCounts, project, model, usage.Count)
+			}
+		}
+	}
+	applyProjectPrimaryModel(agg.Projects, projectModelCounts)
+	sortProjectStats(agg.Projects)
 
 	// 2. 转换星期统计
 	weekdayData := make([]WeekdayItem, 7)
@@ -35,6 +47,10 @@ func (agg *ProjectAggregate) finalize() {
 			Date:         date,
 			MessageCount: agg.DailyActivity[date],
 		}
+		if span, ok := agg.DailyBounds[date]; ok {
+			agg.DailyActivityList[i].FirstTime = formatSecondOfDay(span[0])
+			agg.DailyActivityList[i].LastTime = formatSecondOfDay(span[1])
+		}
 	}
 
 	// 4. 转换小时数据
@@ -53,9 +69,7 @@ func (agg *ProjectAggregate) finalize() {
 	for _, model := range agg.ModelUsage {
 		agg.ModelUsageList = append(agg.ModelUsageList, *model)
 	}
-	sort.Slice(agg.ModelUsageList, func(i, j int) bool {
-		return agg.ModelUsageList[i].Count > agg.ModelUsageList[j].Count
-	})
+	sortModelUsage(agg.ModelUsageList)
 
 	// 6. 转换工具分析
 	agg.finalizeToolAnalysis()
@@ -103,7 +117,90 @@ func (agg *ProjectAggregate) finalize() {
 		WorkHoursRatio: workRatio,
 		PeakHour:       peakHour,
 		PeakHourCount:  peakCount,
+		PeakHours:      peakHours(agg.HourlyData, peakCount),
+	}
+}
+
+// peakHours 返回次数等于峰值的全部小时；峰值为 0（没有数据）时返回 nil
+func peakHours(hourly []HourlyItem, peakCount int) []int {
+	if peakCount <= 0 {
+		return nil
+	}
+	var hours []int
+	for _, item := range hourly {
+		if item.Count == peakCount {
+			hours = append(hours, item.Hour)
+		}
+	}
+	return hours
+}
+
+// applyProjectActivity 按 date→project→count 计算每个项目的活跃天数、最长连续活跃天数
+// 以及首次/最近活动日期
+func applyProjectActivity(projects []ProjectStatItem, dailyProjectCounts map[string]map[string]int) {
+	projectDates := make(map[string][]string)
+	for date, counts := range dailyProjectCounts {
+		for project, count := range counts {
+			if count > 0 {
+				projectDates[project] = append(projectDates[project], date)
+			}
+		}
+	}
+	for i := range projects {
+		dates := projectDates[projects[i].Project]
+		projects[i].ActiveDays = len(dates)
+		projects[i].LongestStreak = longestDateStreak(dates)
+		projects[i].FirstSeen, projects[i].LastSeen = "", ""
+		if len(dates) > 0 {
+			sort.Strings(dates)
+			projects[i].FirstSeen = dates[0]
+			projects[i].LastSeen = dates[len(dates)-1]
+		}
+	}
+}
+
+// addProjectModelCount 累加 project→model 的消息数
+func addProjectModelCount(counts map[string]map[string]int, project, model string, n int) {
+	if counts[project] == nil {
+		counts[project] = make(map[string]int)
+	}
+	counts[project][model] += n
+}
+
+// applyProjectPrimaryModel 为每个项目选出消息数最多的模型，同数时按模型名字典序取前者
+func applyProjectPrimaryModel(projects []ProjectStatItem, projectModelCounts map[string]map[string]int) {
+	for i := range projects {
+		best, bestCount := "", 0
+		for model, count := range projectModelCounts[projects[i].Project] {
+			if count > bestCount || (count == bestCount && count > 0 && model < best) {
+				best, bestCount = model, count
+			}
+		}
+		projects[i].PrimaryModel = best
+	}
+}
+
+// longestDateStreak 返回 "2006-01-02" 日期列表中最长的连续天数
+func longestDateStreak(dates []string) int {
+	if len(dates) == 0 {
+		return 0
+	}
+	sort.Strings(dates)
+	longest, current := 1, 1
+	prev, prevErr := time.Parse("2006-01-02", dates[0])
+	for _, date := range dates[1:] {
+		day, err := time.Parse("2006-01-02", date)
+		if err == nil && prevErr == nil && day.Sub(prev) == 24*time.Hour {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+		prev, prevErr = day, err
 	}
+	return longest
 }
 
 func (agg *ProjectAggregate) finalizeToolAnalysis() {
@@ -9,23 +9,50 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // APIResponse API 响应结构
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	// APIVersion 响应结构版本，由 sendJSON 统一填写；响应结构出现不兼容变化时递增
+	APIVersion string `json:"api_version"`
+	// LastUpdate 数据新鲜度（RFC3339）：已加载缓存时为缓存构建时间，否则为本次实时解析的时间
+	LastUpdate string      `json:"last_update,omitempty"`
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
 }
 
-// globalCache 全局缓存实例
-var globalCache *CacheFile
+// apiVersion 当前 API 响应结构版本
+const apiVersion = "1"
+
+// dataLastUpdate 响应中 last_update 的取值
+func dataLastUpdate() string {
+	if cache := loadGlobalCache(); cache != nil && !cache.LastUpdate.IsZero() {
+		return cache.LastUpdate.Format(time.RFC3339)
+	}
+	return time.Now().Format(time.RFC3339)
+}
+
+// globalCache 全局缓存实例；--watch 与 /api/reload 会在处理请求期间替换，读写都经 loadGlobalCache/storeGlobalCache
+var globalCache atomic.Pointer[CacheFile]
+
+// loadGlobalCache 当前缓存，尚未加载时为 nil；同一次请求内应只取一次，避免前后读到不同的缓存
+func loadGlobalCache() *CacheFile {
+	return globalCache.Load()
+}
+
+// storeGlobalCache 替换当前缓存
+func storeGlobalCache(cache *CacheFile) {
+	globalCache.Store(cache)
+}
 
 // DashboardData Dashboard 数据
 type DashboardData struct {
 	Timestamp        string                  `json:"timestamp"`
 	TimeRange        TimeRangeInfo           `json:"time_range"`
+	Summary          *DashboardSummary       `json:"summary,omitempty"`
 	Commands         []CommandStats          `json:"commands"`
 	HourlyCounts     map[string]int          `json:"hourly_counts"`
 	DailyTrend       DailyTrendData          `json:"daily_trend"`
@@ -34,6 +61,7 @@ type DashboardData struct {
 	ProjectStats     *ProjectStatsData       `json:"project_stats,omitempty"`
 	WeekdayStats     *WeekdayStats           `json:"weekday_stats,omitempty"`
 	ModelUsage       []ModelUsageItem        `json:"model_usage,omitempty"`
+	ModelWeight      string                  `json:"model_weight,omitempty"`
 	WorkHoursStats   *WorkHoursStats         `json:"work_hours_stats,omitempty"`
 	ToolAnalysis     *ToolAnalysisData       `json:"tool_analysis,omitempty"`
 	SkillAnalysis    *SkillAnalysisData      `json:"skill_analysis,omitempty"`
@@ -47,6 +75,21 @@ type DashboardData struct {
 	TaskPlanAnalysis *TaskPlanAnalysisData   `json:"task_plan_analysis,omitempty"`
 	ToolPerformance  *ToolPerformanceData    `json:"tool_performance,omitempty"`
 	Coverage         map[string]CoverageInfo `json:"coverage,omitempty"`
+	DataQuality      *ParseStats             `json:"data_quality,omitempty"`
+	Samples          []MetricSamples         `json:"samples,omitempty"`
+	DailyIntensity   []DailyIntensityItem    `json:"daily_intensity,omitempty"`
+	VersionStats     []VersionStat           `json:"version_stats,omitempty"`
+	DailyModelMix    *DailyModelMix          `json:"daily_model_mix,omitempty"`
+	StopReasonStats  []StopReasonStat        `json:"stop_reason_stats,omitempty"`
+	// DailyOutputTokens 每日 output token 合计与每条消息平均值
+	DailyOutputTokens []DailyOutputTokenItem `json:"daily_output_tokens,omitempty"`
+	// DailyActiveHours 每日首条与末条消息的时刻
+	DailyActiveHours []DailyActiveHoursItem `json:"daily_active_hours,omitempty"`
+	PasteStats       *PasteStats            `json:"paste_stats,omitempty"`
+	// Facets 去重的模型、命令与工具名，仅 facets=1 时返回
+	Facets *DashboardFacets `json:"facets,omitempty"`
+	// ParseTimings 实时解析各数据源耗时（秒），仅 debug=1 时返回
+	ParseTimings map[string]float64 `json:"parse_timings,omitempty"`
 }
 
 type CoverageInfo struct {
@@ -59,6 +102,64 @@ type TimeRangeInfo struct {
 	Preset string `json:"preset"`
 	Start  string `json:"start,omitempty"`
 	End    string `json:"end,omitempty"`
+	// Since 增量模式下的起点（缓存 LastUpdate，RFC3339）
+	Since string `json:"since,omitempty"`
+	// StartLabel/EndLabel 仅在 relative_dates=true 时填充相对时间标签
+	StartLabel string `json:"start_label,omitempty"`
+	EndLabel   string `json:"end_label,omitempty"`
+	// Clamped clamp=1 时 start/end 被收窄到有数据的日期范围
+	Clamped bool `json:"clamped,omitempty"`
+}
+
+// fillOpenEnds 时间范围未限定的一端（如 preset=all）用数据中实际出现的最早/最晚日期补全，
+// 保证响应总能给出具体的 start/end；没有数据时保持为空
+func (info *TimeRangeInfo) fillOpenEnds(dates []string) {
+	if len(dates) == 0 {
+		return
+	}
+	first, last := dates[0], dates[0]
+	for _, date := range dates[1:] {
+		if date < first {
+			first = date
+		}
+		if date > last {
+			last = date
+		}
+	}
+	if info.Start == "" {
+		info.Start = first
+	}
+	if info.End == "" {
+		info.End = last
+	}
+}
+
+// clampToData 把 start/end 收窄到 trend 中有消息的最早/最晚日期（clamp=1），
+// 避免预设范围早于数据起点时图表出现大段空白；没有数据时保持不变
+func (info *TimeRangeInfo) clampToData(trend DailyTrendData) {
+	first, last := "", ""
+	for i, date := range trend.Dates {
+		if i >= len(trend.Counts) || trend.Counts[i] <= 0 {
+			continue
+		}
+		if first == "" || date < first {
+			first = date
+		}
+		if date > last {
+			last = date
+		}
+	}
+	if first == "" {
+		return
+	}
+	if info.Start == "" || info.Start < first {
+		info.Start = first
+		info.Clamped = true
+	}
+	if info.End == "" || info.End > last {
+		info.End = last
+		info.Clamped = true
+	}
 }
 
 // DailyTrendData 每日趋势数据
@@ -67,6 +168,45 @@ type DailyTrendData struct {
 	Counts []int    `json:"counts"`
 }
 
+// 突发日判定：最忙一小时占当天消息数的比例超过阈值，且当天消息数不少于 burstyMinMessages
+// （消息极少的日子比例天然偏高，不标记）
+const (
+	burstyIntensityThreshold = 0.5
+	burstyMinMessages        = 5
+)
+
+// DailyIntensityItem 单日活动集中度：intensity 为最忙一小时的消息占比（1.0 表示全部集中在同一小时）
+type DailyIntensityItem struct {
+	Date          string  `json:"date"`
+	Messages      int     `json:"messages"`
+	PeakHour      int     `json:"peak_hour"`
+	PeakHourCount int     `json:"peak_hour_count"`
+	Intensity     float64 `json:"intensity"`
+	Bursty        bool    `json:"bursty"`
+}
+
+// buildDailyIntensity 由每日 24 小时消息分布计算集中度，按日期升序；没有消息的日期跳过
+func buildDailyIntensity(dailyHourly map[string][24]int) []DailyIntensityItem {
+	items := make([]DailyIntensityItem, 0, len(dailyHourly))
+	for date, hours := range dailyHourly {
+		item := DailyIntensityItem{Date: date}
+		for hour, count := range hours {
+			item.Messages += count
+			if count > item.PeakHourCount {
+				item.PeakHour, item.PeakHourCount = hour, count
+			}
+		}
+		if item.Messages == 0 {
+			continue
+		}
+		item.Intensity = float64(item.PeakHourCount) / float64(item.Messages)
+		item.Bursty = item.Messages >= burstyMinMessages && item.Intensity > burstyIntensityThreshold
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Date < items[j].Date })
+	return items
+}
+
 // handleDataAPI 处理数据 API 请求
 func handleDataAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -89,9 +229,11 @@ func handleDataAPI(w http.ResponseWriter, r *http.Request) {
 	resultCh := make(chan result, 1)
 
 	go func() {
+		buildStart := time.Now()
 		data, source, err := buildDashboardDataWithFilter(filter)
 
 		if err == nil {
+			metrics.observeData(source, time.Since(buildStart))
 			maybeValidateDashboardData(source, data)
 		}
 
@@ -122,11 +264,12 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 
 	type historyResult struct {
 		commands []CommandStats
+		pastes   *PasteStats
 	}
 	historyCh := make(chan historyResult, 1)
 	go func() {
-		cmdStats, _, _ := safeParseHistoryConcurrent(tf)
-		historyCh <- historyResult{commands: cmdStats}
+		cmdStats, pastes := safeParseHistoryWithPastes(tf)
+		historyCh <- historyResult{commands: cmdStats, pastes: pastes}
 	}()
 
 	// 确定查询时间范围
@@ -140,7 +283,11 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 
 	// 从缓存查询时间范围数据
 	queryStartedAt := time.Now()
-	cached := globalCache.QueryByTimeRange(start, end)
+	cache := loadGlobalCache()
+	if cache == nil {
+		return nil, fmt.Errorf("缓存未加载")
+	}
+	cached := cache.QueryByTimeRange(start, end)
 	queryDuration := time.Since(queryStartedAt)
 
 	// 构建 RuntimeTools（从缓存中的 RuntimeToolSignals 转换）
@@ -184,14 +331,21 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 		}
 	}
 	sortDatesAndCounts(dates, counts)
+	dailyHourly := make(map[string][24]int, len(cached.DailyStats))
+	for date, dayStats := range cached.DailyStats {
+		dailyHourly[date] = dayStats.HourlyCounts
+	}
 
 	sessionStats := &SessionStats{
-		TotalSessions:   cached.TotalSessions,
-		PeakDate:        peakDate,
-		PeakCount:       peakCount,
-		ValleyDate:      valleyDate,
-		ValleyCount:     valleyCount,
-		DailySessionMap: dailySessionMap,
+		TotalSessions:         cached.TotalSessions,
+		PeakDate:              peakDate,
+		PeakCount:             peakCount,
+		ValleyDate:            valleyDate,
+		ValleyCount:           valleyCount,
+		DailySessionMap:       dailySessionMap,
+		AvgMessagesPerSession: avgMessagesPerSession(cached.TotalMessages, cached.TotalSessions),
+		LogicalSessions:       cached.TotalLogicalSessions,
+		SessionGapMinutes:     cfg.SessionGapMinutes,
 	}
 
 	// 单次遍历 HourlyStats：同时构建 hourly_counts 和工作时段统计。
@@ -248,12 +402,47 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 		WorkHoursRatio: workRatio,
 		PeakHour:       peakHour,
 		PeakHourCount:  peakHourCount,
+		PeakHours:      peakHours(hourlyData, peakHourCount),
 	}
 
 	projects := make([]ProjectStatItem, 0, len(cached.ProjectStats))
 	for _, stats := range cached.ProjectStats {
 		projects = append(projects, *stats)
 	}
+	dailyProjectCounts := make(map[string]map[string]int, len(cached.DailyStats))
+	for date, day := range cached.DailyStats {
+		if day != nil {
+			dailyProjectCounts[date] = day.ProjectCounts
+		}
+	}
+	applyProjectActivity(projects, dailyProjectCounts)
+	dailyVersionCounts := make(map[string]map[string]int, len(cached.DailyStats))
+	dailyStopReasons := make(map[string]map[string]int, len(cached.DailyStats))
+	dailyModelCounts := make(map[string]map[string]int, len(cached.DailyStats))
+	dailyAssistantMessages := make(map[string]int, len(cached.DailyStats))
+	dailyOutputTokens := make(map[string]int, len(cached.DailyStats))
+	dailyBounds := make(map[string][2]int, len(cached.DailyStats))
+	for date, day := range cached.DailyStats {
+		if day != nil {
+			dailyAssistantMessages[date] = day.AssistantMessages
+			dailyOutputTokens[date] = day.OutputTokens
+			if day.HasBounds {
+				dailyBounds[date] = day.Bounds
+			}
+			dailyVersionCounts[date] = day.VersionCounts
+			dailyStopReasons[date] = day.StopReasons
+			dailyModelCounts[date] = day.ModelCounts
+		}
+	}
+	projectModelCounts := make(map[string]map[string]int)
+	for _, runtimeProjects := range cached.DailyProjectRuntime {
+		for project, snapshot := range runtimeProjects {
+			for model, usage := range snapshot.ModelUsage {
+				addProjectModelCount(projectModelCounts, project, model, usage.Count)
+			}
+		}
+	}
+	applyProjectPrimaryModel(projects, projectModelCounts)
 	sortProjectStats(projects)
 
 	weekdayStats := &WeekdayStats{
@@ -291,16 +480,26 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 	if tf.End != nil {
 		rangeInfo.End = tf.End.Format("2006-01-02")
 	}
+	rangeInfo.fillOpenEnds(dates)
 
-	cmdStats := (<-historyCh).commands
+	history := <-historyCh
+	cmdStats := history.commands
+	dataQuality := cached.DataQuality
 	data := &DashboardData{
-		Timestamp:    time.Now().Format("2006-01-02 15:04:05"),
-		TimeRange:    rangeInfo,
-		Commands:     cmdStats,
-		HourlyCounts: hourlyCountsMap,
-		DailyTrend:   DailyTrendData{Dates: dates, Counts: counts},
-		RuntimeTools: runtimeTools,
-		Sessions:     sessionStats,
+		Timestamp:         time.Now().Format("2006-01-02 15:04:05"),
+		TimeRange:         rangeInfo,
+		Commands:          cmdStats,
+		HourlyCounts:      hourlyCountsMap,
+		DailyTrend:        DailyTrendData{Dates: dates, Counts: counts},
+		DailyIntensity:    buildDailyIntensity(dailyHourly),
+		VersionStats:      buildVersionStats(dailyVersionCounts),
+		DailyModelMix:     buildDailyModelMix(dailyModelCounts),
+		StopReasonStats:   buildStopReasonStats(dailyStopReasons),
+		DailyOutputTokens: buildDailyOutputTokens(dailyAssistantMessages, dailyOutputTokens),
+		DailyActiveHours:  buildDailyActiveHours(dailyBounds),
+		PasteStats:        history.pastes,
+		RuntimeTools:      runtimeTools,
+		Sessions:          sessionStats,
 		ProjectStats: &ProjectStatsData{
 			Projects:      projects,
 			TotalMessages: cached.TotalMessages,
@@ -320,6 +519,7 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 		FileAnalysis:     fileAnalysis,
 		TaskPlanAnalysis: taskPlanAnalysis,
 		ToolPerformance:  toolPerformance,
+		DataQuality:      &dataQuality,
 	}
 	Debug("缓存数据组装完成",
 		"preset", preset,
@@ -335,38 +535,47 @@ func buildDataFromCache(tf TimeFilter, preset string) (*DashboardData, error) {
 func buildDataFromParsing(tf TimeFilter, preset string) (*DashboardData, error) {
 	// P1 优化: 三大数据源并行解析（history / projects / debug 独立运行）
 	var cmdStats []CommandStats
-	var hourlyCountsMap map[string]int
+	var pasteStats *PasteStats
 	var aggregate *ProjectAggregate
 	var toolStats []RuntimeToolSignal
 	var taskAnalysis *TaskAnalysisData
 
+	// 各数据源耗时（秒），每个 goroutine 只写自己的变量
+	var historySeconds, projectsSeconds, debugSeconds, tasksSeconds float64
+
 	var wg sync.WaitGroup
 	wg.Add(4)
 
 	// 1. history.jsonl 解析（独立）
 	go func() {
 		defer wg.Done()
-		var hc map[string]int
-		cmdStats, hc, _ = safeParseHistoryConcurrent(tf)
-		hourlyCountsMap = hc
+		startedAt := time.Now()
+		cmdStats, pasteStats = safeParseHistoryWithPastes(tf)
+		historySeconds = time.Since(startedAt).Seconds()
 	}()
 
 	// 2. projects/*.jsonl 解析（独立，~22s 瓶颈）
 	go func() {
 		defer wg.Done()
+		startedAt := time.Now()
 		aggregate, _ = safeParseProjectsOnce(tf)
+		projectsSeconds = time.Since(startedAt).Seconds()
 	}()
 
 	// 3. debug/*.txt 解析（独立）
 	go func() {
 		defer wg.Done()
+		startedAt := time.Now()
 		toolStats, _ = safeParseDebugLogs(tf)
+		debugSeconds = time.Since(startedAt).Seconds()
 	}()
 
 	// 4. tasks/ 目录扫描（M4: task_plan_analysis）
 	go func() {
 		defer wg.Done()
+		startedAt := time.Now()
 		taskAnalysis, _ = safeParseTasksOnce(tf)
+		tasksSeconds = time.Since(startedAt).Seconds()
 	}()
 
 	wg.Wait()
@@ -379,8 +588,31 @@ func buildDataFromParsing(tf TimeFilter, preset string) (*DashboardData, error)
 		aggregate.TaskPlanAnalysis.Tasks = *taskAnalysis
 	}
 
+	// 构建时间范围信息
+	rangeInfo := TimeRangeInfo{Preset: preset}
+	if tf.Start != nil {
+		rangeInfo.Start = tf.Start.Format("2006-01-02")
+	}
+	if tf.End != nil {
+		rangeInfo.End = tf.End.Format("2006-01-02")
+	}
+
+	data := dashboardDataFromAggregate(aggregate, cmdStats, toolStats, rangeInfo)
+	data.PasteStats = pasteStats
+	data.ParseTimings = map[string]float64{
+		"history":  historySeconds,
+		"projects": projectsSeconds,
+		"debug":    debugSeconds,
+		"tasks":    tasksSeconds,
+	}
+	return data, nil
+}
+
+// dashboardDataFromAggregate 由实时解析得到的聚合结果组装 Dashboard 响应
+func dashboardDataFromAggregate(aggregate *ProjectAggregate, cmdStats []CommandStats, toolStats []RuntimeToolSignal, rangeInfo TimeRangeInfo) *DashboardData {
 	// SessionStats 从已解析的 aggregate 中提取（P0，依赖 projects结果）
 	sessionStats, _ := extractSessionStatsFromAggregate(aggregate)
+	parseStats := aggregate.ParseStats
 
 	// 从聚合数据中提取每日活动趋势（确保非 nil）
 	dates := make([]string, 0)
@@ -389,23 +621,15 @@ func buildDataFromParsing(tf TimeFilter, preset string) (*DashboardData, error)
 		dates = append(dates, day.Date)
 		counts = append(counts, day.MessageCount)
 	}
+	rangeInfo.fillOpenEnds(dates)
 
 	// 将小时数据转换为map格式
-	hourlyCountsMap = make(map[string]int)
+	hourlyCountsMap := make(map[string]int)
 	for _, item := range aggregate.HourlyData {
 		hourKey := fmt.Sprintf("%02d", item.Hour)
 		hourlyCountsMap[hourKey] = item.Count
 	}
 
-	// 构建时间范围信息
-	rangeInfo := TimeRangeInfo{Preset: preset}
-	if tf.Start != nil {
-		rangeInfo.Start = tf.Start.Format("2006-01-02")
-	}
-	if tf.End != nil {
-		rangeInfo.End = tf.End.Format("2006-01-02")
-	}
-
 	// 构建响应数据
 	projectStatsData := &ProjectStatsData{
 		Projects:      aggregate.Projects,
@@ -418,29 +642,36 @@ func buildDataFromParsing(tf TimeFilter, preset string) (*DashboardData, error)
 	}
 
 	return &DashboardData{
-		Timestamp:        time.Now().Format("2006-01-02 15:04:05"),
-		TimeRange:        rangeInfo,
-		Commands:         cmdStats,
-		HourlyCounts:     hourlyCountsMap,
-		DailyTrend:       DailyTrendData{Dates: dates, Counts: counts},
-		RuntimeTools:     toolStats,
-		Sessions:         sessionStats,
-		ProjectStats:     projectStatsData,
-		WeekdayStats:     aggregate.WeekdayStats,
-		ModelUsage:       aggregate.ModelUsageList,
-		WorkHoursStats:   aggregate.WorkHoursStats,
-		ToolAnalysis:     aggregate.ToolAnalysis,
-		SkillAnalysis:    aggregate.SkillAnalysis,
-		EventAnalysis:    aggregate.EventAnalysis,
-		AgentAnalysis:    aggregate.AgentAnalysis,
-		CommandAnalysis:  aggregate.CommandAnalysis,
-		CostAnalysis:     aggregate.CostAnalysis,
-		FailureAnalysis:  aggregate.FailureAnalysis,
-		SessionAnalysis:  aggregate.SessionAnalysis,
-		FileAnalysis:     aggregate.FileAnalysis,
-		TaskPlanAnalysis: aggregate.TaskPlanAnalysis,
-		ToolPerformance:  aggregate.ToolPerformance,
-	}, nil
+		Timestamp:         time.Now().Format("2006-01-02 15:04:05"),
+		TimeRange:         rangeInfo,
+		Commands:          cmdStats,
+		HourlyCounts:      hourlyCountsMap,
+		DailyTrend:        DailyTrendData{Dates: dates, Counts: counts},
+		DailyIntensity:    buildDailyIntensity(aggregate.DailyHourlyCounts),
+		VersionStats:      buildVersionStats(aggregate.DailyVersionCounts),
+		DailyModelMix:     buildDailyModelMix(aggregate.DailyModelCounts),
+		StopReasonStats:   buildStopReasonStats(aggregate.DailyStopReasonCounts),
+		DailyOutputTokens: buildDailyOutputTokens(aggregate.DailyAssistantMessages, aggregate.DailyOutputTokens),
+		DailyActiveHours:  buildDailyActiveHours(aggregate.DailyBounds),
+		RuntimeTools:      toolStats,
+		Sessions:          sessionStats,
+		ProjectStats:      projectStatsData,
+		WeekdayStats:      aggregate.WeekdayStats,
+		ModelUsage:        aggregate.ModelUsageList,
+		WorkHoursStats:    aggregate.WorkHoursStats,
+		ToolAnalysis:      aggregate.ToolAnalysis,
+		SkillAnalysis:     aggregate.SkillAnalysis,
+		EventAnalysis:     aggregate.EventAnalysis,
+		AgentAnalysis:     aggregate.AgentAnalysis,
+		CommandAnalysis:   aggregate.CommandAnalysis,
+		CostAnalysis:      aggregate.CostAnalysis,
+		FailureAnalysis:   aggregate.FailureAnalysis,
+		SessionAnalysis:   aggregate.SessionAnalysis,
+		FileAnalysis:      aggregate.FileAnalysis,
+		TaskPlanAnalysis:  aggregate.TaskPlanAnalysis,
+		ToolPerformance:   aggregate.ToolPerformance,
+		DataQuality:       &parseStats,
+	}
 }
 
 func buildToolAnalysisFromCache(cache *CacheFile) *ToolAnalysisData {
@@ -488,6 +719,16 @@ func sortProjectStats(projects []ProjectStatItem) {
 	})
 }
 
+// sortProjectStatsByTokens 按 token 数排序项目统计
+func sortProjectStatsByTokens(projects []ProjectStatItem) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Tokens != projects[j].Tokens {
+			return projects[i].Tokens > projects[j].Tokens
+		}
+		return projects[i].Project < projects[j].Project
+	})
+}
+
 func sortModelUsage(models []ModelUsageItem) {
 	sort.SliceStable(models, func(i, j int) bool {
 		if models[i].Count != models[j].Count {
@@ -497,6 +738,107 @@ func sortModelUsage(models []ModelUsageItem) {
 	})
 }
 
+// 模型占比的权重口径：请求次数 / token / 估算费用
+const (
+	ModelWeightCount  = "count"
+	ModelWeightTokens = "tokens"
+	ModelWeightCost   = "cost"
+)
+
+func normalizeModelWeight(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", ModelWeightCount:
+		return ModelWeightCount, nil
+	case ModelWeightTokens:
+		return ModelWeightTokens, nil
+	case ModelWeightCost:
+		return ModelWeightCost, nil
+	default:
+		return "", fmt.Errorf("无效的 model_weight: %s（可选 count|tokens|cost）", value)
+	}
+}
+
+// unknownModelName 缺少 model 字段的 assistant 消息在模型用量中的归类名
+const unknownModelName = "(unknown model)"
+
+// addUnknownModelBucket 把 assistant 消息数与各模型请求数之差计入 "(unknown model)"。
+// 旧数据或出错的 assistant 记录可能没有 model 字段，会计入消息数却不计入任何模型。
+// assistant 消息数取自 daily_output_tokens（不受 count_mode 影响），count_mode=user/all 时 user 记录不会被算作模型调用
+func addUnknownModelBucket(data *DashboardData) {
+	if data == nil {
+		return
+	}
+	assistantMessages := 0
+	for _, day := range data.DailyOutputTokens {
+		assistantMessages += day.Messages
+	}
+	counted := 0
+	for _, item := range data.ModelUsage {
+		if item.Model == unknownModelName {
+			return
+		}
+		counted += item.Count
+	}
+	if missing := assistantMessages - counted; missing > 0 {
+		data.ModelUsage = append(data.ModelUsage, ModelUsageItem{Model: unknownModelName, Count: missing})
+		sortModelUsage(data.ModelUsage)
+	}
+}
+
+// applyModelWeight 用 cost_analysis 的模型费用回填 ModelUsage，并按权重口径重新排序。
+func applyModelWeight(data *DashboardData, weight string) {
+	if data == nil {
+		return
+	}
+	if weight == "" {
+		weight = ModelWeightCount
+	}
+	if data.CostAnalysis != nil {
+		costs := make(map[string]float64, len(data.CostAnalysis.ByModel))
+		for _, stat := range data.CostAnalysis.ByModel {
+			costs[stat.Model] = stat.CostCNY
+		}
+		for i := range data.ModelUsage {
+			data.ModelUsage[i].CostCNY = costs[data.ModelUsage[i].Model]
+		}
+	}
+	sortModelUsageByWeight(data.ModelUsage, weight)
+	data.ModelWeight = weight
+}
+
+func sortModelUsageByWeight(models []ModelUsageItem, weight string) {
+	switch weight {
+	case ModelWeightTokens:
+		sort.SliceStable(models, func(i, j int) bool {
+			if models[i].Tokens != models[j].Tokens {
+				return models[i].Tokens > models[j].Tokens
+			}
+			return models[i].Model < models[j].Model
+		})
+	case ModelWeightCost:
+		sort.SliceStable(models, func(i, j int) bool {
+			if models[i].CostCNY != models[j].CostCNY {
+				return models[i].CostCNY > models[j].CostCNY
+			}
+			return models[i].Model < models[j].Model
+		})
+	default:
+		sortModelUsage(models)
+	}
+}
+
+func sortCommandStats(commands []CommandStats) {
+	sort.SliceStable(commands, func(i, j int) bool {
+		if commands[i].Count != commands[j].Count {
+			return commands[i].Count > commands[j].Count
+		}
+		if commands[i].Command != commands[j].Command {
+			return commands[i].Command < commands[j].Command
+		}
+		return commands[i].SubCommand < commands[j].SubCommand
+	})
+}
+
 func sortRuntimeToolSignals(tools []RuntimeToolSignal) {
 	sort.SliceStable(tools, func(i, j int) bool {
 		if tools[i].Count != tools[j].Count {
@@ -536,10 +878,26 @@ func dashboardValidationEnabled() bool {
 }
 
 func buildDashboardData(tf TimeFilter, preset string) (*DashboardData, string, error) {
-	if globalCache != nil {
+	data, source, err := buildDashboardDataFromSource(tf, preset)
+	if err == nil && cfg.UnknownModelBucket {
+		addUnknownModelBucket(data)
+	}
+	if err == nil {
+		data.Summary = buildDashboardSummary(data)
+	}
+	return data, source, err
+}
+
+// buildDashboardDataFromSource 优先读缓存，失败时降级到实时解析。
+// 缓存不区分 userType 且按天聚合，按 userType 或精确时间过滤时直接实时解析
+func buildDashboardDataFromSource(tf TimeFilter, preset string) (*DashboardData, string, error) {
+	if loadGlobalCache() != nil && !tf.bypassesCache() {
 		if err := refreshGlobalCacheIfRulesChanged(); err != nil {
 			Warn("Bash 规则刷新失败，继续尝试现有缓存", "error", err.Error())
 		}
+		if err := refreshGlobalCacheIfTooOld(); err != nil {
+			Warn("超龄缓存刷新失败，继续使用现有缓存", "error", err.Error())
+		}
 		data, err := buildDataFromCache(tf, preset)
 		if err == nil {
 			return data, "cache", nil
@@ -555,25 +913,40 @@ func buildDashboardData(tf TimeFilter, preset string) (*DashboardData, string, e
 }
 
 func refreshGlobalCacheIfRulesChanged() error {
-	if globalCache == nil {
+	cache := loadGlobalCache()
+	if cache == nil {
 		return nil
 	}
 	rulesHash, err := currentBashRulesHash()
 	if err != nil {
 		return err
 	}
-	if globalCache.BashRulesHash == rulesHash {
+	if cache.BashRulesHash == rulesHash {
 		return nil
 	}
 	Info("Bash 命令规则已变更，刷新缓存")
 	return refreshGlobalCache(false)
 }
 
-// sendJSON 发送 JSON 响应
+// sendJSON 发送 JSON 响应；APIResponse 与交互式响应统一附带 api_version 和 last_update
 func sendJSON(w http.ResponseWriter, v interface{}) error {
+	switch resp := v.(type) {
+	case APIResponse:
+		v = stampAPIResponse(resp)
+	case interactiveAPIResponse:
+		resp.APIVersion, resp.LastUpdate = apiVersion, dataLastUpdate()
+		v = resp
+	}
 	return json.NewEncoder(w).Encode(v)
 }
 
+// stampAPIResponse 填写版本与数据新鲜度（WebSocket 推送等不经过 sendJSON 的出口也需调用）
+func stampAPIResponse(resp APIResponse) APIResponse {
+	resp.APIVersion = apiVersion
+	resp.LastUpdate = dataLastUpdate()
+	return resp
+}
+
 // sendError 发送错误响应
 func sendError(w http.ResponseWriter, message string) {
 	w.WriteHeader(http.StatusBadRequest)
@@ -640,6 +1013,26 @@ func safeParseHistoryConcurrent(tf TimeFilter) ([]CommandStats, map[string]int,
 	return cmdStats, hourlyCounts, nil
 }
 
+// safeParseHistoryWithPastes 安全解析 history 的命令与粘贴统计（容错包装）
+func safeParseHistoryWithPastes(tf TimeFilter) ([]CommandStats, *PasteStats) {
+	cmdStats, _, pastes, err := ParseHistoryConcurrentWithPastes(tf)
+	if err != nil {
+		Warn("ParseHistoryConcurrent 失败，使用空结果", "error", err.Error())
+		return []CommandStats{}, &PasteStats{}
+	}
+	return cmdStats, &pastes
+}
+
+// safeParseHistoryCommandArgs 安全解析按参数拆分的命令统计（容错包装）
+func safeParseHistoryCommandArgs(tf TimeFilter) ([]CommandStats, map[string]int, error) {
+	cmdStats, hourlyCounts, err := ParseHistoryConcurrentWithArgs(tf)
+	if err != nil {
+		Warn("ParseHistoryConcurrentWithArgs 失败，使用空结果", "error", err.Error())
+		return []CommandStats{}, make(map[string]int), nil
+	}
+	return cmdStats, hourlyCounts, nil
+}
+
 // safeParseProjectsOnce 安全解析项目数据（容错包装）
 func safeParseProjectsOnce(tf TimeFilter) (*ProjectAggregate, error) {
 	agg, err := ParseProjectsConcurrentOnce(tf)
@@ -694,14 +1087,29 @@ func extractSessionStatsFromAggregate(agg *ProjectAggregate) (*SessionStats, err
 	}
 
 	dailyMap := make(map[string]int)
-	totalSessions := 0
+	totalSessions, totalMessages, logicalSessions := 0, 0, 0
+	short := shortSessions(agg.DailySessionSpans, cfg.MinSessionMessages)
 	peakDate, peakCount := "", 0
 	valleyDate, valleyCount := "", 0
 
 	for date, sessions := range agg.DailySessions {
 		count := len(sessions)
+		for sessionID := range sessions {
+			if short[sessionID] {
+				count--
+			}
+		}
 		dailyMap[date] = count
 		totalSessions += count
+		totalMessages += agg.DailyActivity[date]
+		if spans := agg.DailySessionSpans[date]; len(spans) > 0 {
+			logicalSessions += countLogicalSessions(withoutSessions(spans, short))
+		} else {
+			logicalSessions += count
+		}
+		if count == 0 {
+			continue
+		}
 		if count > peakCount {
 			peakCount = count
 			peakDate = date
@@ -713,11 +1121,22 @@ func extractSessionStatsFromAggregate(agg *ProjectAggregate) (*SessionStats, err
 	}
 
 	return &SessionStats{
-		TotalSessions:   totalSessions,
-		PeakDate:        peakDate,
-		PeakCount:       peakCount,
-		ValleyDate:      valleyDate,
-		ValleyCount:     valleyCount,
-		DailySessionMap: dailyMap,
+		TotalSessions:         totalSessions,
+		PeakDate:              peakDate,
+		PeakCount:             peakCount,
+		ValleyDate:            valleyDate,
+		ValleyCount:           valleyCount,
+		DailySessionMap:       dailyMap,
+		AvgMessagesPerSession: avgMessagesPerSession(totalMessages, totalSessions),
+		LogicalSessions:       logicalSessions,
+		SessionGapMinutes:     cfg.SessionGapMinutes,
 	}, nil
 }
+
+// avgMessagesPerSession 平均每会话消息数，sessions 为 0 时返回 0
+func avgMessagesPerSession(messages, sessions int) float64 {
+	if sessions <= 0 {
+		return 0
+	}
+	return float64(messages) / float64(sessions)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 增量模式（/api/data?delta=1）：只返回缓存 LastUpdate 之后新增的记录聚合，
+// 已持有全量数据的客户端可直接追加，无需重新拉取整个时间范围。
+
+// buildDeltaDashboardData 只解析 mtime 晚于缓存 LastUpdate 的数据文件，且只统计该时间点之后的记录
+func buildDeltaDashboardData(cache *CacheFile) (*DashboardData, error) {
+	if cache == nil {
+		return nil, fmt.Errorf("缓存未加载，无法计算增量数据")
+	}
+	since := cache.LastUpdate
+	tf := TimeFilter{Start: &since}
+
+	aggregate, err := parseProjectFilesModifiedSince(cfg.DataDir, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmdStats []CommandStats
+	var historyPaths []string
+	for _, path := range historyFilePaths() {
+		if info, err := statData(path); err == nil && info.ModTime().After(since) {
+			historyPaths = append(historyPaths, path)
+		}
+	}
+	if len(historyPaths) > 0 {
+		cmdStats, _, err = parseHistoryFiles(historyPaths, tf, false)
+		if err != nil {
+			return nil, err
+		}
+		sortCommandStats(cmdStats)
+	}
+
+	rangeInfo := TimeRangeInfo{
+		Preset: "delta",
+		Since:  since.Format(time.RFC3339),
+	}
+	return dashboardDataFromAggregate(aggregate, cmdStats, nil, rangeInfo), nil
+}
+
+// parseProjectFilesModifiedSince 解析 projects/ 下 mtime 晚于 since 的 JSONL，只保留 since 之后的记录
+func parseProjectFilesModifiedSince(dataDir string, since time.Time) (*ProjectAggregate, error) {
+	files, err := listProjectJSONLFileInfos(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	tf := TimeFilter{Start: &since}
+	aggregate := newProjectAggregate()
+	for _, info := range files {
+		if info.ModTime <= since.UnixNano() {
+			continue
+		}
+		parseProjectFileAggregate(info.AbsPath, tf, aggregate)
+	}
+	aggregate.finalize()
+	return aggregate, nil
+}
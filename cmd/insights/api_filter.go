@@ -7,11 +7,57 @@ import (
 )
 
 func buildDashboardDataWithFilter(filter AnalysisFilter) (*DashboardData, string, error) {
-	data, source, err := buildDashboardData(filter.TimeFilter, filter.Preset)
+	var data *DashboardData
+	var source string
+	var err error
+	if filter.Delta {
+		data, err = buildDeltaDashboardData(loadGlobalCache())
+		source = "delta"
+	} else {
+		data, source, err = dashboardResultMemo.get(dashboardMemoKey(filter), cfg.ResultTTL, func() (*DashboardData, string, error) {
+			return buildDashboardData(filter.TimeFilter, filter.Preset)
+		})
+	}
 	if err != nil {
 		return nil, source, err
 	}
+	if !filter.Debug {
+		data.ParseTimings = nil
+	}
 	applyDashboardFilter(data, filter)
+	if len(filter.ExcludeModels) > 0 {
+		excludeModels(data, newModelExcluder(filter.ExcludeModels))
+	}
+	if filter.MinTokens > 0 {
+		dropLowTokenModels(data, filter.MinTokens)
+	}
+	applyModelWeight(data, filter.ModelWeight)
+	if filter.MinCount > 1 {
+		collapseRareEntries(data, filter.MinCount)
+	}
+	data.Summary = buildDashboardSummary(data)
+	if filter.Facets {
+		data.Facets = buildDashboardFacets(data)
+	}
+	if filter.RecordSamples > 0 {
+		samples, err := collectRecordSamples(filter)
+		if err != nil {
+			return nil, source, err
+		}
+		data.Samples = samples
+	}
+	if filter.Clamp {
+		data.TimeRange.clampToData(data.DailyTrend)
+	}
+	if filter.RelativeDates {
+		now := time.Now()
+		if data.TimeRange.Start != "" {
+			data.TimeRange.StartLabel = formatRelativeDate(data.TimeRange.Start, now)
+		}
+		if data.TimeRange.End != "" {
+			data.TimeRange.EndLabel = formatRelativeDate(data.TimeRange.End, now)
+		}
+	}
 	return data, source, nil
 }
 
@@ -222,12 +268,13 @@ func applyDimensionTimeSeries(data *DashboardData, filter AnalysisFilter) {
 	for i := range weekdayStats.WeekdayData {
 		weekdayStats.WeekdayData[i] = WeekdayItem{Weekday: i, WeekdayName: weekdayName(i)}
 	}
-	if globalCache == nil {
+	cache := loadGlobalCache()
+	if cache == nil {
 		clearUnscopedTimeSeries(data)
 		return
 	}
 	for _, date := range dates {
-		day := globalCache.DailyStats[date]
+		day := cache.DailyStats[date]
 		count := 0
 		if day != nil {
 			if filter.Project != "" {
@@ -245,6 +292,15 @@ func applyDimensionTimeSeries(data *DashboardData, filter AnalysisFilter) {
 	data.DailyTrend = DailyTrendData{Dates: dates, Counts: counts}
 	data.WeekdayStats = weekdayStats
 	data.HourlyCounts = map[string]int{}
+	data.DailyIntensity = nil
+	data.VersionStats = nil
+	// 仅模型过滤时每日模型构成仍然精确，由 filterModels 裁剪序列
+	if filter.Project != "" {
+		data.DailyModelMix = nil
+	}
+	data.StopReasonStats = nil
+	data.DailyOutputTokens = nil
+	data.DailyActiveHours = nil
 	data.WorkHoursStats = nil
 	if data.Sessions != nil {
 		data.Sessions.PeakDate = ""
@@ -256,7 +312,8 @@ func applyDimensionTimeSeries(data *DashboardData, filter AnalysisFilter) {
 }
 
 func applyRuntimeDailyTrend(data *DashboardData, filter AnalysisFilter) bool {
-	if globalCache == nil || (len(globalCache.DailyRuntime) == 0 && len(globalCache.DailyProjectRuntime) == 0 && len(globalCache.DailySessionRuntime) == 0) {
+	cache := loadGlobalCache()
+	if cache == nil || (len(cache.DailyRuntime) == 0 && len(cache.DailyProjectRuntime) == 0 && len(cache.DailySessionRuntime) == 0) {
 		return false
 	}
 	if filter.Family != "" || (filter.Tool == "" && filter.Reason == "" && filter.Category == "" && filter.Model == "") {
@@ -264,7 +321,7 @@ func applyRuntimeDailyTrend(data *DashboardData, filter AnalysisFilter) bool {
 	}
 	dates := append([]string(nil), data.DailyTrend.Dates...)
 	if len(dates) == 0 {
-		for date := range globalCache.DailyStats {
+		for date := range cache.DailyStats {
 			dates = append(dates, date)
 		}
 		sort.Strings(dates)
@@ -275,7 +332,7 @@ func applyRuntimeDailyTrend(data *DashboardData, filter AnalysisFilter) bool {
 		weekdayStats.WeekdayData[i] = WeekdayItem{Weekday: i, WeekdayName: weekdayName(i)}
 	}
 	for _, date := range dates {
-		count := dailyRuntimeCountForDate(date, filter)
+		count := dailyRuntimeCountForDate(cache, date, filter)
 		counts = append(counts, count)
 		if parsed, err := parseDateOnly(date); err == nil {
 			weekday := (int(parsed.Weekday()) + 6) % 7
@@ -283,7 +340,14 @@ func applyRuntimeDailyTrend(data *DashboardData, filter AnalysisFilter) bool {
 		}
 	}
 	data.Commands = nil
+	data.PasteStats = nil
 	data.HourlyCounts = map[string]int{}
+	data.DailyIntensity = nil
+	data.VersionStats = nil
+	data.DailyModelMix = nil
+	data.StopReasonStats = nil
+	data.DailyOutputTokens = nil
+	data.DailyActiveHours = nil
 	data.DailyTrend = DailyTrendData{Dates: dates, Counts: counts}
 	data.WeekdayStats = weekdayStats
 	data.WorkHoursStats = nil
@@ -297,10 +361,10 @@ func applyRuntimeDailyTrend(data *DashboardData, filter AnalysisFilter) bool {
 	return true
 }
 
-func dailyRuntimeCountForDate(date string, filter AnalysisFilter) int {
+func dailyRuntimeCountForDate(cache *CacheFile, date string, filter AnalysisFilter) int {
 	if filter.Session != "" {
 		total := 0
-		for sessionID, snapshot := range globalCache.DailySessionRuntime[date] {
+		for sessionID, snapshot := range cache.DailySessionRuntime[date] {
 			if matchContains(filter.Session, sessionID) {
 				total += dailyRuntimeCount(snapshot, filter)
 			}
@@ -309,14 +373,14 @@ func dailyRuntimeCountForDate(date string, filter AnalysisFilter) int {
 	}
 	if filter.Project != "" {
 		total := 0
-		for project, snapshot := range globalCache.DailyProjectRuntime[date] {
+		for project, snapshot := range cache.DailyProjectRuntime[date] {
 			if matchContains(filter.Project, project) {
 				total += dailyRuntimeCount(snapshot, filter)
 			}
 		}
 		return total
 	}
-	return dailyRuntimeCount(globalCache.DailyRuntime[date], filter)
+	return dailyRuntimeCount(cache.DailyRuntime[date], filter)
 }
 
 func dailyRuntimeCount(snapshot ProjectFileAggregate, filter AnalysisFilter) int {
@@ -370,7 +434,14 @@ func dailyRuntimeCount(snapshot ProjectFileAggregate, filter AnalysisFilter) int
 
 func clearUnscopedTimeSeries(data *DashboardData) {
 	data.Commands = nil
+	data.PasteStats = nil
 	data.HourlyCounts = map[string]int{}
+	data.DailyIntensity = nil
+	data.VersionStats = nil
+	data.DailyModelMix = nil
+	data.StopReasonStats = nil
+	data.DailyOutputTokens = nil
+	data.DailyActiveHours = nil
 	data.DailyTrend = DailyTrendData{}
 	data.WeekdayStats = nil
 	data.WorkHoursStats = nil
@@ -404,6 +475,9 @@ func filterModels(data *DashboardData, model string) {
 	data.ModelUsage = filterSlice(data.ModelUsage, func(item ModelUsageItem) bool {
 		return matchContains(model, item.Model)
 	})
+	data.DailyModelMix = filterDailyModelMix(data.DailyModelMix, func(name string) bool {
+		return matchContains(model, name)
+	})
 	if data.ToolAnalysis != nil {
 		data.ToolAnalysis.ByModel = filterSlice(data.ToolAnalysis.ByModel, func(item ToolModelStatItem) bool {
 			return matchContains(model, item.Model)
@@ -505,6 +579,185 @@ func sessionUsedModel(stat SessionAnalysisItem, model string) bool {
 	return false
 }
 
+// parseModelList 解析逗号分隔的模型列表，去除空项与重复项（统一小写）。
+func parseModelList(value string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" || seen[part] {
+			continue
+		}
+		seen[part] = true
+		out = append(out, part)
+	}
+	return out
+}
+
+// newModelExcluder 构造模型排除谓词：按子串匹配，"haiku" 会排除 claude-haiku-4-5、claude-3-5-haiku 等整个家族。
+func newModelExcluder(patterns []string) func(model string) bool {
+	return func(model string) bool {
+		for _, pattern := range patterns {
+			if matchContains(pattern, model) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sessionOnlyUsedExcludedModels 判断 session 是否只使用了被排除的模型；未记录模型的 session 保留。
+func sessionOnlyUsedExcludedModels(stat SessionAnalysisItem, excluded func(string) bool) bool {
+	if len(stat.ModelsUsed) == 0 {
+		return stat.PrimaryModel != "" && excluded(stat.PrimaryModel)
+	}
+	for m := range stat.ModelsUsed {
+		if !excluded(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// rareOthersLabel min_count 合并长尾后的条目名称
+const rareOthersLabel = "(other)"
+
+// CollapseRare 把 count 低于 minCount 的条目合并为一条 other(合计)，并按次数降序重排；
+// 没有低于阈值的条目时原样返回
+func CollapseRare[T any](items []T, minCount int, count func(T) int, other func(total int) T) []T {
+	kept := make([]T, 0, len(items))
+	total, collapsed := 0, 0
+	for _, item := range items {
+		if c := count(item); c < minCount {
+			total += c
+			collapsed++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	if collapsed == 0 {
+		return items
+	}
+	kept = append(kept, other(total))
+	sort.SliceStable(kept, func(i, j int) bool { return count(kept[i]) > count(kept[j]) })
+	return kept
+}
+
+// collapseRareEntries 对 Commands 与 RuntimeTools 应用 min_count 长尾合并
+func collapseRareEntries(data *DashboardData, minCount int) {
+	data.Commands = CollapseRare(data.Commands, minCount,
+		func(item CommandStats) int { return item.Count },
+		func(total int) CommandStats { return CommandStats{Command: rareOthersLabel, Count: total} })
+	data.RuntimeTools = CollapseRare(data.RuntimeTools, minCount,
+		func(item RuntimeToolSignal) int { return item.Count },
+		func(total int) RuntimeToolSignal { return RuntimeToolSignal{Tool: rareOthersLabel, Count: total} })
+}
+
+// dropLowTokenModels 从 ModelUsage 中剔除 token 数低于 minTokens 的模型（后台探测等小调用），
+// 在按权重排序之前执行，排名只反映保留下来的模型
+func dropLowTokenModels(data *DashboardData, minTokens int) {
+	data.ModelUsage = filterSlice(data.ModelUsage, func(item ModelUsageItem) bool { return item.Tokens >= minTokens })
+}
+
+// excludeModels 从所有按模型归因的统计中剔除被排除的模型，并重算受影响的合计与每日趋势。
+func excludeModels(data *DashboardData, excluded func(model string) bool) {
+	if data == nil || excluded == nil {
+		return
+	}
+	keep := func(model string) bool { return !excluded(model) }
+
+	data.ModelUsage = filterSlice(data.ModelUsage, func(item ModelUsageItem) bool { return keep(item.Model) })
+	// 先按过滤前的每日模型构成扣减趋势，再裁剪构成本身
+	excludeModelsFromDailyTrend(data, excluded)
+	data.DailyModelMix = filterDailyModelMix(data.DailyModelMix, keep)
+	if data.ToolAnalysis != nil {
+		data.ToolAnalysis.ByModel = filterSlice(data.ToolAnalysis.ByModel, func(item ToolModelStatItem) bool { return keep(item.Model) })
+	}
+	if data.FailureAnalysis != nil {
+		data.FailureAnalysis.ByModelReason = filterSlice(data.FailureAnalysis.ByModelReason, func(item FailureModelReasonStat) bool { return keep(item.Model) })
+		data.FailureAnalysis.Samples = filterSlice(data.FailureAnalysis.Samples, func(item ToolFailureSample) bool { return keep(item.Model) })
+	}
+	// command/file: 只有存在按模型归因数据时才能重建主列表，否则保留原值
+	if data.CommandAnalysis != nil && len(data.CommandAnalysis.ByModel) > 0 {
+		data.CommandAnalysis.ByModel = filterSlice(data.CommandAnalysis.ByModel, func(item BashCommandModelStat) bool { return keep(item.Model) })
+		data.CommandAnalysis.BashCommands = rebuildBashCommandsFromModel(data.CommandAnalysis.ByModel)
+	}
+	if data.FileAnalysis != nil && len(data.FileAnalysis.ByModel) > 0 {
+		data.FileAnalysis.ByModel = filterSlice(data.FileAnalysis.ByModel, func(item FileOperationModelStat) bool { return keep(item.Model) })
+		if data.CommandAnalysis != nil {
+			data.CommandAnalysis.FileOperations = rebuildFileOperationsFromModel(data.FileAnalysis.ByModel)
+		}
+	}
+	if data.AgentAnalysis != nil {
+		data.AgentAnalysis.ByModel = filterSlice(data.AgentAnalysis.ByModel, func(item AgentModelStat) bool { return keep(item.Model) })
+	}
+	if data.SkillAnalysis != nil {
+		data.SkillAnalysis.ByModel = filterSlice(data.SkillAnalysis.ByModel, func(item SkillModelStat) bool { return keep(item.Model) })
+	}
+	if data.CostAnalysis != nil {
+		data.CostAnalysis.ByModel = filterSlice(data.CostAnalysis.ByModel, func(item CostModelStat) bool { return keep(item.Model) })
+		data.CostAnalysis.BySession = filterSlice(data.CostAnalysis.BySession, func(item CostSessionStat) bool { return keep(item.Model) })
+		recomputeCostTotals(data.CostAnalysis)
+	}
+	if data.ToolPerformance != nil {
+		data.ToolPerformance.SlowestCalls = filterSlice(data.ToolPerformance.SlowestCalls, func(item ToolSlowCallItem) bool { return keep(item.Model) })
+		recomputeToolPerformanceTotals(data.ToolPerformance)
+	}
+	if data.SessionAnalysis != nil {
+		keepSession := func(item SessionAnalysisItem) bool { return !sessionOnlyUsedExcludedModels(item, excluded) }
+		data.SessionAnalysis.Sessions = filterSlice(data.SessionAnalysis.Sessions, keepSession)
+		data.SessionAnalysis.TopFailures = filterSlice(data.SessionAnalysis.TopFailures, keepSession)
+		data.SessionAnalysis.LongRunning = filterSlice(data.SessionAnalysis.LongRunning, keepSession)
+		recomputeSessionTotals(data, keepSession)
+	}
+}
+
+// recomputeSessionTotals 按 keep 过滤未截断的 session 摘要，重算会话数与平均消息数；
+// Sessions 列表只保留最近 100 条，不能直接用来计数
+func recomputeSessionTotals(data *DashboardData, keep func(SessionAnalysisItem) bool) {
+	if data.SessionAnalysis == nil {
+		return
+	}
+	all := data.SessionAnalysis.Summaries
+	if all == nil {
+		all = data.SessionAnalysis.Sessions
+	}
+	kept := filterSlice(all, keep)
+	data.SessionAnalysis.Summaries = kept
+	if data.Sessions != nil {
+		data.Sessions.TotalSessions = len(kept)
+		data.Sessions.AvgMessagesPerSession = avgMessagesPerSession(sumSessionAssistantMessages(kept), len(kept))
+	}
+}
+
+// excludeModelsFromDailyTrend 按每日模型构成（缓存与实时解析两条路径都有）从每日趋势中扣除被排除模型的请求数。
+func excludeModelsFromDailyTrend(data *DashboardData, excluded func(string) bool) {
+	mix := data.DailyModelMix
+	if mix == nil || len(data.DailyTrend.Dates) != len(data.DailyTrend.Counts) {
+		return
+	}
+	dateIndex := make(map[string]int, len(mix.Dates))
+	for i, date := range mix.Dates {
+		dateIndex[date] = i
+	}
+	counts := append([]int(nil), data.DailyTrend.Counts...)
+	for i, date := range data.DailyTrend.Dates {
+		j, ok := dateIndex[date]
+		if !ok {
+			continue
+		}
+		for _, series := range mix.Models {
+			if excluded(series.Model) && j < len(series.Counts) {
+				counts[i] -= series.Counts[j]
+			}
+		}
+		if counts[i] < 0 {
+			counts[i] = 0
+		}
+	}
+	data.DailyTrend.Counts = counts
+}
+
 func filterTools(data *DashboardData, tool string) {
 	if tool == "" {
 		return
@@ -583,9 +836,10 @@ func filterSessions(data *DashboardData, filter AnalysisFilter) {
 	if filter.Session != "" {
 		data.SessionAnalysis.QueueOperations = nil
 	}
-	if data.Sessions != nil {
-		data.Sessions.TotalSessions = len(data.SessionAnalysis.Sessions)
-	}
+	recomputeSessionTotals(data, func(item SessionAnalysisItem) bool {
+		return matchEqual(sf.Session, item.SessionID) && matchContains(sf.Project, item.Project) &&
+			(filter.Model == "" || sessionUsedModel(item, filter.Model))
+	})
 	if data.TaskPlanAnalysis != nil {
 		data.TaskPlanAnalysis.ReminderSummary.TopTaskSessions = filterReminderSessions(data.TaskPlanAnalysis.ReminderSummary.TopTaskSessions, sf)
 		data.TaskPlanAnalysis.ReminderSummary.TopTodoSessions = filterReminderSessions(data.TaskPlanAnalysis.ReminderSummary.TopTodoSessions, sf)
@@ -672,7 +926,14 @@ func filterAgents(data *DashboardData, session string) {
 func applyPrecisionGuard(data *DashboardData, filter AnalysisFilter) {
 	if filter.Project != "" || filter.Tool != "" || filter.Reason != "" || filter.Category != "" || filter.Session != "" || filter.Family != "" {
 		data.Commands = nil
+		data.PasteStats = nil
 		data.HourlyCounts = map[string]int{}
+		data.DailyIntensity = nil
+		data.VersionStats = nil
+		data.DailyModelMix = nil
+		data.StopReasonStats = nil
+		data.DailyOutputTokens = nil
+		data.DailyActiveHours = nil
 		data.WorkHoursStats = nil
 		data.RuntimeTools = filterRuntimeToolsForPrecision(data.RuntimeTools, filter)
 	}
@@ -876,6 +1137,15 @@ func sortFailureBreakdowns(analysis *FailureAnalysisData) {
 	})
 }
 
+// sumSessionAssistantMessages 会话列表的 assistant 消息合计
+func sumSessionAssistantMessages(sessions []SessionAnalysisItem) int {
+	total := 0
+	for _, item := range sessions {
+		total += item.AssistantMessageCount
+	}
+	return total
+}
+
 func recomputeDashboardTotals(data *DashboardData) {
 	if data.ProjectStats != nil {
 		totalMessages := 0
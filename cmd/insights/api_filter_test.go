@@ -1,6 +1,16 @@
 package main
 
-import "testing"
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestApplyDashboardFilterNarrowsDashboardData(t *testing.T) {
 	data := &DashboardData{
@@ -102,8 +112,8 @@ func TestApplyDashboardFilterNarrowsDashboardData(t *testing.T) {
 }
 
 func TestApplyDashboardFilterKeepsPreciseToolData(t *testing.T) {
-	origCache := globalCache
-	globalCache = &CacheFile{
+	origCache := loadGlobalCache()
+	storeGlobalCache(&CacheFile{
 		DailyStats: map[string]*DayAggregate{
 			"2026-06-01": {Date: "2026-06-01"},
 		},
@@ -115,8 +125,8 @@ func TestApplyDashboardFilterKeepsPreciseToolData(t *testing.T) {
 				},
 			},
 		},
-	}
-	defer func() { globalCache = origCache }()
+	})
+	defer func() { storeGlobalCache(origCache) }()
 
 	data := &DashboardData{
 		DailyTrend: DailyTrendData{Dates: []string{"2026-06-01"}, Counts: []int{10}},
@@ -160,8 +170,8 @@ func TestApplyDashboardFilterKeepsPreciseToolData(t *testing.T) {
 }
 
 func TestApplyDashboardFilterRebuildsReasonTrend(t *testing.T) {
-	origCache := globalCache
-	globalCache = &CacheFile{
+	origCache := loadGlobalCache()
+	storeGlobalCache(&CacheFile{
 		DailyStats: map[string]*DayAggregate{
 			"2026-06-01": {Date: "2026-06-01"},
 			"2026-06-02": {Date: "2026-06-02"},
@@ -179,8 +189,8 @@ func TestApplyDashboardFilterRebuildsReasonTrend(t *testing.T) {
 				},
 			},
 		},
-	}
-	defer func() { globalCache = origCache }()
+	})
+	defer func() { storeGlobalCache(origCache) }()
 
 	data := &DashboardData{
 		DailyTrend: DailyTrendData{Dates: []string{"2026-06-01", "2026-06-02"}, Counts: []int{10, 20}},
@@ -262,8 +272,8 @@ func TestCoverageKeepsCommandFamilyExact(t *testing.T) {
 }
 
 func TestApplyDashboardFilterUsesDailyProjectRuntime(t *testing.T) {
-	origCache := globalCache
-	globalCache = &CacheFile{
+	origCache := loadGlobalCache()
+	storeGlobalCache(&CacheFile{
 		DailyStats: map[string]*DayAggregate{
 			"2026-06-01": {Date: "2026-06-01"},
 		},
@@ -281,8 +291,8 @@ func TestApplyDashboardFilterUsesDailyProjectRuntime(t *testing.T) {
 				},
 			},
 		},
-	}
-	defer func() { globalCache = origCache }()
+	})
+	defer func() { storeGlobalCache(origCache) }()
 
 	data := &DashboardData{
 		DailyTrend: DailyTrendData{Dates: []string{"2026-06-01"}, Counts: []int{99}},
@@ -302,8 +312,8 @@ func TestApplyDashboardFilterUsesDailyProjectRuntime(t *testing.T) {
 }
 
 func TestApplyDashboardFilterUsesDailySessionRuntime(t *testing.T) {
-	origCache := globalCache
-	globalCache = &CacheFile{
+	origCache := loadGlobalCache()
+	storeGlobalCache(&CacheFile{
 		DailyStats: map[string]*DayAggregate{
 			"2026-06-01": {Date: "2026-06-01"},
 		},
@@ -321,8 +331,8 @@ func TestApplyDashboardFilterUsesDailySessionRuntime(t *testing.T) {
 				},
 			},
 		},
-	}
-	defer func() { globalCache = origCache }()
+	})
+	defer func() { storeGlobalCache(origCache) }()
 
 	data := &DashboardData{
 		DailyTrend: DailyTrendData{Dates: []string{"2026-06-01"}, Counts: []int{99}},
@@ -340,3 +350,302 @@ func TestApplyDashboardFilterUsesDailySessionRuntime(t *testing.T) {
 		t.Fatalf("daily coverage = %+v, want exact", data.Coverage["dailyTrend"])
 	}
 }
+
+func TestApplyModelWeightReordersByCost(t *testing.T) {
+	// haiku 请求多但便宜，opus 只用了一次却占了大部分费用
+	newData := func() *DashboardData {
+		return &DashboardData{
+			ModelUsage: []ModelUsageItem{
+				{Model: "haiku", Count: 20, Tokens: 4000},
+				{Model: "opus", Count: 1, Tokens: 3000},
+			},
+			CostAnalysis: &CostAnalysisData{
+				ByModel: []CostModelStat{
+					{Model: "haiku", RequestCount: 20, TotalTokens: 4000, CostCNY: 0.05},
+					{Model: "opus", RequestCount: 1, TotalTokens: 3000, CostCNY: 1.8},
+				},
+			},
+		}
+	}
+
+	byCount := newData()
+	applyModelWeight(byCount, ModelWeightCount)
+	if byCount.ModelUsage[0].Model != "haiku" || byCount.ModelWeight != ModelWeightCount {
+		t.Fatalf("count weight order=%+v, want haiku first", byCount.ModelUsage)
+	}
+
+	byCost := newData()
+	applyModelWeight(byCost, ModelWeightCost)
+	if byCost.ModelUsage[0].Model != "opus" || byCost.ModelWeight != ModelWeightCost {
+		t.Fatalf("cost weight order=%+v, want opus first", byCost.ModelUsage)
+	}
+	if byCost.ModelUsage[0].CostCNY != 1.8 || byCost.ModelUsage[1].CostCNY != 0.05 {
+		t.Fatalf("CostCNY not backfilled: %+v", byCost.ModelUsage)
+	}
+}
+
+func TestExcludeModelsDropsFamilyFromAllStats(t *testing.T) {
+	data := &DashboardData{
+		DailyTrend: DailyTrendData{Dates: []string{"2026-06-01"}, Counts: []int{10}},
+		DailyModelMix: buildDailyModelMix(map[string]map[string]int{
+			"2026-06-01": {"claude-opus-4-6": 4, "claude-haiku-4-5": 5, "claude-3-5-haiku": 1},
+		}),
+		ModelUsage: []ModelUsageItem{
+			{Model: "claude-opus-4-6", Count: 4},
+			{Model: "claude-haiku-4-5", Count: 5},
+			{Model: "claude-3-5-haiku", Count: 1},
+		},
+		ToolAnalysis: &ToolAnalysisData{ByModel: []ToolModelStatItem{
+			{Model: "claude-opus-4-6", Tool: "Bash", CallCount: 3},
+			{Model: "claude-haiku-4-5", Tool: "Bash", CallCount: 9},
+		}},
+		CommandAnalysis: &CommandAnalysisData{ByModel: []BashCommandModelStat{
+			{Model: "claude-opus-4-6", CommandName: "go", CallCount: 2},
+			{Model: "claude-haiku-4-5", CommandName: "git", CallCount: 7},
+		}},
+		CostAnalysis: &CostAnalysisData{
+			ByModel: []CostModelStat{
+				{Model: "claude-opus-4-6", RequestCount: 4, TotalTokens: 400},
+				{Model: "claude-haiku-4-5", RequestCount: 5, TotalTokens: 500},
+			},
+			BySession: []CostSessionStat{
+				{SessionID: "s1", Model: "claude-opus-4-6", RequestCount: 4, TotalTokens: 400},
+				{SessionID: "s2", Model: "claude-haiku-4-5", RequestCount: 5, TotalTokens: 500},
+			},
+		},
+		SessionAnalysis: &SessionAnalysisData{Sessions: []SessionAnalysisItem{
+			{SessionID: "s1", ModelsUsed: map[string]int{"claude-opus-4-6": 4}},
+			{SessionID: "s2", ModelsUsed: map[string]int{"claude-haiku-4-5": 5}},
+			{SessionID: "s3", ModelsUsed: map[string]int{"claude-haiku-4-5": 1, "claude-opus-4-6": 1}},
+		}},
+	}
+
+	excludeModels(data, newModelExcluder(parseModelList(" Haiku, ,haiku")))
+
+	if len(data.ModelUsage) != 1 || data.ModelUsage[0].Model != "claude-opus-4-6" {
+		t.Fatalf("ModelUsage=%+v, want only opus", data.ModelUsage)
+	}
+	if data.DailyTrend.Counts[0] != 4 {
+		t.Fatalf("daily count=%d, want 4 after excluding haiku family", data.DailyTrend.Counts[0])
+	}
+	if len(data.ToolAnalysis.ByModel) != 1 || data.ToolAnalysis.ByModel[0].Model != "claude-opus-4-6" {
+		t.Fatalf("ToolAnalysis.ByModel=%+v", data.ToolAnalysis.ByModel)
+	}
+	if len(data.CommandAnalysis.BashCommands) != 1 || data.CommandAnalysis.BashCommands[0].CommandName != "go" {
+		t.Fatalf("BashCommands=%+v, want only go", data.CommandAnalysis.BashCommands)
+	}
+	if data.CostAnalysis.Totals.RequestCount != 4 || data.CostAnalysis.Totals.TotalTokens != 400 || len(data.CostAnalysis.ByModel) != 1 {
+		t.Fatalf("CostAnalysis=%+v, want opus only", data.CostAnalysis)
+	}
+	if len(data.SessionAnalysis.Sessions) != 2 {
+		t.Fatalf("sessions=%+v, want s1 and mixed s3 kept", data.SessionAnalysis.Sessions)
+	}
+}
+
+func TestCollapseRareMergesSubThresholdEntries(t *testing.T) {
+	data := &DashboardData{
+		Commands: []CommandStats{
+			{Command: "/model", Count: 9},
+			{Command: "/clear", Count: 3},
+			{Command: "/init", Count: 2},
+			{Command: "/cost", Count: 1},
+			{Command: "/help", Count: 1},
+		},
+		RuntimeTools: []RuntimeToolSignal{
+			{Server: "github", Tool: "search", Count: 5},
+			{Server: "github", Tool: "issue", Count: 2},
+			{Server: "fs", Tool: "read", Count: 2},
+		},
+	}
+	filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?minCount=3", nil))
+	if err != nil || filter.MinCount != 3 {
+		t.Fatalf("MinCount=%d err=%v, want 3", filter.MinCount, err)
+	}
+	collapseRareEntries(data, filter.MinCount)
+
+	wantCommands := []CommandStats{
+		{Command: "/model", Count: 9},
+		{Command: rareOthersLabel, Count: 4},
+		{Command: "/clear", Count: 3},
+	}
+	if !reflect.DeepEqual(data.Commands, wantCommands) {
+		t.Fatalf("commands=%+v, want %+v", data.Commands, wantCommands)
+	}
+	wantTools := []RuntimeToolSignal{
+		{Server: "github", Tool: "search", Count: 5},
+		{Tool: rareOthersLabel, Count: 4},
+	}
+	if !reflect.DeepEqual(data.RuntimeTools, wantTools) {
+		t.Fatalf("runtime tools=%+v, want %+v", data.RuntimeTools, wantTools)
+	}
+
+	// 没有低于阈值的条目时不追加 (other)
+	kept := []CommandStats{{Command: "/model", Count: 9}}
+	if got := CollapseRare(kept, 3, func(item CommandStats) int { return item.Count }, func(total int) CommandStats {
+		return CommandStats{Command: rareOthersLabel, Count: total}
+	}); !reflect.DeepEqual(got, kept) {
+		t.Fatalf("collapse without rare entries = %+v", got)
+	}
+}
+
+func TestMinTokensDropsSmallModelsInLiveAndCachePaths(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	withTokens := func(model string, input int, ts time.Time) string {
+		return strings.Replace(modelRecordJSON("s1", model, ts), `"input_tokens":10,"output_tokens":5`, `"input_tokens":`+strconv.Itoa(input)+`,"output_tokens":0`, 1)
+	}
+	content := withTokens("claude-haiku-4.5", 10, base) + "\n" +
+		withTokens("claude-opus-4.5", 10000, base.Add(time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?preset=all&minTokens=100", nil))
+	if err != nil || filter.MinTokens != 100 {
+		t.Fatalf("MinTokens=%d err=%v, want 100", filter.MinTokens, err)
+	}
+	check := func(wantSource string) {
+		t.Helper()
+		data, source, err := buildDashboardDataWithFilter(filter)
+		if err != nil {
+			t.Fatalf("buildDashboardDataWithFilter() error = %v", err)
+		}
+		if source != wantSource || len(data.ModelUsage) != 1 || data.ModelUsage[0].Model != "claude-opus-4.5" || data.ModelUsage[0].Tokens != 10000 {
+			t.Fatalf("source=%s model_usage=%+v, want %s 只保留 claude-opus-4.5", source, data.ModelUsage, wantSource)
+		}
+	}
+	check("parsing")
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	check("cache")
+}
+
+func TestExcludeModelsKeepsUncappedSessionTotalsInLiveAndCachePaths(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 120 个 sonnet 会话超过 SessionAnalysis.Sessions 的 100 条上限，另有 5 个只用 haiku 的会话
+	// （会话所用模型按工具调用归因，haiku 记录带一次 tool_use）
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	var content strings.Builder
+	for i := 0; i < 120; i++ {
+		content.WriteString(projectRecordJSON("/tmp/demo", fmt.Sprintf("s%03d", i), base.Add(time.Duration(i)*time.Minute)) + "\n")
+	}
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(&content, `{"type":"assistant","cwd":"/tmp/demo","sessionId":"h%d","timestamp":%q,"message":{"model":"claude-haiku-4.5","content":[{"type":"tool_use","id":"toolu_h%d","name":"Read","input":{}}],"usage":{"input_tokens":10,"output_tokens":5}}}`+"\n",
+			i, base.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano), i)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?preset=all&exclude_models=haiku", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(wantSource string) {
+		t.Helper()
+		data, source, err := buildDashboardDataWithFilter(filter)
+		if err != nil {
+			t.Fatalf("buildDashboardDataWithFilter() error = %v", err)
+		}
+		if source != wantSource {
+			t.Fatalf("source=%s, want %s", source, wantSource)
+		}
+		if data.Sessions.TotalSessions != 120 || data.Summary.TotalSessions != 120 {
+			t.Fatalf("%s: total_sessions=%d summary=%d, want 120", source, data.Sessions.TotalSessions, data.Summary.TotalSessions)
+		}
+		if !reflect.DeepEqual(data.DailyTrend.Counts, []int{120}) {
+			t.Fatalf("%s: daily trend=%+v, want haiku 的 5 条被扣除", source, data.DailyTrend)
+		}
+	}
+	check("parsing")
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	check("cache")
+}
+
+func TestClampNarrowsTimeRangeToData(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	earliest := time.Now().UTC().AddDate(0, 0, -10)
+	content := projectRecordJSON("/tmp/demo", "s1", earliest) + "\n" +
+		projectRecordJSON("/tmp/demo", "s2", earliest.AddDate(0, 0, 3)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	build := func(query string) TimeRangeInfo {
+		t.Helper()
+		filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?preset=90d"+query, nil))
+		if err != nil {
+			t.Fatalf("parseAnalysisFilter() error = %v", err)
+		}
+		data, _, err := buildDashboardDataWithFilter(filter)
+		if err != nil {
+			t.Fatalf("buildDashboardDataWithFilter() error = %v", err)
+		}
+		return data.TimeRange
+	}
+
+	unclamped := build("")
+	if unclamped.Clamped || unclamped.Start >= earliest.Format("2006-01-02") {
+		t.Fatalf("未 clamp 时应保留预设起点: %+v", unclamped)
+	}
+	clamped := build("&clamp=1")
+	want := TimeRangeInfo{
+		Preset:  "90d",
+		Start:   earliest.Format("2006-01-02"),
+		End:     earliest.AddDate(0, 0, 3).Format("2006-01-02"),
+		Clamped: true,
+	}
+	if !reflect.DeepEqual(clamped, want) {
+		t.Fatalf("clamp=1 time_range=%+v, want %+v", clamped, want)
+	}
+}
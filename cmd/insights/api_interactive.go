@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -17,10 +18,12 @@ type apiMeta struct {
 }
 
 type interactiveAPIResponse struct {
-	Success bool        `json:"success"`
-	Meta    apiMeta     `json:"meta"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	APIVersion string      `json:"api_version"`
+	LastUpdate string      `json:"last_update,omitempty"`
+	Success    bool        `json:"success"`
+	Meta       apiMeta     `json:"meta"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
 }
 
 type AnalysisFilter struct {
@@ -41,7 +44,35 @@ type AnalysisFilter struct {
 	Severity   string
 	Target     string
 	Family     string
-}
+	// ModelWeight 模型占比口径（count|tokens|cost）
+	ModelWeight string
+	// RelativeDates 在 time_range 中附带相对时间标签
+	RelativeDates bool
+	// ExcludeModels 需要从所有统计中剔除的模型（子串匹配，可按家族排除）
+	ExcludeModels []string
+	// Breakdown 命令统计粒度：空为按命令名，args 为按「命令 + 首个参数」
+	Breakdown string
+	// Delta 只返回缓存 LastUpdate 之后新增的数据（/api/data 增量模式）
+	Delta bool
+	// MinCount /api/data 中次数低于该值的命令与运行时工具合并为 (other)（<=1 为关闭）
+	MinCount int
+	// MinTokens /api/data 中 token 数低于该值的模型从 model_usage 中剔除（0 为关闭）
+	MinTokens int
+	// Debug /api/data 附带实时解析各数据源耗时（parse_timings）
+	Debug bool
+	// Facets /api/data 附带去重的模型、命令与工具名（facets）
+	Facets bool
+	// Clamp 把响应的 time_range 收窄到范围内实际有数据的日期
+	Clamp bool
+	// RecordSamples /api/data 每个分类附带的原始记录样例数（0 为关闭）
+	RecordSamples int
+	// SampleMetric 样例对应的指标（model|command），SampleKey 只取某一个分类
+	SampleMetric string
+	SampleKey    string
+}
+
+// CommandBreakdownArgs 按 slash command 首个参数拆分统计
+const CommandBreakdownArgs = "args"
 
 type overviewData struct {
 	Summary     overviewSummary `json:"summary"`
@@ -247,6 +278,268 @@ func handleTimelineAPI(w http.ResponseWriter, r *http.Request) {
 	sendInteractiveJSON(w, payload, source, data.TimeRange, filter, startedAt)
 }
 
+// commandListData /api/commands 返回的完整 slash 命令统计（支持 offset/limit 分页）
+type commandListData struct {
+	TotalCalls    int            `json:"total_calls"`
+	TotalCommands int            `json:"total_commands"`
+	Offset        int            `json:"offset"`
+	Limit         int            `json:"limit"`
+	Commands      []CommandStats `json:"commands"`
+	// CommandCategories 按 --command-categories 分类汇总的次数（不分页，始终按命令名归类）
+	CommandCategories []CategoryStat `json:"command_categories"`
+}
+
+// handleCommandsAPI 返回不截断的命令统计；limit 缺省时返回全部
+func handleCommandsAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	offset := parsePositiveInt(q.Get("offset"), 0)
+	limit := parsePositiveInt(q.Get("limit"), 0)
+
+	startedAt := time.Now()
+	parse := safeParseHistoryConcurrent
+	if filter.Breakdown == CommandBreakdownArgs {
+		parse = safeParseHistoryCommandArgs
+	}
+	cmdStats, _, _ := parse(filter.TimeFilter)
+	payload := buildCommandListData(cmdStats, offset, limit)
+	categories, err := ParseCommandCategories(filter.TimeFilter)
+	if err != nil {
+		Warn("ParseCommandCategories 失败，使用空结果", "error", err.Error())
+	}
+	payload.CommandCategories = categories
+	if payload.CommandCategories == nil {
+		payload.CommandCategories = []CategoryStat{}
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, payload, "parsing", rangeInfo, filter, startedAt)
+}
+
+func buildCommandListData(stats []CommandStats, offset, limit int) commandListData {
+	sorted := append([]CommandStats(nil), stats...)
+	sortCommandStats(sorted)
+
+	payload := commandListData{
+		TotalCommands: len(sorted),
+		Offset:        offset,
+		Limit:         limit,
+		Commands:      []CommandStats{},
+	}
+	for _, item := range sorted {
+		payload.TotalCalls += item.Count
+	}
+	if offset >= len(sorted) {
+		return payload
+	}
+	end := len(sorted)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	payload.Commands = sorted[offset:end]
+	return payload
+}
+
+// sessionListData /api/sessions 返回的原始会话列表（按开始时间倒序，支持 offset/limit 分页）
+type sessionListData struct {
+	TotalSessions int           `json:"total_sessions"`
+	Offset        int           `json:"offset"`
+	Limit         int           `json:"limit"`
+	Sessions      []SessionInfo `json:"sessions"`
+}
+
+// handleSessionsAPI 直接解析 projects 列出会话，便于逐条排查；limit 缺省时返回全部
+func handleSessionsAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	offset := parsePositiveInt(q.Get("offset"), 0)
+	limit := parsePositiveInt(q.Get("limit"), 0)
+
+	startedAt := time.Now()
+	sessions, err := ParseSessionList(filter.TimeFilter)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	payload := sessionListData{
+		TotalSessions: len(sessions),
+		Offset:        offset,
+		Limit:         limit,
+		Sessions:      []SessionInfo{},
+	}
+	if offset < len(sessions) {
+		end := len(sessions)
+		if limit > 0 && offset+limit < end {
+			end = offset + limit
+		}
+		payload.Sessions = sessions[offset:end]
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, payload, "parsing", rangeInfo, filter, startedAt)
+}
+
+// presetInfo 单个预设按当前时间解析出的范围（RFC3339）；all 不限定起止
+type presetInfo struct {
+	Key     string `json:"key"`
+	Start   string `json:"start,omitempty"`
+	End     string `json:"end,omitempty"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// handlePresetsAPI 列出后端支持的时间预设及其解析结果，前端据此渲染预设按钮，避免与后端常量脱节
+func handlePresetsAPI(w http.ResponseWriter, r *http.Request) {
+	presets := make([]presetInfo, 0, len(rangePresets))
+	for _, preset := range rangePresets {
+		item := presetInfo{Key: string(preset), Default: preset == Range30Days}
+		tf := NewTimeFilterFromPreset(preset)
+		if tf.Start != nil {
+			item.Start = tf.Start.Format(time.RFC3339)
+		}
+		if tf.End != nil {
+			item.End = tf.End.Format(time.RFC3339)
+		}
+		presets = append(presets, item)
+	}
+	sendJSON(w, APIResponse{Success: true, Data: map[string]interface{}{"presets": presets}})
+}
+
+// branchListData /api/branches 返回的分支统计
+type branchListData struct {
+	Project       string       `json:"project,omitempty"`
+	TotalMessages int          `json:"total_messages"`
+	Branches      []BranchStat `json:"branches"`
+}
+
+// handleBranchesAPI 按 git 分支统计消息数，可用 project 限定到单个项目
+func handleBranchesAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startedAt := time.Now()
+	branches, err := ParseBranchStats(filter.TimeFilter, filter.Project)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	payload := branchListData{Project: filter.Project, Branches: branches}
+	for _, item := range branches {
+		payload.TotalMessages += item.Messages
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, payload, "parsing", rangeInfo, filter, startedAt)
+}
+
+// 项目列表排序口径
+const (
+	ProjectSortMessages = "messages"
+	ProjectSortTokens   = "tokens"
+)
+
+// projectListData /api/projects 返回的完整项目统计（支持 sort 与 offset/limit 分页）
+type projectListData struct {
+	TotalMessages int               `json:"total_messages"`
+	TotalTokens   int               `json:"total_tokens"`
+	TotalProjects int               `json:"total_projects"`
+	Sort          string            `json:"sort"`
+	Offset        int               `json:"offset"`
+	Limit         int               `json:"limit"`
+	Projects      []ProjectStatItem `json:"projects"`
+}
+
+// handleProjectsAPI 返回不截断的项目统计；sort=tokens 时按 token 数排序
+func handleProjectsAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	sortKey := strings.ToLower(strings.TrimSpace(q.Get("sort")))
+	if sortKey == "" {
+		sortKey = ProjectSortMessages
+	}
+	if sortKey != ProjectSortMessages && sortKey != ProjectSortTokens {
+		sendInteractiveError(w, fmt.Sprintf("无效的 sort: %s（可选 messages|tokens）", sortKey), http.StatusBadRequest)
+		return
+	}
+	offset := parsePositiveInt(q.Get("offset"), 0)
+	limit := parsePositiveInt(q.Get("limit"), 0)
+
+	startedAt := time.Now()
+	data, source, err := buildDashboardDataWithFilter(filter)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var projects []ProjectStatItem
+	if data.ProjectStats != nil {
+		projects = data.ProjectStats.Projects
+	}
+	payload := buildProjectListData(projects, sortKey, offset, limit)
+	sendInteractiveJSON(w, payload, source, data.TimeRange, filter, startedAt)
+}
+
+func buildProjectListData(projects []ProjectStatItem, sortKey string, offset, limit int) projectListData {
+	sorted := append([]ProjectStatItem(nil), projects...)
+	if sortKey == ProjectSortTokens {
+		sortProjectStatsByTokens(sorted)
+	} else {
+		sortProjectStats(sorted)
+	}
+
+	payload := projectListData{
+		TotalProjects: len(sorted),
+		Sort:          sortKey,
+		Offset:        offset,
+		Limit:         limit,
+		Projects:      []ProjectStatItem{},
+	}
+	for _, item := range sorted {
+		payload.TotalMessages += item.MessageCount
+		payload.TotalTokens += item.Tokens
+	}
+	if offset >= len(sorted) {
+		return payload
+	}
+	end := len(sorted)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	payload.Projects = sorted[offset:end]
+	return payload
+}
+
 func buildRecommendationDataWithFilter(filter AnalysisFilter) (*DashboardData, string, error) {
 	data, source, err := buildRecommendationDashboardData(filter.TimeFilter, filter.Preset)
 	if err != nil {
@@ -284,24 +577,69 @@ func parseAnalysisFilter(r *http.Request) (AnalysisFilter, error) {
 	if err != nil {
 		return AnalysisFilter{}, err
 	}
+	tf.UserType = strings.TrimSpace(q.Get("user_type"))
+	if tf.UserType == "" {
+		tf.UserType = strings.TrimSpace(q.Get("userType"))
+	}
+	countModeParam := q.Get("count_mode")
+	if countModeParam == "" {
+		countModeParam = q.Get("countMode")
+	}
+	if tf.CountMode, err = normalizeCountMode(countModeParam); err != nil {
+		return AnalysisFilter{}, err
+	}
+	modelWeight, err := normalizeModelWeight(q.Get("model_weight"))
+	if err != nil {
+		return AnalysisFilter{}, err
+	}
+	sampleMetric, err := normalizeSampleMetric(q.Get("sample_metric"))
+	if err != nil {
+		return AnalysisFilter{}, err
+	}
+	minCountParam := q.Get("min_count")
+	if minCountParam == "" {
+		minCountParam = q.Get("minCount")
+	}
+	minCount := parsePositiveInt(minCountParam, 0)
+	minTokensParam := q.Get("min_tokens")
+	if minTokensParam == "" {
+		minTokensParam = q.Get("minTokens")
+	}
+	breakdown := strings.ToLower(strings.TrimSpace(q.Get("breakdown")))
+	if breakdown != "" && breakdown != CommandBreakdownArgs {
+		return AnalysisFilter{}, fmt.Errorf("无效的 breakdown: %s（可选 args）", breakdown)
+	}
 	return AnalysisFilter{
-		TimeFilter: tf,
-		Preset:     normalizedPreset,
-		Start:      start,
-		End:        end,
-		Limit:      opts.Limit,
-		Samples:    opts.Samples,
-		ID:         opts.ID,
-		Detail:     opts.Detail,
-		Project:    opts.Project,
-		Session:    opts.Session,
-		Tool:       opts.Tool,
-		Model:      opts.Model,
-		Category:   opts.Category,
-		Reason:     opts.Reason,
-		Severity:   strings.TrimSpace(q.Get("severity")),
-		Target:     strings.TrimSpace(q.Get("target")),
-		Family:     strings.TrimSpace(q.Get("family")),
+		TimeFilter:    tf,
+		Preset:        normalizedPreset,
+		Start:         start,
+		End:           end,
+		Limit:         opts.Limit,
+		Samples:       opts.Samples,
+		ID:            opts.ID,
+		Detail:        opts.Detail,
+		Project:       opts.Project,
+		Session:       opts.Session,
+		Tool:          opts.Tool,
+		Model:         opts.Model,
+		Category:      opts.Category,
+		Reason:        opts.Reason,
+		Severity:      strings.TrimSpace(q.Get("severity")),
+		Target:        strings.TrimSpace(q.Get("target")),
+		Family:        strings.TrimSpace(q.Get("family")),
+		ModelWeight:   modelWeight,
+		RelativeDates: parseBoolQuery(q.Get("relative_dates")),
+		ExcludeModels: parseModelList(q.Get("exclude_models")),
+		Breakdown:     breakdown,
+		Delta:         parseBoolQuery(q.Get("delta")),
+		MinCount:      minCount,
+		MinTokens:     parsePositiveInt(minTokensParam, 0),
+		Debug:         parseBoolQuery(q.Get("debug")),
+		Facets:        parseBoolQuery(q.Get("facets")),
+		Clamp:         parseBoolQuery(q.Get("clamp")),
+		RecordSamples: parsePositiveInt(q.Get("samples"), 0),
+		SampleMetric:  sampleMetric,
+		SampleKey:     strings.TrimSpace(q.Get("sample_key")),
 	}, nil
 }
 
@@ -357,9 +695,9 @@ func buildOverviewData(data *DashboardData, diagnostics cliRecommendationReport,
 			trend.Sessions = append(trend.Sessions, data.Sessions.DailySessionMap[date])
 		}
 	}
-	if globalCache != nil {
+	if cache := loadGlobalCache(); cache != nil {
 		for _, date := range trend.Dates {
-			day := globalCache.DailyStats[date]
+			day := cache.DailyStats[date]
 			if day == nil {
 				trend.Tokens = append(trend.Tokens, 0)
 				trend.Failures = append(trend.Failures, 0)
@@ -397,10 +735,11 @@ func buildOverviewData(data *DashboardData, diagnostics cliRecommendationReport,
 
 func buildTimelineData(data *DashboardData) timelineData {
 	out := timelineData{Days: make([]timelineDay, 0, len(data.DailyTrend.Dates))}
+	cache := loadGlobalCache()
 	for _, date := range data.DailyTrend.Dates {
 		day := timelineDay{Date: date}
-		if globalCache != nil && globalCache.DailyStats[date] != nil {
-			stats := globalCache.DailyStats[date]
+		if cache != nil && cache.DailyStats[date] != nil {
+			stats := cache.DailyStats[date]
 			day.Messages = stats.MessageCount
 			day.Sessions = stats.SessionCount
 			day.ToolCalls = stats.ToolCallCount
@@ -506,6 +845,8 @@ func (filter AnalysisFilter) metaFilters() map[string]interface{} {
 	add("severity", filter.Severity)
 	add("target", filter.Target)
 	add("family", filter.Family)
+	add("exclude_models", strings.Join(filter.ExcludeModels, ","))
+	add("breakdown", filter.Breakdown)
 	if filter.Detail {
 		values["detail"] = true
 	}
@@ -515,8 +856,8 @@ func (filter AnalysisFilter) metaFilters() map[string]interface{} {
 }
 
 func cacheVersionForMeta() string {
-	if globalCache != nil {
-		return globalCache.Version
+	if cache := loadGlobalCache(); cache != nil {
+		return cache.Version
 	}
 	return CacheVersion
 }
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseAnalysisFilter(t *testing.T) {
@@ -25,6 +33,126 @@ func TestParseAnalysisFilter(t *testing.T) {
 	}
 }
 
+func TestParseAnalysisFilterModelWeight(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/data?preset=7d&model_weight=Cost", nil)
+	filter, err := parseAnalysisFilter(req)
+	if err != nil {
+		t.Fatalf("parseAnalysisFilter returned error: %v", err)
+	}
+	if filter.ModelWeight != ModelWeightCost {
+		t.Fatalf("ModelWeight=%q, want cost", filter.ModelWeight)
+	}
+
+	req = httptest.NewRequest("GET", "/api/data?model_weight=latency", nil)
+	if _, err := parseAnalysisFilter(req); err == nil {
+		t.Fatal("expected error for invalid model_weight")
+	}
+}
+
+func TestCustomRangeSpanCap(t *testing.T) {
+	origMax := cfg.MaxRangeDays
+	cfg.MaxRangeDays = 365
+	defer func() { cfg.MaxRangeDays = origMax }()
+
+	// 2020-01-01 ~ 2025-06-22 共 2000 天
+	w := httptest.NewRecorder()
+	handleDataAPI(w, httptest.NewRequest("GET", "/api/data?preset=custom&start=2020-01-01&end=2025-06-22", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "2000 天") || !strings.Contains(w.Body.String(), "365 天") {
+		t.Fatalf("status=%d body=%s, want 400 with span message", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{"preset=all", "preset=90d", "start=2025-01-01&end=2025-12-31"} {
+		if _, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?"+query, nil)); err != nil {
+			t.Fatalf("%s 不应被拒绝: %v", query, err)
+		}
+	}
+	if _, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?start=2025-02-01&end=2025-01-01", nil)); err == nil {
+		t.Fatal("结束日期早于开始日期时应报错")
+	}
+
+	cfg.MaxRangeDays = 0
+	if _, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?start=2000-01-01&end=2030-01-01", nil)); err != nil {
+		t.Fatalf("未设置上限时不应限制跨度: %v", err)
+	}
+}
+
+func TestParseRangeBoundRelativeExpressions(t *testing.T) {
+	now := time.Date(2026, 3, 31, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		expr    string
+		isEnd   bool
+		want    time.Time
+		wantErr bool
+	}{
+		{expr: "-7d", want: now.AddDate(0, 0, -7)},
+		{expr: "-3w", want: now.AddDate(0, 0, -21)},
+		{expr: "-1m", want: now.AddDate(0, -1, 0)},
+		{expr: "now", isEnd: true, want: now},
+		{expr: "2026-01-05", want: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{expr: "2026-01-05", isEnd: true, want: time.Date(2026, 1, 5, 23, 59, 59, 999999999, time.Local)},
+		{expr: "-7x", wantErr: true},
+		{expr: "-+7d", wantErr: true},
+		{expr: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseRangeBound(tt.expr, now, tt.isEnd)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("parseRangeBound(%q) 应报错，得到 %v", tt.expr, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Fatalf("parseRangeBound(%q, end=%v) = %v, %v; want %v", tt.expr, tt.isEnd, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?start=-14d&end=now", nil)); err != nil {
+		t.Fatalf("相对范围不应被拒绝: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handleDataAPI(w, httptest.NewRequest("GET", "/api/data?start=-7days&end=now", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "-7days") {
+		t.Fatalf("status=%d body=%s, want 400 naming the bad expression", w.Code, w.Body.String())
+	}
+}
+
+func TestCustomRangeEndIncludesSubsecondRecords(t *testing.T) {
+	tf, err := NewTimeFilterCustom("2026-01-05", "2026-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	boundary := time.Date(2026, 1, 6, 23, 59, 59, 500_000_000, time.Local)
+	nextDay := time.Date(2026, 1, 7, 0, 0, 0, 0, time.Local)
+	if !tf.Contains(boundary) {
+		t.Fatalf("结束日 23:59:59.5 的记录应在范围内，end=%v", tf.End)
+	}
+	if tf.Contains(nextDay) {
+		t.Fatalf("次日 00:00 的记录不应在范围内，end=%v", tf.End)
+	}
+
+	// 解析路径：结束日最后一秒内的记录同样计入
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := projectRecordJSON("/tmp/demo", "s1", boundary) + "\n" + projectRecordJSON("/tmp/demo", "s1", nextDay) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+	stats, err := ParseBranchStats(tf, "")
+	if err != nil {
+		t.Fatalf("ParseBranchStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].Messages != 1 {
+		t.Fatalf("branch stats = %+v, want 1 message", stats)
+	}
+}
+
 func TestFilterDiagnosticFindings(t *testing.T) {
 	items := []diagnosticFinding{
 		{ID: "a", Severity: "high", Targets: []string{"tool"}, Evidence: []diagnosticEvidence{{Label: "项目", Value: "/tmp/demo"}}},
@@ -44,9 +172,9 @@ func TestBuildTimelineData(t *testing.T) {
 		},
 		Sessions: &SessionStats{DailySessionMap: map[string]int{"2026-06-12": 1, "2026-06-13": 2}},
 	}
-	oldCache := globalCache
-	globalCache = nil
-	t.Cleanup(func() { globalCache = oldCache })
+	oldCache := loadGlobalCache()
+	storeGlobalCache(nil)
+	t.Cleanup(func() { storeGlobalCache(oldCache) })
 
 	timeline := buildTimelineData(data)
 	if timeline.Start != "2026-06-12" || timeline.End != "2026-06-13" {
@@ -56,3 +184,188 @@ func TestBuildTimelineData(t *testing.T) {
 		t.Fatalf("timeline=%+v", timeline)
 	}
 }
+
+func TestHandleCommandsAPIReturnsAllCommandsWithTotal(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now()
+	var lines []string
+	add := func(display string, times int) {
+		for i := 0; i < times; i++ {
+			lines = append(lines, `{"display":"`+display+`","timestamp":`+strconv.FormatInt(now.Add(-time.Duration(len(lines))*time.Minute).UnixMilli(), 10)+`,"project":"demo"}`)
+		}
+	}
+	// 20 个不同命令，超过图表 Top 15 的截断
+	for i := 0; i < 20; i++ {
+		add("/cmd"+strconv.Itoa(i), i+1)
+	}
+	add("/alpha", 20) // 与 /cmd19 同次数，按名称排序在前
+	add("plain prompt", 3)
+	if err := os.WriteFile(filepath.Join(tmpDir, "history.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("写入 history.jsonl 失败: %v", err)
+	}
+
+	origDataDir, origCategories := cfg.DataDir, cfg.CommandCategories
+	cfg.DataDir = tmpDir
+	cfg.CommandCategories = map[string][]string{"first": {"/alpha", "/cmd0"}}
+	defer func() { cfg.DataDir, cfg.CommandCategories = origDataDir, origCategories }()
+
+	req := httptest.NewRequest("GET", "/api/commands?preset=all", nil)
+	w := httptest.NewRecorder()
+	handleCommandsAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    commandListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("Success=false: %s", w.Body.String())
+	}
+	if resp.Data.TotalCommands != 21 || len(resp.Data.Commands) != 21 {
+		t.Fatalf("commands=%d/%d, want 21 (unlimited)", resp.Data.TotalCommands, len(resp.Data.Commands))
+	}
+	if resp.Data.TotalCalls != 210+20 {
+		t.Fatalf("TotalCalls=%d, want 230", resp.Data.TotalCalls)
+	}
+	if resp.Data.Commands[0].Command != "/alpha" || resp.Data.Commands[1].Command != "/cmd19" || resp.Data.Commands[20].Command != "/cmd0" {
+		t.Fatalf("unexpected ordering: %+v", resp.Data.Commands)
+	}
+
+	req = httptest.NewRequest("GET", "/api/commands?preset=all&offset=2&limit=3", nil)
+	w = httptest.NewRecorder()
+	handleCommandsAPI(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if len(resp.Data.Commands) != 3 || resp.Data.Commands[0].Command != "/cmd18" || resp.Data.TotalCommands != 21 {
+		t.Fatalf("paged commands=%+v total=%d, want 3 starting at /cmd18", resp.Data.Commands, resp.Data.TotalCommands)
+	}
+	// 分类汇总不受分页影响
+	wantCategories := []CategoryStat{
+		{Category: commandCategoryOther, Count: 209, Commands: []string{
+			"/cmd1", "/cmd10", "/cmd11", "/cmd12", "/cmd13", "/cmd14", "/cmd15", "/cmd16", "/cmd17", "/cmd18", "/cmd19",
+			"/cmd2", "/cmd3", "/cmd4", "/cmd5", "/cmd6", "/cmd7", "/cmd8", "/cmd9",
+		}},
+		{Category: "first", Count: 21, Commands: []string{"/alpha", "/cmd0"}},
+	}
+	if !reflect.DeepEqual(resp.Data.CommandCategories, wantCategories) {
+		t.Fatalf("command_categories = %+v, want %+v", resp.Data.CommandCategories, wantCategories)
+	}
+}
+
+func TestHandleProjectsAPISortsByTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "projects", "p")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Hour)
+	// /tmp/chatty：消息多但 token 少（3×15=45）；/tmp/heavy：1 条消息 1500 token
+	heavy := strings.Replace(projectRecordJSON("/tmp/heavy", "s2", now), `"input_tokens":10,"output_tokens":5`, `"input_tokens":1000,"output_tokens":500`, 1)
+	content := projectRecordJSON("/tmp/chatty", "s1", now) + "\n" +
+		projectRecordJSON("/tmp/chatty", "s1", now.Add(time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/chatty", "s1", now.Add(2*time.Minute)) + "\n" +
+		heavy + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "s.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(tmpDir, "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: tmpDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = tmpDir
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	for _, source := range []struct {
+		name  string
+		cache *CacheFile
+	}{{"parsing", nil}, {"cache", cache}} {
+		storeGlobalCache(source.cache)
+		get := func(query string) projectListData {
+			t.Helper()
+			w := httptest.NewRecorder()
+			handleProjectsAPI(w, httptest.NewRequest("GET", "/api/projects?preset=all"+query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: 状态码 = %d, body=%s", source.name, w.Code, w.Body.String())
+			}
+			var resp struct {
+				Data projectListData `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("无法解析响应 JSON: %v", err)
+			}
+			return resp.Data
+		}
+
+		byMessages := get("")
+		if len(byMessages.Projects) != 2 || byMessages.Projects[0].Project != "/tmp/chatty" {
+			t.Fatalf("%s: 默认应按消息数排序: %+v", source.name, byMessages.Projects)
+		}
+		byTokens := get("&sort=tokens")
+		if byTokens.Projects[0].Project != "/tmp/heavy" || byTokens.Projects[0].Tokens != 1500 || byTokens.Projects[1].Tokens != 45 {
+			t.Fatalf("%s: sort=tokens 排序或合计不符: %+v", source.name, byTokens.Projects)
+		}
+		if byTokens.TotalTokens != 1545 {
+			t.Fatalf("%s: total_tokens=%d, want 1545", source.name, byTokens.TotalTokens)
+		}
+		if paged := get("&sort=tokens&offset=1&limit=1"); len(paged.Projects) != 1 || paged.Projects[0].Project != "/tmp/chatty" {
+			t.Fatalf("%s: 分页结果不符: %+v", source.name, paged.Projects)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleProjectsAPI(w, httptest.NewRequest("GET", "/api/projects?sort=bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("无效 sort 应返回 400, got %d", w.Code)
+	}
+}
+
+func TestHandlePresetsAPIListsResolvedRanges(t *testing.T) {
+	w := httptest.NewRecorder()
+	handlePresetsAPI(w, httptest.NewRequest("GET", "/api/presets", nil))
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Presets []presetInfo `json:"presets"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	byKey := map[string]presetInfo{}
+	for _, item := range resp.Data.Presets {
+		byKey[item.Key] = item
+	}
+	if !resp.Success || len(byKey) != len(rangePresets) || !byKey["30d"].Default {
+		t.Fatalf("预设列表不符: %s", w.Body.String())
+	}
+
+	day, ok := byKey["24h"]
+	if !ok {
+		t.Fatalf("缺少 24h 预设: %s", w.Body.String())
+	}
+	start, err1 := time.Parse(time.RFC3339, day.Start)
+	end, err2 := time.Parse(time.RFC3339, day.End)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("24h 起止无法解析: start=%q end=%q", day.Start, day.End)
+	}
+	if span := end.Sub(start); span < 23*time.Hour || span > 25*time.Hour {
+		t.Fatalf("24h 窗口跨度 = %v, want ~24h", span)
+	}
+	if all := byKey["all"]; all.Start != "" || all.End != "" {
+		t.Fatalf("all 不应限定起止: %+v", all)
+	}
+}
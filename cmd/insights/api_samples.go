@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 原始记录样例：/api/data?samples=N 时，按指标附带计入该分类的若干条原始记录位置，
+// 便于对照源文件核实某个数字的归类是否正确。
+// 样例只给出分类结果与定位信息（文件、行号、时间、会话），不回传提示词与消息正文。
+
+const (
+	SampleMetricModel   = "model"
+	SampleMetricCommand = "command"
+)
+
+// maxRecordSamples 单个分类最多返回的样例数
+const maxRecordSamples = 20
+
+// RecordSample 一条计入指标的原始记录
+type RecordSample struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Project   string `json:"project,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// MetricSamples 某个指标分类（如某个模型、某条命令）的样例
+type MetricSamples struct {
+	Metric  string         `json:"metric"`
+	Key     string         `json:"key"`
+	Records []RecordSample `json:"records"`
+}
+
+// normalizeSampleMetric 校验 sample_metric，空值默认为 model
+func normalizeSampleMetric(value string) (string, error) {
+	switch metric := strings.ToLower(strings.TrimSpace(value)); metric {
+	case "":
+		return SampleMetricModel, nil
+	case SampleMetricModel, SampleMetricCommand:
+		return metric, nil
+	default:
+		return "", fmt.Errorf("无效的 sample_metric: %s（可选 model|command）", value)
+	}
+}
+
+// collectRecordSamples 按 filter 重新扫描原始数据，收集每个分类的前 N 条样例。
+// 分类逻辑与统计解析器一致：模型取 assistant 消息的 message.model，命令取 slashCommandKey。
+func collectRecordSamples(filter AnalysisFilter) ([]MetricSamples, error) {
+	limit := filter.RecordSamples
+	if limit <= 0 {
+		return nil, nil
+	}
+	if limit > maxRecordSamples {
+		limit = maxRecordSamples
+	}
+	samples := make(map[string][]RecordSample)
+	add := func(key string, sample RecordSample) {
+		if filter.SampleKey != "" && key != filter.SampleKey {
+			return
+		}
+		if len(samples[key]) < limit {
+			samples[key] = append(samples[key], sample)
+		}
+	}
+
+	var err error
+	if filter.SampleMetric == SampleMetricCommand {
+		err = collectCommandSamples(filter, add)
+	} else {
+		err = collectModelSamples(filter, add)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(samples))
+	for key := range samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]MetricSamples, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, MetricSamples{Metric: filter.SampleMetric, Key: key, Records: samples[key]})
+	}
+	return result, nil
+}
+
+// collectModelSamples 扫描 projects 下的会话文件，收集计入各模型的 assistant 消息
+func collectModelSamples(filter AnalysisFilter, add func(string, RecordSample)) error {
+	projectDirs, err := listProjectDirs(GetDataPath("projects"))
+	if err != nil {
+		return fmt.Errorf("读取 projects 目录失败: %w", err)
+	}
+	var files []string
+	for _, projectDir := range projectDirs {
+		projectFiles, err := projectJSONLFiles(projectDir)
+		if err != nil {
+			continue
+		}
+		files = append(files, projectFiles...)
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		f, err := dataSource.Open(path)
+		if err != nil {
+			continue
+		}
+		lines := newJSONLReader(f)
+		for {
+			line, ok := lines.Next()
+			if !ok {
+				break
+			}
+			var record ProjectRecord
+			if err := json.Unmarshal(line, &record); err != nil || record.Type != "assistant" {
+				continue
+			}
+			timestamp, hasTimestamp := parseProjectRecordTimestamp(record.Timestamp)
+			if (hasTimestamp && !filter.TimeFilter.Contains(timestamp)) || (!hasTimestamp && hasTimeFilter(filter.TimeFilter)) {
+				continue
+			}
+			if !filter.TimeFilter.MatchesUserType(record.UserType) || isExcludedProject(record.Cwd) {
+				continue
+			}
+			projectName := projectNameFromCwd(record.Cwd)
+			if filter.Project != "" && !matchContains(filter.Project, projectName) {
+				continue
+			}
+			var msg AssistantMessage
+			if err := json.Unmarshal(record.Message, &msg); err != nil || msg.Model == "" {
+				continue
+			}
+			add(msg.Model, RecordSample{
+				File:      path,
+				Line:      lines.Line(),
+				Timestamp: record.Timestamp,
+				Project:   projectName,
+				SessionID: record.SessionID,
+			})
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// collectCommandSamples 扫描全部 history 分片，收集计入各 slash command 的记录
+func collectCommandSamples(filter AnalysisFilter, add func(string, RecordSample)) error {
+	for _, path := range historyFilePaths() {
+		if err := collectCommandSamplesFromFile(path, filter, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func collectCommandSamplesFromFile(path string, filter AnalysisFilter, add func(string, RecordSample)) error {
+	f, err := dataSource.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+
+	withArgs := filter.Breakdown == CommandBreakdownArgs
+	lines := newJSONLReader(f)
+	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		recordTime := parseHistoryTimestamp(record.Timestamp)
+		if !filter.TimeFilter.Contains(recordTime) || isExcludedProject(record.Project) {
+			continue
+		}
+		if filter.Project != "" && !matchContains(filter.Project, record.Project) {
+			continue
+		}
+		key, ok := slashCommandKey(record.Display, withArgs)
+		if !ok || isExcludedCommand(key.Command) {
+			continue
+		}
+		name := key.Command
+		if key.SubCommand != "" {
+			name += " " + key.SubCommand
+		}
+		add(name, RecordSample{
+			File:      path,
+			Line:      lines.Line(),
+			Timestamp: recordTime.UTC().Format(time.RFC3339),
+			Project:   record.Project,
+		})
+	}
+	return nil
+}
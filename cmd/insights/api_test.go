@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -70,6 +72,46 @@ func TestGracefulDegradation_MissingDebugDir(t *testing.T) {
 	}
 }
 
+// TestGetDailyTrendDatesAlignWithCounts 日期取自与计数相同的记录：乱序输入时按日期排序后取最近 7 天
+func TestGetDailyTrendDatesAlignWithCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `{"dailyActivity": [
+		{"date": "2026-01-09", "messageCount": 9},
+		{"date": "2026-01-01", "messageCount": 1},
+		{"date": "2026-01-05", "messageCount": 5},
+		{"date": "2026-01-03", "messageCount": 3},
+		{"date": "2026-01-08", "messageCount": 8},
+		{"date": "2026-01-02", "messageCount": 2},
+		{"date": "2026-01-07", "messageCount": 7},
+		{"date": "2026-01-04", "messageCount": 4},
+		{"date": "2026-01-06", "messageCount": 6}
+	]}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "stats-cache.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = tmpDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	dates, counts, err := GetDailyTrend()
+	if err != nil {
+		t.Fatalf("GetDailyTrend() error = %v", err)
+	}
+	wantDates := []string{"2026-01-03", "2026-01-04", "2026-01-05", "2026-01-06", "2026-01-07", "2026-01-08", "2026-01-09"}
+	if !reflect.DeepEqual(dates, wantDates) {
+		t.Fatalf("dates = %v, want %v", dates, wantDates)
+	}
+	if len(counts) != len(dates) {
+		t.Fatalf("len(counts)=%d, len(dates)=%d", len(counts), len(dates))
+	}
+	for i, date := range dates {
+		// 测试数据中计数等于日期的日
+		if want := int(date[len(date)-1] - '0'); counts[i] != want {
+			t.Fatalf("%s count = %d, want %d", date, counts[i], want)
+		}
+	}
+}
+
 // TestGracefulDegradation_CorruptStatsCache 测试 stats-cache.json 损坏时的降级
 // P0: JSON 文件被截断时应容错
 func TestGracefulDegradation_CorruptStatsCache(t *testing.T) {
@@ -179,6 +221,85 @@ func TestHandleDataAPIRejectsUnknownPreset(t *testing.T) {
 	}
 }
 
+func TestHandleDataAPIParseTimingsOnlyInDebug(t *testing.T) {
+	useFixtureDataDir(t)
+
+	fetch := func(query string) *DashboardData {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleDataAPI(w, httptest.NewRequest("GET", "/api/data?preset=all"+query, nil))
+		var resp struct {
+			Success bool           `json:"success"`
+			Data    *DashboardData `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.Success {
+			t.Fatalf("响应无效 err=%v body=%s", err, w.Body.String())
+		}
+		return resp.Data
+	}
+
+	timings := fetch("&debug=1").ParseTimings
+	for _, key := range []string{"history", "projects", "debug", "tasks"} {
+		if seconds, ok := timings[key]; !ok || seconds < 0 {
+			t.Fatalf("parse_timings[%q] = %v, %v; timings=%v", key, seconds, ok, timings)
+		}
+	}
+	if len(timings) != 4 {
+		t.Fatalf("parse_timings=%v, want 4 sources", timings)
+	}
+	if data := fetch(""); data.ParseTimings != nil {
+		t.Fatalf("未指定 debug 时不应返回 parse_timings: %v", data.ParseTimings)
+	}
+}
+
+func TestAPIResponsesCarryVersionAndFreshness(t *testing.T) {
+	useFixtureDataDir(t)
+	fields := func(t *testing.T, body []byte) (string, string) {
+		t.Helper()
+		var resp struct {
+			APIVersion string `json:"api_version"`
+			LastUpdate string `json:"last_update"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("无法解析响应 JSON: %v (%s)", err, body)
+		}
+		return resp.APIVersion, resp.LastUpdate
+	}
+
+	// 未加载缓存：last_update 为本次实时解析时间
+	w := httptest.NewRecorder()
+	handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly?date=2026-01-05", nil))
+	if version, updated := fields(t, w.Body.Bytes()); version != apiVersion || updated == "" {
+		t.Fatalf("api_version=%q last_update=%q", version, updated)
+	}
+
+	// 已加载缓存：各类响应（含错误响应）都回显缓存构建时间
+	lastUpdate := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	rulesHash, err := currentBashRulesHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeGlobalCache(&CacheFile{Version: CacheVersion, LastUpdate: lastUpdate, BashRulesHash: rulesHash, DailyStats: map[string]*DayAggregate{}})
+	want := lastUpdate.Format(time.RFC3339)
+	for name, handle := range map[string]func(*httptest.ResponseRecorder){
+		"hourly": func(w *httptest.ResponseRecorder) {
+			handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly?date=2026-01-05", nil))
+		},
+		"hourly error": func(w *httptest.ResponseRecorder) {
+			handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly", nil))
+		},
+		"data": func(w *httptest.ResponseRecorder) {
+			handleDataAPI(w, httptest.NewRequest("GET", "/api/data?preset=all", nil))
+		},
+	} {
+		w := httptest.NewRecorder()
+		handle(w)
+		if version, updated := fields(t, w.Body.Bytes()); version != apiVersion || updated != want {
+			t.Fatalf("%s: api_version=%q last_update=%q, want %q/%q", name, version, updated, apiVersion, want)
+		}
+	}
+}
+
 // TestSafeParseHistoryConcurrent 测试 history 解析的容错包装
 // P0 错误隔离: 文件不存在/损坏时不 panic
 func TestSafeParseHistoryConcurrent(t *testing.T) {
@@ -771,3 +892,236 @@ func TestQueryByTimeRange_FilterGlobalStats(t *testing.T) {
 	t.Logf("✅ QueryByTimeRange 正确过滤了全局统计: projects=%d, models=%d",
 		len(result.ProjectStats), len(result.ModelUsage))
 }
+
+func TestHandleDataAPIDeltaReturnsOnlyPostUpdateRecords(t *testing.T) {
+	tmpDir := t.TempDir()
+	lastUpdate := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, dir := range []string{"old", "new"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, "projects", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// old.jsonl 在缓存更新前就未再修改，不应被解析
+	oldPath := filepath.Join(tmpDir, "projects", "old", "old.jsonl")
+	if err := os.WriteFile(oldPath, []byte(projectRecordJSON("/tmp/old", "old-session", lastUpdate.Add(-2*time.Hour))+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldMtime := lastUpdate.Add(-time.Hour)
+	if err := os.Chtimes(oldPath, oldMtime, oldMtime); err != nil {
+		t.Fatal(err)
+	}
+	// new.jsonl 之后被追加：只统计 LastUpdate 之后的两条
+	newContent := projectRecordJSON("/tmp/new", "new-session", lastUpdate.Add(-30*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/new", "new-session", lastUpdate.Add(10*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/new", "new-session", lastUpdate.Add(20*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "projects", "new", "new.jsonl"), []byte(newContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	historyContent := `{"display":"/old","timestamp":` + formatUnixMilli(lastUpdate.Add(-time.Minute)) + `,"project":"/tmp/new"}` + "\n" +
+		`{"display":"/new","timestamp":` + formatUnixMilli(lastUpdate.Add(time.Minute)) + `,"project":"/tmp/new"}` + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "history.jsonl"), []byte(historyContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = tmpDir
+	storeGlobalCache(&CacheFile{LastUpdate: lastUpdate})
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	req := httptest.NewRequest("GET", "/api/data?delta=1", nil)
+	w := httptest.NewRecorder()
+	handleDataAPI(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d; body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Success bool          `json:"success"`
+		Data    DashboardData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	data := resp.Data
+	if data.TimeRange.Preset != "delta" || data.TimeRange.Since != lastUpdate.Format(time.RFC3339) {
+		t.Fatalf("time_range = %+v, want delta since %s", data.TimeRange, lastUpdate.Format(time.RFC3339))
+	}
+	if data.ProjectStats == nil || data.ProjectStats.TotalMessages != 2 || len(data.ProjectStats.Projects) != 1 {
+		t.Fatalf("增量应只包含 new 项目的 2 条记录: %+v", data.ProjectStats)
+	}
+	if len(data.Commands) != 1 || data.Commands[0].Command != "/new" {
+		t.Fatalf("增量命令应只有 /new: %+v", data.Commands)
+	}
+}
+
+func TestUnknownModelBucketReconcilesWithMessageTotals(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "projects", "p")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Hour)
+	noModel := `{"type":"assistant","cwd":"/tmp/p","sessionId":"s1","timestamp":"` + now.Add(time.Minute).UTC().Format(time.RFC3339Nano) + `","message":{"usage":{"input_tokens":1,"output_tokens":1}}}`
+	userRecord := func(at time.Time) string {
+		return `{"type":"user","cwd":"/tmp/p","sessionId":"s1","timestamp":"` + at.UTC().Format(time.RFC3339Nano) + `","message":{"role":"user","content":"hi"}}`
+	}
+	content := userRecord(now.Add(-time.Minute)) + "\n" + projectRecordJSON("/tmp/p", "s1", now) + "\n" + noModel + "\n" +
+		userRecord(now.Add(90*time.Second)) + "\n" + projectRecordJSON("/tmp/p", "s1", now.Add(2*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "s.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	origCfg, origCache := cfg, loadGlobalCache()
+	defer func() {
+		cfg = origCfg
+		storeGlobalCache(origCache)
+	}()
+	cfg.DataDir = tmpDir
+	storeGlobalCache(nil)
+	tf := NewTimeFilterFromPreset("all")
+
+	sumCounts := func(data *DashboardData) (int, int) {
+		total, unknown := 0, 0
+		for _, item := range data.ModelUsage {
+			total += item.Count
+			if item.Model == unknownModelName {
+				unknown = item.Count
+			}
+		}
+		return total, unknown
+	}
+
+	cfg.UnknownModelBucket = false
+	data, _, err := buildDashboardData(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDashboardData failed: %v", err)
+	}
+	if total, unknown := sumCounts(data); total != 2 || unknown != 0 || data.ProjectStats.TotalMessages != 3 {
+		t.Fatalf("默认不应有 unknown 桶: model_total=%d unknown=%d messages=%d", total, unknown, data.ProjectStats.TotalMessages)
+	}
+
+	cfg.UnknownModelBucket = true
+	data, _, err = buildDashboardData(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDashboardData failed: %v", err)
+	}
+	total, unknown := sumCounts(data)
+	if unknown != 1 {
+		t.Fatalf("缺少 model 的消息应计入 %s: %+v", unknownModelName, data.ModelUsage)
+	}
+	if total != data.ProjectStats.TotalMessages {
+		t.Fatalf("模型用量合计 %d 应与消息总数 %d 一致", total, data.ProjectStats.TotalMessages)
+	}
+
+	// count_mode=user/all 时消息数含 user 记录，unknown 桶仍只补缺少 model 的 assistant 消息
+	for _, mode := range []string{CountModeUser, CountModeAll} {
+		modeFilter := tf
+		modeFilter.CountMode = mode
+		data, _, err = buildDashboardData(modeFilter, "all")
+		if err != nil {
+			t.Fatalf("buildDashboardData(count_mode=%s) failed: %v", mode, err)
+		}
+		if total, unknown := sumCounts(data); total != 3 || unknown != 1 {
+			t.Fatalf("count_mode=%s: model_total=%d unknown=%d, want 3/1 (messages=%d)", mode, total, unknown, data.ProjectStats.TotalMessages)
+		}
+	}
+}
+
+func TestDailyIntensityFlagsBurstyDay(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(filepath.Join(dataDir, "projects", "demo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 1 月 5 日的 6 条消息全部落在 14 点；1 月 6 日每小时一条，较平稳
+	var content strings.Builder
+	for i := 0; i < 6; i++ {
+		content.WriteString(projectRecordJSON("/tmp/demo", "s1", time.Date(2026, 1, 5, 14, i*5, 0, 0, time.UTC)) + "\n")
+		content.WriteString(projectRecordJSON("/tmp/demo", "s2", time.Date(2026, 1, 6, 8+i, 0, 0, 0, time.UTC)) + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "projects", "demo", "s.jsonl"), []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	tf := NewTimeFilterFromPreset("all")
+	parsed, err := buildDataFromParsing(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromParsing failed: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	loaded, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	storeGlobalCache(loaded)
+	cached, err := buildDataFromCache(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+
+	want := []DailyIntensityItem{
+		{Date: "2026-01-05", Messages: 6, PeakHour: 14, PeakHourCount: 6, Intensity: 1.0, Bursty: true},
+		{Date: "2026-01-06", Messages: 6, PeakHour: 8, PeakHourCount: 1, Intensity: 1.0 / 6, Bursty: false},
+	}
+	for name, data := range map[string]*DashboardData{"parsing": parsed, "cache": cached} {
+		if !reflect.DeepEqual(data.DailyIntensity, want) {
+			t.Fatalf("%s daily_intensity = %+v, want %+v", name, data.DailyIntensity, want)
+		}
+	}
+}
+
+func TestEqualCountItemsSortByName(t *testing.T) {
+	// map 遍历顺序随机，多跑几轮确认同数条目始终按名称升序
+	for round := 0; round < 20; round++ {
+		commands := commandStatsFromCounts(map[commandKey]int{
+			{Command: "/model"}: 2, {Command: "/clear"}: 2, {Command: "/help"}: 5,
+		})
+		if got := []string{commands[0].Command, commands[1].Command, commands[2].Command}; !reflect.DeepEqual(got, []string{"/help", "/clear", "/model"}) {
+			t.Fatalf("commands order=%v", got)
+		}
+
+		agg := newProjectAggregate()
+		agg.ProjectStats["/repo/beta"] = &ProjectStatItem{Project: "/repo/beta", MessageCount: 3}
+		agg.ProjectStats["/repo/alpha"] = &ProjectStatItem{Project: "/repo/alpha", MessageCount: 3}
+		agg.ModelUsage["claude-sonnet-4.5"] = &ModelUsageItem{Model: "claude-sonnet-4.5", Count: 4}
+		agg.ModelUsage["claude-opus-4.5"] = &ModelUsageItem{Model: "claude-opus-4.5", Count: 4}
+		agg.finalize()
+		if agg.Projects[0].Project != "/repo/alpha" || agg.Projects[1].Project != "/repo/beta" {
+			t.Fatalf("projects order=%+v", agg.Projects)
+		}
+		if agg.ModelUsageList[0].Model != "claude-opus-4.5" || agg.ModelUsageList[1].Model != "claude-sonnet-4.5" {
+			t.Fatalf("model usage order=%+v", agg.ModelUsageList)
+		}
+	}
+
+	dataDir := t.TempDir()
+	debugDir := filepath.Join(dataDir, "debug")
+	if err := os.MkdirAll(debugDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "2026-01-05T12:00:05.000Z [DEBUG] mcp__zeta__call\n" +
+		"2026-01-05T12:00:06.000Z [DEBUG] mcp__alpha__call\n"
+	if err := os.WriteFile(filepath.Join(debugDir, "s1.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for round := 0; round < 20; round++ {
+		tools, err := ParseDebugLogsConcurrentFromDir(TimeFilter{}, dataDir)
+		if err != nil {
+			t.Fatalf("ParseDebugLogsConcurrentFromDir() error = %v", err)
+		}
+		if len(tools) != 2 || tools[0].Server != "alpha" || tools[1].Server != "zeta" {
+			t.Fatalf("runtime tools order=%+v", tools)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// --token 共享密钥：设置后 /api/* 与 /ws 需要携带 Authorization: Bearer <token> 或 ?token=<token>，
+// 否则返回 401；Dashboard 页面、静态资源与 /metrics 保持公开。未设置时不做任何校验。
+
+// requiresAuth 需要校验 token 的路径
+func requiresAuth(path string) bool {
+	return strings.HasPrefix(path, "/api/") || path == "/ws"
+}
+
+// requestToken 取请求携带的 token：优先 Authorization Bearer，其次 token 查询参数（便于浏览器直接访问与 WebSocket）
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if scheme, value, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value)
+		}
+	}
+	return r.URL.Query().Get("token")
+}
+
+// redactQueryToken 访问日志中隐藏 token 查询参数的值
+func redactQueryToken(rawQuery string) string {
+	if !strings.Contains(rawQuery, "token=") {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil || !values.Has("token") {
+		return rawQuery
+	}
+	values.Set("token", "REDACTED")
+	return values.Encode()
+}
+
+// tokenMatches 常量时间比较，避免按响应时间逐字节猜测 token
+func tokenMatches(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// AuthMiddleware token 非空时校验 /api/* 与 /ws 请求的 token；token 为空时原样透传
+func AuthMiddleware(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requiresAuth(r.URL.Path) && !tokenMatches(requestToken(r), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cc-insights"`)
+			sendInteractiveError(w, "未授权：缺少或错误的 token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	protected := AuthMiddleware(ok, "s3cret")
+
+	cases := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"缺少 token", "/api/data", "", http.StatusUnauthorized},
+		{"错误 token", "/api/data", "Bearer wrong", http.StatusUnauthorized},
+		{"非 Bearer 方案", "/api/data", "Basic s3cret", http.StatusUnauthorized},
+		{"正确 Bearer", "/api/data", "Bearer s3cret", http.StatusOK},
+		{"正确查询参数", "/api/data?token=s3cret", "", http.StatusOK},
+		{"错误查询参数", "/api/data?token=s3cre", "", http.StatusUnauthorized},
+		{"WebSocket 同样校验", "/ws", "", http.StatusUnauthorized},
+		{"Dashboard 页面公开", "/dashboard", "", http.StatusOK},
+		{"静态资源公开", "/static/echarts.min.js", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: status = %d, want %d", tc.name, w.Code, tc.want)
+		}
+		if tc.want == http.StatusUnauthorized && !strings.Contains(w.Body.String(), `"success":false`) {
+			t.Fatalf("%s: 401 响应应为 JSON 错误: %s", tc.name, w.Body.String())
+		}
+	}
+
+	// 未设置 token 时全部放行
+	w := httptest.NewRecorder()
+	AuthMiddleware(ok, "").ServeHTTP(w, httptest.NewRequest("GET", "/api/data", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("未设置 token 时 status = %d, want 200", w.Code)
+	}
+}
+
+func TestRedactQueryToken(t *testing.T) {
+	if got := redactQueryToken("preset=7d&token=s3cret"); strings.Contains(got, "s3cret") || !strings.Contains(got, "preset=7d") {
+		t.Fatalf("redactQueryToken = %q", got)
+	}
+	if got := redactQueryToken("preset=7d"); got != "preset=7d" {
+		t.Fatalf("无 token 时应原样返回, got %q", got)
+	}
+}
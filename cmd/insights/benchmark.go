@@ -0,0 +1,72 @@
+package main
+
+import "time"
+
+// 性能测试（-tags=bench 的 main）使用的测量函数。放在无构建标签的文件中，
+// 便于在普通 go test 中验证测量结果。
+
+// 性能测试的数据源
+const (
+	benchSourceHistory = "history"
+	benchSourceDebug   = "debug"
+)
+
+// BenchmarkResult 单个数据源一次解析的测量结果；-json 时每个数据源输出一行
+type BenchmarkResult struct {
+	Source         string  `json:"source"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Count          int     `json:"count"` // 命令数 / 工具数
+	Total          int     `json:"total"` // 总记录 / 总调用
+	Error          string  `json:"error,omitempty"`
+}
+
+// measureHistoryParse 测量 history.jsonl 的解析耗时
+func measureHistoryParse(tf TimeFilter) BenchmarkResult {
+	result := BenchmarkResult{Source: benchSourceHistory}
+	start := time.Now()
+	cmdStats, _, err := ParseHistoryConcurrent(tf)
+	result.ElapsedSeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Count = len(cmdStats)
+	result.Total = sumCounts(cmdStats)
+	return result
+}
+
+// measureDebugParse 测量 debug/ 日志的解析耗时
+func measureDebugParse(tf TimeFilter) BenchmarkResult {
+	result := BenchmarkResult{Source: benchSourceDebug}
+	start := time.Now()
+	toolStats, err := ParseDebugLogsConcurrent(tf)
+	result.ElapsedSeconds = time.Since(start).Seconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Count = len(toolStats)
+	result.Total = sumToolCounts(toolStats)
+	return result
+}
+
+// runBenchmarks 依次测量全部数据源
+func runBenchmarks(tf TimeFilter) []BenchmarkResult {
+	return []BenchmarkResult{measureHistoryParse(tf), measureDebugParse(tf)}
+}
+
+func sumCounts(stats []CommandStats) int {
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+	}
+	return total
+}
+
+func sumToolCounts(stats []RuntimeToolSignal) int {
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+	}
+	return total
+}
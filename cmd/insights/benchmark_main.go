@@ -3,66 +3,54 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
-	"time"
+	"os"
 )
 
 func main() {
 	rangePreset := flag.String("range", "7d", "测试时间范围: 7d 或 all")
+	jsonOutput := flag.Bool("json", false, "每个数据源输出一行 JSON（source、elapsed_seconds、count、total），便于比较多次运行")
 	flag.Parse()
 
-	fmt.Println("=== Claude Code Dashboard 性能测试 ===")
-	fmt.Printf("数据目录: %s\n\n", cfg.DataDir)
-
 	preset := Range7Days
 	if *rangePreset == "all" {
 		preset = RangeAll
 	}
-	fmt.Printf("测试时间范围: %s\n", *rangePreset)
 	tf := NewTimeFilterFromPreset(preset)
 
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		for _, result := range runBenchmarks(tf) {
+			enc.Encode(result)
+		}
+		return
+	}
+
+	fmt.Println("=== Claude Code Dashboard 性能测试 ===")
+	fmt.Printf("数据目录: %s\n\n", cfg.DataDir)
+	fmt.Printf("测试时间范围: %s\n", *rangePreset)
+
 	// 测试 history.jsonl 解析
 	fmt.Println("\n1. history.jsonl 解析测试:")
-	start := time.Now()
-	cmdStats, _, err := ParseHistoryConcurrent(tf)
-	if err != nil {
-		fmt.Printf("   错误: %v\n", err)
+	if result := measureHistoryParse(tf); result.Error != "" {
+		fmt.Printf("   错误: %s\n", result.Error)
 	} else {
-		elapsed := time.Since(start)
-		fmt.Printf("   ✓ 耗时: %.2fs\n", elapsed.Seconds())
-		fmt.Printf("   ✓ 命令数: %d\n", len(cmdStats))
-		fmt.Printf("   ✓ 总记录: %d\n", sumCounts(cmdStats))
+		fmt.Printf("   ✓ 耗时: %.2fs\n", result.ElapsedSeconds)
+		fmt.Printf("   ✓ 命令数: %d\n", result.Count)
+		fmt.Printf("   ✓ 总记录: %d\n", result.Total)
 	}
 
 	// 测试 debug 日志解析
 	fmt.Println("\n2. debug/ 日志解析测试:")
-	start = time.Now()
-	toolStats, err := ParseDebugLogsConcurrent(tf)
-	if err != nil {
-		fmt.Printf("   错误: %v\n", err)
+	if result := measureDebugParse(tf); result.Error != "" {
+		fmt.Printf("   错误: %s\n", result.Error)
 	} else {
-		elapsed := time.Since(start)
-		fmt.Printf("   ✓ 耗时: %.2fs\n", elapsed.Seconds())
-		fmt.Printf("   ✓ 工具数: %d\n", len(toolStats))
-		fmt.Printf("   ✓ 总调用: %d\n", sumToolCounts(toolStats))
+		fmt.Printf("   ✓ 耗时: %.2fs\n", result.ElapsedSeconds)
+		fmt.Printf("   ✓ 工具数: %d\n", result.Count)
+		fmt.Printf("   ✓ 总调用: %d\n", result.Total)
 	}
 
 	fmt.Println("\n=== 测试完成 ===")
 }
-
-func sumCounts(stats []CommandStats) int {
-	total := 0
-	for _, s := range stats {
-		total += s.Count
-	}
-	return total
-}
-
-func sumToolCounts(stats []RuntimeToolSignal) int {
-	total := 0
-	for _, s := range stats {
-		total += s.Count
-	}
-	return total
-}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestRunBenchmarksReportsEachSource(t *testing.T) {
+	useFixtureDataDir(t)
+
+	results := runBenchmarks(TimeFilter{})
+	if len(results) != 2 || results[0].Source != benchSourceHistory || results[1].Source != benchSourceDebug {
+		t.Fatalf("results = %+v, want history 与 debug 两项", results)
+	}
+	history, _, err := ParseHistoryConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryConcurrent() error = %v", err)
+	}
+	tools, err := ParseDebugLogsConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseDebugLogsConcurrent() error = %v", err)
+	}
+	want := []struct{ count, total int }{
+		{len(history), sumCounts(history)},
+		{len(tools), sumToolCounts(tools)},
+	}
+	for i, result := range results {
+		if result.Error != "" || result.ElapsedSeconds < 0 {
+			t.Fatalf("%s: error=%q elapsed=%v", result.Source, result.Error, result.ElapsedSeconds)
+		}
+		if result.Count == 0 || result.Count != want[i].count || result.Total != want[i].total {
+			t.Fatalf("%s: count=%d total=%d, want %d/%d", result.Source, result.Count, result.Total, want[i].count, want[i].total)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// noBranchLabel 记录缺少 gitBranch 时的归类（不在 git 仓库中或旧版本未记录）
+const noBranchLabel = "(no branch)"
+
+// BranchStat 单个 git 分支的 assistant 消息数
+type BranchStat struct {
+	Branch   string `json:"branch"`
+	Messages int    `json:"messages"`
+	Sessions int    `json:"sessions"`
+}
+
+// branchKey 归一化 ProjectRecord.GitBranch，空值归入 (no branch)
+func branchKey(branch string) string {
+	if branch = strings.TrimSpace(branch); branch == "" {
+		return noBranchLabel
+	}
+	return branch
+}
+
+// ParseBranchStats 遍历 projects 下全部 JSONL，按 gitBranch 统计消息数（口径同 tf.CountMode，默认 assistant）与会话数。
+// project 非空时只统计 cwd 匹配该项目的记录（匹配规则同 project 过滤参数）。
+// 结果按消息数降序，同数按分支名排序。
+func ParseBranchStats(tf TimeFilter, project string) ([]BranchStat, error) {
+	projectDirs, err := listProjectDirs(GetDataPath("projects"))
+	if err != nil {
+		return nil, fmt.Errorf("读取 projects 目录失败: %w", err)
+	}
+
+	messages := make(map[string]int)
+	sessions := make(map[string]map[string]bool)
+	for _, projectDir := range projectDirs {
+		files, err := projectJSONLFiles(projectDir)
+		if err != nil {
+			continue
+		}
+		for _, path := range files {
+			f, err := dataSource.Open(path)
+			if err != nil {
+				continue
+			}
+			lines := newJSONLReader(f)
+			for {
+				line, ok := lines.Next()
+				if !ok {
+					break
+				}
+				var record ProjectRecord
+				if err := json.Unmarshal(line, &record); err != nil || !tf.countsRecordType(record.Type) {
+					continue
+				}
+				timestamp, hasTimestamp := parseProjectRecordTimestamp(record.Timestamp)
+				if (hasTimestamp && !tf.Contains(timestamp)) || (!hasTimestamp && hasTimeFilter(tf)) {
+					continue
+				}
+				if !tf.MatchesUserType(record.UserType) || isExcludedProject(record.Cwd) {
+					continue
+				}
+				projectName := projectNameFromCwd(record.Cwd)
+				if project != "" && !matchContains(project, projectName) {
+					continue
+				}
+				branch := branchKey(record.GitBranch)
+				messages[branch]++
+				if record.SessionID != "" {
+					if sessions[branch] == nil {
+						sessions[branch] = make(map[string]bool)
+					}
+					sessions[branch][record.SessionID] = true
+				}
+			}
+			f.Close()
+		}
+	}
+
+	stats := make([]BranchStat, 0, len(messages))
+	for branch, count := range messages {
+		stats = append(stats, BranchStat{Branch: branch, Messages: count, Sessions: len(sessions[branch])})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Messages != stats[j].Messages {
+			return stats[i].Messages > stats[j].Messages
+		}
+		return stats[i].Branch < stats[j].Branch
+	})
+	return stats, nil
+}
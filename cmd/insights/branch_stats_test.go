@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// branchRecordJSON 带 gitBranch 字段的 assistant 记录；branch 为空时不写该字段
+func branchRecordJSON(cwd, sessionID, branch string, ts time.Time) string {
+	record := projectRecordJSON(cwd, sessionID, ts)
+	if branch == "" {
+		return record
+	}
+	return strings.Replace(record, `"type":"assistant"`, `"type":"assistant","gitBranch":"`+branch+`"`, 1)
+}
+
+func TestParseBranchStatsPerBranchAndProject(t *testing.T) {
+	dataDir := t.TempDir()
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	files := map[string]string{
+		"alpha/a.jsonl": branchRecordJSON("/tmp/alpha", "s1", "main", base) + "\n" +
+			branchRecordJSON("/tmp/alpha", "s1", "feature/x", base.Add(time.Minute)) + "\n" +
+			branchRecordJSON("/tmp/alpha", "s2", "feature/x", base.Add(time.Hour)) + "\n" +
+			branchRecordJSON("/tmp/alpha", "s2", "", base.Add(2*time.Hour)) + "\n",
+		"beta/b.jsonl": branchRecordJSON("/tmp/beta", "s3", "main", base) + "\n" +
+			branchRecordJSON("/tmp/beta", "s3", "main", base.Add(time.Minute)) + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dataDir, "projects", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	stats, err := ParseBranchStats(TimeFilter{}, "")
+	if err != nil {
+		t.Fatalf("ParseBranchStats() error = %v", err)
+	}
+	want := []BranchStat{
+		{Branch: "main", Messages: 3, Sessions: 2},
+		{Branch: "feature/x", Messages: 2, Sessions: 2},
+		{Branch: noBranchLabel, Messages: 1, Sessions: 1},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("branch stats = %+v, want %+v", stats, want)
+	}
+
+	req := httptest.NewRequest("GET", "/api/branches?preset=all&project=alpha", nil)
+	w := httptest.NewRecorder()
+	handleBranchesAPI(w, req)
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    branchListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	wantAlpha := []BranchStat{
+		{Branch: "feature/x", Messages: 2, Sessions: 2},
+		{Branch: noBranchLabel, Messages: 1, Sessions: 1},
+		{Branch: "main", Messages: 1, Sessions: 1},
+	}
+	if !resp.Success || resp.Data.TotalMessages != 4 || !reflect.DeepEqual(resp.Data.Branches, wantAlpha) {
+		t.Fatalf("project=alpha 结果不符: %s", w.Body.String())
+	}
+}
@@ -4,47 +4,60 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 )
 
-const CacheVersion = "3.7"
+const CacheVersion = "3.18"
 
 // CacheFile 缓存文件结构
 type CacheFile struct {
-	Version       string           // 缓存格式版本
-	LastUpdate    time.Time        // 最后缓存时间戳
-	TimeRange     TimeRange        // 缓存覆盖的时间范围
-	BashRulesHash string           `json:"bash_rules_hash,omitempty"`
-	BuildStats    *CacheBuildStats `json:"build_stats,omitempty"`
+	Version       string    // 缓存格式版本
+	LastUpdate    time.Time // 最后缓存时间戳
+	TimeRange     TimeRange // 缓存覆盖的时间范围
+	BashRulesHash string    `json:"bash_rules_hash,omitempty"`
+	// MCPPattern 构建时使用的非默认 MCP 工具正则（默认正则为空），变更后需要重建
+	MCPPattern string `json:"mcp_pattern,omitempty"`
+	// ExcludeProjects 构建时生效的项目排除前缀（见 excludeProjectsCacheKey），变更后需要重建
+	ExcludeProjects string `json:"exclude_projects,omitempty"`
+	// CollapseHome 构建时项目路径是否把家目录折叠为 ~，变更后需要重建
+	CollapseHome bool `json:"collapse_home,omitempty"`
+	// SessionGapMinutes 构建时使用的非默认会话切分间隔（见 sessionGapCacheKey，默认为 0），变更后需要重建
+	SessionGapMinutes int `json:"session_gap_minutes,omitempty"`
+	// MinSessionMessages 构建时使用的非默认活跃会话阈值（见 minSessionMessagesCacheKey，默认为 0），变更后需要重建
+	MinSessionMessages int              `json:"min_session_messages,omitempty"`
+	BuildStats         *CacheBuildStats `json:"build_stats,omitempty"`
+	// DataQuality 构建缓存时 projects JSONL 的解析/跳过记录数（全量，不随时间范围过滤）
+	DataQuality ParseStats `json:"data_quality"`
 
 	// 预聚合数据
 	DailyStats  map[string]*DayAggregate // "2026-01-08" -> 当天所有统计
 	HourlyStats [24]*HourAggregate       // 每小时统计
 
 	// 全局统计
-	TotalMessages       int // 总消息数
-	TotalSessions       int // 总会话数
-	ProjectStats        map[string]*ProjectStatItem
-	ModelUsage          map[string]*ModelUsageItem
-	WeekdayStats        [7]*WeekdayItem
-	RuntimeToolSignals  map[string]int
-	ToolStats           map[string]*ToolStatItem
-	ToolAnalysis        *ToolAnalysisData
-	SkillAnalysis       *SkillAnalysisData
-	FailureAnalysis     *FailureAnalysisData
-	SessionAnalysis     *SessionAnalysisData
-	EventAnalysis       *EventAnalysisData
-	AgentAnalysis       *AgentAnalysisData
-	CommandAnalysis     *CommandAnalysisData
-	CostAnalysis        *CostAnalysisData
-	FileAnalysis        *FileAnalysisData
-	TaskPlanAnalysis    *TaskPlanAnalysisData                      `json:"task_plan_analysis,omitempty"`
-	ToolPerformance     *ToolPerformanceData                       `json:"tool_performance,omitempty"`
-	ProjectFiles        map[string]*ProjectFileCache               `json:"project_file_caches,omitempty"`
-	DailyRuntime        map[string]ProjectFileAggregate            `json:"daily_runtime,omitempty"`
-	DailyProjectRuntime map[string]map[string]ProjectFileAggregate `json:"daily_project_runtime,omitempty"`
-	DailySessionRuntime map[string]map[string]ProjectFileAggregate `json:"daily_session_runtime,omitempty"`
+	TotalMessages int // 总消息数
+	TotalSessions int // 总会话数
+	// TotalLogicalSessions 按会话间隔切分后的逻辑会话总数
+	TotalLogicalSessions int
+	ProjectStats         map[string]*ProjectStatItem
+	ModelUsage           map[string]*ModelUsageItem
+	WeekdayStats         [7]*WeekdayItem
+	RuntimeToolSignals   map[string]int
+	ToolStats            map[string]*ToolStatItem
+	ToolAnalysis         *ToolAnalysisData
+	SkillAnalysis        *SkillAnalysisData
+	FailureAnalysis      *FailureAnalysisData
+	SessionAnalysis      *SessionAnalysisData
+	EventAnalysis        *EventAnalysisData
+	AgentAnalysis        *AgentAnalysisData
+	CommandAnalysis      *CommandAnalysisData
+	CostAnalysis         *CostAnalysisData
+	FileAnalysis         *FileAnalysisData
+	TaskPlanAnalysis     *TaskPlanAnalysisData                      `json:"task_plan_analysis,omitempty"`
+	ToolPerformance      *ToolPerformanceData                       `json:"tool_performance,omitempty"`
+	ProjectFiles         map[string]*ProjectFileCache               `json:"project_file_caches,omitempty"`
+	DailyRuntime         map[string]ProjectFileAggregate            `json:"daily_runtime,omitempty"`
+	DailyProjectRuntime  map[string]map[string]ProjectFileAggregate `json:"daily_project_runtime,omitempty"`
+	DailySessionRuntime  map[string]map[string]ProjectFileAggregate `json:"daily_session_runtime,omitempty"`
 }
 
 // CacheBuildStats 记录最近一次缓存构建的结构化元数据
@@ -71,10 +84,19 @@ type ProjectFileAggregate struct {
 	WeekdayData             [7]WeekdayItem                             `json:"weekday_data"`
 	DailyActivity           map[string]int                             `json:"daily_activity,omitempty"`
 	DailySessions           map[string][]string                        `json:"daily_sessions,omitempty"`
+	DailySessionSpans       map[string]map[string]SessionSpan          `json:"daily_session_spans,omitempty"`
 	DailyProjectCounts      map[string]map[string]int                  `json:"daily_project_counts,omitempty"`
 	DailyModelCounts        map[string]map[string]int                  `json:"daily_model_counts,omitempty"`
+	DailyVersionCounts      map[string]map[string]int                  `json:"daily_version_counts,omitempty"`
+	DailyStopReasonCounts   map[string]map[string]int                  `json:"daily_stop_reason_counts,omitempty"`
+	DailyOutputTokens       map[string]int                             `json:"daily_output_tokens,omitempty"`
+	DailyAssistantMessages  map[string]int                             `json:"daily_assistant_messages,omitempty"`
 	DailyModelTokens        map[string]map[string]int                  `json:"daily_model_tokens,omitempty"`
+	DailyProjectTokens      map[string]map[string]int                  `json:"daily_project_tokens,omitempty"`
+	ParseStats              *ParseStats                                `json:"parse_stats,omitempty"`
 	DailyHourlyCounts       map[string][24]int                         `json:"daily_hourly_counts,omitempty"`
+	DailyHalfHourCounts     map[string][48]int                         `json:"daily_half_hour_counts,omitempty"`
+	DailyBounds             map[string][2]int                          `json:"daily_bounds,omitempty"`
 	DailyRuntime            map[string]ProjectFileAggregate            `json:"daily_runtime,omitempty"`
 	DailyProjectRuntime     map[string]map[string]ProjectFileAggregate `json:"daily_project_runtime,omitempty"`
 	DailySessionRuntime     map[string]map[string]ProjectFileAggregate `json:"daily_session_runtime,omitempty"`
@@ -134,14 +156,27 @@ type ProjectFileAggregate struct {
 
 // DayAggregate 每日聚合数据
 type DayAggregate struct {
-	Date          string         // "2026-01-08"
-	MessageCount  int            // 当天消息数
-	SessionCount  int            // 当天会话数
-	ToolCallCount int            // 当天工具调用数
-	HourlyCounts  [24]int        // 每小时消息数
-	ProjectCounts map[string]int // 项目 -> 消息数
-	ModelCounts   map[string]int // 模型 -> 请求次数
-	ModelTokens   map[string]int // 模型 -> token 数
+	Date         string // "2026-01-08"
+	MessageCount int    // 当天消息数
+	SessionCount int    // 当天会话数
+	// LogicalSessions 当天按会话间隔切分后的逻辑会话数
+	LogicalSessions int
+	ToolCallCount   int            // 当天工具调用数
+	OutputTokens    int            // 当天 assistant 消息 output token 数
+	HourlyCounts    [24]int        // 每小时消息数
+	HourSessions    [24]int        // 每小时会话数（同一小时内按 sessionID 去重）
+	HalfHourCounts  [48]int        // 每半小时消息数，下标为 hour*2+minute/30
+	ProjectCounts   map[string]int // 项目 -> 消息数
+	ModelCounts     map[string]int // 模型 -> 请求次数
+	VersionCounts   map[string]int // Claude Code 版本 -> 消息数
+	StopReasons     map[string]int // stop_reason -> 消息数
+	ModelTokens     map[string]int // 模型 -> token 数
+	ProjectTokens   map[string]int // 项目 -> token 数
+	// AssistantMessages 当天 assistant 消息数，作为平均 output token 的分母（MessageCount 随计数口径变化）
+	AssistantMessages int
+	// Bounds 当天首条与末条消息的当天秒数，HasBounds 为 false 时无效
+	Bounds    [2]int
+	HasBounds bool
 }
 
 // HourAggregate 每小时聚合数据
@@ -177,25 +212,17 @@ func (tr TimeRange) Contains(t time.Time) bool {
 	return true
 }
 
-// Save 保存缓存到文件
+// Save 保存缓存到文件：先写同目录临时文件再 rename 覆盖，写入中途失败或进程崩溃时原缓存保持完整
 func (cf *CacheFile) Save(path string) error {
-	// 确保目录存在
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("创建缓存目录失败: %w", err)
-	}
-
 	// 序列化为紧凑 JSON。缓存偏向机器读写，避免 pretty print 放大文件级聚合缓存体积。
 	data, err := json.Marshal(cf)
 	if err != nil {
 		return fmt.Errorf("序列化缓存失败: %w", err)
 	}
 
-	// 写入文件
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := writeFileAtomic(path, data); err != nil {
 		return fmt.Errorf("写入缓存文件失败: %w", err)
 	}
-
 	return nil
 }
 
@@ -245,6 +272,7 @@ func (cf *CacheFile) QueryByTimeRange(start, end time.Time) *CacheFile {
 		},
 		BashRulesHash:       cf.BashRulesHash,
 		BuildStats:          cloneCacheBuildStats(cf.BuildStats),
+		DataQuality:         cf.DataQuality,
 		DailyStats:          make(map[string]*DayAggregate),
 		HourlyStats:         [24]*HourAggregate{},
 		ProjectStats:        make(map[string]*ProjectStatItem),
@@ -271,11 +299,15 @@ func (cf *CacheFile) QueryByTimeRange(start, end time.Time) *CacheFile {
 			dayCopy := *dayStats
 			dayCopy.ProjectCounts = copyIntMap(dayStats.ProjectCounts)
 			dayCopy.ModelCounts = copyIntMap(dayStats.ModelCounts)
+			dayCopy.VersionCounts = copyIntMap(dayStats.VersionCounts)
+			dayCopy.StopReasons = copyIntMap(dayStats.StopReasons)
 			dayCopy.ModelTokens = copyIntMap(dayStats.ModelTokens)
+			dayCopy.ProjectTokens = copyIntMap(dayStats.ProjectTokens)
 			result.DailyStats[date] = &dayCopy
 
 			result.TotalMessages += dayStats.MessageCount
 			result.TotalSessions += dayStats.SessionCount
+			result.TotalLogicalSessions += dayStats.LogicalSessions
 
 			for hour, count := range dayStats.HourlyCounts {
 				if count == 0 {
@@ -285,6 +317,7 @@ func (cf *CacheFile) QueryByTimeRange(start, end time.Time) *CacheFile {
 					result.HourlyStats[hour] = &HourAggregate{Hour: hour}
 				}
 				result.HourlyStats[hour].MessageCount += count
+				result.HourlyStats[hour].SessionCount += dayStats.HourSessions[hour]
 			}
 			weekday := (int(dateParsed.Weekday()) + 6) % 7
 			if result.WeekdayStats[weekday] == nil {
@@ -301,6 +334,12 @@ func (cf *CacheFile) QueryByTimeRange(start, end time.Time) *CacheFile {
 				}
 				result.ProjectStats[project].MessageCount += count
 			}
+			for project, tokens := range dayStats.ProjectTokens {
+				if result.ProjectStats[project] == nil {
+					result.ProjectStats[project] = &ProjectStatItem{Project: project}
+				}
+				result.ProjectStats[project].Tokens += tokens
+			}
 			for model, count := range dayStats.ModelCounts {
 				if result.ModelUsage[model] == nil {
 					result.ModelUsage[model] = &ModelUsageItem{Model: model}
@@ -382,6 +421,7 @@ func cloneSessionAnalysis(source *SessionAnalysisData) *SessionAnalysisData {
 	copyValue.Outcomes = append([]SessionOutcomeStat(nil), source.Outcomes...)
 	copyValue.QueueOperations = append([]QueueOperationStat(nil), source.QueueOperations...)
 	copyValue.Titles = append([]SessionTitleStat(nil), source.Titles...)
+	copyValue.Summaries = append([]SessionAnalysisItem(nil), source.Summaries...)
 	return &copyValue
 }
 
@@ -528,6 +568,34 @@ func weekdayName(weekday int) string {
 	return names[weekday]
 }
 
+// addHourlyMessage 累加小时聚合，按需初始化对应小时的指针
+func (cf *CacheFile) addHourlyMessage(hour int, newSession bool) {
+	if hour < 0 || hour >= 24 {
+		return
+	}
+	if cf.HourlyStats[hour] == nil {
+		cf.HourlyStats[hour] = &HourAggregate{Hour: hour}
+	}
+	cf.HourlyStats[hour].MessageCount++
+	if newSession {
+		cf.HourlyStats[hour].SessionCount++
+	}
+}
+
+// addWeekdayMessage 累加星期聚合（0=周一），按需初始化带名称的 WeekdayItem
+func (cf *CacheFile) addWeekdayMessage(weekday int) {
+	if weekday < 0 || weekday >= 7 {
+		return
+	}
+	if cf.WeekdayStats[weekday] == nil {
+		cf.WeekdayStats[weekday] = &WeekdayItem{
+			Weekday:     weekday,
+			WeekdayName: weekdayName(weekday),
+		}
+	}
+	cf.WeekdayStats[weekday].MessageCount++
+}
+
 // AddMessage 添加一条消息记录到每日聚合
 func (da *DayAggregate) AddMessage(project string, hour int) {
 	da.MessageCount++
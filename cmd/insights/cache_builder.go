@@ -3,12 +3,12 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
-	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -23,6 +23,24 @@ type CacheBuilder struct {
 
 var cacheRefreshMu sync.Mutex
 
+// cacheRefreshCall 进行中的一次缓存刷新；刷新期间到达的调用直接等待并共享其结果，
+// 避免并发的 /api/reload 与 --watch 排队各做一遍重建
+type cacheRefreshCall struct {
+	force bool
+	done  chan struct{}
+	err   error
+}
+
+var (
+	cacheRefreshFlightMu sync.Mutex
+	cacheRefreshInFlight *cacheRefreshCall
+	// rebuildGlobalCache 实际执行刷新的函数（测试可替换）
+	rebuildGlobalCache = rebuildGlobalCacheLocked
+)
+
+// errCacheRefreshAborted 刷新中途 panic 时等待者收到的错误
+var errCacheRefreshAborted = errors.New("缓存刷新异常中断")
+
 type projectFileInfo struct {
 	RelPath string
 	AbsPath string
@@ -50,8 +68,15 @@ func (cb *CacheBuilder) BuildFullCache() error {
 		return fmt.Errorf("加载 Bash 规则失败: %w", err)
 	}
 
+	// 跨进程互斥：拿到锁之后再读取上一次缓存，等待者可以直接复用前一个构建者的文件级快照
+	release, err := acquireCacheLock(cb.CachePath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	previous, _ := LoadCacheFile(cb.CachePath)
-	if previous != nil && (previous.Version != CacheVersion || previous.BashRulesHash != rulesHash) {
+	if previous != nil && (previous.Version != CacheVersion || previous.BashRulesHash != rulesHash || previous.ExcludeProjects != excludeProjectsCacheKey() || previous.CollapseHome != cfg.CollapseHome || previous.SessionGapMinutes != sessionGapCacheKey()) {
 		previous = nil
 	}
 
@@ -74,10 +99,16 @@ func (cb *CacheBuilder) BuildFullCache() error {
 
 	// 创建缓存结构
 	cache := &CacheFile{
-		Version:       CacheVersion,
-		LastUpdate:    time.Now(),
-		TimeRange:     TimeRange{},
-		BashRulesHash: rulesHash,
+		Version:            CacheVersion,
+		LastUpdate:         time.Now(),
+		TimeRange:          TimeRange{},
+		BashRulesHash:      rulesHash,
+		MCPPattern:         mcpPatternCacheKey(),
+		ExcludeProjects:    excludeProjectsCacheKey(),
+		CollapseHome:       cfg.CollapseHome,
+		SessionGapMinutes:  sessionGapCacheKey(),
+		MinSessionMessages: minSessionMessagesCacheKey(),
+		DataQuality:        aggregate.ParseStats,
 		BuildStats: &CacheBuildStats{
 			BuiltAt:       buildStartedAt.Format(time.RFC3339),
 			TotalFiles:    reused + parsed,
@@ -158,15 +189,29 @@ func (cb *CacheBuilder) BuildFullCache() error {
 		if sessionStats != nil && sessionStats.DailySessionMap != nil {
 			sessionCount = sessionStats.DailySessionMap[day.Date]
 		}
+		logicalSessions := sessionCount
+		if spans := aggregate.DailySessionSpans[day.Date]; len(spans) > 0 {
+			logicalSessions = countLogicalSessions(spans)
+		}
 		cache.DailyStats[day.Date] = &DayAggregate{
-			Date:          day.Date,
-			MessageCount:  day.MessageCount,
-			SessionCount:  sessionCount,
-			ToolCallCount: 0,
-			HourlyCounts:  aggregate.DailyHourlyCounts[day.Date],
-			ProjectCounts: copyIntMap(aggregate.DailyProjectCounts[day.Date]),
-			ModelCounts:   copyIntMap(aggregate.DailyModelCounts[day.Date]),
-			ModelTokens:   copyIntMap(aggregate.DailyModelTokens[day.Date]),
+			Date:            day.Date,
+			MessageCount:    day.MessageCount,
+			SessionCount:    sessionCount,
+			LogicalSessions: logicalSessions,
+			ToolCallCount:   0,
+			OutputTokens:    aggregate.DailyOutputTokens[day.Date],
+			HourlyCounts:    aggregate.DailyHourlyCounts[day.Date],
+			HalfHourCounts:  aggregate.DailyHalfHourCounts[day.Date],
+			ProjectCounts:   copyIntMap(aggregate.DailyProjectCounts[day.Date]),
+			ModelCounts:     copyIntMap(aggregate.DailyModelCounts[day.Date]),
+			VersionCounts:   copyIntMap(aggregate.DailyVersionCounts[day.Date]),
+			StopReasons:     copyIntMap(aggregate.DailyStopReasonCounts[day.Date]),
+			ModelTokens:     copyIntMap(aggregate.DailyModelTokens[day.Date]),
+			ProjectTokens:   copyIntMap(aggregate.DailyProjectTokens[day.Date]),
+		}
+		cache.DailyStats[day.Date].AssistantMessages = aggregate.DailyAssistantMessages[day.Date]
+		if span, ok := aggregate.DailyBounds[day.Date]; ok {
+			cache.DailyStats[day.Date].Bounds, cache.DailyStats[day.Date].HasBounds = span, true
 		}
 	}
 
@@ -207,21 +252,73 @@ func diagnosticsCachePath(cachePath string) string {
 	return filepath.Join(filepath.Dir(cachePath), "diagnostics.db")
 }
 
+// cacheExceedsMaxAge 判断缓存是否超过 --cache-max-age（未配置时恒为 false）
+func cacheExceedsMaxAge(cache *CacheFile, now time.Time) bool {
+	return cfg.CacheMaxAge > 0 && cache != nil && now.Sub(cache.LastUpdate) > cfg.CacheMaxAge
+}
+
+// refreshGlobalCacheIfTooOld 服务请求前检查内存中的缓存是否超龄，超龄则走一次刷新
+func refreshGlobalCacheIfTooOld() error {
+	if !cacheExceedsMaxAge(loadGlobalCache(), time.Now()) {
+		return nil
+	}
+	return refreshGlobalCache(false)
+}
+
+// refreshGlobalCache 刷新并替换 globalCache。同一时间只有一次刷新在执行：已有刷新进行中时，
+// 非强制调用（或进行中的本身就是强制刷新）等待并返回其结果；强制调用遇到非强制刷新则等它结束后再发起一次
 func refreshGlobalCache(force bool) error {
+	if cfg.Demo {
+		// 演示数据只在内存中，不落盘缓存，始终实时解析
+		return nil
+	}
+	for {
+		cacheRefreshFlightMu.Lock()
+		call := cacheRefreshInFlight
+		if call == nil {
+			call = &cacheRefreshCall{force: force, done: make(chan struct{})}
+			cacheRefreshInFlight = call
+			cacheRefreshFlightMu.Unlock()
+			return runGlobalCacheRefresh(call)
+		}
+		cacheRefreshFlightMu.Unlock()
+		<-call.done
+		if call.force || !force {
+			return call.err
+		}
+	}
+}
+
+// runGlobalCacheRefresh 执行 call 对应的刷新，结束后唤醒共享该结果的等待者。
+// 清理放在 defer 中：刷新 panic 时也要清除进行中的调用并唤醒等待者，否则之后的刷新会永远阻塞
+func runGlobalCacheRefresh(call *cacheRefreshCall) error {
 	cacheRefreshMu.Lock()
 	defer cacheRefreshMu.Unlock()
+	call.err = errCacheRefreshAborted
+	defer func() {
+		cacheRefreshFlightMu.Lock()
+		cacheRefreshInFlight = nil
+		cacheRefreshFlightMu.Unlock()
+		close(call.done)
+	}()
+	call.err = rebuildGlobalCache(call.force)
+	return call.err
+}
 
-	if err := os.MkdirAll(cfg.CacheDir, 0755); err != nil {
-		return fmt.Errorf("创建缓存目录失败: %w", err)
+// rebuildGlobalCacheLocked 按需构建缓存文件并加载到 globalCache，调用方需持有 cacheRefreshMu
+func rebuildGlobalCacheLocked(force bool) error {
+	cachePath := cacheFilePath()
+	if err := ensureCacheDir(); err != nil {
+		return err
 	}
 
-	cachePath := filepath.Join(cfg.CacheDir, "cache.db")
 	builder := &CacheBuilder{
 		CachePath: cachePath,
 		DataDir:   cfg.DataDir,
 	}
 
-	if force || builder.NeedsRebuild() {
+	rebuilt := force || builder.NeedsRebuild()
+	if rebuilt {
 		Info("正在构建缓存...", "cache_path", cachePath, "force", force)
 		start := time.Now()
 		if err := builder.BuildFullCache(); err != nil {
@@ -234,16 +331,21 @@ func refreshGlobalCache(force bool) error {
 		Info("使用现有缓存", "cache_path", cachePath)
 	}
 
-	cache, err := LoadCacheFile(cachePath)
+	cache, err := LoadOrRebuildCache(cachePath, cfg.DataDir)
 	if err != nil {
 		Error("加载缓存失败", "error", err.Error())
 		return fmt.Errorf("加载缓存失败: %w", err)
 	}
 
-	globalCache = cache
+	storeGlobalCache(cache)
+	globalCacheLoadedAt.Store(time.Now().UnixNano())
+	dashboardResultMemo.invalidate()
+	liveUpdates.notify()
 	Info("缓存已加载",
-		"messages", globalCache.TotalMessages,
-		"sessions", globalCache.TotalSessions,
+		"cache_path", cachePath,
+		"rebuilt", rebuilt,
+		"messages", cache.TotalMessages,
+		"sessions", cache.TotalSessions,
 	)
 	return nil
 }
@@ -270,6 +372,8 @@ func (cb *CacheBuilder) buildProjectAggregateIncremental(dataDir string, previou
 	var toParse []projectFileInfo
 	reused := 0
 
+	// 复用的快照按文件路径顺序先于新解析的文件合并：合并时登记其项目写法（见 canonicalProjectName），
+	// 新文件中同一目录的其它大小写写法沿用之前运行选定的名字
 	for _, info := range files {
 		if previous != nil && previous.ProjectFiles != nil {
 			if cached := previous.ProjectFiles[info.RelPath]; cached != nil && cached.Size == info.Size && cached.ModTimeUnix == info.ModTime {
@@ -311,33 +415,41 @@ func (cb *CacheBuilder) buildProjectAggregateIncremental(dataDir string, previou
 }
 
 func listProjectJSONLFileInfos(dataDir string) ([]projectFileInfo, error) {
-	projectsDir := filepath.Join(dataDir, "projects")
-	var files []projectFileInfo
-	err := filepath.WalkDir(projectsDir, func(path string, entry os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
-			return nil
+	projectDirs, err := listProjectDirs(filepath.Join(dataDir, "projects"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
-		info, err := entry.Info()
-		if err != nil {
+		return nil, err
+	}
+	var files []projectFileInfo
+	for _, projectDir := range projectDirs {
+		err := walkData(projectDir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(dataDir, path)
+			if err != nil {
+				rel = path
+			}
+			files = append(files, projectFileInfo{
+				RelPath: filepath.ToSlash(rel),
+				AbsPath: path,
+				Size:    info.Size(),
+				ModTime: info.ModTime().UnixNano(),
+			})
 			return nil
-		}
-		rel, err := filepath.Rel(dataDir, path)
+		})
 		if err != nil {
-			rel = path
+			return nil, err
 		}
-		files = append(files, projectFileInfo{
-			RelPath: filepath.ToSlash(rel),
-			AbsPath: path,
-			Size:    info.Size(),
-			ModTime: info.ModTime().UnixNano(),
-		})
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].RelPath < files[j].RelPath
@@ -349,7 +461,7 @@ func parseProjectFilesForCache(files []projectFileInfo) ([]projectFileResult, er
 	if len(files) == 0 {
 		return nil, nil
 	}
-	maxWorkers := runtime.NumCPU()
+	maxWorkers := getWorkerCount()
 	if len(files) < maxWorkers {
 		maxWorkers = len(files)
 	}
@@ -394,6 +506,39 @@ func parseProjectFilesForCache(files []projectFileInfo) ([]projectFileResult, er
 	return items, nil
 }
 
+// isCorruptCacheError 缓存文件存在但内容无法解析（写入中断、磁盘损坏导致的截断或乱码）
+func isCorruptCacheError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
+
+// LoadOrRebuildCache 加载缓存文件；文件不存在或已损坏时删除坏文件并全量重建，而不是把解析错误抛给调用方。
+// 读取失败（如权限问题）等其它错误仍然返回。
+func LoadOrRebuildCache(path, dataDir string) (*CacheFile, error) {
+	cache, err := LoadCacheFile(path)
+	if err == nil {
+		return cache, nil
+	}
+	if _, statErr := os.Stat(path); statErr == nil {
+		if !isCorruptCacheError(err) {
+			return nil, err
+		}
+		Warn("缓存文件已损坏，删除后重建", "cache_path", path, "error", err.Error())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("删除损坏的缓存文件失败: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	if err := (&CacheBuilder{CachePath: path, DataDir: dataDir}).BuildFullCache(); err != nil {
+		return nil, fmt.Errorf("重建缓存失败: %w", err)
+	}
+	return LoadCacheFile(path)
+}
+
 // RebuildIfChanged 在数据变化时重建缓存。
 func (cb *CacheBuilder) RebuildIfChanged() error {
 	cache, err := LoadCacheFile(cb.CachePath)
@@ -440,6 +585,14 @@ func (cb *CacheBuilder) NeedsRebuild() bool {
 	if cache.BashRulesHash != rulesHash {
 		return true
 	}
+	if cache.MCPPattern != mcpPatternCacheKey() || cache.ExcludeProjects != excludeProjectsCacheKey() || cache.CollapseHome != cfg.CollapseHome || cache.SessionGapMinutes != sessionGapCacheKey() || cache.MinSessionMessages != minSessionMessagesCacheKey() {
+		return true
+	}
+	// 超过最长可服务时长时不信任 mtime，交给增量构建按文件大小/修改时间逐个核对
+	if cacheExceedsMaxAge(cache, time.Now()) {
+		Info("缓存超过最长可服务时长，强制检查数据", "cache_time", cache.LastUpdate.Format("2006-01-02 15:04:05"), "max_age", cfg.CacheMaxAge.String())
+		return true
+	}
 
 	// 获取数据最后修改时间
 	lastDataMod, err := cb.GetLastDataModified()
@@ -462,45 +615,61 @@ func (cb *CacheBuilder) GetLastDataModified() (time.Time, error) {
 			return time.Time{}, err
 		}
 	}
+	// scanDirectory 不跟随符号链接，指向外部目录的项目单独扫描
+	projectDirs, _ := listProjectDirs(projectsDir)
+	for _, projectDir := range projectDirs {
+		if info, err := os.Lstat(projectDir); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if err := cb.scanDirectory(projectDir, &lastMod); err != nil && !os.IsNotExist(err) {
+			return time.Time{}, err
+		}
+	}
 
 	return lastMod, nil
 }
 
 // scanDirectory 递归扫描目录获取最后修改时间
 func (cb *CacheBuilder) scanDirectory(dirPath string, lastMod *time.Time) error {
-	entries, err := os.ReadDir(dirPath)
+	return walkDataFiles(dirPath, func(path string, info fs.FileInfo) {
+		if info.ModTime().After(*lastMod) {
+			*lastMod = info.ModTime()
+		}
+	})
+}
+
+// walkDataFiles 经 dataSource 递归遍历 dirPath，对每个非目录条目调用 visit（info 含大小与修改时间）。
+// 不跟随符号链接，读不到信息的条目跳过；读不了的子目录跳过并继续，最后返回遇到的第一个错误
+func walkDataFiles(dirPath string, visit func(path string, info fs.FileInfo)) error {
+	entries, err := dataSource.ReadDir(dirPath)
 	if err != nil {
 		return err
 	}
 
+	var firstErr error
 	for _, entry := range entries {
 		fullPath := filepath.Join(dirPath, entry.Name())
 
 		if entry.IsDir() {
-			// 递归扫描子目录
-			if err := cb.scanDirectory(fullPath, lastMod); err != nil {
-				return err
-			}
-		} else {
-			// 检查文件修改时间
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-
-			if info.ModTime().After(*lastMod) {
-				*lastMod = info.ModTime()
+			if err := walkDataFiles(fullPath, visit); err != nil && firstErr == nil {
+				firstErr = err
 			}
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
 		}
+		visit(fullPath, info)
 	}
 
-	return nil
+	return firstErr
 }
 
 // buildFromHistory 从 history.jsonl 构建缓存
 func (cb *CacheBuilder) buildFromHistory(cache *CacheFile) error {
 	path := filepath.Join(cb.DataDir, "history.jsonl")
-	f, err := os.Open(path)
+	f, err := openDataFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // 文件不存在不是错误
@@ -509,18 +678,19 @@ func (cb *CacheBuilder) buildFromHistory(cache *CacheFile) error {
 	}
 	defer f.Close()
 
-	decoder := json.NewDecoder(f)
+	lines := newJSONLReader(f)
 	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
 		var record HistoryRecord
-		if err := decoder.Decode(&record); err != nil {
-			if err == io.EOF {
-				break
-			}
+		if err := json.Unmarshal(line, &record); err != nil {
 			continue
 		}
 
 		// 解析时间戳
-		timestamp := time.Unix(record.Timestamp/1000, 0)
+		timestamp := parseHistoryTimestamp(record.Timestamp)
 		dateKey := timestamp.Format("2006-01-02")
 		hour := timestamp.Hour()
 
@@ -533,8 +703,9 @@ func (cb *CacheBuilder) buildFromHistory(cache *CacheFile) error {
 			}
 		}
 
-		// 添加消息
+		// 添加消息（history 记录没有 sessionID，只累加小时消息数）
 		cache.DailyStats[dateKey].AddMessage(record.Project, hour)
+		cache.addHourlyMessage(hour, false)
 		cache.TotalMessages++
 	}
 
@@ -543,8 +714,7 @@ func (cb *CacheBuilder) buildFromHistory(cache *CacheFile) error {
 
 // buildFromProjects 从 projects/*.jsonl 构建缓存
 func (cb *CacheBuilder) buildFromProjects(cache *CacheFile) error {
-	projectsDir := filepath.Join(cb.DataDir, "projects")
-	entries, err := os.ReadDir(projectsDir)
+	projectDirs, err := listProjectDirs(filepath.Join(cb.DataDir, "projects"))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // 目录不存在不是错误
@@ -552,16 +722,16 @@ func (cb *CacheBuilder) buildFromProjects(cache *CacheFile) error {
 		return err
 	}
 
-	// 统计会话数
-	sessions := make(map[string]bool)
+	// 统计会话数（全局 / 每小时 / 每项目分别去重）
+	sessions := newCacheSessionSets()
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, projectDir := range projectDirs {
+		if info, err := statData(projectDir); err == nil && !info.IsDir() {
+			// projects 下直接放置的会话文件
+			cb.parseProjectFile(projectDir, cache, sessions)
 			continue
 		}
-
-		projectDir := filepath.Join(projectsDir, entry.Name())
-		files, err := os.ReadDir(projectDir)
+		files, err := dataSource.ReadDir(projectDir)
 		if err != nil {
 			continue
 		}
@@ -577,14 +747,44 @@ func (cb *CacheBuilder) buildFromProjects(cache *CacheFile) error {
 		}
 	}
 
-	cache.TotalSessions = len(sessions)
+	cache.TotalSessions = len(sessions.all)
 	return nil
 }
 
+// cacheSessionSets 旧版逐文件构建路径的会话去重状态
+type cacheSessionSets struct {
+	all       map[string]bool
+	byHour    map[string]map[string]bool // "2006-01-02 15" -> sessionID 集合
+	byProject map[string]map[string]bool // project -> sessionID 集合
+}
+
+func newCacheSessionSets() *cacheSessionSets {
+	return &cacheSessionSets{
+		all:       make(map[string]bool),
+		byHour:    make(map[string]map[string]bool),
+		byProject: make(map[string]map[string]bool),
+	}
+}
+
+// markSessionOnce 把 sessionID 记入 sets[key]，首次出现时返回 true
+func markSessionOnce(sets map[string]map[string]bool, key, sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	if sets[key][sessionID] {
+		return false
+	}
+	sets[key][sessionID] = true
+	return true
+}
+
 // buildFromDebugLogs 从 debug 日志构建缓存
 func (cb *CacheBuilder) buildFromDebugLogs(cache *CacheFile) error {
 	debugDir := filepath.Join(cb.DataDir, "debug")
-	entries, err := os.ReadDir(debugDir)
+	entries, err := dataSource.ReadDir(debugDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // 目录不存在不是错误
@@ -618,20 +818,21 @@ func (cb *CacheBuilder) buildFromDebugLogs(cache *CacheFile) error {
 }
 
 // parseProjectFile 解析单个项目文件
-func (cb *CacheBuilder) parseProjectFile(filePath string, cache *CacheFile, sessions map[string]bool) error {
-	f, err := os.Open(filePath)
+func (cb *CacheBuilder) parseProjectFile(filePath string, cache *CacheFile, sessions *cacheSessionSets) error {
+	f, err := openDataFile(filePath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	decoder := json.NewDecoder(f)
+	lines := newJSONLReader(f)
 	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
 		var record ProjectRecord
-		if err := decoder.Decode(&record); err != nil {
-			if err == io.EOF {
-				break
-			}
+		if err := json.Unmarshal(line, &record); err != nil {
 			continue
 		}
 
@@ -648,22 +849,63 @@ func (cb *CacheBuilder) parseProjectFile(filePath string, cache *CacheFile, sess
 
 		// 统计会话
 		if record.SessionID != "" {
-			sessions[record.SessionID] = true
+			sessions.all[record.SessionID] = true
+		}
+
+		// 项目统计（与 parseProjectFileAggregate 一致，缺失 cwd 归为 Unknown）
+		projectName := projectNameFromCwd(record.Cwd)
+		if cache.ProjectStats == nil {
+			cache.ProjectStats = make(map[string]*ProjectStatItem)
+		}
+		if cache.ProjectStats[projectName] == nil {
+			cache.ProjectStats[projectName] = &ProjectStatItem{Project: projectName}
+		}
+		cache.ProjectStats[projectName].MessageCount++
+		if markSessionOnce(sessions.byProject, projectName, record.SessionID) {
+			cache.ProjectStats[projectName].SessionCount++
+		}
+
+		dateKey := timestamp.Format("2006-01-02")
+		if cache.DailyStats[dateKey] == nil {
+			cache.DailyStats[dateKey] = &DayAggregate{
+				Date:          dateKey,
+				ProjectCounts: make(map[string]int),
+				ModelCounts:   make(map[string]int),
+			}
+		}
+
+		// 统计小时分布（同一小时内的会话只计一次）
+		hour := timestamp.Hour()
+		cache.DailyStats[dateKey].AddMessage(projectName, hour)
+		cache.TotalMessages++
+		newHourSession := markSessionOnce(sessions.byHour, timestamp.Format("2006-01-02 15"), record.SessionID)
+		if newHourSession {
+			cache.DailyStats[dateKey].HourSessions[hour]++
 		}
+		cache.addHourlyMessage(hour, newHourSession)
+
+		// 统计星期分布（与 parseProjectFileAggregate 一致：0=周一）
+		cache.addWeekdayMessage((int(timestamp.Weekday()) + 6) % 7)
 
 		// 统计模型使用
 		var msg AssistantMessage
 		if err := json.Unmarshal(record.Message, &msg); err == nil {
+			tokens := msg.Usage.InputTokens + msg.Usage.OutputTokens
+			cache.ProjectStats[projectName].Tokens += tokens
+			if cache.DailyStats[dateKey].ProjectTokens == nil {
+				cache.DailyStats[dateKey].ProjectTokens = make(map[string]int)
+			}
+			cache.DailyStats[dateKey].ProjectTokens[projectName] += tokens
 			if msg.Model != "" {
-				dateKey := timestamp.Format("2006-01-02")
-				if cache.DailyStats[dateKey] == nil {
-					cache.DailyStats[dateKey] = &DayAggregate{
-						Date:          dateKey,
-						ProjectCounts: make(map[string]int),
-						ModelCounts:   make(map[string]int),
-					}
-				}
 				cache.DailyStats[dateKey].ModelCounts[msg.Model]++
+				if cache.ModelUsage == nil {
+					cache.ModelUsage = make(map[string]*ModelUsageItem)
+				}
+				if cache.ModelUsage[msg.Model] == nil {
+					cache.ModelUsage[msg.Model] = &ModelUsageItem{Model: msg.Model}
+				}
+				cache.ModelUsage[msg.Model].Count++
+				cache.ModelUsage[msg.Model].Tokens += tokens
 			}
 		}
 	}
@@ -673,7 +915,7 @@ func (cb *CacheBuilder) parseProjectFile(filePath string, cache *CacheFile, sess
 
 // parseDebugFile 解析单个 debug 日志文件
 func (cb *CacheBuilder) parseDebugFile(filePath string, cache *CacheFile) error {
-	f, err := os.Open(filePath)
+	f, err := dataSource.Open(filePath)
 	if err != nil {
 		return err
 	}
@@ -681,9 +923,6 @@ func (cb *CacheBuilder) parseDebugFile(filePath string, cache *CacheFile) error
 
 	// 使用正则匹配 Runtime 工具信号
 	pattern := mcpPattern
-	if pattern == nil {
-		pattern = regexp.MustCompile(`mcp__(\w+)__(\w+)`)
-	}
 
 	buf := make([]byte, 0, 64*1024)
 	scanner := newScanner(f, buf, 1024*1024)
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -365,6 +368,7 @@ func TestCacheBuilderNeedsRebuild(t *testing.T) {
 		cacheLastUpdate  time.Time
 		dataLastModified time.Time
 		cacheRulesHash   string
+		cacheMaxAge      time.Duration
 		wantNeedsRebuild bool
 	}{
 		{
@@ -392,11 +396,28 @@ func TestCacheBuilderNeedsRebuild(t *testing.T) {
 			dataLastModified: time.Now().Add(-2 * time.Hour),
 			wantNeedsRebuild: true,
 		},
+		{
+			name:             "超过最长可服务时长，即使 mtime 未变也需要检查",
+			cacheLastUpdate:  time.Now().Add(-1 * time.Hour),
+			dataLastModified: time.Now().Add(-2 * time.Hour),
+			cacheMaxAge:      30 * time.Minute,
+			wantNeedsRebuild: true,
+		},
+		{
+			name:             "未超过最长可服务时长，不需要重建",
+			cacheLastUpdate:  time.Now().Add(-1 * time.Hour),
+			dataLastModified: time.Now().Add(-2 * time.Hour),
+			cacheMaxAge:      2 * time.Hour,
+			wantNeedsRebuild: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Arrange
+			origMaxAge := cfg.CacheMaxAge
+			cfg.CacheMaxAge = tt.cacheMaxAge
+			defer func() { cfg.CacheMaxAge = origMaxAge }()
 			tmpDir := t.TempDir()
 			cachePath := filepath.Join(tmpDir, "cache.db")
 
@@ -503,3 +524,420 @@ func TestCacheBuilderGetLastDataModified(t *testing.T) {
 		t.Errorf("GetLastDataModified() = %v, want %v", lastMod, expected)
 	}
 }
+
+// TestCacheBuilderPopulatesHourlyStats 验证 projects/history 两条构建路径都会填充 HourlyStats
+func TestCacheBuilderPopulatesHourlyStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectDir := filepath.Join(dataDir, "projects", "hourly-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("创建测试项目目录失败: %v", err)
+	}
+
+	// 09 点两个会话各一条、10 点同一会话两条、15 点一条
+	base := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	records := []struct {
+		sessionID string
+		ts        time.Time
+	}{
+		{"s1", base},
+		{"s2", base.Add(10 * time.Minute)},
+		{"s1", base.Add(time.Hour)},
+		{"s1", base.Add(time.Hour + 5*time.Minute)},
+		{"s2", base.Add(6 * time.Hour)},
+	}
+	content := ""
+	for _, r := range records {
+		content += projectRecordJSON("/tmp/hourly-project", r.sessionID, r.ts) + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入测试 jsonl 失败: %v", err)
+	}
+
+	builder := &CacheBuilder{DataDir: dataDir}
+	cache := &CacheFile{DailyStats: make(map[string]*DayAggregate)}
+	if err := builder.buildFromProjects(cache); err != nil {
+		t.Fatalf("buildFromProjects() failed: %v", err)
+	}
+
+	hourlyTotal := 0
+	for _, hourly := range cache.HourlyStats {
+		if hourly != nil {
+			hourlyTotal += hourly.MessageCount
+		}
+	}
+	if hourlyTotal == 0 || hourlyTotal != cache.TotalMessages {
+		t.Fatalf("Hourly total=%d, want %d", hourlyTotal, cache.TotalMessages)
+	}
+	wantHours := map[int][2]int{9: {2, 2}, 10: {2, 1}, 15: {1, 1}}
+	for hour, want := range wantHours {
+		got := cache.HourlyStats[hour]
+		if got == nil || got.MessageCount != want[0] || got.SessionCount != want[1] {
+			t.Fatalf("HourlyStats[%d]=%+v, want messages=%d sessions=%d", hour, got, want[0], want[1])
+		}
+	}
+
+	// QueryByTimeRange 需要保留小时消息数与会话数
+	day := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	result := cache.QueryByTimeRange(day, day.AddDate(0, 0, 1))
+	for hour, want := range wantHours {
+		got := result.HourlyStats[hour]
+		if got == nil || got.MessageCount != want[0] || got.SessionCount != want[1] {
+			t.Fatalf("QueryByTimeRange HourlyStats[%d]=%+v, want messages=%d sessions=%d", hour, got, want[0], want[1])
+		}
+	}
+
+	// history 路径只有消息数
+	history := `{"display":"hi","timestamp":` + strconv.FormatInt(base.Add(2*time.Hour).UnixMilli(), 10) + `,"project":"/tmp/hourly-project"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(history), 0644); err != nil {
+		t.Fatalf("写入 history.jsonl 失败: %v", err)
+	}
+	if err := builder.buildFromHistory(cache); err != nil {
+		t.Fatalf("buildFromHistory() failed: %v", err)
+	}
+	historyHour := base.Add(2 * time.Hour).Local().Hour()
+	if cache.HourlyStats[historyHour] == nil || cache.HourlyStats[historyHour].MessageCount != 1 {
+		t.Fatalf("HourlyStats[%d]=%+v, want one history message", historyHour, cache.HourlyStats[historyHour])
+	}
+}
+
+// TestCacheBuilderPopulatesWeekdayStats 验证 projects 构建路径按 0=周一 填充 WeekdayStats
+func TestCacheBuilderPopulatesWeekdayStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectDir := filepath.Join(dataDir, "projects", "weekday-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("创建测试项目目录失败: %v", err)
+	}
+
+	// 2026-03-03 是周二，2026-03-08 是周日
+	tuesday := time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)
+	sunday := time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC)
+	content := projectRecordJSON("/tmp/weekday-project", "s1", tuesday) + "\n" +
+		projectRecordJSON("/tmp/weekday-project", "s1", tuesday.Add(time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/weekday-project", "s2", sunday) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入测试 jsonl 失败: %v", err)
+	}
+
+	builder := &CacheBuilder{DataDir: dataDir}
+	cache := &CacheFile{DailyStats: make(map[string]*DayAggregate)}
+	if err := builder.buildFromProjects(cache); err != nil {
+		t.Fatalf("buildFromProjects() failed: %v", err)
+	}
+
+	for i, item := range cache.WeekdayStats {
+		switch i {
+		case 1:
+			if item == nil || item.MessageCount != 2 || item.WeekdayName != "周二" {
+				t.Fatalf("WeekdayStats[1]=%+v, want 周二 with 2 messages", item)
+			}
+		case 6:
+			if item == nil || item.MessageCount != 1 || item.WeekdayName != "周日" {
+				t.Fatalf("WeekdayStats[6]=%+v, want 周日 with 1 message", item)
+			}
+		default:
+			if item != nil && item.MessageCount != 0 {
+				t.Fatalf("WeekdayStats[%d]=%+v, want empty", i, item)
+			}
+		}
+	}
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	result := cache.QueryByTimeRange(start, start.AddDate(0, 0, 10))
+	if result.WeekdayStats[1] == nil || result.WeekdayStats[1].MessageCount != 2 {
+		t.Fatalf("QueryByTimeRange WeekdayStats[1]=%+v, want 2", result.WeekdayStats[1])
+	}
+	if result.WeekdayStats[6] == nil || result.WeekdayStats[6].MessageCount != 1 {
+		t.Fatalf("QueryByTimeRange WeekdayStats[6]=%+v, want 1", result.WeekdayStats[6])
+	}
+}
+
+// TestCacheBuilderLockSerializesBuilders 模拟两个构建者：锁被占用时第二个必须等待，释放后缓存仍可加载
+func TestCacheBuilderLockSerializesBuilders(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := createTestDataDir(t, tmpDir)
+	cachePath := filepath.Join(tmpDir, "cache", "cache.db")
+
+	origPoll := cacheLockPoll
+	cacheLockPoll = 10 * time.Millisecond
+	defer func() { cacheLockPoll = origPoll }()
+
+	// 第一个构建者持有锁
+	release, err := acquireCacheLock(cachePath)
+	if err != nil {
+		t.Fatalf("acquireCacheLock() failed: %v", err)
+	}
+	if _, err := os.Stat(cacheLockPath(cachePath)); err != nil {
+		t.Fatalf("lock file not created: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache()
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("second builder finished while lock held: %v", err)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("BuildFullCache() after release failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("second builder did not finish after lock released")
+	}
+
+	// 两个构建者并发，缓存依然完整可读
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache()
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("concurrent BuildFullCache() failed: %v", err)
+		}
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	if cache.TotalMessages != 2 {
+		t.Fatalf("TotalMessages=%d, want 2", cache.TotalMessages)
+	}
+	if _, err := os.Stat(cacheLockPath(cachePath)); !os.IsNotExist(err) {
+		t.Fatalf("lock file should be removed after build, stat err=%v", err)
+	}
+}
+
+// TestAcquireCacheLockRemovesStaleLock 崩溃残留的过期锁不应永久阻塞构建
+func TestAcquireCacheLockRemovesStaleLock(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	lockPath := cacheLockPath(cachePath)
+	if err := os.WriteFile(lockPath, []byte("12345"), 0644); err != nil {
+		t.Fatalf("写入锁文件失败: %v", err)
+	}
+	old := time.Now().Add(-cacheLockStale - time.Minute)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("修改锁文件时间失败: %v", err)
+	}
+
+	release, err := acquireCacheLock(cachePath)
+	if err != nil {
+		t.Fatalf("acquireCacheLock() with stale lock failed: %v", err)
+	}
+	release()
+}
+
+// TestCacheBuilderPopulatesProjectStats 旧版构建路径的 ProjectStats/ModelUsage 应与实时解析一致
+func TestCacheBuilderPopulatesProjectStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := createTestDataDir(t, tmpDir)
+	base := time.Now().Add(-time.Hour)
+	appendProjectRecord(t, dataDir, "/tmp/test-project", "session-2", base)
+	appendProjectRecord(t, dataDir, "/tmp/other-project", "session-3", base.Add(time.Minute))
+
+	builder := &CacheBuilder{DataDir: dataDir}
+	cache := &CacheFile{DailyStats: make(map[string]*DayAggregate)}
+	if err := builder.buildFromProjects(cache); err != nil {
+		t.Fatalf("buildFromProjects() failed: %v", err)
+	}
+	if len(cache.ProjectStats) == 0 {
+		t.Fatal("ProjectStats should not be empty")
+	}
+
+	live, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	if len(cache.ProjectStats) != len(live.ProjectStats) {
+		t.Fatalf("cached projects=%d, live=%d", len(cache.ProjectStats), len(live.ProjectStats))
+	}
+	for project, stat := range live.ProjectStats {
+		cached := cache.ProjectStats[project]
+		if cached == nil || cached.MessageCount != stat.MessageCount {
+			t.Fatalf("ProjectStats[%s]=%+v, live=%+v", project, cached, stat)
+		}
+	}
+	if cache.ProjectStats["/tmp/test-project"].SessionCount != 2 {
+		t.Fatalf("/tmp/test-project SessionCount=%d, want 2", cache.ProjectStats["/tmp/test-project"].SessionCount)
+	}
+	for model, stat := range live.ModelUsage {
+		cached := cache.ModelUsage[model]
+		if cached == nil || cached.Count != stat.Count || cached.Tokens != stat.Tokens {
+			t.Fatalf("ModelUsage[%s]=%+v, live=%+v", model, cached, stat)
+		}
+	}
+}
+
+func TestRefreshGlobalCacheIfTooOldRebuildsStaleCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := createTestDataDir(t, tmpDir)
+	origCfg, origCache := cfg, loadGlobalCache()
+	defer func() {
+		cfg = origCfg
+		storeGlobalCache(origCache)
+	}()
+	cfg.DataDir = dataDir
+	cfg.CacheDir = filepath.Join(tmpDir, "cache")
+
+	if err := refreshGlobalCache(true); err != nil {
+		t.Fatalf("refreshGlobalCache() failed: %v", err)
+	}
+	// 把缓存时间拨回 1 小时前；数据文件 mtime 比缓存旧，仅靠 mtime 判断不会刷新
+	stale := time.Now().Add(-time.Hour)
+	loadGlobalCache().LastUpdate = stale
+	if err := loadGlobalCache().Save(filepath.Join(cfg.CacheDir, "cache.db")); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dataDir, "projects", "test-project", "session.jsonl"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dataDir, "history.jsonl"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dataDir, "debug", "debug.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.CacheMaxAge = 0
+	if err := refreshGlobalCacheIfTooOld(); err != nil {
+		t.Fatalf("refreshGlobalCacheIfTooOld() failed: %v", err)
+	}
+	if !loadGlobalCache().LastUpdate.Equal(stale) {
+		t.Fatalf("未配置 max age 时不应刷新, LastUpdate=%v", loadGlobalCache().LastUpdate)
+	}
+
+	cfg.CacheMaxAge = 10 * time.Minute
+	if err := refreshGlobalCacheIfTooOld(); err != nil {
+		t.Fatalf("refreshGlobalCacheIfTooOld() failed: %v", err)
+	}
+	if time.Since(loadGlobalCache().LastUpdate) > time.Minute {
+		t.Fatalf("超龄缓存应触发刷新, LastUpdate=%v", loadGlobalCache().LastUpdate)
+	}
+}
+
+func TestLoadOrRebuildCacheRecoversFromCorruptFile(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := os.WriteFile(cachePath, []byte(`{"Version":"3.`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCacheFile(cachePath); err == nil {
+		t.Fatal("截断的缓存文件应加载失败")
+	}
+
+	cache, err := LoadOrRebuildCache(cachePath, dataDir)
+	if err != nil {
+		t.Fatalf("LoadOrRebuildCache() error = %v", err)
+	}
+	if cache.Version != CacheVersion || cache.TotalMessages != 5 {
+		t.Fatalf("重建后 version=%s messages=%d, want %s/5", cache.Version, cache.TotalMessages, CacheVersion)
+	}
+	// 坏文件已被替换为可正常加载的缓存
+	if reloaded, err := LoadCacheFile(cachePath); err != nil || reloaded.TotalMessages != 5 {
+		t.Fatalf("重建后的缓存文件无法加载: %v", err)
+	}
+}
+
+func TestConcurrentRefreshGlobalCacheSharesOneRebuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := createTestDataDir(t, tmpDir)
+	origCfg, origCache := cfg, loadGlobalCache()
+	defer func() {
+		cfg = origCfg
+		storeGlobalCache(origCache)
+	}()
+	cfg.DataDir = dataDir
+	cfg.CacheDir = filepath.Join(tmpDir, "cache")
+
+	// 模拟并发的 /api/reload（强制）与 --watch（非强制）刷新；go test -race 下不应出现数据竞争
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(force bool) {
+			defer wg.Done()
+			errs <- refreshGlobalCache(force)
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("并发刷新失败: %v", err)
+		}
+	}
+
+	if cacheRefreshInFlight != nil {
+		t.Fatal("刷新结束后不应残留进行中的调用")
+	}
+	cachePath := filepath.Join(cfg.CacheDir, "cache.db")
+	if _, err := os.Stat(cacheLockPath(cachePath)); !os.IsNotExist(err) {
+		t.Fatalf("缓存锁未释放: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("并发刷新后缓存文件不可读: %v", err)
+	}
+	if loadGlobalCache() == nil || cache.TotalMessages != loadGlobalCache().TotalMessages || cache.TotalMessages == 0 {
+		t.Fatalf("cache messages=%d, loadGlobalCache()=%+v", cache.TotalMessages, loadGlobalCache())
+	}
+}
+
+func TestRefreshGlobalCacheRecoversFromPanic(t *testing.T) {
+	origRebuild := rebuildGlobalCache
+	defer func() { rebuildGlobalCache = origRebuild }()
+
+	// 刷新 panic：进行中的调用应被清除，等待者被唤醒并收到错误
+	var call *cacheRefreshCall
+	rebuildGlobalCache = func(force bool) error {
+		cacheRefreshFlightMu.Lock()
+		call = cacheRefreshInFlight
+		cacheRefreshFlightMu.Unlock()
+		panic("boom")
+	}
+	func() {
+		defer func() {
+			if got := recover(); got != "boom" {
+				t.Fatalf("recover() = %v, want boom", got)
+			}
+		}()
+		refreshGlobalCache(true)
+	}()
+	if call == nil {
+		t.Fatal("刷新期间应登记进行中的调用")
+	}
+	select {
+	case <-call.done:
+	default:
+		t.Fatal("刷新 panic 后未唤醒等待者")
+	}
+	if !errors.Is(call.err, errCacheRefreshAborted) {
+		t.Fatalf("等待者收到的 err = %v, want %v", call.err, errCacheRefreshAborted)
+	}
+	if cacheRefreshInFlight != nil {
+		t.Fatal("刷新 panic 后不应残留进行中的调用")
+	}
+
+	// 之后的刷新正常执行，不会因残留的调用或锁而阻塞
+	rebuildGlobalCache = func(force bool) error { return nil }
+	done := make(chan error, 1)
+	go func() { done <- refreshGlobalCache(false) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("refreshGlobalCache() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("刷新 panic 后后续刷新被阻塞")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 跨进程缓存构建锁：cache.db.lock 以 O_EXCL 创建，存在即视为有其他构建者在写缓存。
+// 进程内并发仍由 cacheRefreshMu 串行化，这里只解决多个实例（如 cron 重建 + 常驻服务）同时写的问题。
+var (
+	cacheLockWait  = 2 * time.Minute        // 等待其他构建者释放锁的最长时间
+	cacheLockStale = 10 * time.Minute       // 超过该时长未更新的锁视为崩溃残留
+	cacheLockPoll  = 100 * time.Millisecond // 轮询间隔
+)
+
+// cacheLockPath 返回缓存文件对应的锁文件路径
+func cacheLockPath(cachePath string) string {
+	return cachePath + ".lock"
+}
+
+// acquireCacheLock 获取缓存构建锁，返回释放函数；超时仍未拿到锁时返回错误。
+func acquireCacheLock(cachePath string) (func(), error) {
+	lockPath := cacheLockPath(cachePath)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+
+	deadline := time.Now().Add(cacheLockWait)
+	waiting := false
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+			f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("创建缓存锁失败: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > cacheLockStale {
+			Warn("清理过期的缓存锁", "lock_path", lockPath, "age_sec", time.Since(info.ModTime()).Seconds())
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("等待缓存锁超时: %s", lockPath)
+		}
+		if !waiting {
+			Info("缓存正在被其他进程构建，等待锁释放", "lock_path", lockPath)
+			waiting = true
+		}
+		time.Sleep(cacheLockPoll)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -109,6 +110,43 @@ func TestCacheFileLoad(t *testing.T) {
 	}
 }
 
+// TestCacheFileSaveFailureKeepsOriginal 写入失败时原缓存保持完整，临时文件被清理
+func TestCacheFileSaveFailureKeepsOriginal(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache.db")
+	original := &CacheFile{Version: CacheVersion, TotalMessages: 100}
+	if err := original.Save(cachePath); err != nil {
+		t.Fatalf("Setup: Save() failed: %v", err)
+	}
+
+	origRename := renameFile
+	renameFile = func(string, string) error { return errors.New("模拟写入失败") }
+	defer func() { renameFile = origRename }()
+
+	if err := (&CacheFile{Version: CacheVersion, TotalMessages: 200}).Save(cachePath); err == nil {
+		t.Fatal("替换失败时 Save() 应返回错误")
+	}
+
+	loaded, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("原缓存应仍可加载: %v", err)
+	}
+	if loaded.TotalMessages != 100 {
+		t.Errorf("TotalMessages = %d, want 100（原缓存不应被覆盖）", loaded.TotalMessages)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cache.db" {
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("目录内容 = %v，临时文件应已删除", names)
+	}
+}
+
 // TestCacheFileNotExists 测试加载不存在的缓存文件
 func TestCacheFileNotExists(t *testing.T) {
 	// Arrange
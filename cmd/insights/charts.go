@@ -1,20 +1,64 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"regexp"
+	"strings"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/components"
 	"github.com/go-echarts/go-echarts/v2/opts"
 )
 
+// chartOthersLabel Top N 之外的长尾合并后的名称
+const chartOthersLabel = "其他"
+
+// defaultChartTheme 图表默认主题
+const defaultChartTheme = "wonderland"
+
+// chartSizePattern 图表尺寸：像素（px 可省略）或百分比
+var chartSizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(px|%)?$`)
+
+// normalizeChartSize 校验 --chart-width/--chart-height，纯数字补 px；空值表示沿用图表默认尺寸
+func normalizeChartSize(size string) (string, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	if size == "" {
+		return "", nil
+	}
+	m := chartSizePattern.FindStringSubmatch(size)
+	if m == nil {
+		return "", fmt.Errorf("无法解析图表尺寸 %q：应为像素（如 1600 或 1600px）或百分比（如 100%%）", size)
+	}
+	if m[2] == "" {
+		return size + "px", nil
+	}
+	return size, nil
+}
+
+// chartInitOpts 各图表共用的初始化选项：主题与尺寸取自配置，未配置尺寸时使用该图表的默认宽高
+func chartInitOpts(defaultWidth, defaultHeight string) opts.Initialization {
+	initOpts := opts.Initialization{Theme: cfg.ChartTheme, Width: defaultWidth, Height: defaultHeight}
+	if initOpts.Theme == "" {
+		initOpts.Theme = defaultChartTheme
+	}
+	if cfg.ChartWidth != "" {
+		initOpts.Width = cfg.ChartWidth
+	}
+	if cfg.ChartHeight != "" {
+		initOpts.Height = cfg.ChartHeight
+	}
+	return initOpts
+}
+
 // CreateCommandChart 创建命令使用统计图表
 func CreateCommandChart(cmdStats []CommandStats) *charts.Bar {
 	cmdNames := make([]string, 0, len(cmdStats))
 	var cmdCounts []opts.BarData
 
-	// 取前15个
+	// 取前15个，其余合并为「其他」
 	limit := 15
 	if len(cmdStats) < limit {
 		limit = len(cmdStats)
@@ -24,6 +68,14 @@ func CreateCommandChart(cmdStats []CommandStats) *charts.Bar {
 		cmdNames = append(cmdNames, cmdStats[i].Command)
 		cmdCounts = append(cmdCounts, opts.BarData{Value: cmdStats[i].Count})
 	}
+	others := 0
+	for _, item := range cmdStats[limit:] {
+		others += item.Count
+	}
+	if others > 0 {
+		cmdNames = append(cmdNames, chartOthersLabel)
+		cmdCounts = append(cmdCounts, opts.BarData{Value: others})
+	}
 
 	bar := charts.NewBar()
 
@@ -43,11 +95,7 @@ func CreateCommandChart(cmdStats []CommandStats) *charts.Bar {
 			Title:    "Slash Commands 使用统计 (Top 15)",
 			Subtitle: "数据来源: history.jsonl",
 		}),
-		charts.WithInitializationOpts(opts.Initialization{
-			Theme:  "wonderland",
-			Width:  "1200px",
-			Height: "500px",
-		}),
+		charts.WithInitializationOpts(chartInitOpts("1200px", "500px")),
 	)
 
 	return bar
@@ -77,16 +125,39 @@ func CreateDailyTrendChart(dates []string, counts []int) *charts.Line {
 			Title:    "每日活动趋势",
 			Subtitle: "数据来源: projects/*.jsonl",
 		}),
-		charts.WithInitializationOpts(opts.Initialization{
-			Theme:  "wonderland",
-			Width:  "1200px",
-			Height: "400px",
-		}),
+		charts.WithInitializationOpts(chartInitOpts("1200px", "400px")),
 	)
 
 	return line
 }
 
+// CreateDailyModelStackChart 创建每日模型构成堆叠柱状图，每个模型一个系列
+func CreateDailyModelStackChart(mix *DailyModelMix) *charts.Bar {
+	bar := charts.NewBar()
+	if mix != nil {
+		bar.SetXAxis(mix.Dates)
+		for _, series := range mix.Models {
+			barData := make([]opts.BarData, 0, len(series.Counts))
+			for _, c := range series.Counts {
+				barData = append(barData, opts.BarData{Value: c})
+			}
+			bar.AddSeries(series.Model, barData, charts.WithBarChartOpts(opts.BarChart{Stack: "models"}))
+		}
+	}
+
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "每日模型构成",
+			Subtitle: "数据来源: projects/*.jsonl",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true, Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: true, Top: "bottom"}),
+		charts.WithInitializationOpts(chartInitOpts("1200px", "400px")),
+	)
+
+	return bar
+}
+
 // CreateHourlyChart 创建小时分布图表
 func CreateHourlyChart(hourlyCounts map[string]int) *charts.Bar {
 	hours := make([]string, 24)
@@ -105,11 +176,7 @@ func CreateHourlyChart(hourlyCounts map[string]int) *charts.Bar {
 			Title:    "24小时活动分布",
 			Subtitle: "数据来源: history.jsonl",
 		}),
-		charts.WithInitializationOpts(opts.Initialization{
-			Theme:  "wonderland",
-			Width:  "1200px",
-			Height: "400px",
-		}),
+		charts.WithInitializationOpts(chartInitOpts("1200px", "400px")),
 	)
 
 	bar.SetSeriesOptions(
@@ -121,15 +188,13 @@ func CreateHourlyChart(hourlyCounts map[string]int) *charts.Bar {
 	return bar
 }
 
-// CreateRuntimeToolsChart 创建 runtime debug 工具信号图表
-func CreateRuntimeToolsChart(toolStats []RuntimeToolSignal) *charts.Pie {
-	// 取前10个
-	limit := 10
+// runtimeToolPieData 取前 limit 个工具信号，其余合并为一块「其他」，使各块占比合计为 100%
+func runtimeToolPieData(toolStats []RuntimeToolSignal, limit int) []opts.PieData {
 	if len(toolStats) < limit {
 		limit = len(toolStats)
 	}
 
-	data := make([]opts.PieData, 0, limit)
+	data := make([]opts.PieData, 0, limit+1)
 	for i := 0; i < limit; i++ {
 		label := toolStats[i].Server + "::" + toolStats[i].Tool
 		data = append(data, opts.PieData{
@@ -137,6 +202,19 @@ func CreateRuntimeToolsChart(toolStats []RuntimeToolSignal) *charts.Pie {
 			Value: toolStats[i].Count,
 		})
 	}
+	others := 0
+	for _, item := range toolStats[limit:] {
+		others += item.Count
+	}
+	if others > 0 {
+		data = append(data, opts.PieData{Name: chartOthersLabel, Value: others})
+	}
+	return data
+}
+
+// CreateRuntimeToolsChart 创建 runtime debug 工具信号图表
+func CreateRuntimeToolsChart(toolStats []RuntimeToolSignal) *charts.Pie {
+	data := runtimeToolPieData(toolStats, 10)
 
 	pie := charts.NewPie()
 
@@ -156,11 +234,7 @@ func CreateRuntimeToolsChart(toolStats []RuntimeToolSignal) *charts.Pie {
 			Left:     "center",
 			Top:      "20px",
 		}),
-		charts.WithInitializationOpts(opts.Initialization{
-			Theme:  "wonderland",
-			Width:  "900px",
-			Height: "700px",
-		}),
+		charts.WithInitializationOpts(chartInitOpts("900px", "700px")),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Trigger:   "item",
 			Formatter: "{b}: {c} ({d}%)",
@@ -217,3 +291,49 @@ func ServeDashboard(output io.Writer) error {
 	page.Render(output)
 	return nil
 }
+
+// CreateDashboardFromData 用已按时间范围过滤的 DashboardData 创建完整 Dashboard，
+// 图表组合与 CreateDashboard 相同，有每日模型数据时追加每日模型构成图
+func CreateDashboardFromData(data *DashboardData) *components.Page {
+	page := components.NewPage()
+	page.SetLayout(components.PageCenterLayout)
+	page.AddCharts(
+		CreateDailyTrendChart(data.DailyTrend.Dates, data.DailyTrend.Counts),
+		CreateCommandChart(data.Commands),
+		CreateHourlyChart(data.HourlyCounts),
+		CreateRuntimeToolsChart(data.RuntimeTools),
+	)
+	if data.DailyModelMix != nil {
+		page.AddCharts(CreateDailyModelStackChart(data.DailyModelMix))
+	}
+	return page
+}
+
+// RenderDashboard 将 data 渲染为完整的 Dashboard HTML（sum --out 导出静态报告）。
+// 默认内联内置的 ECharts 脚本，报告离线可打开；--echarts-cdn 时引用 CDN
+func RenderDashboard(output io.Writer, data *DashboardData) error {
+	page := CreateDashboardFromData(data)
+	page.AssetsHost = echartsAssetsHost()
+	if cfg.EChartsCDN {
+		return page.Render(output)
+	}
+	var buf bytes.Buffer
+	if err := page.Render(&buf); err != nil {
+		return err
+	}
+	_, err := output.Write(inlineEChartsAssets(buf.Bytes(), page.JSAssets.Values))
+	return err
+}
+
+// writeDashboardHTML 渲染 Dashboard 并原子写入 path
+func writeDashboardHTML(path string, data *DashboardData) error {
+	var buf bytes.Buffer
+	if err := RenderDashboard(&buf, data); err != nil {
+		return fmt.Errorf("渲染 Dashboard 失败: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Dashboard 已写入 %s\n", path)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+func TestRuntimeToolsChartCollapsesTailIntoOthers(t *testing.T) {
+	// 13 个工具，次数 13..1：第 11–13 个（3+2+1）合并为一块「其他」
+	toolStats := make([]RuntimeToolSignal, 0, 13)
+	total := 0
+	for i := 13; i >= 1; i-- {
+		toolStats = append(toolStats, RuntimeToolSignal{Server: "srv", Tool: fmt.Sprintf("tool%02d", i), Count: i})
+		total += i
+	}
+
+	pie := CreateRuntimeToolsChart(toolStats)
+	if len(pie.MultiSeries) != 1 {
+		t.Fatalf("series=%d, want 1", len(pie.MultiSeries))
+	}
+	data, ok := pie.MultiSeries[0].Data.([]opts.PieData)
+	if !ok {
+		t.Fatalf("pie data type = %T", pie.MultiSeries[0].Data)
+	}
+	if len(data) != 11 {
+		t.Fatalf("slices=%d, want 10 + others", len(data))
+	}
+	if data[9].Name != "srv::tool04" || data[10].Name != chartOthersLabel || data[10].Value != 6 {
+		t.Fatalf("tail slices = %+v, %+v; want srv::tool04 then %s=6", data[9], data[10], chartOthersLabel)
+	}
+	sum := 0
+	for _, slice := range data {
+		sum += slice.Value.(int)
+	}
+	if sum != total {
+		t.Fatalf("slices sum=%d, want %d", sum, total)
+	}
+
+	// 不足 Top N 时不追加「其他」
+	if short := runtimeToolPieData(toolStats[:3], 10); len(short) != 3 {
+		t.Fatalf("short slices=%+v, want 3 without others", short)
+	}
+}
+
+func TestCommandChartCollapsesTailIntoOthers(t *testing.T) {
+	cmdStats := make([]CommandStats, 0, 17)
+	for i := 17; i >= 1; i-- {
+		cmdStats = append(cmdStats, CommandStats{Command: fmt.Sprintf("/c%02d", i), Count: i})
+	}
+	bar := CreateCommandChart(cmdStats)
+	data, ok := bar.MultiSeries[0].Data.([]opts.BarData)
+	if !ok {
+		t.Fatalf("bar data type = %T", bar.MultiSeries[0].Data)
+	}
+	// 第 16、17 个（2+1）合并
+	if len(data) != 16 || data[15].Value != 3 {
+		t.Fatalf("bars=%d last=%+v, want 16 bars with others=3", len(data), data[len(data)-1])
+	}
+}
+
+func TestRenderDashboardFromFilteredData(t *testing.T) {
+	useFixtureDataDir(t)
+	tf, err := NewTimeFilterCustom("2026-01-05", "2026-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := buildDashboardData(tf, "custom")
+	if err != nil {
+		t.Fatalf("buildDashboardData() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := RenderDashboard(&buf, data); err != nil {
+		t.Fatalf("RenderDashboard() error = %v", err)
+	}
+	html := buf.String()
+	if !strings.HasPrefix(strings.TrimSpace(html), "<!DOCTYPE html>") {
+		t.Fatalf("输出不是完整 HTML 页面: %.80s", html)
+	}
+	// 每个图表一次 echarts.init：每日趋势、命令、小时分布、运行时工具、每日模型构成
+	if n := strings.Count(html, "echarts.init(document.getElementById("); n != 5 {
+		t.Fatalf("charts=%d, want 5", n)
+	}
+	if len(data.DailyTrend.Dates) == 0 {
+		t.Fatal("fixture 过滤后每日趋势为空")
+	}
+	for _, date := range data.DailyTrend.Dates {
+		if !strings.Contains(html, date) {
+			t.Fatalf("HTML 缺少过滤范围内的日期 %s", date)
+		}
+	}
+	if strings.Contains(html, "2026-01-07") {
+		t.Fatal("HTML 不应包含过滤范围外的日期 2026-01-07")
+	}
+}
+
+func TestChartThemeAndSizeFromConfig(t *testing.T) {
+	origCfg := cfg
+	defer func() { cfg = origCfg }()
+
+	// 默认配置保持原有主题与各图表自己的尺寸
+	cfg = defaultConfig()
+	if initOpts := CreateCommandChart(nil).Initialization; initOpts.Theme != "wonderland" || initOpts.Width != "1200px" || initOpts.Height != "500px" {
+		t.Fatalf("默认 initialization = %+v", initOpts)
+	}
+
+	cfg.ChartTheme = "dark"
+	cfg.ChartWidth = "100%"
+	line := CreateDailyTrendChart([]string{"2026-01-05"}, []int{3})
+	if initOpts := line.Initialization; initOpts.Theme != "dark" || initOpts.Width != "100%" || initOpts.Height != "400px" {
+		t.Fatalf("自定义 initialization = %+v, want dark/100%%/400px", initOpts)
+	}
+	var buf bytes.Buffer
+	if err := line.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if html := buf.String(); !strings.Contains(html, `"dark"`) || !strings.Contains(html, "width:100%") {
+		t.Fatalf("渲染结果未应用主题或宽度: %.400s", html)
+	}
+
+	for input, want := range map[string]string{"": "", "1600": "1600px", "1600px": "1600px", " 100% ": "100%"} {
+		if got, err := normalizeChartSize(input); err != nil || got != want {
+			t.Fatalf("normalizeChartSize(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := normalizeChartSize("wide"); err == nil {
+		t.Fatal("无效尺寸应报错")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type cliOptions struct {
@@ -28,6 +29,17 @@ type cliOptions struct {
 	ID       string
 	Detail   bool
 	Prompts  bool
+	// SinceLastRun 只统计上次成功运行（--since-last-run）之后的记录
+	SinceLastRun bool
+	// Out 非空时 sum 把 Dashboard 渲染为静态 HTML 写入该文件，不输出文本报告
+	Out string
+	// RelativeDates 报告中的日期显示为相对时间（今天/昨天/N 天前）
+	RelativeDates bool
+	// Args flag 之后的位置参数（如 analyze-file 的文件路径）
+	Args []string
+
+	lastRun *time.Time // --since-last-run 的起点（首次运行为 nil，即不限起点）
+	runAt   time.Time  // --since-last-run 的终点，成功后作为下一次的起点
 
 	jsonOut     bool // -j：输出 JSON（仅分析命令注册）
 	markdownOut bool // -m：输出 Markdown（仅分析命令注册）
@@ -48,6 +60,8 @@ type cliSummary struct {
 	Tokens          int           `json:"tokens"`
 	TopProject      string        `json:"top_project"`
 	TopModel        string        `json:"top_model"`
+	LastActive      string        `json:"last_active,omitempty"`
+	LastActiveLabel string        `json:"last_active_label,omitempty"`
 	Insights        []string      `json:"insights"`
 }
 
@@ -204,6 +218,9 @@ func runCLI(args []string) error {
 		return err
 	}
 	cfg = opts.Config
+	if opts.SinceLastRun {
+		return runSinceLastRun(cmd, opts)
+	}
 	return cmd.Run(opts)
 }
 
@@ -235,11 +252,21 @@ func parseCLIOptions(cmd *Command, args []string) (cliOptions, error) {
 		cmd.Flags(fs, &opts) // 分析命令注册通用分析 flag（含 -j/-m）；web 注册 --addr/--base
 	}
 
-	if err := fs.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			return opts, errHelp
+	// 允许位置参数与 flag 交错（analyze-file <path> -j）：逐个摘出位置参数后继续解析剩余 flag
+	rest := args
+	for {
+		if err := fs.Parse(rest); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return opts, errHelp
+			}
+			return opts, err
 		}
-		return opts, err
+		rest = fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		opts.Args = append(opts.Args, rest[0])
+		rest = rest[1:]
 	}
 
 	if opts.jsonOut {
@@ -258,6 +285,31 @@ func parseCLIOptions(cmd *Command, args []string) (cliOptions, error) {
 	if opts.Samples <= 0 {
 		opts.Samples = opts.Limit
 	}
+	logFormat, err := normalizeLogFormat(opts.LogFormat)
+	if err != nil {
+		return opts, err
+	}
+	opts.LogFormat = logFormat
+	if opts.MinSessionMessages < 0 {
+		return opts, fmt.Errorf("--min-session-messages 不能为负数: %d", opts.MinSessionMessages)
+	}
+	if opts.CommandCategoriesPath != "" {
+		if opts.CommandCategories, err = loadCommandCategories(opts.CommandCategoriesPath); err != nil {
+			return opts, fmt.Errorf("--command-categories: %w", err)
+		}
+	}
+	if opts.ReadRetries < 0 {
+		return opts, fmt.Errorf("--read-retries 不能为负数: %d", opts.ReadRetries)
+	}
+	if opts.ChartWidth, err = normalizeChartSize(opts.ChartWidth); err != nil {
+		return opts, fmt.Errorf("--chart-width: %w", err)
+	}
+	if opts.ChartHeight, err = normalizeChartSize(opts.ChartHeight); err != nil {
+		return opts, fmt.Errorf("--chart-height: %w", err)
+	}
+	if opts.ChartTheme = strings.TrimSpace(opts.ChartTheme); opts.ChartTheme == "" {
+		opts.ChartTheme = defaultChartTheme
+	}
 	return opts, nil
 }
 
@@ -270,17 +322,28 @@ func prepareCLIDataForRecommendations() error {
 }
 
 func prepareCLIDataWithCacheRefresh(refreshStale bool) error {
-	if _, err := os.Stat(cfg.DataDir); os.IsNotExist(err) {
-		return fmt.Errorf("数据目录不存在: %s", cfg.DataDir)
+	if err := applyArchiveConfig(); err != nil {
+		return err
+	}
+	if err := applyMCPPatternConfig(); err != nil {
+		return err
+	}
+	if err := applyDebugPatternConfig(); err != nil {
+		return err
+	}
+	dataSummary, err := ValidateDataDir(cfg.DataDir)
+	if err != nil {
+		return err
 	}
 	logDir := filepath.Join(filepath.Dir(cfg.CacheDir), "logs")
 	if err := InitLogger(logDir); err != nil {
 		return fmt.Errorf("日志初始化失败: %w", err)
 	}
+	warnIfDataDirEmpty(cfg.DataDir, dataSummary)
 	if !refreshStale {
 		if loaded, err := loadReusableCacheSnapshot(); err == nil {
-			globalCache = loaded
-			Info("使用现有缓存快照", "messages", globalCache.TotalMessages, "sessions", globalCache.TotalSessions)
+			storeGlobalCache(loaded)
+			Info("使用现有缓存快照", "messages", loaded.TotalMessages, "sessions", loaded.TotalSessions)
 			return nil
 		} else {
 			Warn("缓存快照不可复用，将刷新缓存", "error", err.Error())
@@ -293,7 +356,7 @@ func prepareCLIDataWithCacheRefresh(refreshStale bool) error {
 }
 
 func loadReusableCacheSnapshot() (*CacheFile, error) {
-	cachePath := filepath.Join(cfg.CacheDir, "cache.db")
+	cachePath := cacheFilePath()
 	cache, err := LoadCacheFile(diagnosticsCachePath(cachePath))
 	if err != nil {
 		cache, err = LoadCacheFile(cachePath)
@@ -311,16 +374,32 @@ func loadReusableCacheSnapshot() (*CacheFile, error) {
 	if cache.BashRulesHash != rulesHash {
 		return nil, fmt.Errorf("Bash 规则已变更")
 	}
+	if cacheExceedsMaxAge(cache, time.Now()) {
+		return nil, fmt.Errorf("缓存已超过最长可服务时长 %s", cfg.CacheMaxAge)
+	}
 	return cache, nil
 }
 
 func timeFilterFromCLIOptions(opts cliOptions) (TimeFilter, string, error) {
+	if opts.SinceLastRun {
+		if opts.Start != "" || opts.End != "" {
+			return TimeFilter{}, "", fmt.Errorf("--since-last-run 不能与 --start/--end 同时使用")
+		}
+		end := opts.runAt
+		return TimeFilter{Start: opts.lastRun, End: &end, Exact: true}, sinceLastRunPreset, nil
+	}
 	if opts.Start != "" || opts.End != "" {
 		if opts.Start == "" || opts.End == "" {
 			return TimeFilter{}, "", fmt.Errorf("--start 和 --end 必须同时提供")
 		}
 		tf, err := NewTimeFilterCustom(opts.Start, opts.End)
-		return tf, "custom", err
+		if err != nil {
+			return TimeFilter{}, "", err
+		}
+		if err := validateCustomRangeSpan(opts.Start, opts.End, opts.Config.MaxRangeDays); err != nil {
+			return TimeFilter{}, "", err
+		}
+		return tf, "custom", nil
 	}
 	preset := strings.TrimSpace(opts.Preset)
 	if preset == "" {
@@ -382,10 +461,24 @@ func buildCLISummary(data *DashboardData) cliSummary {
 		TopProject:      topProject,
 		TopModel:        topModel,
 	}
+	for i := len(data.DailyTrend.Dates) - 1; i >= 0; i-- {
+		if i < len(data.DailyTrend.Counts) && data.DailyTrend.Counts[i] > 0 {
+			summary.LastActive = data.DailyTrend.Dates[i]
+			summary.LastActiveLabel = summary.LastActive
+			break
+		}
+	}
 	summary.Insights = buildSummaryInsights(data, summary)
 	return summary
 }
 
+// applyRelativeDates 把摘要里的日期标签换成相对时间，JSON 中的原始日期保持不变
+func (summary *cliSummary) applyRelativeDates(now time.Time) {
+	if summary.LastActive != "" {
+		summary.LastActiveLabel = formatRelativeDate(summary.LastActive, now)
+	}
+}
+
 func buildSummaryInsights(data *DashboardData, summary cliSummary) []string {
 	insights := []string{}
 	if data.WeekdayStats != nil && len(data.WeekdayStats.WeekdayData) > 0 {
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 单文件分析：对导出的单个会话 transcript（项目记录）或 history.jsonl 直接运行解析器，
+// 不依赖数据目录与缓存，便于调试某一次对话。
+
+const (
+	transcriptFormatProject = "project"
+	transcriptFormatHistory = "history"
+)
+
+// cliFileAnalysisReport analyze-file 的输出
+type cliFileAnalysisReport struct {
+	Path     string                `json:"path"`
+	Format   string                `json:"format"` // project | history
+	Records  int                   `json:"records"`
+	Sessions []SessionAnalysisItem `json:"sessions,omitempty"`
+	Models   []ModelUsageItem      `json:"models,omitempty"`
+	Tokens   *TokenUsageBreakdown  `json:"tokens,omitempty"`
+	Tools    []ToolStatItem        `json:"tools,omitempty"`
+	Commands []CommandStats        `json:"commands,omitempty"`
+}
+
+// detectTranscriptFormat 根据前若干行的字段判断文件是项目记录还是 history 格式
+func detectTranscriptFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for checked := 0; scanner.Scan() && checked < 20; {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), string(utf8BOM)))
+		if line == "" {
+			continue
+		}
+		checked++
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			continue
+		}
+		if _, ok := fields["sessionId"]; ok {
+			return transcriptFormatProject, nil
+		}
+		if _, ok := fields["type"]; ok {
+			return transcriptFormatProject, nil
+		}
+		if _, ok := fields["display"]; ok {
+			return transcriptFormatHistory, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("无法识别文件格式（既不是项目会话记录也不是 history）: %s", path)
+}
+
+// analyzeTranscriptFile 对单个 JSONL 文件运行对应的解析器
+func analyzeTranscriptFile(path string) (cliFileAnalysisReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return cliFileAnalysisReport{}, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if info.IsDir() {
+		return cliFileAnalysisReport{}, fmt.Errorf("%s 是目录，analyze-file 只接受单个 .jsonl 文件（目录请用 --data）", path)
+	}
+	format, err := detectTranscriptFormat(path)
+	if err != nil {
+		return cliFileAnalysisReport{}, err
+	}
+
+	report := cliFileAnalysisReport{Path: path, Format: format}
+	if format == transcriptFormatHistory {
+		commands, hourlyCounts, err := parseHistoryFile(path, TimeFilter{}, false)
+		if err != nil {
+			return report, err
+		}
+		sortCommandStats(commands)
+		report.Commands = commands
+		for _, count := range hourlyCounts {
+			report.Records += count
+		}
+		return report, nil
+	}
+
+	agg := newProjectAggregate()
+	parseProjectFileAggregate(path, TimeFilter{}, agg)
+	agg.finalize()
+	for _, project := range agg.Projects {
+		report.Records += project.MessageCount
+	}
+	if agg.SessionAnalysis != nil {
+		report.Sessions = agg.SessionAnalysis.Sessions
+	}
+	report.Models = agg.ModelUsageList
+	if agg.CostAnalysis != nil {
+		totals := agg.CostAnalysis.Totals
+		report.Tokens = &totals
+	}
+	if agg.ToolAnalysis != nil {
+		report.Tools = agg.ToolAnalysis.Tools
+	}
+	return report, nil
+}
+
+// limitFileAnalysisReport 按 Top N 截断列表字段
+func limitFileAnalysisReport(report cliFileAnalysisReport, limit int) cliFileAnalysisReport {
+	if limit <= 0 {
+		return report
+	}
+	if len(report.Models) > limit {
+		report.Models = report.Models[:limit]
+	}
+	if len(report.Tools) > limit {
+		report.Tools = report.Tools[:limit]
+	}
+	if len(report.Commands) > limit {
+		report.Commands = report.Commands[:limit]
+	}
+	return report
+}
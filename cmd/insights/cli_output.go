@@ -5,6 +5,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func writeTable(value any, w io.Writer) error {
@@ -19,6 +20,9 @@ func writeTable(value any, w io.Writer) error {
 		fmt.Fprintf(w, "%-12s %s\n", "Token", formatCompactInt(v.Tokens))
 		fmt.Fprintf(w, "%-12s %s\n", "Top 项目", v.TopProject)
 		fmt.Fprintf(w, "%-12s %s\n", "Top 模型", v.TopModel)
+		if v.LastActiveLabel != "" {
+			fmt.Fprintf(w, "%-12s %s\n", "最近活跃", v.LastActiveLabel)
+		}
 		writeInsights(w, v.Insights)
 	case cliFailureReport:
 		fmt.Fprintf(w, "Claude Code Failures · %s\n\n", formatRange(v.TimeRange))
@@ -168,6 +172,31 @@ func writeTable(value any, w io.Writer) error {
 			}
 		}
 		writeInsights(w, v.Insights)
+	case cliFileAnalysisReport:
+		fmt.Fprintf(w, "Claude Code File Analysis · %s (%s)\n\n", v.Path, v.Format)
+		fmt.Fprintf(w, "%-12s %s\n", "记录数", formatInt(v.Records))
+		if v.Format == transcriptFormatHistory {
+			fmt.Fprintln(w, "\nTop 命令:")
+			for _, item := range v.Commands {
+				fmt.Fprintf(w, "  %-24s %s\n", item.Command, formatInt(item.Count))
+			}
+			return nil
+		}
+		if v.Tokens != nil {
+			fmt.Fprintf(w, "%-12s %s  请求: %s\n", "Token", formatCompactInt(v.Tokens.TotalTokens), formatInt(v.Tokens.RequestCount))
+		}
+		fmt.Fprintln(w, "\n会话:")
+		for _, item := range v.Sessions {
+			fmt.Fprintf(w, "  %-36s %6s msgs  %5s tools  %s\n", item.SessionID, formatInt(item.MessageCount), formatInt(item.ToolCallCount), item.PrimaryModel)
+		}
+		fmt.Fprintln(w, "\n模型:")
+		for _, item := range v.Models {
+			fmt.Fprintf(w, "  %-26s %8s requests  %10s tokens\n", item.Model, formatInt(item.Count), formatCompactInt(item.Tokens))
+		}
+		fmt.Fprintln(w, "\n工具:")
+		for _, item := range v.Tools {
+			fmt.Fprintf(w, "  %-20s %8s calls  %s failures\n", item.Tool, formatInt(item.CallCount), formatInt(item.FailureCount))
+		}
 	default:
 		return fmt.Errorf("不支持 table 输出类型 %T", value)
 	}
@@ -180,6 +209,9 @@ func writeMarkdown(value any, w io.Writer) error {
 		fmt.Fprintf(w, "# Claude Code Insights\n\n范围: %s\n\n", formatRange(v.TimeRange))
 		fmt.Fprintf(w, "- 消息数: %s\n- 会话数: %s\n- Token: %s\n- 工具失败率: %.1f%%\n- Top 项目: %s\n- Top 模型: %s\n",
 			formatInt(v.Messages), formatInt(v.Sessions), formatCompactInt(v.Tokens), v.ToolFailureRate, v.TopProject, v.TopModel)
+		if v.LastActiveLabel != "" {
+			fmt.Fprintf(w, "- 最近活跃: %s\n", v.LastActiveLabel)
+		}
 		writeMarkdownInsights(w, v.Insights)
 	case cliFailureReport:
 		fmt.Fprintf(w, "# Claude Code Failures\n\n范围: %s\n\n", formatRange(v.TimeRange))
@@ -328,6 +360,34 @@ func writeMarkdown(value any, w io.Writer) error {
 			}
 		}
 		writeMarkdownInsights(w, v.Insights)
+	case cliFileAnalysisReport:
+		fmt.Fprintf(w, "# Claude Code File Analysis\n\n文件: `%s`（%s）\n\n- 记录数: %s\n", v.Path, v.Format, formatInt(v.Records))
+		if v.Format == transcriptFormatHistory {
+			fmt.Fprintln(w, "\n## Top 命令")
+			fmt.Fprintln(w)
+			for _, item := range v.Commands {
+				fmt.Fprintf(w, "- `%s`: %s\n", item.Command, formatInt(item.Count))
+			}
+			return nil
+		}
+		if v.Tokens != nil {
+			fmt.Fprintf(w, "- Token: %s（%s 次请求）\n", formatCompactInt(v.Tokens.TotalTokens), formatInt(v.Tokens.RequestCount))
+		}
+		fmt.Fprintln(w, "\n## 会话")
+		fmt.Fprintln(w)
+		for _, item := range v.Sessions {
+			fmt.Fprintf(w, "- `%s`: %s 条消息，%s 次工具调用，%s\n", item.SessionID, formatInt(item.MessageCount), formatInt(item.ToolCallCount), item.PrimaryModel)
+		}
+		fmt.Fprintln(w, "\n## 模型")
+		fmt.Fprintln(w)
+		for _, item := range v.Models {
+			fmt.Fprintf(w, "- `%s`: %s 次请求，%s tokens\n", item.Model, formatInt(item.Count), formatCompactInt(item.Tokens))
+		}
+		fmt.Fprintln(w, "\n## 工具")
+		fmt.Fprintln(w)
+		for _, item := range v.Tools {
+			fmt.Fprintf(w, "- `%s`: %s 次调用，%s 次失败\n", item.Tool, formatInt(item.CallCount), formatInt(item.FailureCount))
+		}
 	default:
 		return fmt.Errorf("不支持 markdown 输出类型 %T", value)
 	}
@@ -728,6 +788,29 @@ func formatRange(value TimeRangeInfo) string {
 	return "all"
 }
 
+// formatRelativeDate 把 YYYY-MM-DD 渲染为相对时间（今天/昨天/N 天前）；
+// 一周及以前、未来日期或无法解析时原样返回绝对日期。
+func formatRelativeDate(date string, now time.Time) string {
+	parsed, err := time.ParseInLocation("2006-01-02", date, now.Location())
+	if err != nil {
+		return date
+	}
+	// 按日历日相减：两端都换成 UTC 零点，避免夏令时切换日只有 23/25 小时
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(today.Sub(day).Hours() / 24)
+	switch {
+	case days == 0:
+		return "今天"
+	case days == 1:
+		return "昨天"
+	case days > 1 && days < 7:
+		return fmt.Sprintf("%d 天前", days)
+	default:
+		return date
+	}
+}
+
 func formatInt(value int) string {
 	sign := ""
 	if value < 0 {
@@ -8,7 +8,7 @@ import (
 )
 
 func buildRecommendationDashboardData(tf TimeFilter, preset string) (*DashboardData, string, error) {
-	if globalCache == nil {
+	if loadGlobalCache() == nil || tf.bypassesCache() {
 		data, source, err := buildDashboardData(tf, preset)
 		return data, source, err
 	}
@@ -23,8 +23,12 @@ func buildRecommendationDashboardData(tf TimeFilter, preset string) (*DashboardD
 	if tf.End != nil {
 		end = *tf.End
 	}
+	cache := loadGlobalCache()
+	if cache == nil {
+		return buildDashboardData(tf, preset)
+	}
 	queryStartedAt := time.Now()
-	cached := globalCache.QueryByTimeRange(start, end)
+	cached := cache.QueryByTimeRange(start, end)
 	Debug("诊断缓存查询完成",
 		"preset", preset,
 		"query_duration", time.Since(queryStartedAt).Round(time.Millisecond),
@@ -889,8 +893,8 @@ func buildPerformanceDiagnostics(data *DashboardData) []diagnosticFinding {
 			})
 		}
 	}
-	if globalCache != nil && globalCache.BuildStats != nil {
-		stats := globalCache.BuildStats
+	if cache := loadGlobalCache(); cache != nil && cache.BuildStats != nil {
+		stats := cache.BuildStats
 		if stats.BuildDurationMs >= 10_000 || stats.ParsedFiles >= 500 {
 			findings = append(findings, diagnosticFinding{
 				ID:       "performance.cache.build_cost",
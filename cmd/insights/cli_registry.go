@@ -25,7 +25,7 @@ type Command struct {
 // errHelp 表示帮助文本已打印，runCLI 应静默退出（退出码 0）。
 var errHelp = errors.New("help shown")
 
-var commands = []*Command{cmdSum, cmdErr, cmdWhy, cmdTok, cmdCmd, cmdSes, cmdRec, cmdWeb}
+var commands = []*Command{cmdSum, cmdErr, cmdWhy, cmdTok, cmdCmd, cmdSes, cmdRec, cmdWeb, cmdAnalyzeFile}
 
 func lookupCommand(name string) *Command {
 	for _, c := range commands {
@@ -41,14 +41,15 @@ func lookupCommand(name string) *Command {
 func registerCommonAnalysisFlags(fs *flag.FlagSet, opts *cliOptions) {
 	fs.StringVar(&opts.Preset, "preset", opts.Preset, "时间范围: 24h|7d|30d|90d|all")
 	fs.StringVar(&opts.Preset, "p", opts.Preset, "时间范围（同 --preset）: 24h|7d|30d|90d|all")
-	fs.StringVar(&opts.Start, "start", "", "自定义开始日期 YYYY-MM-DD")
-	fs.StringVar(&opts.End, "end", "", "自定义结束日期 YYYY-MM-DD")
+	fs.StringVar(&opts.Start, "start", "", "自定义开始日期 YYYY-MM-DD，或 now、-14d、-3w、-2m")
+	fs.StringVar(&opts.End, "end", "", "自定义结束日期 YYYY-MM-DD，或 now、-14d、-3w、-2m")
 	fs.StringVar(&opts.Format, "format", opts.Format, "输出格式: table|json|markdown")
 	fs.StringVar(&opts.Format, "f", opts.Format, "输出格式（同 --format）: table|json|markdown")
 	fs.IntVar(&opts.Limit, "limit", opts.Limit, "Top N 结果数量")
 	fs.IntVar(&opts.Limit, "n", opts.Limit, "Top N 结果数量（同 --limit；why/ses 下兼作样例数）")
 	fs.BoolVar(&opts.jsonOut, "j", false, "输出 JSON（等价 --format=json）")
 	fs.BoolVar(&opts.markdownOut, "m", false, "输出 Markdown（等价 --format=markdown）")
+	fs.BoolVar(&opts.SinceLastRun, "since-last-run", false, "只统计上次使用该 flag 成功运行之后的新记录（首次运行统计全部）")
 }
 
 // fastRun 是 err/why/tok/cmd/ses 共用的执行模板：
@@ -75,8 +76,12 @@ var cmdSum = &Command{
 	Name:     "sum",
 	Short:    "全局使用概览",
 	Long:     "汇总时间范围内的消息数、会话数、命令数、工具调用、Token 消耗、失败率以及主要项目/模型，作为整体用法的入口快照。",
-	Examples: []string{"cc-insights", "cc-insights sum -p 30d -j"},
-	Flags:    registerCommonAnalysisFlags,
+	Examples: []string{"cc-insights", "cc-insights sum -p 30d -j", "cc-insights sum -p 90d --out report.html"},
+	Flags: func(fs *flag.FlagSet, opts *cliOptions) {
+		registerCommonAnalysisFlags(fs, opts)
+		fs.StringVar(&opts.Out, "out", "", "将 Dashboard 渲染为静态 HTML 写入该文件后退出（不启动服务）")
+		fs.BoolVar(&opts.RelativeDates, "relative", false, "日期显示为相对时间（今天/昨天/N 天前），一周前仍显示绝对日期")
+	},
 	Run: func(opts cliOptions) error {
 		tf, preset, err := timeFilterFromCLIOptions(opts)
 		if err != nil {
@@ -90,7 +95,14 @@ var cmdSum = &Command{
 		if err != nil {
 			return err
 		}
-		return outputCLI(buildCLISummary(data), opts.Format, os.Stdout)
+		if opts.Out != "" {
+			return writeDashboardHTML(opts.Out, data)
+		}
+		summary := buildCLISummary(data)
+		if opts.RelativeDates {
+			summary.applyRelativeDates(time.Now())
+		}
+		return outputCLI(summary, opts.Format, os.Stdout)
 	},
 }
 
@@ -250,6 +262,32 @@ var cmdWeb = &Command{
 	},
 }
 
+var cmdAnalyzeFile = &Command{
+	Name:  "analyze-file",
+	Short: "分析单个导出的会话文件",
+	Long: "对单个 JSONL 文件（导出的会话 transcript 或 history.jsonl）直接运行解析器，输出会话/模型/Token/工具统计。\n" +
+		"自动识别文件格式，不读取数据目录，也不使用缓存，适合调试某一次对话。",
+	Examples: []string{"cc-insights analyze-file ./session.jsonl", "cc-insights analyze-file ./session.jsonl -j"},
+	Flags: func(fs *flag.FlagSet, opts *cliOptions) {
+		fs.StringVar(&opts.Format, "format", opts.Format, "输出格式: table|json|markdown")
+		fs.StringVar(&opts.Format, "f", opts.Format, "输出格式（同 --format）: table|json|markdown")
+		fs.IntVar(&opts.Limit, "limit", opts.Limit, "Top N 结果数量")
+		fs.IntVar(&opts.Limit, "n", opts.Limit, "Top N 结果数量（同 --limit）")
+		fs.BoolVar(&opts.jsonOut, "j", false, "输出 JSON（等价 --format=json）")
+		fs.BoolVar(&opts.markdownOut, "m", false, "输出 Markdown（等价 --format=markdown）")
+	},
+	Run: func(opts cliOptions) error {
+		if len(opts.Args) != 1 {
+			return errors.New("用法: cc-insights analyze-file <path.jsonl>")
+		}
+		report, err := analyzeTranscriptFile(opts.Args[0])
+		if err != nil {
+			return err
+		}
+		return outputCLI(limitFileAnalysisReport(report, opts.Limit), opts.Format, os.Stdout)
+	},
+}
+
 // configFlagSet 构造一个仅注册 Config flag 的 FlagSet，供全局帮助复用。
 func configFlagSet() *flag.FlagSet {
 	fs := flag.NewFlagSet("config", flag.ContinueOnError)
@@ -267,8 +305,14 @@ func printGlobalHelp(w io.Writer) {
   不带命令等价于 cc-insights sum
 
 命令:`)
+	width := 5
+	for _, c := range commands {
+		if len(c.Name) > width {
+			width = len(c.Name)
+		}
+	}
 	for _, c := range commands {
-		fmt.Fprintf(w, "  %-5s %s\n", c.Name, c.Short)
+		fmt.Fprintf(w, "  %-*s %s\n", width, c.Name, c.Short)
 	}
 	fmt.Fprintln(w, `
 我想看…
@@ -280,6 +324,7 @@ func printGlobalHelp(w io.Writer) {
   · 哪些会话太长/失败多       → ses
   · 怎么改 CLAUDE.md/hooks   → rec
   · 交互式看图、按模型下钻    → web
+  · 只看某个导出的会话文件    → analyze-file
 
 全局参数（所有命令通用）:`)
 	writeFlagSetHelp(w, configFlagSet())
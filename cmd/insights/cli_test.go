@@ -3,8 +3,11 @@ package main
 import (
 	"bytes"
 	"flag"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestResolveCLICommand(t *testing.T) {
@@ -203,3 +206,153 @@ func TestFlagRestriction_SumRejectsReason(t *testing.T) {
 		t.Fatal("runCLI sum --reason should error: reason is not a sum flag")
 	}
 }
+
+func TestFlagRestriction_RelativeOnlyForSum(t *testing.T) {
+	// --relative 只有 sum 的摘要会用到，其他命令不接受
+	if err := runCLI([]string{"err", "--relative"}); err == nil {
+		t.Fatal("runCLI err --relative should error: relative is a sum-only flag")
+	}
+}
+
+func TestFormatRelativeDateAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	// 2026-03-08 切换夏令时，当天只有 23 小时
+	now := time.Date(2026, 3, 9, 10, 0, 0, 0, loc)
+	for date, want := range map[string]string{"2026-03-08": "昨天", "2026-03-06": "3 天前"} {
+		if got := formatRelativeDate(date, now); got != want {
+			t.Fatalf("formatRelativeDate(%q) = %q, want %q", date, got, want)
+		}
+	}
+}
+
+func TestFormatRelativeDate(t *testing.T) {
+	now := time.Date(2026, 5, 20, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		date string
+		want string
+	}{
+		{date: "2026-05-20", want: "今天"},
+		{date: "2026-05-19", want: "昨天"},
+		{date: "2026-05-17", want: "3 天前"},
+		{date: "2026-05-13", want: "2026-05-13"},
+		{date: "2025-12-01", want: "2025-12-01"},
+		{date: "2026-05-21", want: "2026-05-21"},
+		{date: "not-a-date", want: "not-a-date"},
+	}
+	for _, tt := range tests {
+		if got := formatRelativeDate(tt.date, now); got != tt.want {
+			t.Fatalf("formatRelativeDate(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryRelativeDatesOutput(t *testing.T) {
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	data := &DashboardData{
+		TimeRange: TimeRangeInfo{Preset: "7d"},
+		DailyTrend: DailyTrendData{
+			Dates:  []string{now.AddDate(0, 0, -3).Format("2006-01-02"), yesterday, now.Format("2006-01-02")},
+			Counts: []int{4, 2, 0},
+		},
+	}
+
+	summary := buildCLISummary(data)
+	if summary.LastActive != yesterday || summary.LastActiveLabel != yesterday {
+		t.Fatalf("LastActive=(%q,%q), want absolute %s", summary.LastActive, summary.LastActiveLabel, yesterday)
+	}
+
+	summary.applyRelativeDates(now)
+	var out bytes.Buffer
+	if err := outputCLI(summary, "markdown", &out); err != nil {
+		t.Fatalf("outputCLI returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "最近活跃: 昨天") {
+		t.Fatalf("markdown output missing relative date:\n%s", out.String())
+	}
+	if summary.LastActive != yesterday {
+		t.Fatalf("LastActive=%q, absolute date should be kept for JSON", summary.LastActive)
+	}
+}
+
+func TestAnalyzeTranscriptFile(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)
+
+	transcript := filepath.Join(dir, "session.jsonl")
+	lines := []string{
+		toolUseRecordWithUsage("/tmp/demo", "sess-1", base, "call-1", "Bash", "claude-opus-4-6", `{"command":"go test ./..."}`, 100, 20),
+		toolUseRecordWithUsage("/tmp/demo", "sess-1", base.Add(time.Minute), "call-2", "Read", "claude-opus-4-6", `{"file_path":"/tmp/demo/main.go"}`, 50, 10),
+		projectRecordJSON("/tmp/demo", "sess-1", base.Add(2*time.Minute)),
+	}
+	if err := os.WriteFile(transcript, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := analyzeTranscriptFile(transcript)
+	if err != nil {
+		t.Fatalf("analyzeTranscriptFile: %v", err)
+	}
+	if report.Format != transcriptFormatProject || report.Records != 3 {
+		t.Fatalf("format=%s records=%d, want project/3", report.Format, report.Records)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0].SessionID != "sess-1" {
+		t.Fatalf("sessions=%+v, want sess-1", report.Sessions)
+	}
+	models := map[string]int{}
+	for _, item := range report.Models {
+		models[item.Model] = item.Count
+	}
+	if models["claude-opus-4-6"] != 2 || models["claude-sonnet-4.5"] != 1 {
+		t.Fatalf("models=%+v", report.Models)
+	}
+	if report.Tokens == nil || report.Tokens.InputTokens != 160 || report.Tokens.OutputTokens != 35 {
+		t.Fatalf("tokens=%+v, want input=160 output=35", report.Tokens)
+	}
+	tools := map[string]int{}
+	for _, item := range report.Tools {
+		tools[item.Tool] = item.CallCount
+	}
+	if tools["Bash"] != 1 || tools["Read"] != 1 {
+		t.Fatalf("tools=%+v", report.Tools)
+	}
+
+	// history 格式按字段自动识别
+	history := filepath.Join(dir, "history.jsonl")
+	historyContent := `{"display":"/model opus","timestamp":1772532000000,"project":"demo"}
+{"display":"/model sonnet","timestamp":1772532001000,"project":"demo"}
+{"display":"fix the test","timestamp":1772532002000,"project":"demo"}
+`
+	if err := os.WriteFile(history, []byte(historyContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = analyzeTranscriptFile(history)
+	if err != nil {
+		t.Fatalf("analyzeTranscriptFile(history): %v", err)
+	}
+	if report.Format != transcriptFormatHistory || report.Records != 3 || len(report.Commands) != 1 || report.Commands[0].Count != 2 {
+		t.Fatalf("history report=%+v", report)
+	}
+
+	if _, err := analyzeTranscriptFile(dir); err == nil {
+		t.Fatal("目录应返回错误")
+	}
+
+	var buf bytes.Buffer
+	if err := writeTable(limitFileAnalysisReport(report, 5), &buf); err != nil || !strings.Contains(buf.String(), "/model") {
+		t.Fatalf("table output err=%v out=%s", err, buf.String())
+	}
+}
+
+func TestParseCLIOptionsInterleavedPositionalArgs(t *testing.T) {
+	opts, err := parseCLIOptions(cmdAnalyzeFile, []string{"session.jsonl", "-j", "-n", "3"})
+	if err != nil {
+		t.Fatalf("parseCLIOptions: %v", err)
+	}
+	if len(opts.Args) != 1 || opts.Args[0] != "session.jsonl" || opts.Format != "json" || opts.Limit != 3 {
+		t.Fatalf("args=%v format=%s limit=%d", opts.Args, opts.Format, opts.Limit)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commandCategoryOther 未归入任何分类的 slash command
+const commandCategoryOther = "other"
+
+// CategoryStat 一个命令分类的调用次数
+type CategoryStat struct {
+	Category string   `json:"category"`
+	Count    int      `json:"count"`
+	Commands []string `json:"commands"` // 该分类下实际出现过的命令，按名称排序
+}
+
+// defaultCommandCategories 内置的命令分类，--command-categories 指定文件时整体替换
+func defaultCommandCategories() map[string][]string {
+	return map[string][]string{
+		"session control": {"/clear", "/compact", "/resume", "/exit"},
+		"config":          {"/model", "/config", "/permissions", "/mcp", "/plugin", "/hooks"},
+		"info":            {"/help", "/status", "/cost", "/doctor"},
+	}
+}
+
+// loadCommandCategories 读取命令分类 YAML：顶层为「分类名: [命令列表]」，命令可省略开头的 /。
+// 同一命令出现在多个分类中时报错
+func loadCommandCategories(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取命令分类失败: %w", err)
+	}
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析命令分类失败: %w", err)
+	}
+	categories := make(map[string][]string, len(raw))
+	owner := make(map[string]string)
+	for category, commands := range raw {
+		category = strings.TrimSpace(category)
+		if category == "" {
+			return nil, fmt.Errorf("命令分类名不能为空")
+		}
+		for _, command := range commands {
+			command = normalizeCategoryCommand(command)
+			if command == "" {
+				continue
+			}
+			if prev, ok := owner[command]; ok && prev != category {
+				return nil, fmt.Errorf("命令 %s 同时属于分类 %q 与 %q", command, prev, category)
+			}
+			owner[command] = category
+			categories[category] = append(categories[category], command)
+		}
+	}
+	return categories, nil
+}
+
+// normalizeCategoryCommand 统一为带 / 前缀的命令名
+func normalizeCategoryCommand(command string) string {
+	command = strings.TrimSpace(command)
+	if command == "" || strings.HasPrefix(command, "/") {
+		return command
+	}
+	return "/" + command
+}
+
+// groupCommandCategories 按分类汇总命令次数，未映射的命令归入 other。
+// 结果按次数降序，同数按分类名排序；breakdown=args 的子命令按所属命令归类
+func groupCommandCategories(stats []CommandStats, categories map[string][]string) []CategoryStat {
+	index := make(map[string]string)
+	for category, commands := range categories {
+		for _, command := range commands {
+			index[normalizeCategoryCommand(command)] = category
+		}
+	}
+
+	byCategory := make(map[string]*CategoryStat)
+	seen := make(map[string]map[string]bool)
+	for _, stat := range stats {
+		category, ok := index[stat.Command]
+		if !ok {
+			category = commandCategoryOther
+		}
+		item := byCategory[category]
+		if item == nil {
+			item = &CategoryStat{Category: category}
+			byCategory[category] = item
+			seen[category] = make(map[string]bool)
+		}
+		item.Count += stat.Count
+		if !seen[category][stat.Command] {
+			seen[category][stat.Command] = true
+			item.Commands = append(item.Commands, stat.Command)
+		}
+	}
+
+	result := make([]CategoryStat, 0, len(byCategory))
+	for _, item := range byCategory {
+		sort.Strings(item.Commands)
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Category < result[j].Category
+	})
+	return result
+}
+
+// ParseCommandCategories 解析 history 中的 slash command，按 cfg.CommandCategories 分类汇总
+func ParseCommandCategories(tf TimeFilter) ([]CategoryStat, error) {
+	cmdStats, _, err := ParseHistoryConcurrent(tf)
+	if err != nil {
+		return nil, err
+	}
+	return groupCommandCategories(cmdStats, cfg.CommandCategories), nil
+}
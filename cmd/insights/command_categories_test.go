@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupCommandCategories(t *testing.T) {
+	categories := map[string][]string{
+		"session control": {"/clear", "compact"},
+		"config":          {"/model", "/config"},
+	}
+	stats := []CommandStats{
+		{Command: "/clear", Count: 4},
+		{Command: "/compact", Count: 2},
+		{Command: "/model", SubCommand: "opus", Count: 3},
+		{Command: "/model", SubCommand: "sonnet", Count: 1},
+		{Command: "/review", Count: 5},
+		{Command: "/tdd", Count: 1},
+	}
+	// 同次数按分类名排序；/model 的两个子命令归入同一分类
+	got := groupCommandCategories(stats, categories)
+	want := []CategoryStat{
+		{Category: "other", Count: 6, Commands: []string{"/review", "/tdd"}},
+		{Category: "session control", Count: 6, Commands: []string{"/clear", "/compact"}},
+		{Category: "config", Count: 4, Commands: []string{"/model"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("groupCommandCategories() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseCommandCategoriesFromConfigFile(t *testing.T) {
+	useFixtureDataDir(t)
+	origCategories := cfg.CommandCategories
+	defer func() { cfg.CommandCategories = origCategories }()
+
+	path := filepath.Join(t.TempDir(), "categories.yml")
+	if err := os.WriteFile(path, []byte("config:\n  - model\n  - /config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := loadCommandCategories(path)
+	if err != nil {
+		t.Fatalf("loadCommandCategories() error = %v", err)
+	}
+	if want := map[string][]string{"config": {"/model", "/config"}}; !reflect.DeepEqual(categories, want) {
+		t.Fatalf("categories = %v, want %v", categories, want)
+	}
+	cfg.CommandCategories = categories
+
+	stats, err := ParseCommandCategories(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseCommandCategories() error = %v", err)
+	}
+	commands, _, err := ParseHistoryConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, grouped := 0, 0
+	for _, stat := range commands {
+		total += stat.Count
+	}
+	for _, stat := range stats {
+		grouped += stat.Count
+		if stat.Category == "config" && !reflect.DeepEqual(stat.Commands, []string{"/model"}) {
+			t.Fatalf("config 分类命令 = %v, want [/model]", stat.Commands)
+		}
+	}
+	if grouped != total || len(stats) != 2 {
+		t.Fatalf("stats = %+v, 分类合计 %d, want %d（config + other 两类）", stats, grouped, total)
+	}
+
+	if err := os.WriteFile(path, []byte("a: [/clear]\nb: [clear]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCommandCategories(path); err == nil || !strings.Contains(err.Error(), "/clear") {
+		t.Fatalf("重复归类应报错, err = %v", err)
+	}
+}
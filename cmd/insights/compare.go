@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// /api/compare：两个时间范围各自实时解析一遍，返回两份 DashboardData 与 a 相对 b 的变化，
+// 用于「本周 vs 上周」之类的复盘。a/b 的范围参数分别带 a_ / b_ 前缀（preset、start、end）。
+
+// CompareResult /api/compare 响应数据
+type CompareResult struct {
+	A      *DashboardData `json:"a"`
+	B      *DashboardData `json:"b"`
+	Deltas CompareDeltas  `json:"deltas"`
+}
+
+// CompareDeltas a 相对 b 的变化；百分比为 (a-b)/b*100，b 为 0 时为 null
+type CompareDeltas struct {
+	MessagesPct *float64       `json:"messages_pct"`
+	SessionsPct *float64       `json:"sessions_pct"`
+	TokensPct   *float64       `json:"tokens_pct"`
+	Messages    int            `json:"messages"` // a - b
+	Sessions    int            `json:"sessions"`
+	Tokens      int            `json:"tokens"`
+	Commands    []CommandDelta `json:"commands"`
+}
+
+// CommandDelta 单个命令在两个范围的次数与差值，按差值绝对值降序
+type CommandDelta struct {
+	Command string `json:"command"`
+	A       int    `json:"a"`
+	B       int    `json:"b"`
+	Change  int    `json:"change"`
+}
+
+// compareTotals 从 DashboardData 取出对比用的消息数、会话数与 token 数
+func compareTotals(data *DashboardData) (messages, sessions, tokens int) {
+	if data == nil {
+		return 0, 0, 0
+	}
+	if data.ProjectStats != nil {
+		messages = data.ProjectStats.TotalMessages
+		for _, project := range data.ProjectStats.Projects {
+			tokens += project.Tokens
+		}
+	}
+	if data.Sessions != nil {
+		sessions = data.Sessions.TotalSessions
+	}
+	return messages, sessions, tokens
+}
+
+// percentChange (a-b)/b*100，保留一位小数；b 为 0 时无意义，返回 nil
+func percentChange(a, b int) *float64 {
+	if b == 0 {
+		return nil
+	}
+	pct := math.Round(float64(a-b)/float64(b)*1000) / 10
+	return &pct
+}
+
+// buildCompareDeltas 计算 a 相对 b 的总量变化与逐命令变化
+func buildCompareDeltas(a, b *DashboardData) CompareDeltas {
+	aMessages, aSessions, aTokens := compareTotals(a)
+	bMessages, bSessions, bTokens := compareTotals(b)
+	deltas := CompareDeltas{
+		MessagesPct: percentChange(aMessages, bMessages),
+		SessionsPct: percentChange(aSessions, bSessions),
+		TokensPct:   percentChange(aTokens, bTokens),
+		Messages:    aMessages - bMessages,
+		Sessions:    aSessions - bSessions,
+		Tokens:      aTokens - bTokens,
+	}
+
+	byCommand := make(map[string]*CommandDelta)
+	ensure := func(command string) *CommandDelta {
+		if byCommand[command] == nil {
+			byCommand[command] = &CommandDelta{Command: command}
+		}
+		return byCommand[command]
+	}
+	if a != nil {
+		for _, cmd := range a.Commands {
+			ensure(cmd.Command).A += cmd.Count
+		}
+	}
+	if b != nil {
+		for _, cmd := range b.Commands {
+			ensure(cmd.Command).B += cmd.Count
+		}
+	}
+	deltas.Commands = make([]CommandDelta, 0, len(byCommand))
+	for _, delta := range byCommand {
+		delta.Change = delta.A - delta.B
+		deltas.Commands = append(deltas.Commands, *delta)
+	}
+	sort.Slice(deltas.Commands, func(i, j int) bool {
+		ci, cj := deltas.Commands[i].Change, deltas.Commands[j].Change
+		if ci < 0 {
+			ci = -ci
+		}
+		if cj < 0 {
+			cj = -cj
+		}
+		if ci != cj {
+			return ci > cj
+		}
+		return deltas.Commands[i].Command < deltas.Commands[j].Command
+	})
+	return deltas
+}
+
+// compareRangeFilter 解析带前缀（a_ / b_）的时间范围参数
+func compareRangeFilter(q url.Values, prefix string) (TimeFilter, string, error) {
+	opts := cliOptions{
+		Config: cfg,
+		Preset: strings.TrimSpace(q.Get(prefix + "preset")),
+		Start:  strings.TrimSpace(q.Get(prefix + "start")),
+		End:    strings.TrimSpace(q.Get(prefix + "end")),
+	}
+	if opts.Preset == "" && opts.Start == "" && opts.End == "" {
+		return TimeFilter{}, "", fmt.Errorf("缺少 %spreset 或 %sstart/%send 参数", prefix, prefix, prefix)
+	}
+	tf, preset, err := timeFilterFromCLIOptions(opts)
+	if err != nil {
+		return TimeFilter{}, "", fmt.Errorf("%s 范围无效: %w", strings.TrimSuffix(prefix, "_"), err)
+	}
+	return tf, preset, nil
+}
+
+// handleCompareAPI /api/compare?a_preset=7d&b_start=-14d&b_end=-7d
+func handleCompareAPI(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	aFilter, aPreset, err := compareRangeFilter(q, "a_")
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	bFilter, bPreset, err := compareRangeFilter(q, "b_")
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+
+	a, err := buildDataFromParsing(aFilter, aPreset)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, APIResponse{Success: false, Error: err.Error()})
+		return
+	}
+	b, err := buildDataFromParsing(bFilter, bPreset)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, APIResponse{Success: false, Error: err.Error()})
+		return
+	}
+	sendJSON(w, APIResponse{Success: true, Data: CompareResult{A: a, B: b, Deltas: buildCompareDeltas(a, b)}})
+}
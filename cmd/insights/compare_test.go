@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleCompareAPIDeltaSigns(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// b 窗口（1/5~1/7）：2 条消息 1 个会话；a 窗口（1/12~1/14）：3 条消息 2 个会话
+	bDay := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	aDay := time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC)
+	records := []string{
+		projectRecordJSON("/tmp/demo", "b1", bDay),
+		projectRecordJSON("/tmp/demo", "b1", bDay.Add(time.Minute)),
+		projectRecordJSON("/tmp/demo", "a1", aDay),
+		projectRecordJSON("/tmp/demo", "a1", aDay.Add(time.Minute)),
+		projectRecordJSON("/tmp/demo", "a2", aDay.AddDate(0, 0, 1)),
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(strings.Join(records, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var history []string
+	addCommand := func(display string, ts time.Time) {
+		history = append(history, `{"display":"`+display+`","timestamp":`+strconv.FormatInt(ts.UnixMilli(), 10)+`,"project":"/tmp/demo"}`)
+	}
+	addCommand("/help", bDay)
+	addCommand("/model", bDay.Add(time.Minute))
+	addCommand("/model", bDay.Add(2*time.Minute))
+	for i := 0; i < 3; i++ {
+		addCommand("/help", aDay.Add(time.Duration(i)*time.Minute))
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(strings.Join(history, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	w := httptest.NewRecorder()
+	handleCompareAPI(w, httptest.NewRequest("GET", "/api/compare?a_start=2026-01-12&a_end=2026-01-14&b_start=2026-01-05&b_end=2026-01-07", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Success bool          `json:"success"`
+		Data    CompareResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.A == nil || resp.Data.B == nil {
+		t.Fatalf("响应缺少 a/b: %s", w.Body.String())
+	}
+	deltas := resp.Data.Deltas
+	if deltas.Messages != 1 || deltas.MessagesPct == nil || *deltas.MessagesPct != 50 {
+		t.Fatalf("消息变化 = %d / %v, want +1 / 50%%", deltas.Messages, deltas.MessagesPct)
+	}
+	if deltas.SessionsPct == nil || *deltas.SessionsPct <= 0 || deltas.TokensPct == nil || *deltas.TokensPct <= 0 {
+		t.Fatalf("会话/token 变化应为正: %+v", deltas)
+	}
+	changes := map[string]int{}
+	for _, cmd := range deltas.Commands {
+		changes[cmd.Command] = cmd.Change
+	}
+	if changes["/help"] != 2 || changes["/model"] != -2 {
+		t.Fatalf("命令变化 = %+v, want /help +2, /model -2", deltas.Commands)
+	}
+
+	w = httptest.NewRecorder()
+	handleCompareAPI(w, httptest.NewRequest("GET", "/api/compare?a_preset=7d", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("缺少 b 范围时 status=%d, want 400", w.Code)
+	}
+}
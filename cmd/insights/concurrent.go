@@ -4,19 +4,22 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-// getWorkerCount 返回推荐的 worker 数量（CPU 核心数的一半，至少为 1）
+// getWorkerCount 返回解析器使用的 worker 数量：--workers 指定时直接使用，
+// 否则为 CPU 核心数的一半（至少为 1）。所有并发解析路径统一经由这里取值。
 func getWorkerCount() int {
+	if cfg.Workers > 0 {
+		return cfg.Workers
+	}
 	n := runtime.NumCPU() / 2
 	if n < 1 {
 		return 1
@@ -26,79 +29,106 @@ func getWorkerCount() int {
 
 // ParseHistoryConcurrent 并发解析 history.jsonl（优化版）
 func ParseHistoryConcurrent(tf TimeFilter) ([]CommandStats, map[string]int, error) {
-	path := GetDataPath("history.jsonl")
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, nil, err
+	cmds, hourly, _, err := parseHistoryConcurrent(tf, false)
+	return cmds, hourly, err
+}
+
+// ParseHistoryConcurrentWithArgs 同 ParseHistoryConcurrent，但按「命令 + 首个参数」拆分统计
+func ParseHistoryConcurrentWithArgs(tf TimeFilter) ([]CommandStats, map[string]int, error) {
+	cmds, hourly, _, err := parseHistoryConcurrent(tf, true)
+	return cmds, hourly, err
+}
+
+// ParseHistoryConcurrentWithPastes 同 ParseHistoryConcurrent，并在同一次遍历中统计粘贴内容
+func ParseHistoryConcurrentWithPastes(tf TimeFilter) ([]CommandStats, map[string]int, PasteStats, error) {
+	return parseHistoryConcurrent(tf, false)
+}
+
+func parseHistoryConcurrent(tf TimeFilter, withArgs bool) ([]CommandStats, map[string]int, PasteStats, error) {
+	// 先打开全部分片，任一打开失败时整体报错（与单文件时一致）
+	var files []fs.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, path := range historyFilePaths() {
+		f, err := openDataFile(path)
+		if err != nil {
+			return nil, nil, PasteStats{}, err
+		}
+		files = append(files, f)
 	}
-	defer f.Close()
 
 	// 使用批量处理
 	batchSize := 1000
 	batches := make(chan []HistoryRecord, runtime.NumCPU())
 	var wg sync.WaitGroup
 
-	// producer: 读取文件并分批
+	// producer: 依次读取各分片并分批
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer close(batches)
 
-		decoder := json.NewDecoder(f)
 		batch := make([]HistoryRecord, 0, batchSize)
-
-		for {
-			var record HistoryRecord
-			if err := decoder.Decode(&record); err != nil {
-				if err == io.EOF {
-					if len(batch) > 0 {
-						batches <- batch
-					}
+		for _, f := range files {
+			lines := newJSONLReader(f)
+			for {
+				line, ok := lines.Next()
+				if !ok {
 					break
 				}
-				continue
-			}
+				var record HistoryRecord
+				if err := json.Unmarshal(line, &record); err != nil {
+					continue
+				}
 
-			// 时间过滤
-			recordTime := time.Unix(record.Timestamp/1000, 0)
-			if !tf.Contains(recordTime) {
-				continue
-			}
+				// 时间过滤
+				recordTime := parseHistoryTimestamp(record.Timestamp)
+				if !tf.Contains(recordTime) || isExcludedProject(record.Project) {
+					continue
+				}
 
-			batch = append(batch, record)
-			if len(batch) >= batchSize {
-				batches <- batch
-				batch = make([]HistoryRecord, 0, batchSize)
+				batch = append(batch, record)
+				if len(batch) >= batchSize {
+					batches <- batch
+					batch = make([]HistoryRecord, 0, batchSize)
+				}
 			}
 		}
+		if len(batch) > 0 {
+			batches <- batch
+		}
 	}()
 
 	// consumers: 并发处理批次
 	workers := getWorkerCount()
 	cmdMu := sync.Mutex{}
-	cmdCounts := make(map[string]int)
+	cmdCounts := make(map[commandKey]int)
 	hourlyCounts := make(map[string]int)
+	var pasteStats PasteStats
 
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			localCmds := make(map[string]int)
+			localCmds := make(map[commandKey]int)
 			localHourly := make(map[string]int)
+			var localPaste PasteStats
 
 			for batch := range batches {
 				for _, record := range batch {
+					localPaste.add(record.PastedContents)
+
 					// 统计 slash commands
-					if strings.HasPrefix(record.Display, "/") {
-						parts := strings.Fields(record.Display)
-						if len(parts) > 0 {
-							localCmds[parts[0]]++
-						}
+					if key, ok := slashCommandKey(record.Display, withArgs); ok && !isExcludedCommand(key.Command) {
+						localCmds[key]++
 					}
 
 					// 统计小时分布
-					recordTime := time.Unix(record.Timestamp/1000, 0)
+					recordTime := parseHistoryTimestamp(record.Timestamp)
 					hour := fmt.Sprintf("%02d", recordTime.Hour())
 					localHourly[hour]++
 				}
@@ -112,22 +142,14 @@ func ParseHistoryConcurrent(tf TimeFilter) ([]CommandStats, map[string]int, erro
 			for hour, count := range localHourly {
 				hourlyCounts[hour] += count
 			}
+			pasteStats.merge(localPaste)
 			cmdMu.Unlock()
 		}()
 	}
 
 	wg.Wait()
 
-	// 转换为切片并排序
-	var cmdStats []CommandStats
-	for cmd, count := range cmdCounts {
-		cmdStats = append(cmdStats, CommandStats{Command: cmd, Count: count})
-	}
-	sort.Slice(cmdStats, func(i, j int) bool {
-		return cmdStats[i].Count > cmdStats[j].Count
-	})
-
-	return cmdStats, hourlyCounts, nil
+	return commandStatsFromCounts(cmdCounts), hourlyCounts, pasteStats, nil
 }
 
 // ParseDebugLogsConcurrent 并发解析 debug 日志（优化版）
@@ -137,13 +159,26 @@ func ParseDebugLogsConcurrent(tf TimeFilter) ([]RuntimeToolSignal, error) {
 
 // ParseDebugLogsConcurrentFromDir 并发解析指定数据目录下的 debug 日志
 func ParseDebugLogsConcurrentFromDir(tf TimeFilter, dataDir string) ([]RuntimeToolSignal, error) {
+	toolStats, _, err := parseDebugLogsFromDir(tf, dataDir)
+	return toolStats, err
+}
+
+// parseDebugLogsFromDir 并发解析 debug 日志，同时返回 MCP 工具信号与 debugPatterns 各模式的命中行数
+// （所有已配置的模式都会出现在结果中，未命中为 0）
+func parseDebugLogsFromDir(tf TimeFilter, dataDir string) ([]RuntimeToolSignal, map[string]int, error) {
+	rules := debugPatterns
+	patternCounts := make(map[string]int, len(rules))
+	for _, rule := range rules {
+		patternCounts[rule.Name] = 0
+	}
+
 	debugDir := filepath.Join(dataDir, "debug")
-	entries, err := os.ReadDir(debugDir)
+	entries, err := dataSource.ReadDir(debugDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []RuntimeToolSignal{}, nil
+			return []RuntimeToolSignal{}, patternCounts, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 获取文件信息并过滤
@@ -175,8 +210,11 @@ func ParseDebugLogsConcurrentFromDir(tf TimeFilter, dataDir string) ([]RuntimeTo
 	sem := make(chan struct{}, maxWorkers)
 	var wg sync.WaitGroup
 
-	results := make(chan map[string]int, len(fileInfos))
-	mcpPattern := regexp.MustCompile(`mcp__(\w+)__(\w+)`)
+	type debugFileCounts struct {
+		tools    map[string]int
+		patterns map[string]int
+	}
+	results := make(chan debugFileCounts, len(fileInfos))
 
 	for _, fileInfo := range fileInfos {
 		wg.Add(1)
@@ -185,9 +223,9 @@ func ParseDebugLogsConcurrentFromDir(tf TimeFilter, dataDir string) ([]RuntimeTo
 			defer func() { <-sem }()
 
 			sem <- struct{}{}
-			toolCounts := make(map[string]int)
-			parseDebugFileOptimized(fp, toolCounts, mcpPattern)
-			results <- toolCounts
+			counts := debugFileCounts{tools: make(map[string]int), patterns: make(map[string]int)}
+			parseDebugFileOptimized(fp, counts.tools, mcpPattern, counts.patterns, rules)
+			results <- counts
 		}(fileInfo.Path)
 	}
 
@@ -199,9 +237,12 @@ func ParseDebugLogsConcurrentFromDir(tf TimeFilter, dataDir string) ([]RuntimeTo
 	// 汇总结果
 	aggregateCounts := make(map[string]int)
 	for counts := range results {
-		for tool, count := range counts {
+		for tool, count := range counts.tools {
 			aggregateCounts[tool] += count
 		}
+		for name, count := range counts.patterns {
+			patternCounts[name] += count
+		}
 	}
 
 	// 转换为切片
@@ -216,16 +257,14 @@ func ParseDebugLogsConcurrentFromDir(tf TimeFilter, dataDir string) ([]RuntimeTo
 			})
 		}
 	}
-	sort.Slice(toolStats, func(i, j int) bool {
-		return toolStats[i].Count > toolStats[j].Count
-	})
+	sortRuntimeToolSignals(toolStats)
 
-	return toolStats, nil
+	return toolStats, patternCounts, nil
 }
 
 // extractTimestampFromFile 从debug文件中提取时间戳
 func extractTimestampFromFile(filePath string) (time.Time, error) {
-	f, err := os.Open(filePath)
+	f, err := dataSource.Open(filePath)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -258,9 +297,10 @@ func extractTimestampFromFile(filePath string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("无法提取时间戳")
 }
 
-// parseDebugFileOptimized 优化的 debug 文件解析
-func parseDebugFileOptimized(path string, counts map[string]int, pattern *regexp.Regexp) {
-	f, err := os.Open(path)
+// parseDebugFileOptimized 优化的 debug 文件解析：MCP 工具信号计入 counts，
+// 同时逐行匹配 rules，命中行数按模式名计入 patternCounts
+func parseDebugFileOptimized(path string, counts map[string]int, pattern *regexp.Regexp, patternCounts map[string]int, rules []debugPatternRule) {
+	f, err := dataSource.Open(path)
 	if err != nil {
 		return
 	}
@@ -280,5 +320,6 @@ func parseDebugFileOptimized(path string, counts map[string]int, pattern *regexp
 				counts[key]++
 			}
 		}
+		countDebugPatterns(line, rules, patternCounts)
 	}
 }
@@ -2,18 +2,84 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Config 应用配置
 type Config struct {
-	DataDir     string
-	CacheDir    string
+	DataDir  string
+	CacheDir string
+	// CacheFile 缓存文件路径（为空时为 CacheDir/cache.db）
+	CacheFile   string
 	ListenAddr  string
 	BaseURL     string
 	RulesPath   string
 	PricingPath string
+	// CommandCategoriesPath 命令分类 YAML 路径（为空时使用内置分类）
+	CommandCategoriesPath string
+	// CommandCategories 分类名 → slash command 列表，未映射的命令归入 other
+	CommandCategories map[string][]string
+	// CacheMaxAge 缓存最长可服务时长，超过后不论 mtime 都强制检查并增量刷新（0 表示不限制）
+	CacheMaxAge time.Duration
+	// ArchivePath tar.gz 数据归档路径（非空时代替 DataDir 作为数据源）
+	ArchivePath string
+	// StatusPath 心跳状态文件路径（为空时不写）
+	StatusPath string
+	// StatusInterval 心跳状态文件刷新间隔
+	StatusInterval time.Duration
+	// Token 非空时 /api/* 与 /ws 需要携带该共享密钥（见 auth.go）
+	Token string
+	// Watch 后台轮询数据目录，数据变更时自动刷新缓存
+	Watch bool
+	// WatchInterval 自动刷新的轮询间隔
+	WatchInterval time.Duration
+	// Quiet 关闭 HTTP 访问日志
+	Quiet bool
+	// AccessLogPath 访问日志追加写入的文件（为空时随应用日志输出到 stderr + 日志文件）
+	AccessLogPath string
+	// DebugEndpoints 启用 /api/debug/* 排障接口（默认关闭）
+	DebugEndpoints bool
+	// Demo 使用内存中生成的演示数据（见 demo_data.go），不读取 DataDir、不写缓存
+	Demo bool
+	// UnknownModelBucket 把缺少 model 字段的 assistant 消息计入 "(unknown model)"，使模型用量与消息总数对齐
+	UnknownModelBucket bool
+	// CORSOrigins 允许跨域访问的来源列表（逗号分隔，为空时不发送 CORS 头）
+	CORSOrigins string
+	// Workers 并发解析的 worker 数量（0 表示自动，取 CPU 核心数的一半）
+	Workers int
+	// ReadRetries 打开 history/projects 文件遇到权限或共享冲突时的重试次数（0 表示不重试）
+	ReadRetries int
+	// MaxRangeDays 自定义时间范围（start/end）最多允许的天数，超出直接拒绝（0 表示不限制；预设范围不受限）
+	MaxRangeDays int
+	// MCPPattern debug 日志中 MCP 工具信号的正则（为空时为 defaultMCPPattern），需要 server、tool 两个捕获组
+	MCPPattern string
+	// DebugPatterns debug 日志行模式：名称 → 正则，/api/debug-patterns 按名称统计命中行数
+	DebugPatterns map[string]string
+	// ExcludeCommands 不计入命令统计的 slash command（完全匹配，如 /clear）
+	ExcludeCommands []string
+	// ExcludeProjects 不计入统计的项目路径前缀（匹配 history 的 project 与会话记录的 cwd）
+	ExcludeProjects []string
+	// CollapseHome 项目路径中的家目录前缀显示为 ~
+	CollapseHome bool
+	// SessionGapMinutes 同一 sessionId 内相邻记录间隔超过该分钟数时计为新的逻辑会话（0 表示不切分）
+	SessionGapMinutes int
+	// MinSessionMessages assistant 消息少于该条数的会话不计入会话数（默认 1，即不过滤）
+	MinSessionMessages int
+	// ResultTTL /api/data 等接口按相同时间范围复用已构建结果的时长（0 表示不缓存），缓存刷新时清空
+	ResultTTL time.Duration
+	// LogFormat stderr 与日志文件的格式：text（默认，人读）或 json（每行一条 JSON 记录）
+	LogFormat string
+	// EChartsCDN 图表页面引用 go-echarts CDN 上的 ECharts 脚本，而不是二进制内置的本地副本
+	EChartsCDN bool
+	// ChartTheme 图表主题（ECharts 主题名，如 wonderland、dark、macarons）
+	ChartTheme string
+	// ChartWidth/ChartHeight 图表尺寸（CSS 长度，如 1600px、100%），为空时沿用各图表自己的默认尺寸
+	ChartWidth  string
+	ChartHeight string
 }
 
 var cfg Config
@@ -29,12 +95,40 @@ func defaultConfig() Config {
 	defaultCacheDir := filepath.Join(insightsHome, "cache")
 
 	return Config{
-		DataDir:     defaultDataDir,
-		CacheDir:    defaultCacheDir,
-		ListenAddr:  ":8932",
-		BaseURL:     "",
-		RulesPath:   "",
-		PricingPath: "",
+		DataDir:            defaultDataDir,
+		CacheDir:           defaultCacheDir,
+		CacheFile:          "",
+		ListenAddr:         ":8932",
+		BaseURL:            "",
+		RulesPath:          "",
+		PricingPath:        "",
+		CommandCategories:  defaultCommandCategories(),
+		CacheMaxAge:        0,
+		ArchivePath:        "",
+		StatusPath:         "",
+		StatusInterval:     30 * time.Second,
+		WatchInterval:      defaultWatchInterval,
+		Quiet:              false,
+		AccessLogPath:      "",
+		DebugEndpoints:     false,
+		CORSOrigins:        "",
+		UnknownModelBucket: false,
+		Workers:            0,
+		ReadRetries:        defaultReadRetries,
+		MaxRangeDays:       0,
+		MCPPattern:         "",
+		DebugPatterns:      defaultDebugPatterns(),
+		ExcludeCommands:    nil,
+		ExcludeProjects:    nil,
+		CollapseHome:       false,
+		SessionGapMinutes:  defaultSessionGapMinutes,
+		MinSessionMessages: defaultMinSessionMessages,
+		ResultTTL:          30 * time.Second,
+		LogFormat:          LogFormatText,
+		EChartsCDN:         false,
+		ChartTheme:         defaultChartTheme,
+		ChartWidth:         "",
+		ChartHeight:        "",
 	}
 }
 
@@ -46,14 +140,66 @@ func init() {
 func registerConfigFlags(fs *flag.FlagSet, target *Config) {
 	fs.StringVar(&target.DataDir, "data", target.DataDir, "数据目录路径 (默认: ~/.claude)")
 	fs.StringVar(&target.CacheDir, "cache", target.CacheDir, "缓存目录路径 (默认: ~/.cc-insights/cache/)")
+	fs.StringVar(&target.CacheFile, "cache-file", target.CacheFile, "缓存文件路径（默认: <缓存目录>/cache.db）")
+	fs.DurationVar(&target.CacheMaxAge, "cache-max-age", target.CacheMaxAge, "缓存最长可服务时长（如 10m），超过后强制检查数据并增量刷新；0 表示仅按 mtime 判断")
 	fs.StringVar(&target.RulesPath, "rules", target.RulesPath, "Bash 命令分类规则 YAML 路径")
+	fs.BoolVar(&target.UnknownModelBucket, "unknown-model", target.UnknownModelBucket, "把缺少 model 字段的 assistant 消息计入 \"(unknown model)\"，使模型用量合计与消息总数一致")
 	fs.StringVar(&target.PricingPath, "pricing", target.PricingPath, "模型定价规则 YAML 路径（默认内置 rules/pricing.yml，可用 ~/.cc-insights/pricing.yml 覆盖）")
+	fs.StringVar(&target.CommandCategoriesPath, "command-categories", target.CommandCategoriesPath, "slash command 分类 YAML 路径（分类名: [命令列表]，如 config: [/model, /config]），替换内置分类")
+	fs.StringVar(&target.MCPPattern, "mcp-pattern", target.MCPPattern, "debug 日志中 MCP 工具信号的正则，需含 server、tool 两个捕获组（默认 mcp__(\\w+)__(\\w+)）")
+	fs.Var(debugPatternValue{&target.DebugPatterns}, "debug-pattern", "统计 debug 日志中命中该正则的行数，格式 name=regex，可重复指定，同名覆盖内置的 error、warn")
+	fs.Var(stringListValue{&target.ExcludeCommands}, "exclude-commands", "不计入命令统计的 slash command，逗号分隔、完全匹配（如 /clear,/exit）")
+	fs.BoolVar(&target.CollapseHome, "collapse-home", target.CollapseHome, "项目路径中的家目录前缀显示为 ~（如 /Users/me/work/foo → ~/work/foo）")
+	fs.Var(stringListValue{&target.ExcludeProjects}, "exclude-projects", "不计入统计的项目目录，逗号分隔（如 /tmp,/home/me/scratch；该目录及其子目录均排除）")
+	fs.StringVar(&target.LogFormat, "log-format", target.LogFormat, "日志格式: text|json（json 时每行一条含 level、msg、context 的记录）")
+	fs.BoolVar(&target.EChartsCDN, "echarts-cdn", target.EChartsCDN, "图表页面从 CDN 加载 ECharts 脚本（默认使用内置的本地副本，离线可用）")
+	fs.StringVar(&target.ChartTheme, "chart-theme", target.ChartTheme, "图表主题（如 wonderland、dark、macarons，默认 wonderland）")
+	fs.StringVar(&target.ChartWidth, "chart-width", target.ChartWidth, "图表宽度，如 1600px 或 100%（自适应页面宽度）；默认沿用各图表的宽度")
+	fs.StringVar(&target.ChartHeight, "chart-height", target.ChartHeight, "图表高度，如 600px；默认沿用各图表的高度")
+	fs.IntVar(&target.MinSessionMessages, "min-session-messages", target.MinSessionMessages, "assistant 消息少于该条数的会话不计入会话数（默认 1；如 2 可排除只发了一条消息的误开会话）")
+	fs.IntVar(&target.SessionGapMinutes, "session-gap", target.SessionGapMinutes, "同一会话内相邻记录间隔超过该分钟数时计为新的逻辑会话（默认 30，0 表示不切分）")
+	fs.IntVar(&target.Workers, "workers", target.Workers, "并发解析的 worker 数量（0 表示自动：CPU 核心数的一半）")
+	fs.IntVar(&target.ReadRetries, "read-retries", target.ReadRetries, "打开数据文件遇到权限/共享冲突（如 Windows 上正在写入的会话文件）时的重试次数（默认 2，0 表示不重试）")
+	fs.StringVar(&target.ArchivePath, "archive", target.ArchivePath, "直接读取 tar.gz 数据归档（不解压到磁盘，优先于 --data）")
 }
 
 // registerServerFlags 注册仅 web 命令使用的服务 flag（监听地址/反向代理）。
 func registerServerFlags(fs *flag.FlagSet, target *Config) {
 	fs.StringVar(&target.ListenAddr, "addr", target.ListenAddr, "监听地址 (默认: :8932)")
 	fs.StringVar(&target.BaseURL, "base", target.BaseURL, "基础 URL（用于反向代理）")
+	fs.StringVar(&target.StatusPath, "status-file", target.StatusPath, "定期写入运行状态 JSON 的文件路径（供外部监控读取）")
+	fs.DurationVar(&target.StatusInterval, "status-interval", target.StatusInterval, "状态文件刷新间隔 (默认: 30s)")
+	fs.BoolVar(&target.Watch, "watch", target.Watch, "后台轮询数据目录，文件变更时自动增量刷新缓存（无需手动调用 /api/reload）")
+	fs.DurationVar(&target.WatchInterval, "watch-interval", target.WatchInterval, "--watch 的轮询间隔 (默认: 30s)")
+	fs.DurationVar(&target.ResultTTL, "result-ttl", target.ResultTTL, "相同时间范围的请求复用已构建结果的时长（默认 30s，0 表示每次重新构建）；刷新缓存时清空")
+	fs.StringVar(&target.Token, "token", target.Token, "访问令牌：设置后 /api/* 与 /ws 需携带 Authorization: Bearer <token> 或 ?token=<token>，否则返回 401（Dashboard 页面可用 /dashboard?token=<token> 打开）")
+	fs.BoolVar(&target.Quiet, "quiet", target.Quiet, "不输出 HTTP 访问日志")
+	fs.StringVar(&target.AccessLogPath, "access-log", target.AccessLogPath, "HTTP 访问日志追加写入该文件（不再输出到 stderr 与日志文件；与 --quiet 同时使用时不记录）")
+	fs.BoolVar(&target.Demo, "demo", target.Demo, "使用内存中生成的演示数据启动（不读取数据目录、不写缓存，优先于 --archive 与 --data）")
+	fs.BoolVar(&target.DebugEndpoints, "debug", target.DebugEndpoints, "启用 /api/debug/files 等排障接口，列出解析器实际读取的数据文件")
+	fs.IntVar(&target.MaxRangeDays, "max-range-days", target.MaxRangeDays, "自定义时间范围最多允许的天数，超出返回 400（0 表示不限制，预设范围不受限）")
+	fs.StringVar(&target.CORSOrigins, "cors", target.CORSOrigins, "允许跨域访问的来源，逗号分隔（如 https://a.example,https://b.example；* 表示任意来源）")
+}
+
+// cacheFilePath 当前生效的缓存文件路径
+func cacheFilePath() string {
+	if cfg.CacheFile != "" {
+		return cfg.CacheFile
+	}
+	return filepath.Join(cfg.CacheDir, "cache.db")
+}
+
+// ensureCacheDir 创建缓存目录（--cache）与缓存文件所在目录（--cache-file 指向别处时两者不同）
+func ensureCacheDir() error {
+	for _, dir := range []string{cfg.CacheDir, filepath.Dir(cacheFilePath())} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建缓存目录失败: %w", err)
+		}
+	}
+	return nil
 }
 
 // GetDataPath 获取数据文件路径
@@ -61,3 +207,71 @@ func GetDataPath(relPath ...string) string {
 	paths := append([]string{cfg.DataDir}, relPath...)
 	return filepath.Join(paths...)
 }
+
+// DataDirSummary 数据目录中可发现的数据文件统计
+type DataDirSummary struct {
+	HistoryFiles int // history*.jsonl 分片数
+	ProjectFiles int // projects/ 下的 *.jsonl
+	DebugFiles   int // debug/ 下的 *.txt
+}
+
+// Total 返回可发现的数据文件总数
+func (s DataDirSummary) Total() int {
+	return s.HistoryFiles + s.ProjectFiles + s.DebugFiles
+}
+
+// Empty 目录存在但没有任何可分析的数据文件
+func (s DataDirSummary) Empty() bool {
+	return s.Total() == 0
+}
+
+// ValidateDataDir 统计数据目录中的 history/projects/debug 文件数。
+// 目录不存在或不是目录时返回错误；目录存在但为空时返回零值统计，由调用方决定如何提示。
+func ValidateDataDir(dir string) (DataDirSummary, error) {
+	var summary DataDirSummary
+	info, err := statData(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return summary, fmt.Errorf("数据目录不存在: %s", dir)
+		}
+		return summary, fmt.Errorf("读取数据目录失败: %w", err)
+	}
+	if !info.IsDir() {
+		return summary, fmt.Errorf("数据路径不是目录: %s", dir)
+	}
+
+	for _, path := range historyFilePathsIn(dir) {
+		if stat, err := statData(path); err == nil && !stat.IsDir() {
+			summary.HistoryFiles++
+		}
+	}
+	projectDirs, _ := listProjectDirs(filepath.Join(dir, "projects"))
+	for _, projectDir := range projectDirs {
+		summary.ProjectFiles += countFilesWithSuffix(projectDir, ".jsonl")
+	}
+	summary.DebugFiles = countFilesWithSuffix(filepath.Join(dir, "debug"), ".txt")
+	return summary, nil
+}
+
+func countFilesWithSuffix(root, suffix string) int {
+	count := 0
+	_ = walkData(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// warnIfDataDirEmpty 目录存在但没有数据时给出明确提示，避免空图表被误认为 bug
+func warnIfDataDirEmpty(dir string, summary DataDirSummary) {
+	if !summary.Empty() {
+		return
+	}
+	Warn("数据目录中没有找到任何 Claude Code 数据文件", "path", dir)
+	Info("提示: 确认 -data 指向 Claude Code 数据根目录（应包含 history.jsonl、projects/ 或 debug/）")
+}
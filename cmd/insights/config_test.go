@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateDataDir(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		if _, err := ValidateDataDir(filepath.Join(t.TempDir(), "nope")); err == nil {
+			t.Fatal("expected error for missing data dir")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		summary, err := ValidateDataDir(t.TempDir())
+		if err != nil {
+			t.Fatalf("ValidateDataDir() error = %v", err)
+		}
+		if !summary.Empty() {
+			t.Fatalf("summary=%+v, want empty", summary)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		dir := t.TempDir()
+		// 只有空的 projects/ 目录和一个非 jsonl 文件，仍视为没有数据
+		if err := os.MkdirAll(filepath.Join(dir, "projects", "demo"), 0755); err != nil {
+			t.Fatalf("创建 projects 目录失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "projects", "demo", "notes.md"), []byte("x"), 0644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		summary, err := ValidateDataDir(dir)
+		if err != nil {
+			t.Fatalf("ValidateDataDir() error = %v", err)
+		}
+		if !summary.Empty() {
+			t.Fatalf("summary=%+v, want empty", summary)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, "history.jsonl"), []byte("{}\n"), 0644); err != nil {
+			t.Fatalf("写入 history.jsonl 失败: %v", err)
+		}
+		summary, err = ValidateDataDir(dir)
+		if err != nil {
+			t.Fatalf("ValidateDataDir() error = %v", err)
+		}
+		if summary.Empty() || summary.HistoryFiles != 1 || summary.ProjectFiles != 0 || summary.DebugFiles != 0 {
+			t.Fatalf("summary=%+v, want only history", summary)
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		dataDir := createTestDataDir(t, t.TempDir())
+		summary, err := ValidateDataDir(dataDir)
+		if err != nil {
+			t.Fatalf("ValidateDataDir() error = %v", err)
+		}
+		if summary.HistoryFiles != 1 || summary.ProjectFiles != 1 || summary.DebugFiles != 1 || summary.Total() != 3 {
+			t.Fatalf("summary=%+v, want 1/1/1", summary)
+		}
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		if _, err := ValidateDataDir(file); err == nil {
+			t.Fatal("expected error when data path is a file")
+		}
+	})
+}
+
+func TestExcludeCommandsAndProjects(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	target := defaultConfig()
+	registerConfigFlags(fs, &target)
+	if err := fs.Parse([]string{"--exclude-commands", "/clear, /exit", "--exclude-commands", "/quit", "--exclude-projects", "/tmp/scratch"}); err != nil {
+		t.Fatalf("解析 flag 失败: %v", err)
+	}
+	if !reflect.DeepEqual(target.ExcludeCommands, []string{"/clear", "/exit", "/quit"}) || !reflect.DeepEqual(target.ExcludeProjects, []string{"/tmp/scratch"}) {
+		t.Fatalf("exclude flags = %q / %q", target.ExcludeCommands, target.ExcludeProjects)
+	}
+
+	dataDir := t.TempDir()
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	history := ""
+	for i, entry := range [][2]string{{"/clear", "/tmp/keep"}, {"/clear", "/tmp/keep"}, {"/help", "/tmp/keep"}, {"/help", "/tmp/scratch/a"}} {
+		history += `{"display":"` + entry[0] + `","timestamp":` + formatUnixMilli(base.Add(time.Duration(i)*time.Minute)) + `,"project":"` + entry[1] + `"}` + "\n"
+	}
+	files := map[string]string{
+		"history.jsonl": history,
+		"projects/keep/a.jsonl": projectRecordJSON("/tmp/keep", "s1", base) + "\n" +
+			projectRecordJSON("/tmp/keep", "s1", base.Add(time.Minute)) + "\n",
+		"projects/scratch/b.jsonl": projectRecordJSON("/tmp/scratch/a", "s2", base) + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origCfg := cfg
+	cfg.DataDir = dataDir
+	cfg.ExcludeCommands = target.ExcludeCommands
+	cfg.ExcludeProjects = target.ExcludeProjects
+	defer func() { cfg = origCfg }()
+
+	commands, _, err := ParseHistoryConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryConcurrent failed: %v", err)
+	}
+	if want := []CommandStats{{Command: "/help", Count: 1}}; !reflect.DeepEqual(commands, want) {
+		t.Fatalf("commands = %+v, want %+v", commands, want)
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	if len(agg.Projects) != 1 || agg.Projects[0].Project != "/tmp/keep" || agg.Projects[0].MessageCount != 2 {
+		t.Fatalf("projects = %+v, want only /tmp/keep with 2 messages", agg.Projects)
+	}
+
+	// 缓存记录排除规则：规则变化后需要重建，否则统计会沿用旧口径
+	builder := &CacheBuilder{CachePath: filepath.Join(t.TempDir(), "cache.db"), DataDir: dataDir}
+	if err := builder.BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(builder.CachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	if cache.TotalMessages != 2 || cache.TotalSessions != 1 {
+		t.Fatalf("cache messages=%d sessions=%d, want 2/1", cache.TotalMessages, cache.TotalSessions)
+	}
+	if builder.NeedsRebuild() {
+		t.Fatal("规则未变时不应重建")
+	}
+	cfg.ExcludeProjects = nil
+	if !builder.NeedsRebuild() {
+		t.Fatal("项目排除规则变化后应重建缓存")
+	}
+}
+
+func TestExcludedEntriesAbsentFromSamplesBranchesAndSessions(t *testing.T) {
+	dataDir := t.TempDir()
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	history := ""
+	for i, entry := range [][2]string{{"/help", "/tmp/keep"}, {"/clear", "/tmp/keep"}, {"/help", "/tmp/scratch"}} {
+		history += `{"display":"` + entry[0] + `","timestamp":` + formatUnixMilli(base.Add(time.Duration(i)*time.Minute)) + `,"project":"` + entry[1] + `"}` + "\n"
+	}
+	files := map[string]string{
+		"history.jsonl":            history,
+		"projects/keep/a.jsonl":    branchRecordJSON("/tmp/keep", "s1", "main", base) + "\n",
+		"projects/scratch/b.jsonl": branchRecordJSON("/tmp/scratch/x", "s2", "wip", base) + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origCfg := cfg
+	defer func() { cfg = origCfg }()
+	cfg.DataDir = dataDir
+	cfg.ExcludeProjects = []string{"/tmp/scratch"}
+	cfg.ExcludeCommands = []string{"/clear"}
+
+	// 样例只列出计入统计的记录
+	for metric, wantKeys := range map[string][]string{SampleMetricModel: {"claude-sonnet-4.5"}, SampleMetricCommand: {"/help"}} {
+		samples, err := collectRecordSamples(AnalysisFilter{RecordSamples: 5, SampleMetric: metric})
+		if err != nil {
+			t.Fatalf("collectRecordSamples(%s) error = %v", metric, err)
+		}
+		var keys []string
+		for _, group := range samples {
+			keys = append(keys, group.Key)
+			for _, record := range group.Records {
+				if record.Project != "/tmp/keep" {
+					t.Fatalf("%s 样例包含被排除的项目: %+v", metric, record)
+				}
+			}
+		}
+		if !reflect.DeepEqual(keys, wantKeys) {
+			t.Fatalf("%s 样例分类 = %v, want %v", metric, keys, wantKeys)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleBranchesAPI(w, httptest.NewRequest("GET", "/api/branches?preset=all", nil))
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    branchListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if want := []BranchStat{{Branch: "main", Messages: 1, Sessions: 1}}; !resp.Success || !reflect.DeepEqual(resp.Data.Branches, want) {
+		t.Fatalf("/api/branches 含被排除的项目: %s", w.Body.String())
+	}
+
+	sessions, err := ParseSessionList(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseSessionList() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != "s1" {
+		t.Fatalf("sessions = %+v, want 只有 s1", sessions)
+	}
+
+	lengths, err := ParseMessageLengthStats(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseMessageLengthStats() error = %v", err)
+	}
+	if lengths.UserPrompts.Count != 2 {
+		t.Fatalf("user_prompts.count = %d, want 2（排除 /tmp/scratch 的提问）", lengths.UserPrompts.Count)
+	}
+}
+
+func TestExcludedProjectMatchesPathBoundaries(t *testing.T) {
+	origCfg := cfg
+	defer func() { cfg = origCfg }()
+	sep := string(filepath.Separator)
+	scratch := sep + filepath.Join("tmp", "scratch")
+	cfg.ExcludeProjects = []string{scratch, sep + filepath.Join("home", "me", "old") + sep}
+
+	for project, want := range map[string]bool{
+		scratch:                                       true,
+		filepath.Join(scratch, "a"):                   true,
+		scratch + "pad":                               false,
+		sep + filepath.Join("tmp", "scratch-2"):       false,
+		sep + filepath.Join("home", "me", "old"):      true,
+		sep + filepath.Join("home", "me", "old", "x"): true,
+		sep + filepath.Join("home", "me", "older"):    false,
+		"": false,
+	} {
+		if got := isExcludedProject(project); got != want {
+			t.Errorf("isExcludedProject(%q) = %v, want %v", project, got, want)
+		}
+	}
+}
+
+func TestCacheFlagCreatesCacheDir(t *testing.T) {
+	useFixtureDataDir(t)
+	cacheDir := filepath.Join(t.TempDir(), "nested", "cache")
+
+	opts, err := parseCLIOptions(cmdSum, []string{"--cache", cacheDir})
+	if err != nil {
+		t.Fatalf("parseCLIOptions failed: %v", err)
+	}
+	if opts.Config.CacheDir != cacheDir {
+		t.Fatalf("CacheDir = %q, want %q", opts.Config.CacheDir, cacheDir)
+	}
+
+	origCacheDir, origCacheFile := cfg.CacheDir, cfg.CacheFile
+	cfg.CacheDir, cfg.CacheFile = opts.Config.CacheDir, ""
+	t.Cleanup(func() { cfg.CacheDir, cfg.CacheFile = origCacheDir, origCacheFile })
+
+	if err := refreshGlobalCache(false); err != nil {
+		t.Fatalf("refreshGlobalCache failed: %v", err)
+	}
+	if info, err := os.Stat(cacheDir); err != nil || !info.IsDir() {
+		t.Fatalf("缓存目录未创建: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "cache.db")); err != nil {
+		t.Fatalf("缓存文件应写入 --cache 目录: %v", err)
+	}
+}
@@ -46,12 +46,12 @@ func TestDashboardDataConsistencyFromScopedCache(t *testing.T) {
 		t.Fatalf("LoadCacheFile failed: %v", err)
 	}
 
-	originalCache := globalCache
+	originalCache := loadGlobalCache()
 	originalDataDir := cfg.DataDir
-	globalCache = cache
+	storeGlobalCache(cache)
 	cfg.DataDir = dataDir
 	defer func() {
-		globalCache = originalCache
+		storeGlobalCache(originalCache)
 		cfg.DataDir = originalDataDir
 	}()
 
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// parseCORSOrigins 解析 --cors 的逗号分隔来源列表（"*" 表示允许任意来源）
+func parseCORSOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// CORSMiddleware 为允许的来源添加 CORS 响应头，并直接应答 /api/ 下的 OPTIONS 预检请求。
+// origins 为空时原样透传，不添加任何 CORS 头。
+func CORSMiddleware(next http.Handler, origins []string) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			header := w.Header()
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Add("Vary", "Origin")
+			if r.Method == http.MethodOptions && strings.HasPrefix(r.URL.Path, "/api/") {
+				header.Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+				if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", reqHeaders)
+				}
+				header.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOriginOrAllowed 判断请求的 Origin 是否可信：没有 Origin（非浏览器客户端）、与服务自身 Host 相同，
+// 或在 --cors 列表中（"*" 表示任意来源）。WebSocket 不受浏览器同源策略保护，升级前必须自行校验。
+func sameOriginOrAllowed(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	origin = strings.TrimRight(origin, "/")
+	for _, allowed := range origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
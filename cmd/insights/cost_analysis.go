@@ -160,6 +160,15 @@ func mergeCostAgentStat(dst *CostAgentStat, src *CostAgentStat) {
 	dst.TotalRoundTripMs += src.TotalRoundTripMs
 }
 
+// applyCostModelPrice 按单价（每百万 token）计算各类 token 的费用及合计
+func applyCostModelPrice(stat *CostModelStat, price resolvedPrice) {
+	stat.InputCostCNY = float64(stat.InputTokens) * price.Input / 1e6
+	stat.OutputCostCNY = float64(stat.OutputTokens) * price.Output / 1e6
+	stat.CacheReadCostCNY = float64(stat.CacheReadTokens) * price.CacheRead / 1e6
+	stat.CacheCreationCostCNY = float64(stat.CacheCreationTokens) * price.CacheCreation / 1e6
+	stat.CostCNY = stat.InputCostCNY + stat.OutputCostCNY + stat.CacheReadCostCNY + stat.CacheCreationCostCNY
+}
+
 func (agg *ProjectAggregate) finalizeCostAnalysis() {
 	analysis := &CostAnalysisData{
 		ByModel:        make([]CostModelStat, 0, len(agg.CostModelStats)),
@@ -186,11 +195,7 @@ func (agg *ProjectAggregate) finalizeCostAnalysis() {
 			statCopy.CacheReadRatio = float64(statCopy.CacheReadTokens) / float64(inputTotal) * 100
 		}
 		if price, ok := rules.matchPrice(statCopy.Model); ok {
-			statCopy.InputCostCNY = float64(statCopy.InputTokens) * price.Input / 1e6
-			statCopy.OutputCostCNY = float64(statCopy.OutputTokens) * price.Output / 1e6
-			statCopy.CacheReadCostCNY = float64(statCopy.CacheReadTokens) * price.CacheRead / 1e6
-			statCopy.CacheCreationCostCNY = float64(statCopy.CacheCreationTokens) * price.CacheCreation / 1e6
-			statCopy.CostCNY = statCopy.InputCostCNY + statCopy.OutputCostCNY + statCopy.CacheReadCostCNY + statCopy.CacheCreationCostCNY
+			applyCostModelPrice(&statCopy, price)
 			analysis.Totals.InputCostCNY += statCopy.InputCostCNY
 			analysis.Totals.OutputCostCNY += statCopy.OutputCostCNY
 			analysis.Totals.CacheReadCostCNY += statCopy.CacheReadCostCNY
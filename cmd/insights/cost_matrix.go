@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CostMatrixRow 成本矩阵的一行：某项目某天的 token 数与费用
+type CostMatrixRow struct {
+	Date    string
+	Project string
+	Tokens  int     // input + output，与 project_stats 的 tokens 同口径
+	Cost    float64 // 按定价规则计算，货币见 pricingCurrency
+}
+
+// buildCostMatrix 由每日项目 token（DailyProjectTokens）与每日项目运行时聚合中的
+// 分模型用量（DailyProjectRuntime → CostModelStats）生成按日期、项目排序的成本矩阵。
+// 定价规则加载失败时费用为 0
+func buildCostMatrix(agg *ProjectAggregate) []CostMatrixRow {
+	if agg == nil {
+		return nil
+	}
+	rules, _ := currentPricingRules()
+	var rows []CostMatrixRow
+	for date, projects := range agg.DailyProjectTokens {
+		for project, tokens := range projects {
+			row := CostMatrixRow{Date: date, Project: project, Tokens: tokens}
+			if runtime := agg.DailyProjectRuntime[date][project]; runtime != nil {
+				for _, stat := range runtime.CostModelStats {
+					if price, ok := rules.matchPrice(stat.Model); ok {
+						priced := *stat
+						applyCostModelPrice(&priced, price)
+						row.Cost += priced.CostCNY
+					}
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Date != rows[j].Date {
+			return rows[i].Date < rows[j].Date
+		}
+		return rows[i].Project < rows[j].Project
+	})
+	return rows
+}
+
+// costMatrixCostColumn 费用列名随定价规则的货币变化，如 cost_cny、cost_usd
+func costMatrixCostColumn() string {
+	return "cost_" + strings.ToLower(pricingCurrency())
+}
+
+// handleExportCostMatrixCSV /api/export/cost-matrix.csv：按 日期×项目 输出 token 数与费用，
+// 列为 date,project,tokens,cost_<货币>，用于分摊核算。查询参数同 /api/data（preset、start/end、project 等）。
+// 需要逐条记录的分模型用量，因此始终实时解析
+func handleExportCostMatrixCSV(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	agg, err := ParseProjectsConcurrentOnce(filter.TimeFilter)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="cost-matrix.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "project", "tokens", costMatrixCostColumn()})
+	for _, row := range buildCostMatrix(agg) {
+		if filter.Project != "" && !matchContains(filter.Project, row.Project) {
+			continue
+		}
+		out.Write([]string{row.Date, row.Project, strconv.Itoa(row.Tokens), strconv.FormatFloat(row.Cost, 'f', 6, 64)})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		Warn("成本矩阵导出中断", "error", err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCostMatrixCSV(t *testing.T) {
+	dataDir := t.TempDir()
+	day1 := time.Date(2026, 1, 5, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	files := map[string]string{
+		// alpha 第一天 2 条、第二天 1 条；beta 两天各 1 条
+		"alpha": projectRecordJSON("/tmp/alpha", "a1", day1) + "\n" +
+			projectRecordJSON("/tmp/alpha", "a1", day1.Add(time.Minute)) + "\n" +
+			projectRecordJSON("/tmp/alpha", "a2", day2) + "\n",
+		"beta": projectRecordJSON("/tmp/beta", "b1", day1) + "\n" +
+			projectRecordJSON("/tmp/beta", "b2", day2) + "\n",
+	}
+	for name, content := range files {
+		dir := filepath.Join(dataDir, "projects", "-tmp-"+name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".jsonl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	rules, err := currentPricingRules()
+	if err != nil {
+		t.Fatalf("currentPricingRules() error = %v", err)
+	}
+	price, _ := rules.matchPrice("claude-sonnet-4.5")
+	// projectRecordJSON 每条 input 10、output 5
+	perMessage := (10*price.Input + 5*price.Output) / 1e6
+
+	w := httptest.NewRecorder()
+	handleExportCostMatrixCSV(w, httptest.NewRequest("GET", "/api/export/cost-matrix.csv?preset=all", nil))
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("status=%d content-type=%q body=%s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("无法解析 CSV: %v", err)
+	}
+	if got := strings.Join(records[0], ","); got != "date,project,tokens,"+costMatrixCostColumn() {
+		t.Fatalf("表头 = %q", got)
+	}
+	want := []struct {
+		date, project string
+		messages      int
+	}{
+		{"2026-01-05", "/tmp/alpha", 2},
+		{"2026-01-05", "/tmp/beta", 1},
+		{"2026-01-06", "/tmp/alpha", 1},
+		{"2026-01-06", "/tmp/beta", 1},
+	}
+	if len(records) != len(want)+1 {
+		t.Fatalf("行数 = %d, want %d: %v", len(records)-1, len(want), records)
+	}
+	for i, row := range records[1:] {
+		if row[0] != want[i].date || row[1] != want[i].project || row[2] != strconv.Itoa(15*want[i].messages) {
+			t.Fatalf("第 %d 行 = %v, want %+v", i+1, row, want[i])
+		}
+		cost, _ := strconv.ParseFloat(row[3], 64)
+		if wantCost := perMessage * float64(want[i].messages); cost <= 0 || cost-wantCost > 1e-6 || wantCost-cost > 1e-6 {
+			t.Fatalf("第 %d 行 cost = %v, want %v", i+1, cost, wantCost)
+		}
+	}
+
+	// project 参数只保留匹配的项目
+	w = httptest.NewRecorder()
+	handleExportCostMatrixCSV(w, httptest.NewRequest("GET", "/api/export/cost-matrix.csv?preset=all&project=beta", nil))
+	if records, _ := csv.NewReader(w.Body).ReadAll(); len(records) != 3 || records[1][1] != "/tmp/beta" || records[2][1] != "/tmp/beta" {
+		t.Fatalf("project=beta 结果 = %v", records)
+	}
+}
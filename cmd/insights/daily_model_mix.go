@@ -0,0 +1,74 @@
+package main
+
+import "sort"
+
+// DailyModelMix 每日各模型请求数，Dates 与每个 Series.Counts 下标一一对应，供堆叠柱状图使用
+type DailyModelMix struct {
+	Dates  []string           `json:"dates"`
+	Models []DailyModelSeries `json:"models"`
+}
+
+// DailyModelSeries 单个模型在 Dates 各日的请求数
+type DailyModelSeries struct {
+	Model  string `json:"model"`
+	Counts []int  `json:"counts"`
+}
+
+// buildDailyModelMix 由「日期 -> 模型 -> 请求数」构建每日模型构成。
+// 日期升序；模型按总请求数降序、同数按名称排序；没有数据时返回 nil
+func buildDailyModelMix(dailyModels map[string]map[string]int) *DailyModelMix {
+	totals := make(map[string]int)
+	dates := make([]string, 0, len(dailyModels))
+	for date, models := range dailyModels {
+		dayTotal := 0
+		for model, count := range models {
+			if count <= 0 {
+				continue
+			}
+			totals[model] += count
+			dayTotal += count
+		}
+		if dayTotal > 0 {
+			dates = append(dates, date)
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	sort.Strings(dates)
+
+	names := make([]string, 0, len(totals))
+	for model := range totals {
+		names = append(names, model)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]] != totals[names[j]] {
+			return totals[names[i]] > totals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	mix := &DailyModelMix{Dates: dates, Models: make([]DailyModelSeries, 0, len(names))}
+	for _, model := range names {
+		counts := make([]int, len(dates))
+		for i, date := range dates {
+			if count := dailyModels[date][model]; count > 0 {
+				counts[i] = count
+			}
+		}
+		mix.Models = append(mix.Models, DailyModelSeries{Model: model, Counts: counts})
+	}
+	return mix
+}
+
+// filterDailyModelMix 只保留 keep 返回 true 的模型；过滤后为空时返回 nil
+func filterDailyModelMix(mix *DailyModelMix, keep func(model string) bool) *DailyModelMix {
+	if mix == nil {
+		return nil
+	}
+	models := filterSlice(mix.Models, func(item DailyModelSeries) bool { return keep(item.Model) })
+	if len(models) == 0 {
+		return nil
+	}
+	return &DailyModelMix{Dates: mix.Dates, Models: models}
+}
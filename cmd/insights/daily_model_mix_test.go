@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// modelRecordJSON 指定模型的 assistant 记录
+func modelRecordJSON(sessionID, model string, ts time.Time) string {
+	return strings.Replace(projectRecordJSON("/tmp/demo", sessionID, ts), `"model":"claude-sonnet-4.5"`, `"model":"`+model+`"`, 1)
+}
+
+func TestDailyModelMixKeepsModelsOfSameDay(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := modelRecordJSON("s1", "claude-sonnet-4.5", base) + "\n" +
+		modelRecordJSON("s1", "claude-sonnet-4.5", base.Add(time.Minute)) + "\n" +
+		modelRecordJSON("s1", "claude-opus-4.5", base.Add(2*time.Minute)) + "\n" +
+		modelRecordJSON("s2", "claude-opus-4.5", base.AddDate(0, 0, 1)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	want := &DailyModelMix{
+		Dates: []string{"2026-01-05", "2026-01-06"},
+		Models: []DailyModelSeries{
+			{Model: "claude-opus-4.5", Counts: []int{1, 1}},
+			{Model: "claude-sonnet-4.5", Counts: []int{2, 0}},
+		},
+	}
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if got := buildDailyModelMix(agg.DailyModelCounts); !reflect.DeepEqual(got, want) {
+		t.Fatalf("daily model mix = %+v, want %+v", got, want)
+	}
+
+	// 缓存路径：DailyStats.ModelCounts 汇总结果应与直接解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	daily := make(map[string]map[string]int)
+	for date, day := range cache.DailyStats {
+		daily[date] = day.ModelCounts
+	}
+	if got := buildDailyModelMix(daily); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached daily model mix = %+v, want %+v", got, want)
+	}
+
+	// 堆叠图：每个模型一个系列，且共用同一个 stack
+	bar := CreateDailyModelStackChart(want)
+	if len(bar.MultiSeries) != 2 {
+		t.Fatalf("series=%d, want 2", len(bar.MultiSeries))
+	}
+	for _, series := range bar.MultiSeries {
+		if series.Stack != "models" {
+			t.Fatalf("series %s stack=%q, want models", series.Name, series.Stack)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+// DashboardSummary Dashboard 顶部「大数字」卡片：由各明细数组汇总得出，客户端无需自行累加
+type DashboardSummary struct {
+	TotalMessages  int     `json:"total_messages"`
+	TotalSessions  int     `json:"total_sessions"`
+	TotalTokens    int     `json:"total_tokens"`     // model_usage 各模型 token 之和
+	TotalCostCNY   float64 `json:"total_cost_cny"`   // cost_analysis.by_model 费用之和（定价规则加载失败时为 0）
+	TotalCommands  int     `json:"total_commands"`   // commands 各 slash command 次数之和
+	TotalToolCalls int     `json:"total_tool_calls"` // tool_analysis.tools 调用次数之和
+	// AvgOutputTokensPerMessage daily_output_tokens 的 output token 合计 / 消息数（无消息时为 0）
+	AvgOutputTokensPerMessage float64 `json:"avg_output_tokens_per_message"`
+}
+
+// buildDashboardSummary 从 DashboardData 的明细汇总出顶部数字；
+// 缓存与实时解析两条路径都在组装完成后调用，过滤后也会重新计算，保证与明细一致
+func buildDashboardSummary(data *DashboardData) *DashboardSummary {
+	if data == nil {
+		return nil
+	}
+	summary := &DashboardSummary{}
+	if data.ProjectStats != nil {
+		summary.TotalMessages = data.ProjectStats.TotalMessages
+		summary.TotalSessions = data.ProjectStats.TotalSessions
+	}
+	if data.Sessions != nil {
+		summary.TotalSessions = data.Sessions.TotalSessions
+	}
+	for _, item := range data.ModelUsage {
+		summary.TotalTokens += item.Tokens
+	}
+	if data.CostAnalysis != nil {
+		for _, stat := range data.CostAnalysis.ByModel {
+			summary.TotalCostCNY += stat.CostCNY
+		}
+	}
+	for _, cmd := range data.Commands {
+		summary.TotalCommands += cmd.Count
+	}
+	if data.ToolAnalysis != nil {
+		for _, tool := range data.ToolAnalysis.Tools {
+			summary.TotalToolCalls += tool.CallCount
+		}
+	}
+	outputTokens, outputMessages := 0, 0
+	for _, day := range data.DailyOutputTokens {
+		outputTokens += day.OutputTokens
+		outputMessages += day.Messages
+	}
+	summary.AvgOutputTokensPerMessage = avgOutputTokens(outputTokens, outputMessages)
+	return summary
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDashboardSummaryMatchesDetailsOnBothPaths(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+	tf := NewTimeFilterFromPreset("all")
+
+	live, source, err := buildDashboardData(tf, "all")
+	if err != nil || source != "parsing" {
+		t.Fatalf("实时解析 source=%s err=%v", source, err)
+	}
+	summary := live.Summary
+	if summary == nil {
+		t.Fatal("实时解析结果缺少 summary")
+	}
+	tokens, commands, toolCalls := 0, 0, 0
+	for _, item := range live.ModelUsage {
+		tokens += item.Tokens
+	}
+	for _, cmd := range live.Commands {
+		commands += cmd.Count
+	}
+	for _, tool := range live.ToolAnalysis.Tools {
+		toolCalls += tool.CallCount
+	}
+	if summary.TotalMessages != 5 || summary.TotalSessions != live.Sessions.TotalSessions ||
+		summary.TotalTokens != tokens || summary.TotalCommands != commands || summary.TotalToolCalls != toolCalls {
+		t.Fatalf("summary = %+v, want messages=5 sessions=%d tokens=%d commands=%d tool_calls=%d",
+			summary, live.Sessions.TotalSessions, tokens, commands, toolCalls)
+	}
+	if tokens == 0 || commands == 0 || toolCalls == 0 {
+		t.Fatalf("fixture 明细不应为空: tokens=%d commands=%d tool_calls=%d", tokens, commands, toolCalls)
+	}
+
+	// 缓存路径组装出的 summary 应与实时解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	cached, source, err := buildDashboardData(tf, "all")
+	if err != nil || source != "cache" {
+		t.Fatalf("缓存路径 source=%s err=%v", source, err)
+	}
+	if cached.Summary == nil || *cached.Summary != *summary {
+		t.Fatalf("缓存 summary = %+v, 实时解析 = %+v", cached.Summary, summary)
+	}
+}
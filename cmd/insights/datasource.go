@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DataSource 数据目录的读取抽象：解析器通过它访问 history/projects/debug 等数据文件，
+// 路径仍是 filepath.Join(cfg.DataDir, ...) 形式的完整路径。
+// 默认直接读本地文件系统；--archive 与 --demo 时换成内存实现（tar.gz 归档内容 / 生成的演示数据），
+// 均不在磁盘上落地数据文件。
+type DataSource interface {
+	Open(name string) (fs.File, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// dataSource 当前生效的数据源
+var dataSource DataSource = osDataSource{}
+
+// osDataSource 本地文件系统数据源
+type osDataSource struct{}
+
+func (osDataSource) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osDataSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// memDataSource 只读内存数据源：以 root 作为虚拟数据目录（cfg.DataDir 指向它），
+// 其下的路径映射到 fsys 中的相对路径。用于 tar.gz 归档与 --demo 演示数据。
+type memDataSource struct {
+	root string
+	fsys *memFS
+}
+
+// openTarDataSource 读取 tar.gz 归档并建立内存索引，以归档路径作为虚拟根目录。
+// gzip 流不可随机访问，因此加载时把条目全部解压到内存。
+// 归档若只有单个顶层目录（如 .claude/），自动以该目录为数据根。
+func openTarDataSource(archivePath string) (*memDataSource, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开归档失败: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("解析 gzip 失败: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string]*memFile{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取归档失败: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(filepath.ToSlash(header.Name), "./"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			files[name] = &memFile{Mode: fs.ModeDir | 0755, ModTime: header.ModTime}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("读取归档条目 %s 失败: %w", name, err)
+			}
+			files[name] = &memFile{Data: data, Mode: 0644, ModTime: header.ModTime}
+		}
+	}
+
+	return &memDataSource{root: filepath.Clean(archivePath), fsys: newMemFS(stripSingleTopDir(files))}, nil
+}
+
+// stripSingleTopDir 归档根下只有一个目录且没有数据文件时，去掉这一层前缀
+func stripSingleTopDir(files map[string]*memFile) map[string]*memFile {
+	top := ""
+	for name := range files {
+		first, _, _ := strings.Cut(name, "/")
+		if top == "" {
+			top = first
+		} else if first != top {
+			return files
+		}
+	}
+	if top == "" || top == "history.jsonl" || top == "projects" || top == "debug" {
+		return files
+	}
+	if entry, ok := files[top]; ok && !entry.Mode.IsDir() {
+		return files
+	}
+	stripped := map[string]*memFile{}
+	for name, file := range files {
+		if rel := strings.TrimPrefix(name, top+"/"); rel != name {
+			stripped[rel] = file
+		}
+	}
+	return stripped
+}
+
+// fsName 把数据目录下的完整路径转换为 fsys 内的相对路径
+func (ds *memDataSource) fsName(name string) (string, error) {
+	rel, err := filepath.Rel(ds.root, filepath.Clean(name))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (ds *memDataSource) Open(name string) (fs.File, error) {
+	rel, err := ds.fsName(name)
+	if err != nil {
+		return nil, err
+	}
+	return ds.fsys.Open(rel)
+}
+
+func (ds *memDataSource) ReadDir(name string) ([]fs.DirEntry, error) {
+	rel, err := ds.fsName(name)
+	if err != nil {
+		return nil, err
+	}
+	return ds.fsys.ReadDir(rel)
+}
+
+// applyArchiveConfig 配置了 --archive 时切换数据源；重复调用只加载一次
+func applyArchiveConfig() error {
+	if cfg.ArchivePath == "" {
+		return nil
+	}
+	if ds, ok := dataSource.(*memDataSource); ok && ds.root == filepath.Clean(cfg.ArchivePath) {
+		cfg.DataDir = ds.root
+		return nil
+	}
+	return useArchiveDataSource(cfg.ArchivePath)
+}
+
+// useArchiveDataSource 切换到归档数据源，并把 cfg.DataDir 指向归档路径作为虚拟根
+func useArchiveDataSource(archivePath string) error {
+	ds, err := openTarDataSource(archivePath)
+	if err != nil {
+		return err
+	}
+	dataSource = ds
+	cfg.DataDir = ds.root
+	return nil
+}
+
+// statData 通过数据源获取文件信息
+func statData(name string) (fs.FileInfo, error) {
+	f, err := dataSource.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// resolveDataPath 解析路径中的符号链接得到真实路径，用于判重；
+// 解析失败（如归档数据源的虚拟路径）时原样返回
+func resolveDataPath(name string) string {
+	if real, err := filepath.EvalSymlinks(name); err == nil {
+		return real
+	}
+	return name
+}
+
+// readDataFile 通过数据源读取整个文件
+func readDataFile(name string) ([]byte, error) {
+	f, err := dataSource.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// walkData 通过数据源递归遍历目录，语义同 filepath.WalkDir
+func walkData(root string, fn fs.WalkDirFunc) error {
+	info, err := statData(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDataDir(root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkDataDir(name string, entry fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(name, entry, nil); err != nil || !entry.IsDir() {
+		if err == fs.SkipDir && entry.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := dataSource.ReadDir(name)
+	if err != nil {
+		if err = fn(name, entry, err); err != nil {
+			if err == fs.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, child := range entries {
+		if err := walkDataDir(filepath.Join(name, child.Name()), child, fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestArchive 把 dataDir 打包成 tar.gz，条目带 data/ 顶层目录（模拟直接打包 ~/.claude）
+func writeTestArchive(t *testing.T, dataDir, archivePath string) {
+	t.Helper()
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("创建归档失败: %v", err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(dataDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(filepath.Dir(dataDir), path)
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("写入归档失败: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTarDataSourceMatchesExtractedDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := createTestDataDir(t, tmpDir)
+	appendProjectRecord(t, dataDir, "/tmp/other-project", "session-3", time.Now().Add(2*time.Hour))
+	archivePath := filepath.Join(tmpDir, "cc-data.tar.gz")
+	writeTestArchive(t, dataDir, archivePath)
+
+	origDataDir, origSource := cfg.DataDir, dataSource
+	defer func() { cfg.DataDir, dataSource = origDataDir, origSource }()
+
+	type counts struct {
+		summary  DataDirSummary
+		messages int
+		sessions int
+		projects int
+		commands int
+		debug    int
+	}
+	collect := func(dir string) counts {
+		summary, err := ValidateDataDir(dir)
+		if err != nil {
+			t.Fatalf("ValidateDataDir(%s): %v", dir, err)
+		}
+		agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dir)
+		if err != nil {
+			t.Fatalf("ParseProjectsConcurrentOnceFromDir(%s): %v", dir, err)
+		}
+		c := counts{summary: summary, projects: len(agg.Projects)}
+		for _, project := range agg.Projects {
+			c.messages += project.MessageCount
+			c.sessions += project.SessionCount
+		}
+		cmdStats, _, err := ParseHistoryWithFilter(TimeFilter{})
+		if err != nil {
+			t.Fatalf("ParseHistoryWithFilter(%s): %v", dir, err)
+		}
+		for _, item := range cmdStats {
+			c.commands += item.Count
+		}
+		signals, err := ParseDebugLogsConcurrentFromDir(TimeFilter{}, dir)
+		if err != nil {
+			t.Fatalf("ParseDebugLogsConcurrentFromDir(%s): %v", dir, err)
+		}
+		for _, item := range signals {
+			c.debug += item.Count
+		}
+		return c
+	}
+
+	cfg.DataDir = dataDir
+	dataSource = osDataSource{}
+	want := collect(dataDir)
+	if want.messages == 0 || want.commands == 0 || want.debug == 0 {
+		t.Fatalf("fixture 数据不完整: %+v", want)
+	}
+
+	cfg.ArchivePath = archivePath
+	defer func() { cfg.ArchivePath = "" }()
+	if err := applyArchiveConfig(); err != nil {
+		t.Fatalf("applyArchiveConfig: %v", err)
+	}
+	if cfg.DataDir != filepath.Clean(archivePath) {
+		t.Fatalf("DataDir=%s, want archive path", cfg.DataDir)
+	}
+	got := collect(cfg.DataDir)
+	if got != want {
+		t.Fatalf("归档统计与解压目录不一致: got=%+v want=%+v", got, want)
+	}
+}
+
+func TestWalkDataSkipDir(t *testing.T) {
+	dataSource = &memDataSource{root: "/archive", fsys: newMemFS(map[string]*memFile{
+		"projects/a/1.jsonl": {Data: []byte("{}")},
+		"projects/b/2.jsonl": {Data: []byte("{}")},
+	})}
+	defer func() { dataSource = osDataSource{} }()
+
+	var visited []string
+	err := walkData("/archive/projects", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == "a" {
+			return fs.SkipDir
+		}
+		if !entry.IsDir() {
+			visited = append(visited, filepath.ToSlash(path))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkData: %v", err)
+	}
+	if len(visited) != 1 || visited[0] != "/archive/projects/b/2.jsonl" {
+		t.Fatalf("visited=%v, want only b/2.jsonl", visited)
+	}
+}
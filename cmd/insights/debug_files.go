@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 数据文件类别
+const (
+	dataFileHistory = "history"
+	dataFileProject = "project"
+	dataFileDebug   = "debug"
+)
+
+// DataFileInfo /api/debug/files 列出的单个数据文件
+type DataFileInfo struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"` // 相对数据目录
+	Size    int64  `json:"size"`
+	ModTime string `json:"mod_time"`
+	// InRange 是否通过当前时间过滤；只有按 mtime 过滤的 debug 文件有此字段，
+	// history 与会话记录逐条按时间戳过滤，文件本身总会被读取
+	InRange *bool `json:"in_range,omitempty"`
+}
+
+// debugFileListData /api/debug/files 返回的文件清单
+type debugFileListData struct {
+	DataDir string         `json:"data_dir"`
+	Total   int            `json:"total"`
+	Files   []DataFileInfo `json:"files"`
+}
+
+// dataFileRelPath 数据文件相对数据目录的路径，无法计算时原样返回
+func dataFileRelPath(path string) string {
+	if rel, err := filepath.Rel(cfg.DataDir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// ListDataFiles 列出解析器会读取的数据文件：history*.jsonl、projects 下全部 *.jsonl
+// 与 debug/*.txt，发现规则与各解析器一致。debug 文件按 mtime 标注是否落在 tf 内
+func ListDataFiles(tf TimeFilter) []DataFileInfo {
+	var files []DataFileInfo
+	add := func(kind, path string, inRange *bool) {
+		info, err := statData(path)
+		if err != nil || info.IsDir() {
+			return
+		}
+		files = append(files, DataFileInfo{
+			Kind:    kind,
+			Path:    dataFileRelPath(path),
+			Size:    info.Size(),
+			ModTime: info.ModTime().Format(time.RFC3339),
+			InRange: inRange,
+		})
+	}
+
+	for _, path := range historyFilePaths() {
+		add(dataFileHistory, path, nil)
+	}
+
+	if projectDirs, err := listProjectDirs(GetDataPath("projects")); err == nil {
+		for _, projectDir := range projectDirs {
+			paths, err := projectJSONLFiles(projectDir)
+			if err != nil {
+				continue
+			}
+			sort.Strings(paths)
+			for _, path := range paths {
+				add(dataFileProject, path, nil)
+			}
+		}
+	}
+
+	debugDir := GetDataPath("debug")
+	if entries, err := dataSource.ReadDir(debugDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			// 与 FilterDebugFiles 同一口径
+			inRange := !hasTimeFilter(tf) || tf.Contains(info.ModTime())
+			add(dataFileDebug, filepath.Join(debugDir, entry.Name()), &inRange)
+		}
+	}
+	return files
+}
+
+// handleDebugFilesAPI 排障用：列出解析器实际考虑的数据文件（大小、修改时间、是否通过时间过滤）。
+// 仅在 --debug 启动时可用，否则返回 404
+func handleDebugFilesAPI(w http.ResponseWriter, r *http.Request) {
+	if !cfg.DebugEndpoints {
+		http.NotFound(w, r)
+		return
+	}
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startedAt := time.Now()
+	files := ListDataFiles(filter.TimeFilter)
+	payload := debugFileListData{DataDir: cfg.DataDir, Total: len(files), Files: files}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, payload, "parsing", rangeInfo, filter, startedAt)
+}
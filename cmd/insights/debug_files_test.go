@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDebugFilesAPIListsDiscoveredFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	inside := time.Date(2026, 1, 6, 12, 0, 0, 0, time.Local)
+	outside := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	files := map[string]time.Time{
+		"history.jsonl":                inside,
+		"history-2025-12.jsonl":        outside,
+		"projects/alpha/a.jsonl":       inside,
+		"projects/beta/sub/b.jsonl":    inside,
+		"projects/beta/notes.txt":      inside,
+		"debug/new.txt":                inside,
+		"debug/old.txt":                outside,
+		"debug/ignored.log":            inside,
+		"todos/unrelated-session.json": inside,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origCfg := cfg
+	cfg.DataDir = dataDir
+	defer func() { cfg = origCfg }()
+
+	// 未开启 --debug 时接口不可用
+	cfg.DebugEndpoints = false
+	w := httptest.NewRecorder()
+	handleDebugFilesAPI(w, httptest.NewRequest("GET", "/api/debug/files", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("未开启 debug 时 status=%d, want 404", w.Code)
+	}
+
+	cfg.DebugEndpoints = true
+	w = httptest.NewRecorder()
+	handleDebugFilesAPI(w, httptest.NewRequest("GET", "/api/debug/files?start=2026-01-05&end=2026-01-07", nil))
+	var resp struct {
+		Success bool              `json:"success"`
+		Data    debugFileListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.Total != len(resp.Data.Files) {
+		t.Fatalf("响应不符: %s", w.Body.String())
+	}
+
+	got := make(map[string]string)
+	inRange := make(map[string]bool)
+	for _, file := range resp.Data.Files {
+		got[file.Path] = file.Kind
+		if file.Size != 3 || file.ModTime == "" {
+			t.Fatalf("%s size=%d mod_time=%q", file.Path, file.Size, file.ModTime)
+		}
+		if file.InRange != nil {
+			inRange[file.Path] = *file.InRange
+		}
+	}
+	want := map[string]string{
+		"history-2025-12.jsonl":     dataFileHistory,
+		"history.jsonl":             dataFileHistory,
+		"projects/alpha/a.jsonl":    dataFileProject,
+		"projects/beta/sub/b.jsonl": dataFileProject,
+		"debug/new.txt":             dataFileDebug,
+		"debug/old.txt":             dataFileDebug,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	// 只有 debug 文件按 mtime 判定时间范围
+	if wantRange := map[string]bool{"debug/new.txt": true, "debug/old.txt": false}; !reflect.DeepEqual(inRange, wantRange) {
+		t.Fatalf("in_range = %v, want %v", inRange, wantRange)
+	}
+}
@@ -3,21 +3,59 @@ package main
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 	"sync"
 )
 
-var mcpPattern = regexp.MustCompile(`mcp__(\w+)__(\w+)`)
+// defaultMCPPattern 默认的 MCP 工具信号正则：两个捕获组依次为 server 与 tool。
+// \w 不含 - 和 .，server/tool 名带连字符或点号时可用 --mcp-pattern 换成
+// `mcp__([\w.-]+?)__([\w.-]+)` 之类更宽的字符类
+const defaultMCPPattern = `mcp__(\w+)__(\w+)`
+
+// mcpPattern 当前生效的 MCP 工具信号正则（由 applyMCPPatternConfig 按 cfg.MCPPattern 设置）
+var mcpPattern = regexp.MustCompile(defaultMCPPattern)
+
+// compileMCPPattern 编译 MCP 工具正则，要求至少两个捕获组（server、tool）
+func compileMCPPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 MCP 工具正则 %q: %w", pattern, err)
+	}
+	if re.NumSubexp() < 2 {
+		return nil, fmt.Errorf("MCP 工具正则 %q 需要两个捕获组（server、tool）", pattern)
+	}
+	return re, nil
+}
+
+// applyMCPPatternConfig 按 cfg.MCPPattern 设置 mcpPattern；为空时使用默认正则
+func applyMCPPatternConfig() error {
+	pattern := cfg.MCPPattern
+	if pattern == "" {
+		pattern = defaultMCPPattern
+	}
+	re, err := compileMCPPattern(pattern)
+	if err != nil {
+		return err
+	}
+	mcpPattern = re
+	return nil
+}
+
+// mcpPatternCacheKey 写入缓存的正则标识；默认正则记为空，兼容旧缓存
+func mcpPatternCacheKey() string {
+	if pattern := mcpPattern.String(); pattern != defaultMCPPattern {
+		return pattern
+	}
+	return ""
+}
 
 // ParseDebugLogs 解析 debug 日志目录
 func ParseDebugLogs() ([]RuntimeToolSignal, error) {
 	debugDir := GetDataPath("debug")
 
-	entries, err := os.ReadDir(debugDir)
+	entries, err := dataSource.ReadDir(debugDir)
 	if err != nil {
 		return nil, fmt.Errorf("读取 debug 目录失败: %w", err)
 	}
@@ -25,7 +63,7 @@ func ParseDebugLogs() ([]RuntimeToolSignal, error) {
 	// 并发解析
 	var wg sync.WaitGroup
 	results := make(chan map[string]int, len(entries))
-	workers := 8
+	workers := getWorkerCount()
 
 	files := make([]string, 0, len(entries))
 	for _, entry := range entries {
@@ -79,9 +117,7 @@ func ParseDebugLogs() ([]RuntimeToolSignal, error) {
 			})
 		}
 	}
-	sort.Slice(toolStats, func(i, j int) bool {
-		return toolStats[i].Count > toolStats[j].Count
-	})
+	sortRuntimeToolSignals(toolStats)
 
 	return toolStats, nil
 }
@@ -90,7 +126,7 @@ func ParseDebugLogs() ([]RuntimeToolSignal, error) {
 func ParseDebugLogsWithFilter(tf TimeFilter) ([]RuntimeToolSignal, error) {
 	debugDir := GetDataPath("debug")
 
-	entries, err := os.ReadDir(debugDir)
+	entries, err := dataSource.ReadDir(debugDir)
 	if err != nil {
 		return nil, fmt.Errorf("读取 debug 目录失败: %w", err)
 	}
@@ -113,7 +149,7 @@ func ParseDebugLogsWithFilter(tf TimeFilter) ([]RuntimeToolSignal, error) {
 	// 并发解析
 	var wg sync.WaitGroup
 	results := make(chan map[string]int, len(filteredFiles))
-	workers := 8
+	workers := getWorkerCount()
 
 	files := make([]string, 0, len(filteredFiles))
 	for _, info := range filteredFiles {
@@ -165,15 +201,13 @@ func ParseDebugLogsWithFilter(tf TimeFilter) ([]RuntimeToolSignal, error) {
 			})
 		}
 	}
-	sort.Slice(toolStats, func(i, j int) bool {
-		return toolStats[i].Count > toolStats[j].Count
-	})
+	sortRuntimeToolSignals(toolStats)
 
 	return toolStats, nil
 }
 
 func parseDebugFile(path string, counts map[string]int) {
-	f, err := os.Open(path)
+	f, err := dataSource.Open(path)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// debugPatternRule 一条 debug 日志行模式：Name 为统计键，逐行匹配 Re
+type debugPatternRule struct {
+	Name string
+	Re   *regexp.Regexp
+}
+
+// debugPatterns 当前生效的 debug 日志行模式（由 applyDebugPatternConfig 按 cfg.DebugPatterns 设置），按名称排序
+var debugPatterns = mustCompileDebugPatterns(defaultDebugPatterns())
+
+// defaultDebugPatterns 内置的 debug 日志行模式：debug 文件每行形如
+// 2025-11-23T06:41:03.513Z [ERROR] ...，按级别标记统计错误与警告行
+func defaultDebugPatterns() map[string]string {
+	return map[string]string{
+		"error": `\[ERROR\]`,
+		"warn":  `\[WARN(ING)?\]`,
+	}
+}
+
+// compileDebugPatterns 编译名称 → 正则的模式集合，结果按名称排序
+func compileDebugPatterns(patterns map[string]string) ([]debugPatternRule, error) {
+	rules := make([]debugPatternRule, 0, len(patterns))
+	for name, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 debug 日志模式 %s=%q: %w", name, pattern, err)
+		}
+		rules = append(rules, debugPatternRule{Name: name, Re: re})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+func mustCompileDebugPatterns(patterns map[string]string) []debugPatternRule {
+	rules, err := compileDebugPatterns(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// applyDebugPatternConfig 按 cfg.DebugPatterns 设置 debugPatterns
+func applyDebugPatternConfig() error {
+	rules, err := compileDebugPatterns(cfg.DebugPatterns)
+	if err != nil {
+		return err
+	}
+	debugPatterns = rules
+	return nil
+}
+
+// debugPatternValue --debug-pattern 的 flag 值：name=regex，可重复指定，同名覆盖（含内置模式）
+type debugPatternValue struct {
+	target *map[string]string
+}
+
+func (v debugPatternValue) String() string {
+	if v.target == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*v.target))
+	for name, pattern := range *v.target {
+		names = append(names, name+"="+pattern)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (v debugPatternValue) Set(value string) error {
+	name, pattern, ok := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || pattern == "" {
+		return fmt.Errorf("debug 日志模式应为 name=regex，得到 %q", value)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("无效的 debug 日志模式 %s=%q: %w", name, pattern, err)
+	}
+	if *v.target == nil {
+		*v.target = make(map[string]string)
+	}
+	(*v.target)[name] = pattern
+	return nil
+}
+
+// countDebugPatterns 统计一行命中的模式，每个模式每行最多计一次
+func countDebugPatterns(line string, rules []debugPatternRule, counts map[string]int) {
+	for _, rule := range rules {
+		if rule.Re.MatchString(line) {
+			counts[rule.Name]++
+		}
+	}
+}
+
+// debugPatternData /api/debug-patterns 返回的各模式命中行数
+type debugPatternData struct {
+	DebugPatternStats map[string]int `json:"debug_pattern_stats"`
+}
+
+// handleDebugPatternsAPI /api/debug-patterns：时间范围内 debug 日志各模式的命中行数，
+// 文件按与 runtime_tools 相同的规则过滤，始终实时解析
+func handleDebugPatternsAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startedAt := time.Now()
+	_, stats, err := parseDebugLogsFromDir(filter.TimeFilter, cfg.DataDir)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, debugPatternData{DebugPatternStats: stats}, "parsing", rangeInfo, filter, startedAt)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDebugPatternsCountedAlongsideMCPCalls(t *testing.T) {
+	dataDir := t.TempDir()
+	debugDir := filepath.Join(dataDir, "debug")
+	if err := os.MkdirAll(debugDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "2026-01-05T12:00:05.000Z [DEBUG] tool call mcp__github__get_file_contents\n" +
+		"2026-01-05T12:00:06.000Z [ERROR] Failed to connect to MCP server\n" +
+		"2026-01-05T12:00:07.000Z [DEBUG] retrying\n" +
+		"2026-01-05T12:00:08.000Z [ERROR] Request timed out\n"
+	if err := os.WriteFile(filepath.Join(debugDir, "s1.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origPatterns := cfg.DataDir, debugPatterns
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir, debugPatterns = origDataDir, origPatterns }()
+
+	tools, stats, err := parseDebugLogsFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("parseDebugLogsFromDir() error = %v", err)
+	}
+	wantTools := []RuntimeToolSignal{{Server: "github", Tool: "get_file_contents", Count: 1}}
+	if !reflect.DeepEqual(tools, wantTools) {
+		t.Fatalf("tools=%+v, want %+v", tools, wantTools)
+	}
+	// 未命中的内置模式也以 0 出现
+	if want := map[string]int{"error": 2, "warn": 0}; !reflect.DeepEqual(stats, want) {
+		t.Fatalf("debug pattern stats=%v, want %v", stats, want)
+	}
+
+	// --debug-pattern 追加自定义模式，同名覆盖内置模式
+	target := defaultConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerConfigFlags(fs, &target)
+	if err := fs.Parse([]string{"--debug-pattern", "timeout=timed out", "--debug-pattern", "error=Failed"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	rules, err := compileDebugPatterns(target.DebugPatterns)
+	if err != nil {
+		t.Fatalf("compileDebugPatterns() error = %v", err)
+	}
+	debugPatterns = rules
+	if _, stats, _ = parseDebugLogsFromDir(TimeFilter{}, dataDir); !reflect.DeepEqual(stats, map[string]int{"error": 1, "timeout": 1, "warn": 0}) {
+		t.Fatalf("custom debug pattern stats=%v", stats)
+	}
+	if err := fs.Parse([]string{"--debug-pattern", "bad=("}); err == nil {
+		t.Fatal("无效正则应报错")
+	}
+
+	w := httptest.NewRecorder()
+	handleDebugPatternsAPI(w, httptest.NewRequest("GET", "/api/debug-patterns?preset=all", nil))
+	var resp struct {
+		Success bool             `json:"success"`
+		Data    debugPatternData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.DebugPatternStats["timeout"] != 1 {
+		t.Fatalf("/api/debug-patterns 结果不符: %s", w.Body.String())
+	}
+}
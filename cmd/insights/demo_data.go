@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --demo 演示模式：启动时在内存中生成一份合成数据（history、项目会话、debug 日志），
+// 数据源切换为 memDataSource，不读取 ~/.claude，也不构建磁盘缓存，始终实时解析。
+
+// demoDataRoot 演示数据的虚拟数据目录，磁盘上不存在
+var demoDataRoot = filepath.Join(string(filepath.Separator), "cc-insights-demo")
+
+const (
+	// demoDays 演示数据覆盖的天数（含今天）
+	demoDays = 14
+	// demoTurnsBase 每个演示会话的最少轮数（一问一答为一轮）
+	demoTurnsBase = 3
+)
+
+var (
+	demoProjects = []string{"/home/demo/web-app", "/home/demo/api-server", "/home/demo/cli-tool"}
+	demoModels   = []string{"claude-sonnet-4-6", "claude-opus-4-6", "claude-haiku-4-5"}
+	demoCommands = []string{"/review", "/compact", "/model opus", "/clear", "/help"}
+	demoPrompts  = []string{"run the tests", "fix the failing build", "add a flag for the output path", "explain this function", "refactor the handler"}
+	// demoTools 每轮 assistant 调用的工具及其输入
+	demoTools = []struct{ name, input string }{
+		{"Bash", `{"command":"go test ./..."}`},
+		{"Read", `{"file_path":"main.go"}`},
+		{"Edit", `{"file_path":"handler.go","old_string":"a","new_string":"b"}`},
+		{"Grep", `{"pattern":"TODO"}`},
+		{"Bash", `{"command":"git status"}`},
+	}
+	demoMCPTools = []string{"mcp__github__get_file_contents", "mcp__crawl__extract_url"}
+)
+
+// generateDemoData 生成以 now 所在日为最后一天、共 demoDays 天的演示数据。
+// 内容只由日期决定（无随机数），同一天内多次生成结果一致
+func generateDemoData(now time.Time) map[string]*memFile {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	files := map[string]*memFile{}
+	addFile := func(name, content string, modTime time.Time) {
+		files[name] = &memFile{Data: []byte(content), Mode: 0644, ModTime: modTime}
+	}
+
+	var history strings.Builder
+	for d := 0; d < demoDays; d++ {
+		day := today.AddDate(0, 0, d-demoDays+1)
+		for p, cwd := range demoProjects {
+			// 每个项目隔几天休息一天，让每日会话数有起伏
+			if (d+p)%4 == 3 {
+				continue
+			}
+			sessionID := fmt.Sprintf("demo-%s-%d", day.Format("20060102"), p)
+			at := day.Add(time.Duration(9+2*p+d%3) * time.Hour)
+			model := demoModels[(d+p)%len(demoModels)]
+
+			var session, debug strings.Builder
+			turns := demoTurnsBase + (d*(p+1))%5
+			for t := 0; t < turns; t++ {
+				tool := demoTools[(d+p+t)%len(demoTools)]
+				toolID := fmt.Sprintf("toolu_%s_%d", sessionID, t)
+				userTS := at.UTC().Format(time.RFC3339Nano)
+				assistantAt := at.Add(20 * time.Second)
+				if t == 0 {
+					fmt.Fprintf(&session, `{"type":"user","cwd":%q,"sessionId":%q,"timestamp":%q,"message":{"role":"user","content":%q}}`+"\n",
+						cwd, sessionID, userTS, demoPrompts[(d+p)%len(demoPrompts)])
+				} else {
+					fmt.Fprintf(&session, `{"type":"user","cwd":%q,"sessionId":%q,"timestamp":%q,"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_%s_%d","content":"ok"}]}}`+"\n",
+						cwd, sessionID, userTS, sessionID, t-1)
+				}
+				fmt.Fprintf(&session, `{"type":"assistant","cwd":%q,"sessionId":%q,"timestamp":%q,"message":{"model":%q,"content":[{"type":"tool_use","id":%q,"name":%q,"input":%s}],"usage":{"input_tokens":%d,"output_tokens":%d,"cache_read_input_tokens":%d}}}`+"\n",
+					cwd, sessionID, assistantAt.UTC().Format(time.RFC3339Nano), model, toolID, tool.name, tool.input, 800+137*t+41*d, 60+23*t+7*p, 2000+300*t)
+				fmt.Fprintf(&debug, "%s [DEBUG] tool call %s\n", assistantAt.UTC().Format("2006-01-02T15:04:05.000Z"), demoMCPTools[(t+p)%len(demoMCPTools)])
+				at = at.Add(3 * time.Minute)
+			}
+			fmt.Fprintf(&history, `{"display":%q,"pastedContents":{},"timestamp":%d,"project":%q}`+"\n",
+				demoCommands[(d+p)%len(demoCommands)], at.UnixMilli(), cwd)
+
+			projectDir := "projects/" + strings.ReplaceAll(cwd, "/", "-")
+			addFile(projectDir+"/"+sessionID+".jsonl", session.String(), at)
+			addFile("debug/"+sessionID+".txt", debug.String(), at)
+		}
+	}
+	addFile("history.jsonl", history.String(), now)
+	return files
+}
+
+// useDemoDataSource 切换到内存演示数据源，并把 cfg.DataDir 指向虚拟根目录
+func useDemoDataSource(now time.Time) {
+	dataSource = &memDataSource{root: demoDataRoot, fsys: newMemFS(generateDemoData(now))}
+	cfg.DataDir = demoDataRoot
+}
+
+// applyDemoConfig 配置了 --demo 时切换到演示数据源（优先于 --archive 与 --data）
+func applyDemoConfig() {
+	if cfg.Demo {
+		useDemoDataSource(time.Now())
+	}
+}
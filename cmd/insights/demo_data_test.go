@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDemoServerServesInMemoryData(t *testing.T) {
+	origDataDir, origCacheDir, origSource, origCache := cfg.DataDir, cfg.CacheDir, dataSource, loadGlobalCache()
+	origDemo, origQuiet := cfg.Demo, cfg.Quiet
+	defer func() {
+		cfg.DataDir, cfg.CacheDir, dataSource = origDataDir, origCacheDir, origSource
+		storeGlobalCache(origCache)
+		cfg.Demo, cfg.Quiet = origDemo, origQuiet
+	}()
+	cacheDir := t.TempDir()
+	cfg.CacheDir = cacheDir
+	cfg.Demo, cfg.Quiet = true, true
+	storeGlobalCache(nil)
+	now := time.Now()
+	useDemoDataSource(now)
+
+	// 期望值直接从生成的数据中数出来
+	wantMessages, wantSessions := 0, 0
+	for name, file := range generateDemoData(now) {
+		if strings.HasPrefix(name, "projects/") {
+			wantSessions++
+			wantMessages += strings.Count(string(file.Data), `"type":"assistant"`)
+		}
+	}
+	if wantMessages == 0 {
+		t.Fatal("演示数据为空")
+	}
+	if _, err := os.Stat(demoDataRoot); !os.IsNotExist(err) {
+		t.Fatalf("演示数据目录不应存在于磁盘: %v", err)
+	}
+	if err := initializeCache(); err != nil {
+		t.Fatalf("initializeCache() error = %v", err)
+	}
+
+	server := httptest.NewServer(newServerHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/api/data?preset=all")
+	if err != nil {
+		t.Fatalf("GET /api/data 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Success bool          `json:"success"`
+		Data    DashboardData `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !body.Success || body.Data.Summary == nil {
+		t.Fatalf("status=%d success=%v", resp.StatusCode, body.Success)
+	}
+	summary := body.Data.Summary
+	if summary.TotalMessages != wantMessages || summary.TotalSessions != wantSessions {
+		t.Fatalf("messages=%d sessions=%d, want %d/%d", summary.TotalMessages, summary.TotalSessions, wantMessages, wantSessions)
+	}
+	if summary.TotalCommands == 0 || summary.TotalToolCalls == 0 || len(body.Data.RuntimeTools) == 0 {
+		t.Fatalf("commands=%d tool_calls=%d runtime_tools=%d, want 都有数据", summary.TotalCommands, summary.TotalToolCalls, len(body.Data.RuntimeTools))
+	}
+	if body.Data.ProjectStats == nil || len(body.Data.ProjectStats.Projects) != len(demoProjects) {
+		t.Fatalf("project_stats = %+v, want %d 个项目", body.Data.ProjectStats, len(demoProjects))
+	}
+
+	// 演示模式不构建磁盘缓存
+	if loadGlobalCache() != nil {
+		t.Fatal("演示模式不应加载缓存")
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Fatalf("缓存目录被写入: %v", entries)
+	}
+}
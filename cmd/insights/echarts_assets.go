@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ECharts 脚本本地化：go-echarts 默认从 CDN 加载 echarts.min.js 与主题脚本，
+// 离线或内网机器上图表无法渲染。脚本随二进制 embed 在 static/vendor（make vendor-echarts 更新），
+// Web 服务在 /static/ 下提供，导出的静态报告直接内联；--echarts-cdn 时仍引用 CDN。
+
+//go:embed static/vendor
+var vendorFS embed.FS
+
+// echartsAssets 内置的 ECharts 脚本，测试可替换
+var echartsAssets fs.FS = mustSubFS(vendorFS, "static/vendor")
+
+// echartsCDNHost go-echarts 默认的资源地址
+const echartsCDNHost = "https://go-echarts.github.io/go-echarts-assets/assets/"
+
+// echartsLocalHost Web 服务提供内置脚本的路径前缀
+const echartsLocalHost = "/static/"
+
+func mustSubFS(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// echartsAssetsHost 图表页面引用脚本的前缀：默认本地副本，--echarts-cdn 时为 CDN
+func echartsAssetsHost() string {
+	if cfg.EChartsCDN {
+		return echartsCDNHost
+	}
+	return echartsLocalHost
+}
+
+// handleEChartsAsset 提供 /static/echarts.min.js 与 /static/themes/*.js。
+// 未内置该文件时：--echarts-cdn 重定向到 CDN，否则 404
+func handleEChartsAsset(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), echartsLocalHost)
+	if !strings.HasSuffix(name, ".js") {
+		http.NotFound(w, r)
+		return
+	}
+	content, err := fs.ReadFile(echartsAssets, name)
+	if err != nil {
+		if cfg.EChartsCDN {
+			http.Redirect(w, r, echartsCDNHost+name, http.StatusFound)
+			return
+		}
+		http.Error(w, "未内置 "+name+"，请运行 make vendor-echarts 或使用 --echarts-cdn", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(content)
+}
+
+// inlineEChartsAssets 把以 echartsLocalHost 引用的脚本替换为内联的内置副本，使导出的 HTML 离线可用；
+// 未内置的脚本改为引用 CDN
+func inlineEChartsAssets(html []byte, assets []string) []byte {
+	for _, asset := range assets {
+		name := strings.TrimPrefix(asset, echartsLocalHost)
+		tag := []byte(`<script src="` + asset + `"></script>`)
+		replacement := []byte(`<script src="` + echartsCDNHost + name + `"></script>`)
+		if content, err := fs.ReadFile(echartsAssets, name); err == nil {
+			replacement = append(append([]byte("<script>\n"), content...), []byte("\n</script>")...)
+		}
+		html = bytes.ReplaceAll(html, tag, replacement)
+	}
+	return html
+}
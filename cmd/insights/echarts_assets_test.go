@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// useEChartsAssets 用内存文件替换内置的 ECharts 脚本
+func useEChartsAssets(t *testing.T, files fstest.MapFS) {
+	t.Helper()
+	orig := echartsAssets
+	echartsAssets = files
+	t.Cleanup(func() { echartsAssets = orig })
+}
+
+func TestEChartsAssetServedLocally(t *testing.T) {
+	useEChartsAssets(t, fstest.MapFS{
+		"echarts.min.js":       {Data: []byte("/* echarts */var echarts={};")},
+		"themes/wonderland.js": {Data: []byte("/* wonderland */")},
+	})
+	origCDN := cfg.EChartsCDN
+	defer func() { cfg.EChartsCDN = origCDN }()
+	cfg.EChartsCDN = false
+
+	w := httptest.NewRecorder()
+	handleEChartsAsset(w, httptest.NewRequest("GET", "/static/echarts.min.js", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/javascript") {
+		t.Fatalf("status=%d content-type=%q, want 200 javascript", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "var echarts") {
+		t.Fatalf("body=%q", w.Body.String())
+	}
+
+	// 只提供 .js，未内置的主题 404
+	for _, path := range []string{"/static/themes/../README.md", "/static/themes/dark.js"} {
+		w = httptest.NewRecorder()
+		handleEChartsAsset(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s status=%d, want 404", path, w.Code)
+		}
+	}
+
+	// --echarts-cdn：缺失的脚本重定向到 CDN
+	cfg.EChartsCDN = true
+	w = httptest.NewRecorder()
+	handleEChartsAsset(w, httptest.NewRequest("GET", "/static/themes/dark.js", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != echartsCDNHost+"themes/dark.js" {
+		t.Fatalf("status=%d location=%q, want redirect to CDN", w.Code, w.Header().Get("Location"))
+	}
+}
+
+// TestEmbeddedEChartsAssetServed 使用真实的内置文件（make build/test 时自动下载到 static/vendor）
+func TestEmbeddedEChartsAssetServed(t *testing.T) {
+	if _, err := fs.Stat(vendorFS, "static/vendor/echarts.min.js"); err != nil {
+		t.Skip("static/vendor 未包含 echarts.min.js，运行 make vendor-echarts 后再测试")
+	}
+	origCDN := cfg.EChartsCDN
+	defer func() { cfg.EChartsCDN = origCDN }()
+	cfg.EChartsCDN = false
+
+	for _, path := range []string{"/static/echarts.min.js", "/static/themes/wonderland.js"} {
+		w := httptest.NewRecorder()
+		handleEChartsAsset(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/javascript") {
+			t.Fatalf("%s status=%d content-type=%q, want 200 javascript", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Body.String(), "echarts") {
+			t.Fatalf("%s 内容不是 ECharts 脚本", path)
+		}
+	}
+}
+
+func TestRenderDashboardInlinesEmbeddedECharts(t *testing.T) {
+	useEChartsAssets(t, fstest.MapFS{
+		"echarts.min.js": {Data: []byte("/* embedded echarts */")},
+	})
+	origCDN := cfg.EChartsCDN
+	defer func() { cfg.EChartsCDN = origCDN }()
+	data := &DashboardData{HourlyCounts: map[string]int{}}
+
+	cfg.EChartsCDN = false
+	var buf bytes.Buffer
+	if err := RenderDashboard(&buf, data); err != nil {
+		t.Fatalf("RenderDashboard() error = %v", err)
+	}
+	html := buf.String()
+	if !strings.Contains(html, "/* embedded echarts */") || strings.Contains(html, `src="`+echartsCDNHost+`echarts.min.js"`) {
+		t.Fatal("默认应内联内置的 echarts.min.js")
+	}
+	// 未内置的主题脚本退回 CDN
+	if !strings.Contains(html, `src="`+echartsCDNHost+`themes/wonderland.js"`) {
+		t.Fatal("未内置的主题脚本应引用 CDN")
+	}
+
+	cfg.EChartsCDN = true
+	buf.Reset()
+	if err := RenderDashboard(&buf, data); err != nil {
+		t.Fatalf("RenderDashboard() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "/* embedded echarts */") || !strings.Contains(buf.String(), `src="`+echartsCDNHost+`echarts.min.js"`) {
+		t.Fatal("--echarts-cdn 时应引用 CDN")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stringListValue 逗号分隔的字符串列表 flag，可重复指定（逐次累加），空项忽略
+type stringListValue struct {
+	target *[]string
+}
+
+func (v stringListValue) String() string {
+	if v.target == nil {
+		return ""
+	}
+	return strings.Join(*v.target, ",")
+}
+
+func (v stringListValue) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v.target = append(*v.target, item)
+		}
+	}
+	return nil
+}
+
+// isExcludedCommand 命令名与 cfg.ExcludeCommands 中某项完全相同时排除（如 /clear）
+func isExcludedCommand(command string) bool {
+	for _, excluded := range cfg.ExcludeCommands {
+		if command == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcludedProject 项目路径等于 cfg.ExcludeProjects 中某项或位于其目录下时排除。
+// 按路径分段匹配：/tmp/scratch 排除 /tmp/scratch/a，但不排除 /tmp/scratchpad。
+func isExcludedProject(project string) bool {
+	if project == "" {
+		return false
+	}
+	for _, prefix := range cfg.ExcludeProjects {
+		prefix = strings.TrimRight(prefix, string(filepath.Separator))
+		if project == prefix || strings.HasPrefix(project, prefix+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeProjectsCacheKey 缓存中记录的项目排除规则；规则变化后文件级快照不可复用
+func excludeProjectsCacheKey() string {
+	if len(cfg.ExcludeProjects) == 0 {
+		return ""
+	}
+	prefixes := append([]string(nil), cfg.ExcludeProjects...)
+	sort.Strings(prefixes)
+	return strings.Join(prefixes, "\n")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// exportFlushEvery 流式导出每写出多少行刷新一次，兼顾客户端及时收到数据与系统调用次数
+const exportFlushEvery = 200
+
+// handleExportSessionsJSONL /api/export/sessions.jsonl：以 JSON Lines 逐条输出 SessionInfo，
+// 查询参数同 /api/sessions（preset、start/end、user_type 等）。
+// 不构建完整响应体，适合导出全部历史；开始写出后出错只能中断连接并记录日志。
+func handleExportSessionsJSONL(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setHeaders := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="sessions.jsonl"`)
+	}
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	written := 0
+	err = WalkSessionList(filter.TimeFilter, func(info SessionInfo) error {
+		if written == 0 {
+			setHeaders()
+		}
+		if err := encoder.Encode(info); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if written == 0 {
+			sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		Warn("会话导出中断", "written", written, "error", err.Error())
+		return
+	}
+	if written == 0 {
+		// 范围内没有会话：返回空文件而不是 404，便于脚本统一处理
+		setHeaders()
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportSessionsJSONLStreamsOneSessionPerLine(t *testing.T) {
+	useFixtureDataDir(t)
+
+	// 经过 MetricsMiddleware，确认包装后的 ResponseWriter 仍可 Flush
+	server := httptest.NewServer(MetricsMiddleware(http.HandlerFunc(handleExportSessionsJSONL)))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/export/sessions.jsonl?preset=all")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		t.Fatalf("status=%d content-type=%q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var got []SessionInfo
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var info SessionInfo
+		if err := json.Unmarshal(scanner.Bytes(), &info); err != nil {
+			t.Fatalf("第 %d 行不是合法 JSON: %v", len(got)+1, err)
+		}
+		got = append(got, info)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("读取响应失败: %v", err)
+	}
+
+	want, err := ParseSessionList(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseSessionList() error = %v", err)
+	}
+	if len(got) != 3 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("导出会话 = %+v\nwant %+v", got, want)
+	}
+}
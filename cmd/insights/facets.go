@@ -0,0 +1,48 @@
+package main
+
+import "sort"
+
+// DashboardFacets /api/data?facets=1 附带的去重名称列表，供前端筛选控件填充选项，
+// 无需读取完整的用量明细。均按名称升序，取自同一响应中的明细数组
+type DashboardFacets struct {
+	Models   []string `json:"models"`   // model_usage 中的模型
+	Commands []string `json:"commands"` // commands 中的 slash command（breakdown=args 时按命令名去重）
+	Tools    []string `json:"tools"`    // tool_analysis.tools 中的工具
+}
+
+// buildDashboardFacets 从已过滤的 DashboardData 提取去重后的模型、命令与工具名
+func buildDashboardFacets(data *DashboardData) *DashboardFacets {
+	facets := &DashboardFacets{
+		Models:   make([]string, 0, len(data.ModelUsage)),
+		Commands: make([]string, 0, len(data.Commands)),
+		Tools:    []string{},
+	}
+	for _, item := range data.ModelUsage {
+		facets.Models = append(facets.Models, item.Model)
+	}
+	for _, item := range data.Commands {
+		facets.Commands = append(facets.Commands, item.Command)
+	}
+	if data.ToolAnalysis != nil {
+		for _, item := range data.ToolAnalysis.Tools {
+			facets.Tools = append(facets.Tools, item.Tool)
+		}
+	}
+	facets.Models = sortedDistinct(facets.Models)
+	facets.Commands = sortedDistinct(facets.Commands)
+	facets.Tools = sortedDistinct(facets.Tools)
+	return facets
+}
+
+// sortedDistinct 原地排序并去掉重复与空字符串
+func sortedDistinct(names []string) []string {
+	sort.Strings(names)
+	out := names[:0]
+	for i, name := range names {
+		if name == "" || (i > 0 && name == names[i-1]) {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDataAPIFacetsMatchDetailArrays(t *testing.T) {
+	useFixtureDataDir(t)
+
+	fetch := func(query string) DashboardData {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleDataAPI(w, httptest.NewRequest("GET", "/api/data?"+query, nil))
+		var resp struct {
+			Success bool          `json:"success"`
+			Data    DashboardData `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.Success {
+			t.Fatalf("响应异常: err=%v body=%s", err, w.Body.String())
+		}
+		return resp.Data
+	}
+
+	if data := fetch("preset=all"); data.Facets != nil {
+		t.Fatalf("未传 facets=1 时不应返回 facets: %+v", data.Facets)
+	}
+
+	data := fetch("preset=all&facets=1")
+	if data.Facets == nil {
+		t.Fatal("facets=1 时应返回 facets")
+	}
+	distinct := func(names []string) []string {
+		seen := map[string]bool{}
+		out := []string{}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	var models, commands, tools []string
+	for _, item := range data.ModelUsage {
+		models = append(models, item.Model)
+	}
+	for _, item := range data.Commands {
+		commands = append(commands, item.Command)
+	}
+	if data.ToolAnalysis != nil {
+		for _, item := range data.ToolAnalysis.Tools {
+			tools = append(tools, item.Tool)
+		}
+	}
+	if len(models) == 0 || len(commands) == 0 || len(tools) == 0 {
+		t.Fatalf("fixture 应同时包含模型、命令与工具: models=%v commands=%v tools=%v", models, commands, tools)
+	}
+	for name, pair := range map[string][2][]string{
+		"models":   {data.Facets.Models, distinct(models)},
+		"commands": {data.Facets.Commands, distinct(commands)},
+		"tools":    {data.Facets.Tools, distinct(tools)},
+	} {
+		if !reflect.DeepEqual(pair[0], pair[1]) {
+			t.Fatalf("facets.%s = %v, want %v", name, pair[0], pair[1])
+		}
+	}
+}
+
+func TestSortedDistinct(t *testing.T) {
+	got := sortedDistinct([]string{"/model", "", "/clear", "/model", "/clear"})
+	if want := []string{"/clear", "/model"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sortedDistinct = %v, want %v", got, want)
+	}
+}
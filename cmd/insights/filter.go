@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,10 +19,55 @@ const (
 	Range24Hours RangePreset = "24h"
 )
 
-// TimeFilter 时间过滤器
+// rangePresets 可直接选用的预设（不含 custom），按跨度从短到长排列，供 /api/presets 列出
+var rangePresets = []RangePreset{Range24Hours, Range7Days, Range30Days, Range90Days, RangeAll}
+
+// TimeFilter 时间过滤器（附带 projects 记录的 userType 过滤）
 type TimeFilter struct {
 	Start *time.Time
 	End   *time.Time
+	// UserType 非空时 projects 解析只统计 userType 相同的记录（如 external），用于排除自动注入的消息
+	UserType string
+	// Exact 按记录时间戳精确过滤（起止可落在一天中间）；缓存按天聚合，精确过滤时改为实时解析
+	Exact bool
+	// CountMode 消息数统计哪些记录：空（等同 assistant）、user 或 all；缓存只有 assistant 口径
+	CountMode string
+}
+
+// 消息计数口径
+const (
+	CountModeAssistant = "assistant"
+	CountModeUser      = "user"
+	CountModeAll       = "all"
+)
+
+// normalizeCountMode 校验 countMode 参数；assistant 为默认口径，归一化为空
+func normalizeCountMode(raw string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "", CountModeAssistant:
+		return "", nil
+	case CountModeUser, CountModeAll:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("无效的 count_mode: %s（可选 assistant、user、all）", raw)
+	}
+}
+
+// countsRecordType 该类型的记录是否按 CountMode 计入消息数
+func (tf TimeFilter) countsRecordType(recordType string) bool {
+	switch tf.CountMode {
+	case CountModeUser:
+		return recordType == "user"
+	case CountModeAll:
+		return recordType == "user" || recordType == "assistant"
+	default:
+		return recordType == "assistant"
+	}
+}
+
+// bypassesCache 过滤条件无法由按天聚合、不区分 userType 与计数口径的缓存回答，需要实时解析
+func (tf TimeFilter) bypassesCache() bool {
+	return tf.UserType != "" || tf.Exact || tf.CountMode != ""
 }
 
 // NewTimeFilterFromPreset 从预设创建时间过滤器
@@ -67,26 +115,85 @@ func NewTimeFilterFromPreset(preset RangePreset) TimeFilter {
 	}
 }
 
-// NewTimeFilterCustom 创建自定义时间过滤器
+// NewTimeFilterCustom 创建自定义时间过滤器。
+// start/end 支持 YYYY-MM-DD 绝对日期，也支持相对当前时刻的表达式（见 parseRangeBound）
 func NewTimeFilterCustom(start, end string) (TimeFilter, error) {
-	layout := "2006-01-02"
-	s, err := time.Parse(layout, start)
-	if err != nil {
-		return TimeFilter{}, err
-	}
-	e, err := time.Parse(layout, end)
+	s, e, err := resolveCustomRange(start, end, time.Now())
 	if err != nil {
 		return TimeFilter{}, err
 	}
-	// 设置结束时间为当天的 23:59:59
-	e = time.Date(e.Year(), e.Month(), e.Day(), 23, 59, 59, 0, time.Local)
-
 	return TimeFilter{
 		Start: &s,
 		End:   &e,
 	}, nil
 }
 
+// resolveCustomRange 把自定义范围的起止表达式解析为具体时间
+func resolveCustomRange(start, end string, now time.Time) (time.Time, time.Time, error) {
+	s, err := parseRangeBound(start, now, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	e, err := parseRangeBound(end, now, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return s, e, nil
+}
+
+// parseRangeBound 解析自定义范围的一端：
+//   - YYYY-MM-DD：绝对日期，作为结束端时取当天 23:59:59.999999999
+//   - now：当前时刻
+//   - -Nd / -Nw / -Nm：当前时刻往前 N 天 / 周 / 月
+func parseRangeBound(expr string, now time.Time, isEnd bool) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(expr, "-") && len(expr) >= 3 {
+		n, err := strconv.Atoi(expr[1 : len(expr)-1])
+		if err == nil && n >= 0 && !strings.HasPrefix(expr[1:], "+") {
+			switch expr[len(expr)-1] {
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			case 'm':
+				return now.AddDate(0, -n, 0), nil
+			}
+		}
+	}
+	t, err := time.Parse("2006-01-02", expr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无法解析时间 %q：应为 YYYY-MM-DD、now 或 -Nd/-Nw/-Nm（天/周/月）", expr)
+	}
+	if isEnd {
+		// 结束时间取当天最后一纳秒：Contains 按闭区间比较，取 23:59:59 会漏掉 23:59:59.5 这类带小数秒的记录
+		t = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, int(time.Second-time.Nanosecond), time.Local)
+	}
+	return t, nil
+}
+
+// validateCustomRangeSpan 校验自定义范围：结束日期不早于开始日期，且跨度（含首尾）不超过 maxDays 天。
+// maxDays <= 0 时不限制跨度；start/end 须已通过 NewTimeFilterCustom 的格式校验
+func validateCustomRangeSpan(start, end string, maxDays int) error {
+	s, e, err := resolveCustomRange(start, end, time.Now())
+	if err != nil {
+		return err
+	}
+	// 按日历日比较，相对表达式落在当天任意时刻都按整天计
+	sDay := time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, time.UTC)
+	eDay := time.Date(e.Year(), e.Month(), e.Day(), 0, 0, 0, 0, time.UTC)
+	if eDay.Before(sDay) {
+		return fmt.Errorf("结束日期 %s 早于开始日期 %s", end, start)
+	}
+	days := int(eDay.Sub(sDay)/(24*time.Hour)) + 1
+	if maxDays > 0 && days > maxDays {
+		return fmt.Errorf("自定义时间范围 %s ~ %s 共 %d 天，超过上限 %d 天；请缩小范围或改用预设范围（preset）", start, end, days, maxDays)
+	}
+	return nil
+}
+
 // Contains 检查时间是否在范围内
 func (tf TimeFilter) Contains(t time.Time) bool {
 	if tf.Start == nil && tf.End == nil {
@@ -104,6 +211,11 @@ func (tf TimeFilter) Contains(t time.Time) bool {
 	return true
 }
 
+// MatchesUserType 检查 projects 记录的 userType 是否满足过滤条件；未设置 UserType 时全部统计
+func (tf TimeFilter) MatchesUserType(userType string) bool {
+	return tf.UserType == "" || userType == tf.UserType
+}
+
 // FilterHistoryRecords 过滤历史记录
 func FilterHistoryRecords(records []HistoryRecord, tf TimeFilter) []HistoryRecord {
 	if tf.Start == nil && tf.End == nil {
@@ -112,8 +224,7 @@ func FilterHistoryRecords(records []HistoryRecord, tf TimeFilter) []HistoryRecor
 
 	result := make([]HistoryRecord, 0)
 	for _, record := range records {
-		// timestamp 是毫秒
-		t := time.Unix(record.Timestamp/1000, 0)
+		t := parseHistoryTimestamp(record.Timestamp)
 		if tf.Contains(t) {
 			result = append(result, record)
 		}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 基于 testdata/fixture 的端到端测试：各解析器、缓存构建与 Dashboard 组装对同一数据集给出精确一致的数字
+
+func TestFixtureParsersExactTotals(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	projects := make(map[string]ProjectStatItem)
+	messages := 0
+	for _, project := range agg.Projects {
+		projects[project.Project] = project
+		messages += project.MessageCount
+	}
+	if len(projects) != 2 || messages != 5 {
+		t.Fatalf("projects=%d messages=%d, want 2/5", len(projects), messages)
+	}
+	if alpha := projects["/tmp/alpha"]; alpha.MessageCount != 2 || alpha.Tokens != 180 || alpha.ActiveDays != 1 || alpha.PrimaryModel != "claude-sonnet-4-6" {
+		t.Fatalf("alpha=%+v", alpha)
+	}
+	if beta := projects["/tmp/beta"]; beta.MessageCount != 3 || beta.Tokens != 287 || beta.ActiveDays != 2 || beta.LongestStreak != 2 || beta.PrimaryModel != "claude-opus-4-6" {
+		t.Fatalf("beta=%+v", beta)
+	}
+
+	wantDaily := map[string]int{"2026-01-05": 2, "2026-01-06": 1, "2026-01-07": 2}
+	if len(agg.DailyActivityList) != len(wantDaily) {
+		t.Fatalf("daily=%+v, want %v", agg.DailyActivityList, wantDaily)
+	}
+	for _, day := range agg.DailyActivityList {
+		if wantDaily[day.Date] != day.MessageCount {
+			t.Fatalf("daily %s=%d, want %d", day.Date, day.MessageCount, wantDaily[day.Date])
+		}
+	}
+
+	models := make(map[string]ModelUsageItem)
+	for _, item := range agg.ModelUsageList {
+		models[item.Model] = item
+	}
+	if len(models) != 2 || models["claude-sonnet-4-6"].Count != 2 || models["claude-sonnet-4-6"].Tokens != 180 ||
+		models["claude-opus-4-6"].Count != 3 || models["claude-opus-4-6"].Tokens != 287 {
+		t.Fatalf("models=%+v", agg.ModelUsageList)
+	}
+
+	if agg.ToolAnalysis == nil {
+		t.Fatal("ToolAnalysis 为 nil")
+	}
+	tools := make(map[string]int)
+	for _, tool := range agg.ToolAnalysis.Tools {
+		tools[tool.Tool] = tool.CallCount
+	}
+	if len(tools) != 2 || tools["Bash"] != 1 || tools["Read"] != 1 {
+		t.Fatalf("tools=%v, want Bash=1 Read=1", tools)
+	}
+
+	sessions, err := extractSessionStatsFromAggregate(agg)
+	if err != nil {
+		t.Fatalf("extractSessionStatsFromAggregate failed: %v", err)
+	}
+	if sessions.TotalSessions != 3 {
+		t.Fatalf("TotalSessions=%d, want 3", sessions.TotalSessions)
+	}
+
+	commands, _, err := ParseHistoryWithFilter(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryWithFilter failed: %v", err)
+	}
+	gotCommands := make(map[string]int)
+	for _, item := range commands {
+		gotCommands[item.Command] = item.Count
+	}
+	if len(gotCommands) != 3 || gotCommands["/help"] != 2 || gotCommands["/model"] != 1 || gotCommands["/compact"] != 1 {
+		t.Fatalf("commands=%v, want /help=2 /model=1 /compact=1", gotCommands)
+	}
+
+	signals, err := ParseDebugLogsConcurrentFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseDebugLogsConcurrentFromDir failed: %v", err)
+	}
+	gotSignals := make(map[string]int)
+	for _, signal := range signals {
+		gotSignals[signal.Server+"::"+signal.Tool] = signal.Count
+	}
+	if len(gotSignals) != 2 || gotSignals["github::get_file_contents"] != 2 || gotSignals["crawl::extract_url"] != 1 {
+		t.Fatalf("debug signals=%v", gotSignals)
+	}
+
+	statsCache, err := ParseStatsCache()
+	if err != nil {
+		t.Fatalf("ParseStatsCache failed: %v", err)
+	}
+	statsMessages := 0
+	for _, day := range statsCache.DailyActivity {
+		statsMessages += day.MessageCount
+	}
+	if len(statsCache.DailyActivity) != 3 || statsMessages != messages {
+		t.Fatalf("stats-cache 每日活动=%+v，合计应为 %d", statsCache.DailyActivity, messages)
+	}
+}
+
+func TestFixtureCacheMatchesParsing(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+	tf := NewTimeFilterFromPreset("all")
+
+	parsed, err := buildDataFromParsing(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromParsing failed: %v", err)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	if cache.TotalMessages != 5 || cache.TotalSessions != 3 {
+		t.Fatalf("cache totals messages=%d sessions=%d, want 5/3", cache.TotalMessages, cache.TotalSessions)
+	}
+	storeGlobalCache(cache)
+	cached, err := buildDataFromCache(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+
+	for _, data := range []*DashboardData{parsed, cached} {
+		if data.ProjectStats.TotalMessages != 5 || len(data.ProjectStats.Projects) != 2 {
+			t.Fatalf("project_stats=%+v", data.ProjectStats)
+		}
+		if len(data.DailyTrend.Dates) != 3 {
+			t.Fatalf("daily_trend=%+v", data.DailyTrend)
+		}
+		if len(data.ModelUsage) != 2 || data.ModelUsage[0].Model != "claude-opus-4-6" || data.ModelUsage[0].Count != 3 {
+			t.Fatalf("model_usage=%+v", data.ModelUsage)
+		}
+		if len(data.Commands) != 3 || data.Commands[0].Command != "/help" || data.Commands[0].Count != 2 {
+			t.Fatalf("commands=%+v", data.Commands)
+		}
+	}
+	// 实时解析的 runtime_tools 来自 debug 日志；缓存只统计项目记录中的 mcp__ 工具调用（fixture 中没有）
+	if len(parsed.RuntimeTools) != 2 || parsed.RuntimeTools[0].Count != 2 {
+		t.Fatalf("runtime_tools=%+v", parsed.RuntimeTools)
+	}
+	if report := buildDashboardConsistencyReport(cached); len(report.Issues) > 0 {
+		t.Fatalf("缓存数据一致性问题: %v", report.Issues)
+	}
+}
+
+func TestFixtureAllRangeReportsDataExtents(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+	tf := NewTimeFilterFromPreset("all")
+
+	parsed, err := buildDataFromParsing(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromParsing failed: %v", err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	loaded, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	storeGlobalCache(loaded)
+	cached, err := buildDataFromCache(tf, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	want := TimeRangeInfo{Preset: "all", Start: "2026-01-05", End: "2026-01-07"}
+	for source, data := range map[string]*DashboardData{"parsing": parsed, "cache": cached} {
+		if data.TimeRange != want {
+			t.Fatalf("%s time_range=%+v, want %+v", source, data.TimeRange, want)
+		}
+	}
+
+	// 只限定起点时，终点取数据中的最晚日期
+	start := time.Date(2026, 1, 6, 0, 0, 0, 0, time.Local)
+	data, err := buildDataFromParsing(TimeFilter{Start: &start}, "custom")
+	if err != nil {
+		t.Fatalf("buildDataFromParsing failed: %v", err)
+	}
+	if data.TimeRange.Start != "2026-01-06" || data.TimeRange.End != "2026-01-07" {
+		t.Fatalf("custom time_range=%+v", data.TimeRange)
+	}
+}
+
+func TestFixtureRecordSamplesMatchClassification(t *testing.T) {
+	useFixtureDataDir(t)
+
+	fetch := func(query string) DashboardData {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/data?preset=all"+query, nil)
+		w := httptest.NewRecorder()
+		handleDataAPI(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("状态码 = %d; body=%s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data DashboardData `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("无法解析响应 JSON: %v", err)
+		}
+		return resp.Data
+	}
+	// lineAt 读取样例指向的原始行
+	lineAt := func(sample RecordSample) []byte {
+		t.Helper()
+		data, err := os.ReadFile(sample.File)
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", sample.File, err)
+		}
+		lines := strings.Split(string(data), "\n")
+		if sample.Line < 1 || sample.Line > len(lines) {
+			t.Fatalf("行号越界: %+v", sample)
+		}
+		return []byte(lines[sample.Line-1])
+	}
+
+	if data := fetch(""); len(data.Samples) != 0 {
+		t.Fatalf("默认不应返回样例: %+v", data.Samples)
+	}
+
+	data := fetch("&samples=5")
+	counts := make(map[string]int)
+	for _, item := range data.ModelUsage {
+		counts[item.Model] = item.Count
+	}
+	if len(counts) != 2 || len(data.Samples) != len(counts) {
+		t.Fatalf("samples=%+v, want one group per model %v", data.Samples, counts)
+	}
+	for _, group := range data.Samples {
+		if group.Metric != SampleMetricModel || len(group.Records) != counts[group.Key] {
+			t.Fatalf("模型 %s 样例数=%d，want %d", group.Key, len(group.Records), counts[group.Key])
+		}
+		for _, sample := range group.Records {
+			var record struct {
+				Type    string `json:"type"`
+				Message struct {
+					Model string `json:"model"`
+				} `json:"message"`
+			}
+			if err := json.Unmarshal(lineAt(sample), &record); err != nil {
+				t.Fatalf("样例行不是合法 JSON: %+v: %v", sample, err)
+			}
+			if record.Type != "assistant" || record.Message.Model != group.Key {
+				t.Fatalf("样例 %+v 实际为 %s/%s，与分类 %s 不符", sample, record.Type, record.Message.Model, group.Key)
+			}
+		}
+	}
+
+	data = fetch("&samples=1&sample_metric=command&sample_key=/help")
+	if len(data.Samples) != 1 || data.Samples[0].Key != "/help" || len(data.Samples[0].Records) != 1 {
+		t.Fatalf("command samples=%+v", data.Samples)
+	}
+	var history HistoryRecord
+	if err := json.Unmarshal(lineAt(data.Samples[0].Records[0]), &history); err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := slashCommandKey(history.Display, false); !ok || key.Command != "/help" {
+		t.Fatalf("样例行 display=%q 不属于 /help", history.Display)
+	}
+}
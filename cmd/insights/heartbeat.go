@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// 心跳状态文件：web 模式下定期把运行状态写到 --status-file，供外部监控/sidecar 读取，
+// 无需访问 HTTP 接口即可判断服务是否存活、数据是否按时刷新。
+
+// globalCacheLoadedAt 最近一次成功加载缓存的时间（UnixNano，0 表示尚未加载）
+var globalCacheLoadedAt atomic.Int64
+
+// serverStatus 状态文件内容
+type serverStatus struct {
+	Status        string `json:"status"`
+	PID           int    `json:"pid"`
+	Version       string `json:"version"`
+	StartedAt     string `json:"started_at"`
+	UpdatedAt     string `json:"updated_at"`
+	UptimeSec     int64  `json:"uptime_sec"`
+	LastReloadAt  string `json:"last_reload_at,omitempty"`
+	CacheLoaded   bool   `json:"cache_loaded"`
+	TotalMessages int    `json:"total_messages"`
+	TotalSessions int    `json:"total_sessions"`
+}
+
+// buildServerStatus 汇总当前进程与给定缓存的状态
+func buildServerStatus(cache *CacheFile, startedAt, now time.Time) serverStatus {
+	status := serverStatus{
+		Status:    "ok",
+		PID:       os.Getpid(),
+		Version:   version,
+		StartedAt: startedAt.Format(time.RFC3339),
+		UpdatedAt: now.Format(time.RFC3339),
+		UptimeSec: int64(now.Sub(startedAt).Seconds()),
+	}
+	if loadedAt := globalCacheLoadedAt.Load(); loadedAt > 0 {
+		status.LastReloadAt = time.Unix(0, loadedAt).Format(time.RFC3339)
+	}
+	if cache != nil {
+		status.CacheLoaded = true
+		status.TotalMessages = cache.TotalMessages
+		status.TotalSessions = cache.TotalSessions
+	} else {
+		status.Status = "degraded"
+	}
+	return status
+}
+
+// writeStatusFile 以原子方式写入状态文件：先写同目录临时文件再 rename，读者不会看到半截 JSON。
+func writeStatusFile(path string, status serverStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// renameFile 临时文件替换目标文件，测试可替换以模拟写入失败
+var renameFile = os.Rename
+
+// writeFileAtomic 写入同目录临时文件后 rename 覆盖目标文件；任一步失败都会删除临时文件，目标文件保持原样
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	if err := renameFile(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替换文件失败: %w", err)
+	}
+	return nil
+}
+
+// startStatusHeartbeat 立即写一次状态文件，之后按 interval 周期用 snapshot 的结果刷新；返回的函数用于停止心跳。
+func startStatusHeartbeat(path string, interval time.Duration, snapshot func() serverStatus) func() {
+	write := func() {
+		if err := writeStatusFile(path, snapshot()); err != nil {
+			Warn("写入状态文件失败", "path", path, "error", err.Error())
+		}
+	}
+	write()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				write()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readStatusFile(t *testing.T, path string) serverStatus {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取状态文件失败: %v", err)
+	}
+	var status serverStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatalf("状态文件不是合法 JSON: %v\n%s", err, data)
+	}
+	return status
+}
+
+func TestStatusHeartbeatWritesAndUpdatesTotals(t *testing.T) {
+	// 心跳 goroutine 通过原子指针读取当前缓存，模拟 reload 替换缓存
+	var current atomic.Pointer[CacheFile]
+	current.Store(&CacheFile{TotalMessages: 10, TotalSessions: 2})
+	startedAt := time.Now().Add(-time.Minute)
+	snapshot := func() serverStatus {
+		return buildServerStatus(current.Load(), startedAt, time.Now())
+	}
+
+	path := filepath.Join(t.TempDir(), "run", "status.json")
+	stop := startStatusHeartbeat(path, 20*time.Millisecond, snapshot)
+	defer stop()
+
+	status := readStatusFile(t, path)
+	if status.Status != "ok" || !status.CacheLoaded || status.TotalMessages != 10 || status.TotalSessions != 2 {
+		t.Fatalf("初始状态不符: %+v", status)
+	}
+	if status.UptimeSec < 60 || status.PID != os.Getpid() {
+		t.Fatalf("uptime/pid 不符: %+v", status)
+	}
+
+	// 缓存刷新后，下一次心跳应写入新的合计
+	current.Store(&CacheFile{TotalMessages: 25, TotalSessions: 4})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status = readStatusFile(t, path)
+		if status.TotalMessages == 25 && status.TotalSessions == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("状态文件未随缓存更新: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("目录中残留临时文件: %v", entries)
+	}
+}
+
+func TestBuildServerStatusWithoutCache(t *testing.T) {
+	now := time.Now()
+	status := buildServerStatus(nil, now.Add(-5*time.Second), now)
+	if status.Status != "degraded" || status.CacheLoaded || status.UptimeSec != 5 {
+		t.Fatalf("无缓存时状态不符: %+v", status)
+	}
+}
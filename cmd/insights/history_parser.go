@@ -3,8 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -12,55 +12,159 @@ import (
 
 // ParseHistoryWithFilter 带时间过滤解析 history.jsonl
 func ParseHistoryWithFilter(tf TimeFilter) ([]CommandStats, map[string]int, error) {
-	path := GetDataPath("history.jsonl")
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, nil, fmt.Errorf("打开 history.jsonl 失败: %w", err)
+	return parseHistoryWithFilter(tf, false)
+}
+
+// ParseHistoryWithArgs 同 ParseHistoryWithFilter，但按「命令 + 首个参数」拆分统计
+func ParseHistoryWithArgs(tf TimeFilter) ([]CommandStats, map[string]int, error) {
+	return parseHistoryWithFilter(tf, true)
+}
+
+func parseHistoryWithFilter(tf TimeFilter, withArgs bool) ([]CommandStats, map[string]int, error) {
+	return parseHistoryFiles(historyFilePaths(), tf, withArgs)
+}
+
+// historyShardPattern history.jsonl 及其轮转分片（如 history-2025-12.jsonl）
+const historyShardPattern = "history*.jsonl"
+
+// historyFilePaths 数据目录下全部 history 分片，按文件名排序；
+// 一个都没有时仍返回 history.jsonl，由调用方按单文件缺失处理
+func historyFilePaths() []string {
+	return historyFilePathsIn(cfg.DataDir)
+}
+
+// historyFilePathsIn 同 historyFilePaths，数据目录由 dir 指定
+func historyFilePathsIn(dir string) []string {
+	var paths []string
+	if entries, err := dataSource.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if matched, _ := filepath.Match(historyShardPattern, entry.Name()); matched {
+				paths = append(paths, filepath.Join(dir, entry.Name()))
+			}
+		}
 	}
-	defer f.Close()
+	if len(paths) == 0 {
+		return []string{filepath.Join(dir, "history.jsonl")}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// parseHistoryFile 解析指定路径的 history 格式文件
+func parseHistoryFile(path string, tf TimeFilter, withArgs bool) ([]CommandStats, map[string]int, error) {
+	return parseHistoryFiles([]string{path}, tf, withArgs)
+}
 
-	cmdCounts := make(map[string]int)
+// parseHistoryFiles 依次解析多个 history 格式文件，合并命令与小时统计
+func parseHistoryFiles(paths []string, tf TimeFilter, withArgs bool) ([]CommandStats, map[string]int, error) {
+	cmdCounts := make(map[commandKey]int)
 	hourlyCounts := make(map[string]int)
+	for _, path := range paths {
+		if err := countHistoryFile(path, tf, withArgs, cmdCounts, hourlyCounts); err != nil {
+			return nil, nil, err
+		}
+	}
+	return commandStatsFromCounts(cmdCounts), hourlyCounts, nil
+}
+
+func countHistoryFile(path string, tf TimeFilter, withArgs bool, cmdCounts map[commandKey]int, hourlyCounts map[string]int) error {
+	f, err := openDataFile(path)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
 
-	decoder := json.NewDecoder(f)
+	lines := newJSONLReader(f)
 	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
 		var record HistoryRecord
-		if err := decoder.Decode(&record); err != nil {
-			if err == io.EOF {
-				break
-			}
+		if err := json.Unmarshal(line, &record); err != nil {
 			continue
 		}
 
 		// 时间过滤
-		recordTime := time.Unix(record.Timestamp/1000, 0)
-		if !tf.Contains(recordTime) {
+		recordTime := parseHistoryTimestamp(record.Timestamp)
+		if !tf.Contains(recordTime) || isExcludedProject(record.Project) {
 			continue
 		}
 
 		// 统计 slash commands
-		if strings.HasPrefix(record.Display, "/") {
-			parts := strings.Fields(record.Display)
-			if len(parts) > 0 {
-				cmdCounts[parts[0]]++
-			}
+		if key, ok := slashCommandKey(record.Display, withArgs); ok && !isExcludedCommand(key.Command) {
+			cmdCounts[key]++
 		}
 
 		// 统计小时分布
 		hour := fmt.Sprintf("%02d", recordTime.Hour())
 		hourlyCounts[hour]++
 	}
+	return nil
+}
 
-	// 转换为切片并排序
-	var cmdStats []CommandStats
-	for cmd, count := range cmdCounts {
-		cmdStats = append(cmdStats, CommandStats{Command: cmd, Count: count})
+// historySecondsThreshold 小于该值的 history 时间戳按秒解释：1e11 毫秒约为 1973 年，
+// 而 1e11 秒已是公元 5000 年以后，两种单位在这里不会混淆。
+const historySecondsThreshold = 100_000_000_000
+
+// parseHistoryTimestamp 解析 history.jsonl 的 timestamp：新版为毫秒，部分旧导出为秒
+func parseHistoryTimestamp(ts int64) time.Time {
+	if ts < historySecondsThreshold {
+		return time.Unix(ts, 0)
 	}
-	sort.Slice(cmdStats, func(i, j int) bool {
-		return cmdStats[i].Count > cmdStats[j].Count
-	})
+	return time.UnixMilli(ts)
+}
+
+// slashCommandArgCommands 首个参数是枚举值（而非自由文本）的内置命令，只有这些命令会按参数拆分。
+// 其他命令（含自定义命令）的参数多为 prose，拆分会把提示词原文带进统计。
+var slashCommandArgCommands = map[string]bool{
+	"/model":        true,
+	"/mcp":          true,
+	"/plugin":       true,
+	"/config":       true,
+	"/permissions":  true,
+	"/output-style": true,
+	"/theme":        true,
+	"/agents":       true,
+	"/hooks":        true,
+}
+
+// slashCommandArgPattern 允许作为子命令的参数形态：短标识符（opus、claude-sonnet-4-6、list）
+var slashCommandArgPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:@-]{0,47}$`)
 
-	return cmdStats, hourlyCounts, nil
+// commandKey 命令统计键：命令名 + 可选子命令
+type commandKey struct {
+	Command    string
+	SubCommand string
+}
+
+// slashCommandKey 从 history 的 display 中提取命令统计键；withArgs 时对枚举类命令保留首个参数。
+func slashCommandKey(display string, withArgs bool) (commandKey, bool) {
+	if !strings.HasPrefix(display, "/") {
+		return commandKey{}, false
+	}
+	parts := strings.Fields(display)
+	if len(parts) == 0 {
+		return commandKey{}, false
+	}
+	key := commandKey{Command: parts[0]}
+	if withArgs && len(parts) > 1 && slashCommandArgCommands[parts[0]] && slashCommandArgPattern.MatchString(parts[1]) {
+		key.SubCommand = strings.ToLower(parts[1])
+	}
+	return key, true
+}
+
+// commandStatsFromCounts 把计数 map 转为按次数降序的切片
+func commandStatsFromCounts(counts map[commandKey]int) []CommandStats {
+	var cmdStats []CommandStats
+	for key, count := range counts {
+		cmdStats = append(cmdStats, CommandStats{Command: key.Command, SubCommand: key.SubCommand, Count: count})
+	}
+	sortCommandStats(cmdStats)
+	return cmdStats
 }
 
 // ParseHistory 解析 history.jsonl（全部数据）
@@ -75,8 +179,9 @@ func ParseStatsCacheWithFilter(tf TimeFilter) (*StatsCache, error) {
 		return nil, err
 	}
 
-	// 过滤每日活动
+	// 过滤每日活动与每日 token
 	cache.DailyActivity = FilterDailyActivity(cache.DailyActivity, tf)
+	cache.DailyModelTokens = filterDailyModelTokens(cache.DailyModelTokens, tf)
 
 	return cache, nil
 }
@@ -84,28 +189,30 @@ func ParseStatsCacheWithFilter(tf TimeFilter) (*StatsCache, error) {
 // ParseStatsCache 解析 stats-cache.json
 func ParseStatsCache() (*StatsCache, error) {
 	path := GetDataPath("stats-cache.json")
-	data, err := os.ReadFile(path)
+	data, err := readDataFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("读取 stats-cache.json 失败: %w", err)
 	}
 
-	var cache StatsCache
-	if err := json.Unmarshal(data, &cache); err != nil {
+	cache, err := decodeStatsCache(data)
+	if err != nil {
 		return nil, fmt.Errorf("解析 stats-cache.json 失败: %w", err)
 	}
 
-	return &cache, nil
+	return cache, nil
 }
 
-// GetDailyTrend 获取每日趋势（最近7天）
+// GetDailyTrend 获取每日趋势（最近7天）。dates 与 counts 一一对应，均取自 stats-cache.json 的同一条记录
 func GetDailyTrend() ([]string, []int, error) {
 	cache, err := ParseStatsCache()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// 取最近7天
-	n := len(cache.DailyActivity)
+	// stats-cache.json 不保证按日期排序，先排序再取最近7天
+	activity := append([]DailyActivity(nil), cache.DailyActivity...)
+	sort.SliceStable(activity, func(i, j int) bool { return activity[i].Date < activity[j].Date })
+	n := len(activity)
 	start := 0
 	if n > 7 {
 		start = n - 7
@@ -113,9 +220,9 @@ func GetDailyTrend() ([]string, []int, error) {
 
 	var dates []string
 	var counts []int
-	for i := start; i < n; i++ {
-		dates = append(dates, cache.DailyActivity[i].Date)
-		counts = append(counts, cache.DailyActivity[i].MessageCount)
+	for _, day := range activity[start:] {
+		dates = append(dates, day.Date)
+		counts = append(counts, day.MessageCount)
 	}
 
 	return dates, counts, nil
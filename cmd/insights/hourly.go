@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 小时分布的分桶粒度
+const (
+	hourlyBin1h  = "1h"
+	hourlyBin30m = "30m"
+)
+
+// HourlyForDate 单日小时消息分布；Bin 为 30m 时 Hours 为 48 个半小时桶，Labels 给出各桶起点
+type HourlyForDate struct {
+	Date   string   `json:"date"`
+	Source string   `json:"source"`
+	Bin    string   `json:"bin"`
+	Hours  []int    `json:"hours"`
+	Labels []string `json:"labels,omitempty"`
+	Total  int      `json:"total"`
+}
+
+// halfHourBin 时间戳所在的半小时桶下标（0-47），如 14:45 落在 14:30 桶（29）
+func halfHourBin(t time.Time) int {
+	return t.Hour()*2 + t.Minute()/30
+}
+
+// parseHourlyBin 解析 bin 参数，缺省为 1h
+func parseHourlyBin(raw string) (string, error) {
+	switch bin := strings.TrimSpace(raw); bin {
+	case "", hourlyBin1h:
+		return hourlyBin1h, nil
+	case hourlyBin30m:
+		return hourlyBin30m, nil
+	default:
+		return "", fmt.Errorf("无效的 bin %q，可选 1h、30m", raw)
+	}
+}
+
+// halfHourLabels 48 个半小时桶的起点标签（00:00、00:30 … 23:30）
+func halfHourLabels() []string {
+	labels := make([]string, 48)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("%02d:%02d", i/2, i%2*30)
+	}
+	return labels
+}
+
+// ParseHourlyForDate 返回 date（YYYY-MM-DD）当天每小时的 assistant 消息数。
+// 日期与小时均按记录时间戳自身的时区划分，与 DayAggregate.HourlyCounts 口径一致；
+// 有缓存时直接读取当天的 DayAggregate，否则实时解析
+func ParseHourlyForDate(date string) ([24]int, string, error) {
+	hours, _, source, err := parseDayBinsForDate(date)
+	return hours, source, err
+}
+
+// parseDayBinsForDate 同时返回 date 当天的小时与半小时分布
+func parseDayBinsForDate(date string) ([24]int, [48]int, string, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return [24]int{}, [48]int{}, "", fmt.Errorf("无效的日期 %q，应为 YYYY-MM-DD", date)
+	}
+	if cache := loadGlobalCache(); cache != nil {
+		if stats := cache.DailyStats[date]; stats != nil {
+			return stats.HourlyCounts, stats.HalfHourCounts, "cache", nil
+		}
+		return [24]int{}, [48]int{}, "cache", nil
+	}
+
+	// 记录按自身时区归日，前后各放宽一天，再取该日期键下的小时分布
+	start := day.AddDate(0, 0, -1)
+	end := day.AddDate(0, 0, 2)
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{Start: &start, End: &end})
+	if err != nil {
+		return [24]int{}, [48]int{}, "parsing", err
+	}
+	return agg.DailyHourlyCounts[date], agg.DailyHalfHourCounts[date], "parsing", nil
+}
+
+// handleHourlyAPI /api/hourly?date=YYYY-MM-DD[&bin=30m]：单日 24 小时（或 48 个半小时）分布
+func handleHourlyAPI(w http.ResponseWriter, r *http.Request) {
+	date := strings.TrimSpace(r.URL.Query().Get("date"))
+	if date == "" {
+		sendError(w, "缺少 date 参数（YYYY-MM-DD）")
+		return
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		sendError(w, fmt.Sprintf("无效的日期 %q，应为 YYYY-MM-DD", date))
+		return
+	}
+	bin, err := parseHourlyBin(r.URL.Query().Get("bin"))
+	if err != nil {
+		sendError(w, err.Error())
+		return
+	}
+	hours, halfHours, source, err := parseDayBinsForDate(date)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, APIResponse{Success: false, Error: err.Error()})
+		return
+	}
+	result := HourlyForDate{Date: date, Source: source, Bin: bin, Hours: hours[:]}
+	if bin == hourlyBin30m {
+		result.Hours = halfHours[:]
+		result.Labels = halfHourLabels()
+	}
+	for _, count := range result.Hours {
+		result.Total += count
+	}
+	sendJSON(w, APIResponse{Success: true, Data: result})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseHourlyForDateCountsOnlyTargetDay(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := time.Date(2026, 1, 8, 14, 25, 0, 0, time.UTC)
+	content := projectRecordJSON("/tmp/demo", "s1", target) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", target.Add(20*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", target.Add(-5*time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s2", target.AddDate(0, 0, 1)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	check := func(wantSource string) {
+		t.Helper()
+		hours, source, err := ParseHourlyForDate("2026-01-08")
+		if err != nil {
+			t.Fatalf("ParseHourlyForDate() error = %v", err)
+		}
+		if source != wantSource || hours[14] != 2 || hours[9] != 1 {
+			t.Fatalf("source=%s hours=%v, want %s with hour14=2 hour9=1", source, hours, wantSource)
+		}
+		total := 0
+		for _, count := range hours {
+			total += count
+		}
+		if total != 3 {
+			t.Fatalf("total=%d, want 3（次日记录不应计入）", total)
+		}
+	}
+	check("parsing")
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	check("cache")
+
+	w := httptest.NewRecorder()
+	handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly?date=2026-01-09", nil))
+	var resp struct {
+		Success bool          `json:"success"`
+		Data    HourlyForDate `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.Hours[14] != 1 || resp.Data.Total != 1 {
+		t.Fatalf("/api/hourly 结果不符: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly?date=2026-1-8", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("无效日期 status=%d, want 400", w.Code)
+	}
+}
+
+func TestHourlyAPIHalfHourBins(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 8, 14, 45, 0, 0, time.UTC)
+	content := projectRecordJSON("/tmp/demo", "s1", at) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", at.Add(-30*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	fetch := func(query string) (int, HourlyForDate) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleHourlyAPI(w, httptest.NewRequest("GET", "/api/hourly?date=2026-01-08"+query, nil))
+		var resp struct {
+			Data HourlyForDate `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("无法解析响应 JSON: %v", err)
+			}
+		}
+		return w.Code, resp.Data
+	}
+	check := func(wantSource string) {
+		t.Helper()
+		_, data := fetch("&bin=30m")
+		if data.Source != wantSource || data.Bin != "30m" || len(data.Hours) != 48 || len(data.Labels) != 48 {
+			t.Fatalf("bin=30m 形状不符: %+v", data)
+		}
+		// 14:45 落在 14:30 桶，14:15 落在 14:00 桶
+		if data.Labels[29] != "14:30" || data.Hours[29] != 1 || data.Hours[28] != 1 || data.Total != 2 {
+			t.Fatalf("bin=30m 分桶不符: labels[29]=%s hours=%v", data.Labels[29], data.Hours)
+		}
+
+		_, data = fetch("")
+		if data.Bin != "1h" || len(data.Hours) != 24 || data.Labels != nil || data.Hours[14] != 2 {
+			t.Fatalf("默认 bin 应保持 24 小时形状: %+v", data)
+		}
+	}
+	check("parsing")
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	check("cache")
+
+	if code, _ := fetch("&bin=15m"); code != http.StatusBadRequest {
+		t.Fatalf("无效 bin status=%d, want 400", code)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// jsonlReader 逐行读取 JSONL。
+// json.Decoder 遇到语法错误后会一直返回同一个错误，无法跳过坏行继续解析；
+// 按行切分后每行独立 json.Unmarshal，半截损坏的文件也只丢失坏掉的那几行。
+type jsonlReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// utf8BOM 部分同步工具会在文件开头写入 UTF-8 BOM，json.Unmarshal 遇到它会判定首行无效
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// newJSONLReader 创建按行读取的 reader，并跳过文件开头的 UTF-8 BOM；
+// CRLF 行尾由 Next 的 TrimSpace 去掉
+func newJSONLReader(r io.Reader) *jsonlReader {
+	br := bufio.NewReaderSize(r, 64*1024)
+	if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return &jsonlReader{r: br}
+}
+
+// Next 返回下一条非空行（不限行长）；读完或读取出错时返回 false
+func (jr *jsonlReader) Next() ([]byte, bool) {
+	for {
+		line, err := jr.r.ReadBytes('\n')
+		if len(line) > 0 {
+			jr.line++
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			return trimmed, true
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+}
+
+// Line 返回 Next 最近一次返回行的行号（从 1 开始，空行也计数）
+func (jr *jsonlReader) Line() int {
+	return jr.line
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sinceLastRunPreset --since-last-run 报告中的 time_range.preset
+const sinceLastRunPreset = "since-last-run"
+
+// lastRunState 缓存目录下记录的上次成功运行时间
+type lastRunState struct {
+	LastRun time.Time `json:"last_run"`
+}
+
+// lastRunPath 上次运行时间文件路径
+func lastRunPath() string {
+	return filepath.Join(cfg.CacheDir, "last-run.json")
+}
+
+// loadLastRun 读取上次成功运行时间；文件不存在（首次运行）时返回 nil
+func loadLastRun(path string) (*time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state lastRunState
+	if err := json.Unmarshal(data, &state); err != nil || state.LastRun.IsZero() {
+		return nil, fmt.Errorf("上次运行记录 %s 无效，删除该文件后将按首次运行统计全部记录", path)
+	}
+	return &state.LastRun, nil
+}
+
+// saveLastRun 原子写入本次运行时间
+func saveLastRun(path string, at time.Time) error {
+	data, err := json.MarshalIndent(lastRunState{LastRun: at}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// runSinceLastRun 以上次成功运行时间为起点、本次开始时间为终点执行命令，
+// 成功后把本次开始时间记为下一次的起点；报告期间新写入的记录留给下一次统计
+func runSinceLastRun(cmd *Command, opts cliOptions) error {
+	path := lastRunPath()
+	lastRun, err := loadLastRun(path)
+	if err != nil {
+		return err
+	}
+	opts.lastRun = lastRun
+	opts.runAt = time.Now()
+	if err := cmd.Run(opts); err != nil {
+		return err
+	}
+	if err := saveLastRun(path, opts.runAt); err != nil {
+		return fmt.Errorf("记录本次运行时间失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSinceLastRunCountsOnlyNewRecords(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(projectDir, "a.jsonl")
+	old := time.Now().Add(-2 * time.Hour)
+	content := projectRecordJSON("/tmp/demo", "s1", old) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", old.Add(time.Minute)) + "\n"
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origCfg, origCache := cfg, loadGlobalCache()
+	cfg.DataDir = dataDir
+	cfg.CacheDir = t.TempDir()
+	storeGlobalCache(nil)
+	defer func() {
+		cfg = origCfg
+		storeGlobalCache(origCache)
+	}()
+
+	var messages int
+	var preset string
+	cmd := &Command{Name: "sum", Run: func(opts cliOptions) error {
+		tf, p, err := timeFilterFromCLIOptions(opts)
+		if err != nil {
+			return err
+		}
+		data, _, err := buildDashboardData(tf, p)
+		if err != nil {
+			return err
+		}
+		messages, preset = data.ProjectStats.TotalMessages, data.TimeRange.Preset
+		return nil
+	}}
+	opts := cliOptions{Config: cfg, SinceLastRun: true}
+
+	// 首次运行：没有记录，统计全部
+	if err := runSinceLastRun(cmd, opts); err != nil {
+		t.Fatalf("首次运行失败: %v", err)
+	}
+	if messages != 2 || preset != sinceLastRunPreset {
+		t.Fatalf("首次运行 messages=%d preset=%s, want 2 %s", messages, preset, sinceLastRunPreset)
+	}
+	firstRun, err := loadLastRun(lastRunPath())
+	if err != nil || firstRun == nil {
+		t.Fatalf("首次运行后应记录运行时间: %v %v", firstRun, err)
+	}
+
+	// 两次运行之间新增 3 条记录
+	time.Sleep(5 * time.Millisecond)
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		f.WriteString(projectRecordJSON("/tmp/demo", "s2", time.Now()) + "\n")
+	}
+	f.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	if err := runSinceLastRun(cmd, opts); err != nil {
+		t.Fatalf("第二次运行失败: %v", err)
+	}
+	if messages != 3 {
+		t.Fatalf("第二次运行 messages=%d, want 3（只统计上次运行之后的记录）", messages)
+	}
+	secondRun, _ := loadLastRun(lastRunPath())
+	if secondRun == nil || !secondRun.After(*firstRun) {
+		t.Fatalf("运行时间未更新: first=%v second=%v", firstRun, secondRun)
+	}
+
+	// 与 --start/--end 互斥
+	if _, _, err := timeFilterFromCLIOptions(cliOptions{SinceLastRun: true, Start: "-7d", End: "now"}); err == nil {
+		t.Fatal("--since-last-run 与 --start/--end 同时使用应报错")
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// /ws 实时推送：连接建立后先推送一份当前 DashboardData，之后每次 globalCache 被替换
+// （/api/reload、超龄或规则变更触发的刷新）再推送一份新数据，前端无需轮询 /api/data。
+// 手写最小化的 RFC 6455 服务端（仅文本帧、不分片、不压缩），不引入第三方依赖。
+
+// wsAcceptGUID RFC 6455 握手用的固定 GUID
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxClientFrame 客户端帧负载上限；客户端只会发 close/ping，超出即断开
+const wsMaxClientFrame = 64 << 10
+
+// errWSOriginForbidden 握手请求来自非本站且不在 --cors 列表中的页面
+var errWSOriginForbidden = errors.New("不允许的 Origin")
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// liveHub 缓存替换的广播中心：每个连接持有一个容量为 1 的通知 channel，
+// 推送不及时的连接只会合并成一次刷新，不会阻塞缓存刷新流程
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newLiveHub() *liveHub {
+	return &liveHub{clients: make(map[chan struct{}]struct{})}
+}
+
+// liveUpdates 全局广播中心
+var liveUpdates = newLiveHub()
+
+// subscribe 注册一个连接，返回通知 channel 与注销函数
+func (h *liveHub) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+	}
+}
+
+// notify 通知所有连接缓存已替换
+func (h *liveHub) notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// count 当前连接数
+func (h *liveHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// wsConn 已完成握手的连接；写操作由推送循环与读循环（回复 ping/close）共享
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+}
+
+func (c *wsConn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeWSFrame(c.rw, opcode, payload, nil); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// writeWSFrame 写入一个完整帧；maskKey 非空时按客户端帧规则加掩码
+func writeWSFrame(w io.Writer, opcode byte, payload []byte, maskKey []byte) error {
+	header := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if len(maskKey) == 4 {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, maskBit|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	if maskBit != 0 {
+		header = append(header, maskKey...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ maskKey[i%4]
+		}
+		payload = masked
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readWSFrame 读取一个完整帧并去掉掩码；负载超过 maxPayload 时返回错误
+func readWSFrame(r io.Reader, maxPayload int) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > uint64(maxPayload) {
+		return 0, nil, fmt.Errorf("websocket 帧过大: %d 字节", length)
+	}
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, maskKey[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// wsAcceptKey 由客户端 Sec-WebSocket-Key 计算 Sec-WebSocket-Accept
+func wsAcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// upgradeWebSocket 校验握手请求并接管底层连接
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !sameOriginOrAllowed(r, parseCORSOrigins(cfg.CORSOrigins)) {
+		return nil, errWSOriginForbidden
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || !headerContainsToken(r.Header.Get("Connection"), "upgrade") {
+		return nil, errors.New("需要 WebSocket 升级请求")
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if key == "" {
+		return nil, errors.New("缺少 Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("当前连接不支持升级")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", wsAcceptKey(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+func headerContainsToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleLiveWS /ws：查询参数同 /api/data（preset、project 等），每次推送一份完整的 APIResponse
+func handleLiveWS(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ws, err := upgradeWebSocket(w, r)
+	if errors.Is(err, errWSOriginForbidden) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.conn.Close()
+
+	updates, unsubscribe := liveUpdates.subscribe()
+	defer unsubscribe()
+
+	// 读循环：处理 ping/close，连接断开时通知推送循环退出
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWSFrame(ws.rw, wsMaxClientFrame)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsOpClose:
+				ws.write(wsOpClose, payload)
+				return
+			case wsOpPing:
+				if ws.write(wsOpPong, payload) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		if err := pushLiveData(ws, filter); err != nil {
+			Warn("实时推送失败", "error", err.Error())
+			return
+		}
+		select {
+		case <-updates:
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// pushLiveData 按连接的过滤条件构建数据并发送一帧
+func pushLiveData(ws *wsConn, filter AnalysisFilter) error {
+	response := APIResponse{Success: true}
+	if data, _, err := buildDashboardDataWithFilter(filter); err != nil {
+		response = APIResponse{Success: false, Error: err.Error()}
+	} else {
+		response.Data = data
+	}
+	payload, err := json.Marshal(stampAPIResponse(response))
+	if err != nil {
+		return err
+	}
+	return ws.write(wsOpText, payload)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialTestWebSocket 建立到 /ws 的 WebSocket 连接并校验握手响应
+func dialTestWebSocket(t *testing.T, serverURL, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	req := "GET " + path + " HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("发送握手失败: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("读取握手响应失败: %v", err)
+	}
+	// RFC 6455 示例 key 对应的 accept
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("握手响应不符: status=%d accept=%q", resp.StatusCode, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+	return conn, reader
+}
+
+// readLivePayload 读取一帧推送并解析为 DashboardData
+func readLivePayload(t *testing.T, conn net.Conn, reader *bufio.Reader) *DashboardData {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	opcode, payload, err := readWSFrame(reader, 64<<20)
+	if err != nil {
+		t.Fatalf("读取推送失败: %v", err)
+	}
+	if opcode != wsOpText {
+		t.Fatalf("opcode=%d, want text", opcode)
+	}
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    *DashboardData `json:"data"`
+		Error   string         `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		t.Fatalf("推送内容不是合法 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data == nil {
+		t.Fatalf("推送失败: %s", resp.Error)
+	}
+	return resp.Data
+}
+
+func TestLiveWebSocketPushesOnCacheReload(t *testing.T) {
+	useFixtureDataDir(t)
+	origCacheDir, origCacheFile := cfg.CacheDir, cfg.CacheFile
+	cfg.CacheDir = t.TempDir()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.db")
+	defer func() { cfg.CacheDir, cfg.CacheFile = origCacheDir, origCacheFile }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", handleLiveWS)
+	mux.HandleFunc("/api/reload", reloadHandler)
+	// wsDone 在 /ws 请求连同中间件全部返回后关闭，避免与后续测试替换全局变量产生竞争
+	wsDone := make(chan struct{})
+	handler := MetricsMiddleware(mux)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		if r.URL.Path == "/ws" {
+			close(wsDone)
+		}
+	}))
+	defer server.Close()
+
+	conn, reader := dialTestWebSocket(t, server.URL, "/ws?preset=all")
+	// 连接后立即推送当前数据（尚无缓存，走实时解析）
+	if data := readLivePayload(t, conn, reader); data.ProjectStats == nil || data.ProjectStats.TotalMessages != 5 {
+		t.Fatalf("初始推送 project_stats=%+v", data.ProjectStats)
+	}
+
+	resp, err := http.Post(server.URL+"/api/reload?force=1", "application/json", nil)
+	if err != nil {
+		t.Fatalf("触发 reload 失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || loadGlobalCache() == nil {
+		t.Fatalf("reload status=%d cache=%v", resp.StatusCode, loadGlobalCache() != nil)
+	}
+	if data := readLivePayload(t, conn, reader); data.ProjectStats == nil || data.ProjectStats.TotalMessages != 5 {
+		t.Fatalf("reload 后推送 project_stats=%+v", data.ProjectStats)
+	}
+
+	// 客户端关闭：服务端回复 close 帧并注销连接
+	if err := writeWSFrame(conn, wsOpClose, []byte{0x03, 0xE8}, []byte{1, 2, 3, 4}); err != nil {
+		t.Fatalf("发送 close 失败: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if opcode, _, err := readWSFrame(reader, wsMaxClientFrame); err != nil || opcode != wsOpClose {
+		t.Fatalf("close 回复 opcode=%d err=%v", opcode, err)
+	}
+	select {
+	case <-wsDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("断开后 /ws 处理未退出")
+	}
+	if n := liveUpdates.count(); n != 0 {
+		t.Fatalf("断开后仍有 %d 个订阅", n)
+	}
+}
+
+func TestLiveWebSocketRejectsPlainRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleLiveWS(w, httptest.NewRequest("GET", "/ws", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status=%d, want 400", w.Code)
+	}
+}
+
+func TestLiveWebSocketChecksOrigin(t *testing.T) {
+	origCORS := cfg.CORSOrigins
+	cfg.CORSOrigins = "https://charts.example"
+	defer func() { cfg.CORSOrigins = origCORS }()
+
+	upgrade := func(origin string) int {
+		r := httptest.NewRequest("GET", "http://localhost:8080/ws", nil)
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Connection", "Upgrade")
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		handleLiveWS(w, r)
+		return w.Code
+	}
+	// 跨站页面发起的握手在升级前被拒绝
+	if code := upgrade("https://evil.example"); code != http.StatusForbidden {
+		t.Fatalf("跨站 Origin status=%d, want 403", code)
+	}
+	// 同源、--cors 列表内或无 Origin 的请求通过来源校验（此处因缺少 Sec-WebSocket-Key 得到 400）
+	for _, origin := range []string{"http://localhost:8080", "https://charts.example/", ""} {
+		if code := upgrade(origin); code != http.StatusBadRequest {
+			t.Fatalf("Origin %q status=%d, want 400", origin, code)
+		}
+	}
+
+	cfg.CORSOrigins = "*"
+	if code := upgrade("https://evil.example"); code != http.StatusBadRequest {
+		t.Fatalf("--cors=* 时 status=%d, want 400", code)
+	}
+}
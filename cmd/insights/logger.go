@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -27,6 +32,32 @@ var levelNames = map[LogLevel]string{
 	LogLevelError: "ERROR",
 }
 
+// levelKeys JSON 格式中的 level 字段
+var levelKeys = map[LogLevel]string{
+	LogLevelDebug: "debug",
+	LogLevelInfo:  "info",
+	LogLevelWarn:  "warn",
+	LogLevelError: "error",
+}
+
+// 日志格式（--log-format）
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// normalizeLogFormat 校验 --log-format，空值为默认的 text
+func normalizeLogFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJSON:
+		return LogFormatJSON, nil
+	default:
+		return "", fmt.Errorf("不支持的日志格式 %q，支持 text|json", format)
+	}
+}
+
 // Logger 结构化日志器，同时输出到 stderr 和日志文件
 type Logger struct {
 	level      LogLevel
@@ -34,6 +65,8 @@ type Logger struct {
 	file       *os.File
 	fileLogger *log.Logger
 	outLogger  *log.Logger
+	// json 每条日志输出为一行 JSON（time/level/msg/context），供日志采集管道解析
+	json bool
 }
 
 // 全局日志实例
@@ -44,6 +77,7 @@ func InitLogger(logDir string) error {
 	appLogger = &Logger{
 		level:     LogLevelInfo,
 		outLogger: log.New(os.Stderr, "", 0),
+		json:      cfg.LogFormat == LogFormatJSON,
 	}
 
 	// 确保日志目录存在
@@ -90,18 +124,66 @@ func (l *Logger) log(level LogLevel, msg string, pairs ...any) {
 	if l == nil || level < l.level {
 		return
 	}
-	prefix := fmt.Sprintf("[%s] %s |", time.Now().Format("15:04:05"), levelNames[level])
-	formatted := formatMessage(msg, pairs...)
+	var line string
+	if l.json {
+		line = formatJSONRecord(time.Now(), level, msg, pairs...)
+	} else {
+		prefix := fmt.Sprintf("[%s] %s |", time.Now().Format("15:04:05"), levelNames[level])
+		line = prefix + " " + formatMessage(msg, pairs...)
+	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if l.outLogger != nil {
-		l.outLogger.Output(2, prefix+" "+formatted)
+		l.outLogger.Output(2, line)
 	}
 	if l.fileLogger != nil {
-		l.fileLogger.Output(3, prefix+" "+formatted) // 更深调用栈以区分来源
+		l.fileLogger.Output(3, line) // 更深调用栈以区分来源
+	}
+}
+
+// jsonLogRecord JSON 格式的一条日志
+type jsonLogRecord struct {
+	Time    string         `json:"time"`
+	Level   string         `json:"level"`
+	Msg     string         `json:"msg"`
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// formatJSONRecord 把 key-value 对放入 context；error、Duration 等转为字符串，其余保留原始类型
+func formatJSONRecord(at time.Time, level LogLevel, msg string, pairs ...any) string {
+	record := jsonLogRecord{Time: at.Format(time.RFC3339Nano), Level: levelKeys[level], Msg: msg}
+	if len(pairs) > 0 {
+		record.Context = make(map[string]any, (len(pairs)+1)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			key, ok := pairs[i].(string)
+			if !ok {
+				key = fmt.Sprintf("%v", pairs[i])
+			}
+			var val any = "?"
+			if i+1 < len(pairs) {
+				switch v := pairs[i+1].(type) {
+				case error:
+					val = v.Error()
+				case fmt.Stringer:
+					val = v.String()
+				default:
+					val = v
+				}
+			}
+			record.Context[key] = val
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		// 值无法序列化时退化为字符串，保证仍是一行合法 JSON
+		for key, val := range record.Context {
+			record.Context[key] = fmt.Sprintf("%v", val)
+		}
+		data, _ = json.Marshal(record)
 	}
+	return string(data)
 }
 
 func Debug(msg string, pairs ...any) { appLogger.log(LogLevelDebug, msg, pairs...) }
@@ -109,14 +191,38 @@ func Info(msg string, pairs ...any)  { appLogger.log(LogLevelInfo, msg, pairs...
 func Warn(msg string, pairs ...any)  { appLogger.log(LogLevelWarn, msg, pairs...) }
 func Error(msg string, pairs ...any) { appLogger.log(LogLevelError, msg, pairs...) }
 
+// accessLogWriter 访问日志输出目标；为 nil 时走 appLogger（stderr + 日志文件）
+var accessLogWriter io.Writer
+
+// openAccessLog 以追加方式打开访问日志文件并设为 accessLogWriter，返回的函数恢复默认输出并关闭文件
+func openAccessLog(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	accessLogWriter = f
+	return func() {
+		accessLogWriter = nil
+		f.Close()
+	}, nil
+}
+
 // RequestLog 记录 HTTP 请求（访问日志）
 func RequestLog(r *http.Request, status int, duration time.Duration, size int64) {
+	if accessLogWriter != nil {
+		fmt.Fprintf(accessLogWriter, "[%s] %s %s -> %d (%s, %dB)\n",
+			time.Now().Format("15:04:05"), r.Method, r.URL.Path, status, duration.Round(time.Millisecond), size)
+		return
+	}
 	if appLogger == nil {
 		return
 	}
 	method := r.Method
 	path := r.URL.Path
-	query := r.URL.RawQuery
+	query := redactQueryToken(r.URL.RawQuery)
 	if len(query) > 100 {
 		query = query[:100] + "..."
 	}
@@ -130,6 +236,18 @@ func RequestLog(r *http.Request, status int, duration time.Duration, size int64)
 	if query != "" {
 		target += "?" + query
 	}
+	if appLogger.json {
+		appLogger.log(LogLevelInfo, "http request",
+			"method", method,
+			"path", target,
+			"status", status,
+			"duration_ms", duration.Milliseconds(),
+			"size", size,
+			"client", clientIP,
+			"ua", userAgent,
+		)
+		return
+	}
 
 	msg := fmt.Sprintf("%s %s -> %d (%s, %dB)", method, target, status, duration.Round(time.Millisecond), size)
 	appLogger.log(LogLevelInfo, msg,
@@ -193,3 +311,23 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	rw.written += int64(n)
 	return n, err
 }
+
+// Flush 透传刷新，供流式导出逐批推送
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 透传底层连接，供 /ws 升级为 WebSocket；升级成功时状态记为 101
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("ResponseWriter 不支持 Hijack")
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err == nil {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
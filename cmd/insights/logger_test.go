@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSONFormatEmitsStructuredRecord(t *testing.T) {
+	var buf bytes.Buffer
+	origLogger := appLogger
+	appLogger = &Logger{level: LogLevelInfo, outLogger: log.New(&buf, "", 0), json: true}
+	defer func() { appLogger = origLogger }()
+
+	Debug("不应输出")
+	Warn("构建数据失败", "error", errors.New("boom"), "records", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("输出 %d 行, want 1: %q", len(lines), buf.String())
+	}
+	var record struct {
+		Time    string         `json:"time"`
+		Level   string         `json:"level"`
+		Msg     string         `json:"msg"`
+		Context map[string]any `json:"context"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("日志行不是合法 JSON: %v (%s)", err, lines[0])
+	}
+	if record.Time == "" || record.Level != "warn" || record.Msg != "构建数据失败" {
+		t.Fatalf("记录字段不符: %+v", record)
+	}
+	if record.Context["error"] != "boom" || record.Context["records"] != float64(3) {
+		t.Fatalf("context = %+v", record.Context)
+	}
+}
+
+func TestNormalizeLogFormat(t *testing.T) {
+	for input, want := range map[string]string{"": LogFormatText, "text": LogFormatText, " JSON ": LogFormatJSON} {
+		if got, err := normalizeLogFormat(input); err != nil || got != want {
+			t.Fatalf("normalizeLogFormat(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := normalizeLogFormat("xml"); err == nil {
+		t.Fatal("normalizeLogFormat(xml) 应返回错误")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import "time"
+
+// 逻辑会话：Claude Code 在长时间中断后可能沿用同一个 sessionId，
+// 同一 sessionId 内相邻两条 assistant 记录间隔超过 Config.SessionGapMinutes 时视为新的一段会话。
+// 与 SessionStats.TotalSessions 的口径一致按天统计：每天每个会话至少算一段，当天内每出现一次超长间隔多算一段。
+
+// defaultSessionGapMinutes 默认的逻辑会话切分间隔（分钟）
+const defaultSessionGapMinutes = 30
+
+// sessionGapCacheKey 缓存中记录的非默认会话间隔（默认值记为 0），变更后需要重建
+func sessionGapCacheKey() int {
+	if cfg.SessionGapMinutes == defaultSessionGapMinutes {
+		return 0
+	}
+	// 0（不切分）与默认值区分开
+	if cfg.SessionGapMinutes <= 0 {
+		return -1
+	}
+	return cfg.SessionGapMinutes
+}
+
+// sessionGap 当前生效的会话切分间隔；<=0 表示不切分
+func sessionGap() time.Duration {
+	return time.Duration(cfg.SessionGapMinutes) * time.Minute
+}
+
+// SessionSpan 一天内单个会话 assistant 记录的摘要（不保存逐条时间）：
+// 首末时间（unix 毫秒）、记录数，以及相邻记录间隔超过会话间隔的次数
+type SessionSpan struct {
+	First  int64 `json:"first"`
+	Last   int64 `json:"last"`
+	Count  int   `json:"count"`
+	Splits int   `json:"splits,omitempty"`
+}
+
+// add 追加一条记录时间；记录基本按时间顺序到达，落在已有区间内部的乱序记录只计数、不改变切分
+func (s SessionSpan) add(ms int64, gap time.Duration) SessionSpan {
+	if s.Count == 0 {
+		return SessionSpan{First: ms, Last: ms, Count: 1}
+	}
+	s.Count++
+	switch {
+	case ms > s.Last:
+		if exceedsGap(ms-s.Last, gap) {
+			s.Splits++
+		}
+		s.Last = ms
+	case ms < s.First:
+		if exceedsGap(s.First-ms, gap) {
+			s.Splits++
+		}
+		s.First = ms
+	}
+	return s
+}
+
+// merge 合并同一会话同一天在不同文件中的摘要；两段不重叠且间隔超过 gap 时多切分一次
+func (s SessionSpan) merge(other SessionSpan, gap time.Duration) SessionSpan {
+	if s.Count == 0 {
+		return other
+	}
+	if other.Count == 0 {
+		return s
+	}
+	merged := SessionSpan{First: s.First, Last: s.Last, Count: s.Count + other.Count, Splits: s.Splits + other.Splits}
+	if other.First < merged.First {
+		merged.First = other.First
+	}
+	if other.Last > merged.Last {
+		merged.Last = other.Last
+	}
+	if other.First > s.Last && exceedsGap(other.First-s.Last, gap) || s.First > other.Last && exceedsGap(s.First-other.Last, gap) {
+		merged.Splits++
+	}
+	return merged
+}
+
+func exceedsGap(deltaMs int64, gap time.Duration) bool {
+	return gap > 0 && time.Duration(deltaMs)*time.Millisecond > gap
+}
+
+// recordSessionSpan 把一条 assistant 记录计入 date→sessionID 的会话摘要
+func recordSessionSpan(daily map[string]map[string]SessionSpan, date, sessionID string, ms int64, gap time.Duration) {
+	if daily[date] == nil {
+		daily[date] = make(map[string]SessionSpan)
+	}
+	daily[date][sessionID] = daily[date][sessionID].add(ms, gap)
+}
+
+// mergeSessionSpans 把 src 的会话摘要并入 dst
+func mergeSessionSpans(dst, src map[string]map[string]SessionSpan, gap time.Duration) {
+	for date, sessions := range src {
+		if dst[date] == nil {
+			dst[date] = make(map[string]SessionSpan, len(sessions))
+		}
+		for sessionID, span := range sessions {
+			dst[date][sessionID] = dst[date][sessionID].merge(span, gap)
+		}
+	}
+}
+
+// countLogicalSessions 统计一天内的逻辑会话数：每个会话一段，外加当天的切分次数
+func countLogicalSessions(sessions map[string]SessionSpan) int {
+	total := 0
+	for _, span := range sessions {
+		if span.Count == 0 {
+			continue
+		}
+		total += 1 + span.Splits
+	}
+	return total
+}
+
+// copySessionSpans 深拷贝 date→sessionID→会话摘要
+func copySessionSpans(src map[string]map[string]SessionSpan) map[string]map[string]SessionSpan {
+	if len(src) == 0 {
+		return nil
+	}
+	out := make(map[string]map[string]SessionSpan, len(src))
+	for date, sessions := range src {
+		out[date] = make(map[string]SessionSpan, len(sessions))
+		for sessionID, span := range sessions {
+			out[date][sessionID] = span
+		}
+	}
+	return out
+}
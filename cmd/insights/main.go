@@ -30,6 +30,7 @@ func main() {
 }
 
 func runWebServer() error {
+	startedAt := time.Now()
 	// 初始化日志系统（在所有操作之前）
 	logDir := filepath.Join(filepath.Dir(cfg.CacheDir), "logs")
 	if err := InitLogger(logDir); err != nil {
@@ -42,16 +43,36 @@ func runWebServer() error {
 		"commit", commit,
 	)
 
-	// 验证数据目录
-	if _, err := os.Stat(cfg.DataDir); os.IsNotExist(err) {
-		Error("数据目录不存在", "path", cfg.DataDir)
+	// 验证数据目录（--demo / --archive 时先切换到内存或归档数据源）
+	if cfg.Demo {
+		applyDemoConfig()
+		Info("演示模式：使用内存中生成的合成数据")
+	} else if err := applyArchiveConfig(); err != nil {
+		Error("数据归档不可用", "path", cfg.ArchivePath, "error", err.Error())
+		return err
+	}
+	if err := applyMCPPatternConfig(); err != nil {
+		Error("MCP 工具正则不可用", "pattern", cfg.MCPPattern, "error", err.Error())
+		return err
+	}
+	if err := applyDebugPatternConfig(); err != nil {
+		Error("debug 日志模式不可用", "error", err.Error())
+		return err
+	}
+	dataSummary, err := ValidateDataDir(cfg.DataDir)
+	if err != nil {
+		Error("数据目录不可用", "path", cfg.DataDir, "error", err.Error())
 		Info("提示: 使用 -data 参数指定数据目录")
-		return fmt.Errorf("数据目录不存在: %s", cfg.DataDir)
+		return err
 	}
+	warnIfDataDirEmpty(cfg.DataDir, dataSummary)
 
 	Info("配置信息",
 		"data_dir", cfg.DataDir,
-		"cache_dir", cfg.CacheDir,
+		"history_files", dataSummary.HistoryFiles,
+		"project_files", dataSummary.ProjectFiles,
+		"debug_files", dataSummary.DebugFiles,
+		"cache_file", cacheFilePath(),
 		"listen_addr", cfg.ListenAddr,
 	)
 
@@ -60,29 +81,38 @@ func runWebServer() error {
 		Warn("缓存初始化失败，将使用实时解析模式", "error", err.Error())
 	}
 
-	// 路由
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", indexHandler)
-	mux.HandleFunc("/dashboard", dashboardPageHandler)
-	mux.HandleFunc("/dashboard/", dashboardPageHandler)
-	mux.HandleFunc("/api/data", handleDataAPI)
-	mux.HandleFunc("/api/overview", handleOverviewAPI)
-	mux.HandleFunc("/api/diagnostics", handleDiagnosticsAPI)
-	mux.HandleFunc("/api/detail/failures", handleDetailFailuresAPI)
-	mux.HandleFunc("/api/detail/commands", handleDetailCommandsAPI)
-	mux.HandleFunc("/api/detail/tokens", handleDetailTokensAPI)
-	mux.HandleFunc("/api/detail/sessions", handleDetailSessionsAPI)
-	mux.HandleFunc("/api/detail/tools", handleDetailToolsAPI)
-	mux.HandleFunc("/api/timeline", handleTimelineAPI)
-	mux.HandleFunc("/api/reload", reloadHandler)
-	mux.HandleFunc("/api/version", versionHandler)
+	// 数据变更时自动刷新缓存
+	if cfg.Watch && !cfg.Demo {
+		stop := startCacheWatcher(cfg.WatchInterval)
+		defer stop()
+		Info("自动刷新已启用", "interval", cfg.WatchInterval.String())
+	}
 
-	// 静态资源：React 构建产物（cmd/insights/static/dist），由 web/ 经 Vite 生成后 embed。
-	distSub, _ := fs.Sub(distFS, "static/dist")
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(distSub))))
+	// 心跳状态文件
+	if cfg.StatusPath != "" {
+		interval := cfg.StatusInterval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		stop := startStatusHeartbeat(cfg.StatusPath, interval, func() serverStatus {
+			return buildServerStatus(loadGlobalCache(), startedAt, time.Now())
+		})
+		defer stop()
+		Info("状态文件已启用", "path", cfg.StatusPath, "interval", interval.String())
+	}
 
-	// 包装日志中间件
-	handler := LoggingMiddleware(mux)
+	// 访问日志单独写入文件
+	if cfg.AccessLogPath != "" && !cfg.Quiet {
+		closeAccessLog, err := openAccessLog(cfg.AccessLogPath)
+		if err != nil {
+			Error("访问日志文件不可用", "path", cfg.AccessLogPath, "error", err.Error())
+			return err
+		}
+		defer closeAccessLog()
+		Info("访问日志写入文件", "path", cfg.AccessLogPath)
+	}
+
+	handler := newServerHandler()
 
 	Info("服务就绪",
 		"url", "http://localhost"+cfg.ListenAddr,
@@ -99,6 +129,71 @@ func runWebServer() error {
 	return nil
 }
 
+// apiRoutes 全部 /api/ 路由；newServerHandler 据此注册，/metrics 也只为这些路径单独计数
+var apiRoutes = []struct {
+	pattern string
+	handler http.HandlerFunc
+}{
+	{"/api/data", handleDataAPI},
+	{"/api/overview", handleOverviewAPI},
+	{"/api/diagnostics", handleDiagnosticsAPI},
+	{"/api/detail/failures", handleDetailFailuresAPI},
+	{"/api/detail/commands", handleDetailCommandsAPI},
+	{"/api/detail/tokens", handleDetailTokensAPI},
+	{"/api/detail/sessions", handleDetailSessionsAPI},
+	{"/api/detail/tools", handleDetailToolsAPI},
+	{"/api/commands", handleCommandsAPI},
+	{"/api/projects", handleProjectsAPI},
+	{"/api/sessions", handleSessionsAPI},
+	{"/api/export/sessions.jsonl", handleExportSessionsJSONL},
+	{"/api/export/cost-matrix.csv", handleExportCostMatrixCSV},
+	{"/api/branches", handleBranchesAPI},
+	{"/api/presets", handlePresetsAPI},
+	{"/api/today", handleTodayAPI},
+	{"/api/hourly", handleHourlyAPI},
+	{"/api/storage", handleStorageAPI},
+	{"/api/debug-patterns", handleDebugPatternsAPI},
+	{"/api/message-lengths", handleMessageLengthsAPI},
+	{"/api/compare", handleCompareAPI},
+	{"/api/timeline", handleTimelineAPI},
+	{"/api/rollup", handleRollupAPI},
+	{"/api/reload", reloadHandler},
+	{"/api/version", versionHandler},
+	{"/api/debug/files", handleDebugFilesAPI},
+	{"/api/debug/errors", handleDebugErrorsAPI},
+}
+
+// newServerHandler 注册全部路由并包装中间件
+func newServerHandler() http.Handler {
+	// 路由
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler)
+	mux.HandleFunc("/dashboard", dashboardPageHandler)
+	mux.HandleFunc("/dashboard/", dashboardPageHandler)
+	for _, route := range apiRoutes {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/ws", handleLiveWS)
+
+	// 内置 ECharts 脚本（比 /static/ 的前端构建产物优先匹配）
+	mux.HandleFunc("/static/echarts.min.js", handleEChartsAsset)
+	mux.HandleFunc("/static/themes/", handleEChartsAsset)
+
+	// 静态资源：React 构建产物（cmd/insights/static/dist），由 web/ 经 Vite 生成后 embed。
+	distSub, _ := fs.Sub(distFS, "static/dist")
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(distSub))))
+
+	// 包装日志中间件（--quiet 时不记录访问日志）
+	// --cors 配置了来源时为跨域前端添加 CORS 头；/metrics 的请求计数覆盖所有路由
+	// --token 设置时校验 /api/* 与 /ws 的令牌；CORS 预检在校验之前应答
+	var handler http.Handler = MetricsMiddleware(CORSMiddleware(AuthMiddleware(mux, cfg.Token), parseCORSOrigins(cfg.CORSOrigins)))
+	if !cfg.Quiet {
+		handler = LoggingMiddleware(handler)
+	}
+	return handler
+}
+
 // indexHandler 根路径重定向到 Dashboard SPA，入口统一。
 func indexHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -143,10 +238,10 @@ func reloadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	messages, sessions, rulesHash := 0, 0, ""
-	if globalCache != nil {
-		messages = globalCache.TotalMessages
-		sessions = globalCache.TotalSessions
-		rulesHash = globalCache.BashRulesHash
+	if cache := loadGlobalCache(); cache != nil {
+		messages = cache.TotalMessages
+		sessions = cache.TotalSessions
+		rulesHash = cache.BashRulesHash
 	}
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":       "ok",
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"flag"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -61,13 +64,13 @@ func TestAPIUsesCachedData(t *testing.T) {
 	// 设置配置
 	originalDataDir := cfg.DataDir
 	originalCacheDir := cfg.CacheDir
-	originalGlobalCache := globalCache
+	originalGlobalCache := loadGlobalCache()
 	cfg.DataDir = dataDir
 	cfg.CacheDir = cacheDir
 	defer func() {
 		cfg.DataDir = originalDataDir
 		cfg.CacheDir = originalCacheDir
-		globalCache = originalGlobalCache
+		storeGlobalCache(originalGlobalCache)
 	}()
 
 	// 构建缓存
@@ -84,7 +87,7 @@ func TestAPIUsesCachedData(t *testing.T) {
 	if loadErr != nil {
 		t.Fatalf("Setup: LoadCacheFile failed: %v", loadErr)
 	}
-	globalCache = cache
+	storeGlobalCache(cache)
 
 	// 创建API请求
 	req := httptest.NewRequest("GET", "/api/data?preset=7d", nil)
@@ -120,16 +123,16 @@ func TestAPIFallbackWhenNoCache(t *testing.T) {
 	// 设置配置
 	originalDataDir := cfg.DataDir
 	originalCacheDir := cfg.CacheDir
-	originalGlobalCache := globalCache
+	originalGlobalCache := loadGlobalCache()
 	cfg.DataDir = dataDir
 	defer func() {
 		cfg.DataDir = originalDataDir
 		cfg.CacheDir = originalCacheDir
-		globalCache = originalGlobalCache
+		storeGlobalCache(originalGlobalCache)
 	}()
 
 	// 确保没有缓存
-	globalCache = nil
+	storeGlobalCache(nil)
 
 	// 创建API请求
 	req := httptest.NewRequest("GET", "/api/data?preset=all", nil)
@@ -169,7 +172,7 @@ func TestReloadHandlerForcesCacheRefresh(t *testing.T) {
 	originalDataDir := cfg.DataDir
 	originalCacheDir := cfg.CacheDir
 	originalRulesPath := cfg.RulesPath
-	originalGlobalCache := globalCache
+	originalGlobalCache := loadGlobalCache()
 	cfg.DataDir = dataDir
 	cfg.CacheDir = cacheDir
 	cfg.RulesPath = ""
@@ -177,13 +180,13 @@ func TestReloadHandlerForcesCacheRefresh(t *testing.T) {
 		cfg.DataDir = originalDataDir
 		cfg.CacheDir = originalCacheDir
 		cfg.RulesPath = originalRulesPath
-		globalCache = originalGlobalCache
+		storeGlobalCache(originalGlobalCache)
 	}()
 
 	if err := refreshGlobalCache(false); err != nil {
 		t.Fatalf("初始化缓存失败: %v", err)
 	}
-	oldHash := globalCache.BashRulesHash
+	oldHash := loadGlobalCache().BashRulesHash
 
 	cfg.RulesPath = rulesPath
 	req := httptest.NewRequest(http.MethodPost, "/api/reload?force=1", nil)
@@ -194,10 +197,159 @@ func TestReloadHandlerForcesCacheRefresh(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("状态码 = %d, want %d", w.Code, http.StatusOK)
 	}
-	if globalCache == nil {
-		t.Fatal("globalCache should not be nil")
+	if loadGlobalCache() == nil {
+		t.Fatal("loadGlobalCache() should not be nil")
 	}
-	if globalCache.BashRulesHash == oldHash {
+	if loadGlobalCache().BashRulesHash == oldHash {
 		t.Fatalf("BashRulesHash did not change after reload")
 	}
 }
+
+func TestLoggingMiddlewareLogsStatusAndSize(t *testing.T) {
+	var buf bytes.Buffer
+	accessLogWriter = &buf
+	defer func() { accessLogWriter = nil }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	handler := LoggingMiddleware(mux)
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path: "/api/ok", want: "GET /api/ok -> 200"},
+		{path: "/api/missing", want: "GET /api/missing -> 404"},
+	} {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+		line := buf.String()
+		if !strings.Contains(line, tc.want) {
+			t.Fatalf("访问日志缺少 %q: %q", tc.want, line)
+		}
+		if tc.path == "/api/ok" && !strings.Contains(line, "5B)") {
+			t.Fatalf("访问日志未记录响应大小: %q", line)
+		}
+	}
+}
+
+func TestAccessLogFileReceivesRequestLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	parsed := defaultConfig()
+	registerServerFlags(fs, &parsed)
+	if err := fs.Parse([]string{"--access-log", path}); err != nil {
+		t.Fatalf("解析 --access-log 失败: %v", err)
+	}
+	if parsed.AccessLogPath != path {
+		t.Fatalf("AccessLogPath = %q, want %q", parsed.AccessLogPath, path)
+	}
+
+	closeAccessLog, err := openAccessLog(parsed.AccessLogPath)
+	if err != nil {
+		t.Fatalf("openAccessLog() error = %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	LoggingMiddleware(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ok", nil))
+	closeAccessLog()
+	if accessLogWriter != nil {
+		t.Fatal("关闭后 accessLogWriter 应恢复为 nil")
+	}
+
+	// 再次打开应追加而不是覆盖
+	closeAccessLog, err = openAccessLog(path)
+	if err != nil {
+		t.Fatalf("openAccessLog() error = %v", err)
+	}
+	LoggingMiddleware(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+	closeAccessLog()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"GET /api/ok -> 200", "GET /api/missing -> 404"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("访问日志文件缺少 %q: %q", want, data)
+		}
+	}
+}
+
+func TestCORSMiddlewareAllowsOnlyConfiguredOrigins(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	handler := CORSMiddleware(mux, parseCORSOrigins("https://charts.example, https://other.example/"))
+
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/data", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "https://charts.example")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://charts.example" {
+		t.Fatalf("允许的来源应回显 Allow-Origin, got %q", got)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "{}" {
+		t.Fatalf("GET 应正常透传: code=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if got := request(http.MethodGet, "https://other.example").Header().Get("Access-Control-Allow-Origin"); got != "https://other.example" {
+		t.Fatalf("末尾斜杠应被规范化, got %q", got)
+	}
+	if got := request(http.MethodGet, "https://evil.example").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("未允许的来源不应有 Allow-Origin, got %q", got)
+	}
+
+	preflight := request(http.MethodOptions, "https://charts.example")
+	if preflight.Code != http.StatusNoContent || !strings.Contains(preflight.Header().Get("Access-Control-Allow-Methods"), "GET") {
+		t.Fatalf("预检请求应返回 204 并允许 GET: code=%d headers=%v", preflight.Code, preflight.Header())
+	}
+
+	plain := CORSMiddleware(mux, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+	req.Header.Set("Origin", "https://charts.example")
+	rec = httptest.NewRecorder()
+	plain.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("未配置 --cors 时不应发送 CORS 头, got %q", got)
+	}
+}
+
+func TestInitializeCacheUsesConfiguredCacheFile(t *testing.T) {
+	useFixtureDataDir(t)
+	origCacheDir, origCacheFile := cfg.CacheDir, cfg.CacheFile
+	cfg.CacheDir = t.TempDir()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "nested", "custom.db")
+	storeGlobalCache(nil)
+	defer func() { cfg.CacheDir, cfg.CacheFile = origCacheDir, origCacheFile }()
+
+	if err := initializeCache(); err != nil {
+		t.Fatalf("initializeCache() failed: %v", err)
+	}
+	if loadGlobalCache() == nil || loadGlobalCache().TotalMessages != 5 || loadGlobalCache().TotalSessions != 3 {
+		t.Fatalf("loadGlobalCache() = %+v, want fixture totals 5/3", loadGlobalCache())
+	}
+	if _, err := os.Stat(cfg.CacheFile); err != nil {
+		t.Fatalf("缓存文件未写到 --cache-file 指定路径: %v", err)
+	}
+
+	// 再次初始化时复用已有缓存文件
+	storeGlobalCache(nil)
+	if err := initializeCache(); err != nil {
+		t.Fatalf("第二次 initializeCache() failed: %v", err)
+	}
+	if loadGlobalCache() == nil || loadGlobalCache().TotalMessages != 5 {
+		t.Fatalf("复用缓存后 loadGlobalCache() = %+v", loadGlobalCache())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// memFS 只读内存文件系统：files 的 key 为 fs.ValidPath 形式的相对路径（以 / 分隔）。
+// 只需登记文件，中间目录按路径前缀自动推出；也可显式登记目录以保留其修改时间。
+// 供 tar.gz 归档与 --demo 演示数据使用，不依赖 testing/fstest。
+type memFS struct {
+	files map[string]*memFile
+	// dirs 目录 → 按名称排序的直接子条目，由 newMemFS 一次建好，Open/ReadDir 不再扫描全部路径
+	dirs map[string][]fs.DirEntry
+}
+
+// memFile 内存文件或目录（Mode 含 fs.ModeDir 时为目录，Data 忽略）
+type memFile struct {
+	Data    []byte
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// implicitDir 未显式登记、由子路径推出的目录
+var implicitDir = &memFile{Mode: fs.ModeDir | 0555}
+
+// newMemFS 以 files 建立内存文件系统，并为每个目录建立子条目索引
+func newMemFS(files map[string]*memFile) *memFS {
+	m := &memFS{files: files, dirs: map[string][]fs.DirEntry{".": nil}}
+	children := make(map[string]map[string]bool)
+	for name, file := range files {
+		if file.Mode.IsDir() && children[name] == nil {
+			children[name] = make(map[string]bool)
+		}
+		for child := name; child != "."; {
+			parent := path.Dir(child)
+			if children[parent] == nil {
+				children[parent] = make(map[string]bool)
+			}
+			if children[parent][path.Base(child)] {
+				break
+			}
+			children[parent][path.Base(child)] = true
+			child = parent
+		}
+	}
+	for dir, names := range children {
+		entries := make([]fs.DirEntry, 0, len(names))
+		for name := range names {
+			full := name
+			if dir != "." {
+				full = dir + "/" + name
+			}
+			file := files[full]
+			if file == nil {
+				file = implicitDir
+			}
+			entries = append(entries, &memFileInfo{name: name, file: file})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		m.dirs[dir] = entries
+	}
+	return m
+}
+
+// memFileInfo 实现 fs.FileInfo 与 fs.DirEntry
+type memFileInfo struct {
+	name string
+	file *memFile
+}
+
+func (fi *memFileInfo) Name() string               { return fi.name }
+func (fi *memFileInfo) Size() int64                { return int64(len(fi.file.Data)) }
+func (fi *memFileInfo) Mode() fs.FileMode          { return fi.file.Mode }
+func (fi *memFileInfo) ModTime() time.Time         { return fi.file.ModTime }
+func (fi *memFileInfo) IsDir() bool                { return fi.file.Mode.IsDir() }
+func (fi *memFileInfo) Sys() any                   { return nil }
+func (fi *memFileInfo) Type() fs.FileMode          { return fi.file.Mode.Type() }
+func (fi *memFileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+// lookup 返回 name 对应的条目；未显式登记但在目录索引中的视为目录
+func (m *memFS) lookup(name string) (*memFile, bool) {
+	if file, ok := m.files[name]; ok {
+		return file, true
+	}
+	if _, ok := m.dirs[name]; ok {
+		return implicitDir, true
+	}
+	return nil, false
+}
+
+// children 目录 name 下的直接子条目（副本，调用方可自由排序或修改）
+func (m *memFS) children(name string) []fs.DirEntry {
+	return append([]fs.DirEntry(nil), m.dirs[name]...)
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	file, ok := m.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := &memFileInfo{name: path.Base(name), file: file}
+	if file.Mode.IsDir() {
+		return &memDir{info: info, path: name, entries: m.children(name)}, nil
+	}
+	return &memOpenFile{info: info, Reader: bytes.NewReader(file.Data)}, nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	file, ok := m.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !file.Mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return m.children(name), nil
+}
+
+// memOpenFile 打开的普通文件，读取直接委托给 bytes.Reader
+type memOpenFile struct {
+	info *memFileInfo
+	*bytes.Reader
+}
+
+func (f *memOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memOpenFile) Close() error               { return nil }
+
+// memDir 打开的目录，ReadDir 按 fs.ReadDirFile 语义分批返回子条目
+type memDir struct {
+	info    *memFileInfo
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMemFSConformsToFSInterface(t *testing.T) {
+	modTime := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	files := newMemFS(map[string]*memFile{
+		"history.jsonl":       {Data: []byte("{}\n"), Mode: 0644, ModTime: modTime},
+		"projects":            {Mode: fs.ModeDir | 0755, ModTime: modTime},
+		"projects/a/1.jsonl":  {Data: []byte("{}"), Mode: 0644},
+		"projects/b/2.jsonl":  {Data: []byte("{}"), Mode: 0644},
+		"debug/session-1.txt": {Data: []byte("log"), Mode: 0644},
+	})
+	// 中间目录（projects/a、debug）未显式登记，也应能打开与列出
+	if err := fstest.TestFS(files, "history.jsonl", "projects/a/1.jsonl", "projects/b/2.jsonl", "debug/session-1.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := files.ReadDir("projects")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "a" || !entries[0].IsDir() {
+		t.Fatalf("ReadDir(projects)=%v err=%v, want [a b] 目录", entries, err)
+	}
+	if _, err := files.ReadDir("history.jsonl"); err == nil {
+		t.Fatal("对文件 ReadDir 应返回错误")
+	}
+	info, err := fs.Stat(files, "projects")
+	if err != nil || !info.IsDir() || !info.ModTime().Equal(modTime) {
+		t.Fatalf("Stat(projects)=%v err=%v, want 显式登记的目录及其修改时间", info, err)
+	}
+	if _, err := files.Open("missing.jsonl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open(missing) err=%v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestMemFSDirectoryIndex(t *testing.T) {
+	files := newMemFS(map[string]*memFile{
+		"empty":           {Mode: fs.ModeDir | 0755},
+		"a/b/c/d.jsonl":   {Data: []byte("{}"), Mode: 0644},
+		"a/b/e.jsonl":     {Data: []byte("{}"), Mode: 0644},
+		"a/z.jsonl":       {Data: []byte("{}"), Mode: 0644},
+		"a/b/c/f/g.jsonl": {Data: []byte("{}"), Mode: 0644},
+	})
+
+	// 显式登记的空目录也应出现在索引中
+	entries, err := files.ReadDir("empty")
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("ReadDir(empty)=%v err=%v, want 空目录", entries, err)
+	}
+	root, err := files.ReadDir(".")
+	if err != nil || len(root) != 2 || root[0].Name() != "a" || root[1].Name() != "empty" {
+		t.Fatalf("ReadDir(.)=%v err=%v, want [a empty]", root, err)
+	}
+
+	// 多层隐式目录逐级可列出，子条目按名称排序
+	want := map[string][]string{
+		"a":     {"b", "z.jsonl"},
+		"a/b":   {"c", "e.jsonl"},
+		"a/b/c": {"d.jsonl", "f"},
+	}
+	for dir, names := range want {
+		entries, err := files.ReadDir(dir)
+		if err != nil || len(entries) != len(names) {
+			t.Fatalf("ReadDir(%s)=%v err=%v, want %v", dir, entries, err, names)
+		}
+		for i, name := range names {
+			if entries[i].Name() != name {
+				t.Fatalf("ReadDir(%s)[%d]=%s, want %s", dir, i, entries[i].Name(), name)
+			}
+		}
+	}
+
+	// 返回的是副本，调用方修改不影响索引
+	entries, _ = files.ReadDir("a")
+	entries[0] = nil
+	if again, _ := files.ReadDir("a"); again[0] == nil || again[0].Name() != "b" {
+		t.Fatalf("修改 ReadDir 返回值污染了目录索引: %v", again)
+	}
+	if _, err := files.ReadDir("a/b/c/d.jsonl/x"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ReadDir(不存在路径) err=%v, want fs.ErrNotExist", err)
+	}
+}
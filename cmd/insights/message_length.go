@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// pastedPlaceholderPattern history.jsonl 中粘贴内容的占位符（如 "[Pasted text #1 +42 lines]"），
+// 粘贴正文在 PastedContents 中，不计入提问长度
+var pastedPlaceholderPattern = regexp.MustCompile(`\[Pasted text #\d+[^\]]*\]`)
+
+// LengthSummary 一组文本的字符数（按 rune 计）分布
+type LengthSummary struct {
+	Count   int     `json:"count"`
+	Average float64 `json:"avg_chars"`
+	Median  int     `json:"median_chars"`
+	P95     int     `json:"p95_chars"`
+}
+
+// MessageLengthStats 用户提问（history.jsonl 的 display）与 assistant 文本回复的长度分布
+type MessageLengthStats struct {
+	UserPrompts   LengthSummary `json:"user_prompts"`
+	AssistantText LengthSummary `json:"assistant_text"`
+}
+
+// summarizeLengths 计算平均值与中位数、P95（最近秩法）；lengths 会被原地排序
+func summarizeLengths(lengths []int) LengthSummary {
+	if len(lengths) == 0 {
+		return LengthSummary{}
+	}
+	sort.Ints(lengths)
+	total := 0
+	for _, n := range lengths {
+		total += n
+	}
+	rank := func(p int) int {
+		idx := (p*len(lengths)+99)/100 - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return lengths[idx]
+	}
+	return LengthSummary{
+		Count:   len(lengths),
+		Average: float64(total) / float64(len(lengths)),
+		Median:  rank(50),
+		P95:     rank(95),
+	}
+}
+
+// userPromptLength 去掉粘贴占位符后的提问字符数
+func userPromptLength(display string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(pastedPlaceholderPattern.ReplaceAllString(display, "")))
+}
+
+// ParseMessageLengthStats 统计时间范围内用户提问与 assistant 文本回复的字符长度。
+// 提问取全部 history 分片的 display（去掉粘贴占位符，粘贴正文不计入）；
+// 回复取 projects 中 assistant 消息的 text 片段之和，只含工具调用、thinking 的消息不计。
+func ParseMessageLengthStats(tf TimeFilter) (*MessageLengthStats, error) {
+	var userLengths, assistantLengths []int
+
+	for _, path := range historyFilePaths() {
+		f, err := dataSource.Open(path)
+		if err != nil {
+			continue
+		}
+		lines := newJSONLReader(f)
+		for {
+			line, ok := lines.Next()
+			if !ok {
+				break
+			}
+			var record HistoryRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				continue
+			}
+			if !tf.Contains(parseHistoryTimestamp(record.Timestamp)) || isExcludedProject(record.Project) {
+				continue
+			}
+			if n := userPromptLength(record.Display); n > 0 {
+				userLengths = append(userLengths, n)
+			}
+		}
+		f.Close()
+	}
+
+	projectDirs, err := listProjectDirs(GetDataPath("projects"))
+	if err != nil {
+		return nil, fmt.Errorf("读取 projects 目录失败: %w", err)
+	}
+	for _, projectDir := range projectDirs {
+		files, err := projectJSONLFiles(projectDir)
+		if err != nil {
+			continue
+		}
+		for _, path := range files {
+			f, err := dataSource.Open(path)
+			if err != nil {
+				continue
+			}
+			lines := newJSONLReader(f)
+			for {
+				line, ok := lines.Next()
+				if !ok {
+					break
+				}
+				var record ProjectRecord
+				if err := json.Unmarshal(line, &record); err != nil || record.Type != "assistant" {
+					continue
+				}
+				timestamp, hasTimestamp := parseProjectRecordTimestamp(record.Timestamp)
+				if (hasTimestamp && !tf.Contains(timestamp)) || (!hasTimestamp && hasTimeFilter(tf)) {
+					continue
+				}
+				if !tf.MatchesUserType(record.UserType) || isExcludedProject(record.Cwd) {
+					continue
+				}
+				var msg AssistantMessage
+				if err := json.Unmarshal(record.Message, &msg); err != nil {
+					continue
+				}
+				n := 0
+				for _, part := range msg.Content {
+					if part.Type == "text" {
+						n += utf8.RuneCountInString(strings.TrimSpace(part.Text))
+					}
+				}
+				if n > 0 {
+					assistantLengths = append(assistantLengths, n)
+				}
+			}
+			f.Close()
+		}
+	}
+
+	return &MessageLengthStats{
+		UserPrompts:   summarizeLengths(userLengths),
+		AssistantText: summarizeLengths(assistantLengths),
+	}, nil
+}
+
+// handleMessageLengthsAPI /api/message-lengths：时间范围内提问与回复的字符长度分布，实时解析不读缓存
+func handleMessageLengthsAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startedAt := time.Now()
+	stats, err := ParseMessageLengthStats(filter.TimeFilter)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, stats, "parsing", rangeInfo, filter, startedAt)
+}
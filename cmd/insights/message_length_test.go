@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assistantTextRecordJSON 带 text 内容的 assistant 记录
+func assistantTextRecordJSON(sessionID, text string, ts time.Time) string {
+	return strings.Replace(projectRecordJSON("/tmp/demo", sessionID, ts), `"message":{`, `"message":{"content":[{"type":"text","text":"`+text+`"}],`, 1)
+}
+
+func TestParseMessageLengthStatsAverages(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	// 提问长度 4、6（按字符计）、2（粘贴占位符与粘贴正文不计入）
+	history := `{"display":"abcd","timestamp":` + formatUnixMilli(base) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"你好世界!!","timestamp":` + formatUnixMilli(base.Add(time.Minute)) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"xx [Pasted text #1 +3 lines]","pastedContents":{"1":"` + strings.Repeat("p", 500) + `"},"timestamp":` + formatUnixMilli(base.Add(2*time.Minute)) + `,"project":"/tmp/demo"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 回复长度 5、10；没有 text 的 assistant 消息不计
+	projects := assistantTextRecordJSON("s1", "hello", base) + "\n" +
+		assistantTextRecordJSON("s1", "abcdefghij", base.Add(time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(2*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(projects), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	stats, err := ParseMessageLengthStats(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseMessageLengthStats() error = %v", err)
+	}
+	if got := stats.UserPrompts; got.Count != 3 || got.Average != 4 || got.Median != 4 || got.P95 != 6 {
+		t.Fatalf("user_prompts = %+v, want count=3 avg=4 median=4 p95=6", got)
+	}
+	if got := stats.AssistantText; got.Count != 2 || got.Average != 7.5 || got.Median != 5 || got.P95 != 10 {
+		t.Fatalf("assistant_text = %+v, want count=2 avg=7.5 median=5 p95=10", got)
+	}
+
+	// /api/message-lengths 返回同一统计
+	w := httptest.NewRecorder()
+	handleMessageLengthsAPI(w, httptest.NewRequest("GET", "/api/message-lengths?preset=all", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Success bool               `json:"success"`
+		Data    MessageLengthStats `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data != *stats {
+		t.Fatalf("/api/message-lengths = %s, want %+v", w.Body.String(), *stats)
+	}
+
+	lengths := make([]int, 0, 20)
+	for i := 20; i >= 1; i-- {
+		lengths = append(lengths, i)
+	}
+	if got := summarizeLengths(lengths); got.Median != 10 || got.P95 != 19 || got.Average != 10.5 {
+		t.Fatalf("summarizeLengths(1..20) = %+v, want median=10 p95=19 avg=10.5", got)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prometheus 指标：手写 text exposition（0.0.4），不引入 client 库。
+// /metrics 暴露请求计数、/api/data 延迟直方图、数据来源（缓存命中/降级解析）计数与缓存规模。
+
+// dataLatencyBuckets /api/data 延迟直方图的桶上界（秒）
+var dataLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type requestMetricKey struct {
+	Path   string
+	Status int
+}
+
+// metricsRegistry 进程内指标
+type metricsRegistry struct {
+	mu            sync.Mutex
+	requests      map[requestMetricKey]uint64
+	dataSources   map[string]uint64
+	latencyCounts []uint64 // 与 dataLatencyBuckets 对齐的非累积计数
+	latencySum    float64
+	latencyCount  uint64
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		requests:      make(map[requestMetricKey]uint64),
+		dataSources:   make(map[string]uint64),
+		latencyCounts: make([]uint64, len(dataLatencyBuckets)),
+	}
+}
+
+// metrics 全局指标实例
+var metrics = newMetricsRegistry()
+
+// observeRequest 记录一次 HTTP 请求
+func (m *metricsRegistry) observeRequest(path string, status int) {
+	m.mu.Lock()
+	m.requests[requestMetricKey{Path: path, Status: status}]++
+	m.mu.Unlock()
+}
+
+// observeData 记录一次 /api/data 的数据来源（cache|parsing|delta）与耗时
+func (m *metricsRegistry) observeData(source string, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dataSources[source]++
+	m.latencySum += seconds
+	m.latencyCount++
+	for i, bound := range dataLatencyBuckets {
+		if seconds <= bound {
+			m.latencyCounts[i]++
+			break
+		}
+	}
+}
+
+// isAPIRoute 判断 path 是否为已注册的 /api/ 路由
+func isAPIRoute(path string) bool {
+	for _, route := range apiRoutes {
+		if route.pattern == path {
+			return true
+		}
+	}
+	return false
+}
+
+// metricsPathLabel 归一化请求路径，避免静态资源、未注册的 /api/ 路径等撑爆标签基数
+func metricsPathLabel(path string) string {
+	switch {
+	case isAPIRoute(path), path == "/metrics", path == "/ws", path == "/":
+		return path
+	case strings.HasPrefix(path, "/static/"):
+		return "/static/"
+	case path == "/dashboard" || strings.HasPrefix(path, "/dashboard/"):
+		return "/dashboard"
+	default:
+		return "other"
+	}
+}
+
+// labelValueEscaper 按 Prometheus 文本格式转义标签值：只转义反斜杠、双引号与换行（%q 会把非 ASCII 转成 \u 序列）
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// MetricsMiddleware 统计每个请求的路径与状态码
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, status: 200}
+		next.ServeHTTP(rw, r)
+		metrics.observeRequest(metricsPathLabel(r.URL.Path), rw.status)
+	})
+}
+
+// writeTo 以 Prometheus 文本格式输出全部指标；cache 为当前全局缓存（可为 nil）
+func (m *metricsRegistry) writeTo(w io.Writer, cache *CacheFile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP cc_insights_http_requests_total HTTP 请求数（按路径与状态码）")
+	fmt.Fprintln(w, "# TYPE cc_insights_http_requests_total counter")
+	keys := make([]requestMetricKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Path != keys[j].Path {
+			return keys[i].Path < keys[j].Path
+		}
+		return keys[i].Status < keys[j].Status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "cc_insights_http_requests_total{path=\"%s\",status=\"%d\"} %d\n", escapeLabelValue(key.Path), key.Status, m.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP cc_insights_data_requests_total /api/data 数据来源：cache 为缓存命中，parsing 为降级实时解析")
+	fmt.Fprintln(w, "# TYPE cc_insights_data_requests_total counter")
+	sources := make([]string, 0, len(m.dataSources))
+	for source := range m.dataSources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		fmt.Fprintf(w, "cc_insights_data_requests_total{source=\"%s\"} %d\n", escapeLabelValue(source), m.dataSources[source])
+	}
+
+	fmt.Fprintln(w, "# HELP cc_insights_data_request_duration_seconds /api/data 数据构建耗时")
+	fmt.Fprintln(w, "# TYPE cc_insights_data_request_duration_seconds histogram")
+	var cumulative uint64
+	for i, bound := range dataLatencyBuckets {
+		cumulative += m.latencyCounts[i]
+		fmt.Fprintf(w, "cc_insights_data_request_duration_seconds_bucket{le=\"%g\"} %d\n", bound, cumulative)
+	}
+	fmt.Fprintf(w, "cc_insights_data_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyCount)
+	fmt.Fprintf(w, "cc_insights_data_request_duration_seconds_sum %g\n", m.latencySum)
+	fmt.Fprintf(w, "cc_insights_data_request_duration_seconds_count %d\n", m.latencyCount)
+
+	loaded, messages, sessions := 0, 0, 0
+	if cache != nil {
+		loaded, messages, sessions = 1, cache.TotalMessages, cache.TotalSessions
+	}
+	fmt.Fprintln(w, "# HELP cc_insights_cache_loaded 是否已加载缓存")
+	fmt.Fprintln(w, "# TYPE cc_insights_cache_loaded gauge")
+	fmt.Fprintf(w, "cc_insights_cache_loaded %d\n", loaded)
+	fmt.Fprintln(w, "# HELP cc_insights_cache_total_messages 缓存中的消息总数")
+	fmt.Fprintln(w, "# TYPE cc_insights_cache_total_messages gauge")
+	fmt.Fprintf(w, "cc_insights_cache_total_messages %d\n", messages)
+	fmt.Fprintln(w, "# HELP cc_insights_cache_total_sessions 缓存中的会话总数")
+	fmt.Fprintln(w, "# TYPE cc_insights_cache_total_sessions gauge")
+	fmt.Fprintf(w, "cc_insights_cache_total_sessions %d\n", sessions)
+}
+
+// handleMetrics 输出 Prometheus 指标
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.writeTo(w, loadGlobalCache())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpointCountsRequests(t *testing.T) {
+	dataDir := useFixtureDataDir(t)
+	origMetrics := metrics
+	metrics = newMetricsRegistry()
+	defer func() { metrics = origMetrics }()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	storeGlobalCache(cache)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/data", handleDataAPI)
+	mux.HandleFunc("/api/version", versionHandler)
+	mux.HandleFunc("/metrics", handleMetrics)
+	handler := MetricsMiddleware(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	scrape := func() string {
+		w := get("/metrics")
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+			t.Fatalf("/metrics status=%d content-type=%s", w.Code, w.Header().Get("Content-Type"))
+		}
+		return w.Body.String()
+	}
+
+	get("/api/version")
+	before := scrape()
+	if !strings.Contains(before, `cc_insights_http_requests_total{path="/api/version",status="200"} 1`+"\n") {
+		t.Fatalf("首次请求后计数不符:\n%s", before)
+	}
+
+	get("/api/version")
+	get("/api/data?preset=all")
+	after := scrape()
+	for _, want := range []string{
+		`cc_insights_http_requests_total{path="/api/version",status="200"} 2`,
+		`cc_insights_http_requests_total{path="/api/data",status="200"} 1`,
+		`cc_insights_http_requests_total{path="/metrics",status="200"} 1`,
+		`cc_insights_data_requests_total{source="cache"} 1`,
+		`cc_insights_data_request_duration_seconds_bucket{le="+Inf"} 1`,
+		`cc_insights_data_request_duration_seconds_count 1`,
+		`cc_insights_cache_loaded 1`,
+		`cc_insights_cache_total_messages 5`,
+		`cc_insights_cache_total_sessions 3`,
+	} {
+		if !strings.Contains(after, want+"\n") {
+			t.Fatalf("缺少指标 %q:\n%s", want, after)
+		}
+	}
+}
+
+func TestMetricsPathLabelCollapsesUnknownAPIPaths(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/data":             "/api/data",
+		"/api/detail/sessions":  "/api/detail/sessions",
+		"/api/no-such-endpoint": "other",
+		"/api/data/extra":       "other",
+		"/static/app.js":        "/static/",
+		"/dashboard/charts":     "/dashboard",
+		"/metrics":              "/metrics",
+	} {
+		if got := metricsPathLabel(path); got != want {
+			t.Fatalf("metricsPathLabel(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMetricsEscapesLabelValues(t *testing.T) {
+	m := newMetricsRegistry()
+	m.observeRequest("/路径\"x\\y\n", 200)
+	var buf strings.Builder
+	m.writeTo(&buf, nil)
+	// 非 ASCII 原样输出，只转义反斜杠、双引号与换行
+	want := `cc_insights_http_requests_total{path="/路径\"x\\y\n",status="200"} 1` + "\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("缺少转义后的指标 %q:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// defaultReadRetries 打开数据文件遇到临时性错误时的默认重试次数（不含首次尝试）
+const defaultReadRetries = 2
+
+// readRetryBackoff 首次重试前的等待时间，之后每次翻倍；测试可调小
+var readRetryBackoff = 50 * time.Millisecond
+
+// openDataFile 通过 dataSource 打开 history/projects 数据文件。
+// Claude Code 正在写入会话文件时，Windows 上偶尔因共享冲突或权限错误打开失败，
+// 此时按指数退避重试 cfg.ReadRetries 次；文件不存在等其他错误直接返回
+func openDataFile(name string) (fs.File, error) {
+	backoff := readRetryBackoff
+	for attempt := 0; ; attempt++ {
+		f, err := dataSource.Open(name)
+		if err == nil || attempt >= cfg.ReadRetries || !isTransientOpenError(err) {
+			return f, err
+		}
+		Debug("数据文件暂时无法打开，稍后重试", "path", name, "attempt", attempt+1, "error", err.Error())
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransientOpenError 是否为可能随写入方释放而消失的打开错误
+func isTransientOpenError(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	return errors.Is(err, fs.ErrPermission) || isSharingViolation(err)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// isSharingViolation 非 Windows 平台没有强制共享锁，打开文件不会出现共享冲突
+func isSharingViolation(err error) bool {
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// flakyDataSource 前 failures 次 Open 返回 err，之后委托给本地文件系统
+type flakyDataSource struct {
+	osDataSource
+	failures int
+	err      error
+	opens    int
+}
+
+func (ds *flakyDataSource) Open(name string) (fs.File, error) {
+	ds.opens++
+	if ds.opens <= ds.failures {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ds.err}
+	}
+	return ds.osDataSource.Open(name)
+}
+
+func TestOpenDataFileRetriesTransientErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jsonl")
+	if err := os.WriteFile(path, []byte(projectRecordJSON("/tmp/demo", "s1", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC))+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origSource, origRetries, origBackoff := dataSource, cfg.ReadRetries, readRetryBackoff
+	defer func() { dataSource, cfg.ReadRetries, readRetryBackoff = origSource, origRetries, origBackoff }()
+	readRetryBackoff = time.Millisecond
+	cfg.ReadRetries = defaultReadRetries
+
+	// 首次打开遇到权限错误：重试后成功，文件内容照常计入
+	flaky := &flakyDataSource{failures: 1, err: fs.ErrPermission}
+	dataSource = flaky
+	agg := newProjectAggregate()
+	parseProjectFileAggregate(path, TimeFilter{}, agg)
+	if flaky.opens != 2 || agg.DailyActivity["2026-01-05"] != 1 {
+		t.Fatalf("opens=%d daily=%v, want 2 次打开并计入 1 条消息", flaky.opens, agg.DailyActivity)
+	}
+
+	// 连续失败超过重试次数时放弃
+	flaky = &flakyDataSource{failures: 10, err: fs.ErrPermission}
+	dataSource = flaky
+	if _, err := openDataFile(path); err == nil || flaky.opens != defaultReadRetries+1 {
+		t.Fatalf("opens=%d err=%v, want %d 次后失败", flaky.opens, err, defaultReadRetries+1)
+	}
+
+	// 文件不存在不重试
+	flaky = &flakyDataSource{failures: 10, err: fs.ErrNotExist}
+	dataSource = flaky
+	if _, err := openDataFile(path); err == nil || flaky.opens != 1 {
+		t.Fatalf("opens=%d err=%v, want 不存在时只打开 1 次", flaky.opens, err)
+	}
+}
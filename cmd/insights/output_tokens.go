@@ -0,0 +1,42 @@
+package main
+
+import "sort"
+
+// DailyOutputTokenItem 单日 assistant 消息的 output token 合计与平均值，用于观察回复长短的变化
+type DailyOutputTokenItem struct {
+	Date            string  `json:"date"`
+	Messages        int     `json:"messages"`
+	OutputTokens    int     `json:"output_tokens"`
+	AvgOutputTokens float64 `json:"avg_output_tokens"`
+}
+
+// avgOutputTokens 每条消息平均 output token 数，消息数为 0 时返回 0
+func avgOutputTokens(outputTokens, messages int) float64 {
+	if messages <= 0 {
+		return 0
+	}
+	return float64(outputTokens) / float64(messages)
+}
+
+// buildDailyOutputTokens 由每日 assistant 消息数与每日 output token 数构建按日期升序的序列；
+// 只包含有消息的日期，没有数据时返回 nil
+func buildDailyOutputTokens(dailyMessages, dailyOutputTokens map[string]int) []DailyOutputTokenItem {
+	items := make([]DailyOutputTokenItem, 0, len(dailyMessages))
+	for date, messages := range dailyMessages {
+		if messages <= 0 {
+			continue
+		}
+		output := dailyOutputTokens[date]
+		items = append(items, DailyOutputTokenItem{
+			Date:            date,
+			Messages:        messages,
+			OutputTokens:    output,
+			AvgOutputTokens: avgOutputTokens(output, messages),
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Date < items[j].Date })
+	return items
+}
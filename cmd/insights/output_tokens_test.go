@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// outputRecordJSON 指定 output_tokens 的 assistant 记录
+func outputRecordJSON(sessionID string, outputTokens int, ts time.Time) string {
+	return strings.Replace(projectRecordJSON("/tmp/demo", sessionID, ts), `"output_tokens":5`, `"output_tokens":`+strconv.Itoa(outputTokens), 1)
+}
+
+func TestAvgOutputTokensPerMessage(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := outputRecordJSON("s1", 10, base) + "\n" +
+		outputRecordJSON("s1", 30, base.Add(time.Minute)) + "\n" +
+		outputRecordJSON("s2", 7, base.AddDate(0, 0, 1)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	want := []DailyOutputTokenItem{
+		{Date: "2026-01-05", Messages: 2, OutputTokens: 40, AvgOutputTokens: 20},
+		{Date: "2026-01-06", Messages: 1, OutputTokens: 7, AvgOutputTokens: 7},
+	}
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if got := buildDailyOutputTokens(agg.DailyActivity, agg.DailyOutputTokens); !reflect.DeepEqual(got, want) {
+		t.Fatalf("daily output tokens = %+v, want %+v", got, want)
+	}
+
+	// 缓存路径：按日落盘的消息数与 output token 数应得到相同序列
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	messages, outputs := make(map[string]int), make(map[string]int)
+	for date, day := range cache.DailyStats {
+		messages[date] = day.AssistantMessages
+		outputs[date] = day.OutputTokens
+	}
+	if got := buildDailyOutputTokens(messages, outputs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached daily output tokens = %+v, want %+v", got, want)
+	}
+
+	// 总体平均按总量相除：(10+30+7)/3，而不是各日平均值的平均
+	summary := buildDashboardSummary(&DashboardData{DailyOutputTokens: want})
+	if math.Abs(summary.AvgOutputTokensPerMessage-47.0/3) > 1e-9 {
+		t.Fatalf("avg_output_tokens_per_message = %v, want %v", summary.AvgOutputTokensPerMessage, 47.0/3)
+	}
+	if empty := buildDashboardSummary(&DashboardData{}); empty.AvgOutputTokensPerMessage != 0 {
+		t.Fatalf("无消息时平均值 = %v, want 0", empty.AvgOutputTokensPerMessage)
+	}
+}
+
+func TestAvgOutputTokensIgnoresCountMode(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, name := range []string{"demo", "solo"} {
+		if err := os.MkdirAll(filepath.Join(dataDir, "projects", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	userRecord := `{"type":"user","cwd":"/tmp/demo","sessionId":"s1","timestamp":"` + base.Format(time.RFC3339Nano) + `","message":{"role":"user","content":"hi"}}`
+	content := userRecord + "\n" +
+		outputRecordJSON("s1", 10, base.Add(time.Minute)) + "\n" +
+		outputRecordJSON("s1", 30, base.Add(2*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "projects", "demo", "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 只有 assistant 记录的项目
+	solo := projectRecordJSON("/tmp/solo", "s2", base.Add(3*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "projects", "solo", "b.jsonl"), []byte(solo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{CountMode: CountModeUser})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	// count_mode=user 时每日消息数只有 1 条 user，平均值仍按 3 条 assistant 计算
+	want := []DailyOutputTokenItem{{Date: "2026-01-05", Messages: 3, OutputTokens: 45, AvgOutputTokens: 15}}
+	if got := buildDailyOutputTokens(agg.DailyAssistantMessages, agg.DailyOutputTokens); !reflect.DeepEqual(got, want) {
+		t.Fatalf("daily output tokens = %+v, want %+v", got, want)
+	}
+	for _, project := range agg.Projects {
+		if project.MessageCount == 0 {
+			t.Fatalf("count_mode=user 不应出现 0 消息的项目: %+v", project)
+		}
+	}
+	if len(agg.Projects) != 1 {
+		t.Fatalf("projects=%+v, want 只有 /tmp/demo", agg.Projects)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// maxParseErrorSample 样例行最多保留的字符数
+const maxParseErrorSample = 200
+
+// FileParseReport 单个 JSONL 文件的解码失败情况
+type FileParseReport struct {
+	Kind        string `json:"kind"` // history | project
+	Path        string `json:"path"` // 相对数据目录
+	Lines       int    `json:"lines"`
+	FailedLines int    `json:"failed_lines"`
+	// FirstFailedLine 第一条解码失败的行号（从 1 开始，空行也计数）与其内容（过长时截断）
+	FirstFailedLine int    `json:"first_failed_line"`
+	Sample          string `json:"sample"`
+	Error           string `json:"error"`
+}
+
+// parseErrorListData /api/debug/errors 返回的解码失败清单
+type parseErrorListData struct {
+	FailedLines int               `json:"failed_lines"`
+	Files       []FileParseReport `json:"files"`
+}
+
+// truncateSample 按字符截断样例行
+func truncateSample(line string) string {
+	runes := []rune(line)
+	if len(runes) <= maxParseErrorSample {
+		return line
+	}
+	return string(runes[:maxParseErrorSample]) + "…"
+}
+
+// scanFileParseErrors 逐行按 decode 解码，统计失败行；没有失败时返回 nil
+func scanFileParseErrors(kind, path string, decode func(line []byte) error) *FileParseReport {
+	f, err := openDataFile(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	report := FileParseReport{Kind: kind, Path: dataFileRelPath(path)}
+	lines := newJSONLReader(f)
+	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
+		report.Lines++
+		if err := decode(line); err != nil {
+			report.FailedLines++
+			if report.FirstFailedLine == 0 {
+				report.FirstFailedLine = lines.Line()
+				report.Sample = truncateSample(string(line))
+				report.Error = err.Error()
+			}
+		}
+	}
+	if report.FailedLines == 0 {
+		return nil
+	}
+	return &report
+}
+
+// CollectParseErrors 重新扫描 history 与 projects 下的 JSONL，报告每个文件解码失败的行数与第一条失败行，
+// 解码方式与各解析器一致（解析器遇到这些行会静默跳过）。修改时间早于 tf 起点的文件不可能含范围内的记录，直接跳过。
+// 结果按失败行数降序，同数按路径排序
+func CollectParseErrors(tf TimeFilter) []FileParseReport {
+	var reports []FileParseReport
+	scan := func(kind, path string, decode func(line []byte) error) {
+		if tf.Start != nil {
+			if info, err := statData(path); err == nil && info.ModTime().Before(*tf.Start) {
+				return
+			}
+		}
+		if report := scanFileParseErrors(kind, path, decode); report != nil {
+			reports = append(reports, *report)
+		}
+	}
+
+	for _, path := range historyFilePaths() {
+		scan(dataFileHistory, path, func(line []byte) error {
+			var record HistoryRecord
+			return json.Unmarshal(line, &record)
+		})
+	}
+	if projectDirs, err := listProjectDirs(GetDataPath("projects")); err == nil {
+		for _, projectDir := range projectDirs {
+			paths, err := projectJSONLFiles(projectDir)
+			if err != nil {
+				continue
+			}
+			for _, path := range paths {
+				scan(dataFileProject, path, func(line []byte) error {
+					var record ProjectRecord
+					return json.Unmarshal(line, &record)
+				})
+			}
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].FailedLines != reports[j].FailedLines {
+			return reports[i].FailedLines > reports[j].FailedLines
+		}
+		return reports[i].Path < reports[j].Path
+	})
+	return reports
+}
+
+// handleDebugErrorsAPI 排障用：逐文件报告解码失败的行。需要重新读取全部 JSONL，较慢；
+// 仅在 --debug 启动时可用，否则返回 404
+func handleDebugErrorsAPI(w http.ResponseWriter, r *http.Request) {
+	if !cfg.DebugEndpoints {
+		http.NotFound(w, r)
+		return
+	}
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startedAt := time.Now()
+	files := CollectParseErrors(filter.TimeFilter)
+	payload := parseErrorListData{Files: files}
+	if payload.Files == nil {
+		payload.Files = []FileParseReport{}
+	}
+	for _, file := range files {
+		payload.FailedLines += file.FailedLines
+	}
+
+	rangeInfo := TimeRangeInfo{Preset: filter.Preset}
+	if filter.TimeFilter.Start != nil {
+		rangeInfo.Start = filter.TimeFilter.Start.Format("2006-01-02")
+	}
+	if filter.TimeFilter.End != nil {
+		rangeInfo.End = filter.TimeFilter.End.Format("2006-01-02")
+	}
+	sendInteractiveJSON(w, payload, "parsing", rangeInfo, filter, startedAt)
+}
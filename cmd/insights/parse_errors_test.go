@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectParseErrorsReportsBadLine(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	badLine := `{"type":"assistant","cwd":"/tmp/demo",`
+	content := projectRecordJSON("/tmp/demo", "s1", ts) + "\n\n" +
+		badLine + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", ts.Add(time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "ok.jsonl"), []byte(projectRecordJSON("/tmp/demo", "s2", ts)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(`{"display":"/help","timestamp":1767603600000}`+"\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origCfg := cfg
+	cfg.DataDir = dataDir
+	defer func() { cfg = origCfg }()
+
+	reports := CollectParseErrors(TimeFilter{})
+	if len(reports) != 2 {
+		t.Fatalf("reports=%+v, want 2 个文件（ok.jsonl 不应出现）", reports)
+	}
+	project := reports[0]
+	if project.Path == "history.jsonl" {
+		project = reports[1]
+	}
+	if project.Kind != dataFileProject || project.Path != "projects/demo/a.jsonl" || project.Lines != 3 || project.FailedLines != 1 ||
+		project.FirstFailedLine != 3 || project.Sample != badLine || project.Error == "" {
+		t.Fatalf("project report=%+v", project)
+	}
+
+	cfg.DebugEndpoints = false
+	w := httptest.NewRecorder()
+	handleDebugErrorsAPI(w, httptest.NewRequest("GET", "/api/debug/errors", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("未开启 debug 时 status=%d, want 404", w.Code)
+	}
+
+	cfg.DebugEndpoints = true
+	w = httptest.NewRecorder()
+	handleDebugErrorsAPI(w, httptest.NewRequest("GET", "/api/debug/errors?preset=all", nil))
+	var resp struct {
+		Success bool               `json:"success"`
+		Data    parseErrorListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.FailedLines != 2 || len(resp.Data.Files) != 2 {
+		t.Fatalf("/api/debug/errors 结果不符: %s", w.Body.String())
+	}
+}
@@ -289,7 +289,7 @@ func ensurePlanModeAgg(agg *ProjectAggregate) *PlanModeAgg {
 }
 
 // recordPlanModeLocked records a plan_mode entry event
-func recordPlanModeLocked(agg *ProjectAggregate, planFilePath string, planExists bool, sessionID, cwd string) {
+func recordPlanModeLocked(agg *ProjectAggregate, planFilePath string, planExists bool, sessionID, project string) {
 	p := ensurePlanModeAgg(agg)
 	p.EntryCount++
 	if sessionID != "" && !p.SessionSet[sessionID] {
@@ -374,7 +374,7 @@ func recordGoalStatusLocked(agg *ProjectAggregate, met, sentinel bool, condition
 }
 
 // recordReminderLocked records task_reminder or todo_reminder frequency
-func recordReminderLocked(agg *ProjectAggregate, kind, sessionID, cwd string) {
+func recordReminderLocked(agg *ProjectAggregate, kind, sessionID, project string) {
 	if agg.ReminderAgg == nil {
 		agg.ReminderAgg = &ReminderAgg{
 			TaskSessionCounts:   make(map[string]int),
@@ -388,13 +388,13 @@ func recordReminderLocked(agg *ProjectAggregate, kind, sessionID, cwd string) {
 		r.TaskReminderCount++
 		r.TaskSessionCounts[sessionID]++
 		if _, exists := r.TaskSessionProjects[sessionID]; !exists {
-			r.TaskSessionProjects[sessionID] = cwd
+			r.TaskSessionProjects[sessionID] = project
 		}
 	} else {
 		r.TodoReminderCount++
 		r.TodoSessionCounts[sessionID]++
 		if _, exists := r.TodoSessionProjects[sessionID]; !exists {
-			r.TodoSessionProjects[sessionID] = cwd
+			r.TodoSessionProjects[sessionID] = project
 		}
 	}
 }
@@ -465,7 +465,7 @@ func recordRuntimeEventLocked(agg *ProjectAggregate, record ProjectRecord, times
 				recordFileHistorySnapshotLocked(agg, record.Attachment, record.SessionID)
 			case "edited_text_file":
 			case "plan_mode":
-				recordPlanModeLocked(agg, attachment.PlanFilePath, attachment.PlanExists, record.SessionID, record.Cwd)
+				recordPlanModeLocked(agg, attachment.PlanFilePath, attachment.PlanExists, record.SessionID, projectName)
 			case "plan_mode_exit":
 				recordPlanModeExitLocked(agg, attachment.ExitReason, record.SessionID)
 			case "plan_mode_reentry":
@@ -475,9 +475,9 @@ func recordRuntimeEventLocked(agg *ProjectAggregate, record ProjectRecord, times
 			case "goal_status":
 				recordGoalStatusLocked(agg, attachment.Met, attachment.Sentinel, attachment.Condition, record.SessionID, timestamp)
 			case "task_reminder":
-				recordReminderLocked(agg, "task", record.SessionID, record.Cwd)
+				recordReminderLocked(agg, "task", record.SessionID, projectName)
 			case "todo_reminder":
-				recordReminderLocked(agg, "todo", record.SessionID, record.Cwd)
+				recordReminderLocked(agg, "todo", record.SessionID, projectName)
 				recordEditedTextFileLocked(agg, attachment.Filename, record.Attachment)
 			}
 			if len(agg.EventSamples) < 40 && (strings.HasPrefix(attachment.Type, "hook_") || attachment.Type == "invoked_skills" || attachment.Type == "queued_command") {
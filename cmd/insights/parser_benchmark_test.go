@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 合成数据集上的解析器基准：不依赖真实 ~/.claude，go test -bench 即可对比解析器改动前后的性能。
+//   go test -run '^$' -bench 'Parse' -benchmem ./cmd/insights
+
+const (
+	benchSyntheticFiles          = 40
+	benchSyntheticRecordsPerFile = 500
+	// syntheticProjectCount 合成会话文件分布到的项目目录数
+	syntheticProjectCount = 4
+)
+
+var syntheticCommands = []string{"/help", "/model opus", "/compact", "/clear", "/review"}
+
+// generateSyntheticData 在 dir 下生成 files 个项目会话文件，每个文件 recordsPerFile 条记录
+// （user 与带 tool_use 的 assistant 交替），并按相同规模生成 history.jsonl 与 debug 日志。
+// 时间戳落在今天之前的 3 天内（7d 范围截止到今天 00:00），7d 与 all 范围都能覆盖全部记录。
+func generateSyntheticData(dir string, files, recordsPerFile int) error {
+	now := time.Now()
+	base := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -3)
+	span := 47 * time.Hour
+	total := files * recordsPerFile
+	at := func(i int) time.Time {
+		if total <= 1 {
+			return base
+		}
+		return base.Add(time.Duration(int64(span) * int64(i) / int64(total-1)))
+	}
+
+	for p := 0; p < syntheticProjectCount; p++ {
+		if err := os.MkdirAll(filepath.Join(dir, "projects", fmt.Sprintf("-tmp-bench-%d", p)), 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "debug"), 0755); err != nil {
+		return err
+	}
+
+	var history, debug strings.Builder
+	for f := 0; f < files; f++ {
+		project := f % syntheticProjectCount
+		cwd := fmt.Sprintf("/tmp/bench-%d", project)
+		sessionID := fmt.Sprintf("bench-session-%d", f)
+		var content strings.Builder
+		for r := 0; r < recordsPerFile; r++ {
+			i := f*recordsPerFile + r
+			ts := at(i).Format(time.RFC3339Nano)
+			toolID := fmt.Sprintf("toolu_%d_%d", f, r-1)
+			if r%2 == 0 {
+				fmt.Fprintf(&content, `{"type":"user","cwd":%q,"sessionId":%q,"timestamp":%q,"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":%q,"content":"ok"}]}}`+"\n", cwd, sessionID, ts, toolID)
+			} else {
+				model := "claude-sonnet-4-6"
+				if r%3 == 0 {
+					model = "claude-opus-4-6"
+				}
+				tool := "Read"
+				if r%4 == 1 {
+					tool = "Bash"
+				}
+				fmt.Fprintf(&content, `{"type":"assistant","cwd":%q,"sessionId":%q,"timestamp":%q,"message":{"model":%q,"content":[{"type":"tool_use","id":"toolu_%d_%d","name":%q,"input":{}}],"usage":{"input_tokens":%d,"output_tokens":%d}}}`+"\n", cwd, sessionID, ts, model, f, r, tool, 100+r%50, 20+r%10)
+			}
+			fmt.Fprintf(&history, `{"display":%q,"timestamp":%d,"project":%q}`+"\n", syntheticCommands[i%len(syntheticCommands)], at(i).UnixMilli(), cwd)
+			fmt.Fprintf(&debug, "%s [DEBUG] tool call mcp__github__get_file_contents\n", at(i).Format("2006-01-02T15:04:05.000Z"))
+		}
+		path := filepath.Join(dir, "projects", fmt.Sprintf("-tmp-bench-%d", project), sessionID+".jsonl")
+		if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "debug", sessionID+".txt"), []byte(debug.String()), 0644); err != nil {
+			return err
+		}
+		debug.Reset()
+	}
+	return os.WriteFile(filepath.Join(dir, "history.jsonl"), []byte(history.String()), 0644)
+}
+
+// useSyntheticDataDir 生成合成数据集并切换 cfg.DataDir，结束时恢复
+func useSyntheticDataDir(tb testing.TB, files, recordsPerFile int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	if err := generateSyntheticData(dir, files, recordsPerFile); err != nil {
+		tb.Fatalf("生成合成数据失败: %v", err)
+	}
+	origDataDir, origCache, origSource := cfg.DataDir, loadGlobalCache(), dataSource
+	cfg.DataDir = dir
+	storeGlobalCache(nil)
+	dataSource = osDataSource{}
+	tb.Cleanup(func() {
+		cfg.DataDir, dataSource = origDataDir, origSource
+		storeGlobalCache(origCache)
+	})
+	return dir
+}
+
+func TestGenerateSyntheticDataParses(t *testing.T) {
+	const files, records = 6, 10
+	dir := useSyntheticDataDir(t, files, records)
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	messages := 0
+	for _, project := range agg.Projects {
+		messages += project.MessageCount
+	}
+	if len(agg.Projects) != syntheticProjectCount || messages != files*records/2 {
+		t.Fatalf("projects=%d messages=%d, want %d/%d", len(agg.Projects), messages, syntheticProjectCount, files*records/2)
+	}
+
+	cmdStats, _, err := ParseHistoryConcurrent(NewTimeFilterFromPreset(Range7Days))
+	if err != nil {
+		t.Fatalf("ParseHistoryConcurrent failed: %v", err)
+	}
+	commands := 0
+	for _, item := range cmdStats {
+		commands += item.Count
+	}
+	if commands != files*records {
+		t.Fatalf("命令合计=%d, want %d", commands, files*records)
+	}
+
+	signals, err := ParseDebugLogsConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseDebugLogsConcurrent failed: %v", err)
+	}
+	calls := 0
+	for _, item := range signals {
+		calls += item.Count
+	}
+	if calls != files*records {
+		t.Fatalf("debug 信号合计=%d, want %d", calls, files*records)
+	}
+}
+
+func BenchmarkParseHistoryConcurrent(b *testing.B) {
+	useSyntheticDataDir(b, benchSyntheticFiles, benchSyntheticRecordsPerFile)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := ParseHistoryConcurrent(TimeFilter{}); err != nil {
+			b.Fatalf("ParseHistoryConcurrent failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkParseProjectsConcurrentOnce(b *testing.B) {
+	useSyntheticDataDir(b, benchSyntheticFiles, benchSyntheticRecordsPerFile)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseProjectsConcurrentOnce(TimeFilter{}); err != nil {
+			b.Fatalf("ParseProjectsConcurrentOnce failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkParseDebugLogsConcurrent(b *testing.B) {
+	useSyntheticDataDir(b, benchSyntheticFiles, benchSyntheticRecordsPerFile)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseDebugLogsConcurrent(TimeFilter{}); err != nil {
+			b.Fatalf("ParseDebugLogsConcurrent failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkBuildFullCacheSynthetic(b *testing.B) {
+	dir := useSyntheticDataDir(b, benchSyntheticFiles, benchSyntheticRecordsPerFile)
+	cachePath := filepath.Join(b.TempDir(), "cache.db")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		os.Remove(cachePath)
+		b.StartTimer()
+		if err := (&CacheBuilder{CachePath: cachePath, DataDir: dir}).BuildFullCache(); err != nil {
+			b.Fatalf("BuildFullCache failed: %v", err)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestParseProjectsConcurrentOnce 测试一次遍历并发解析所有项目统计
@@ -17,12 +21,7 @@ import (
 //   - ParseModelUsageFromProjects（模型使用）
 //   - ParseWorkHoursStats（工作时段）
 func TestParseProjectsConcurrentOnce(t *testing.T) {
-	tmpDir := t.TempDir()
-	dataDir := createTestDataDir(t, tmpDir)
-
-	origDataDir := cfg.DataDir
-	cfg.DataDir = dataDir
-	defer func() { cfg.DataDir = origDataDir }()
+	useFixtureDataDir(t)
 
 	// Arrange
 	tf := TimeFilter{Start: nil, End: nil}
@@ -90,11 +89,19 @@ func TestParseProjectsConcurrentOnce(t *testing.T) {
 		t.Errorf("数据不一致: 项目总计=%d, 每日总计=%d", totalMessages, totalFromDaily)
 	}
 
-	t.Logf("✅ 一次遍历成功获取所有统计数据:")
-	t.Logf("  项目数: %d", len(aggregate.Projects))
-	t.Logf("  总消息数: %d", totalMessages)
-	t.Logf("  天数: %d", len(aggregate.DailyActivityList))
-	t.Logf("  模型数: %d", len(aggregate.ModelUsageList))
+	// 与 testdata/fixture 的精确数字对齐
+	if len(aggregate.Projects) != 2 {
+		t.Errorf("项目数=%d, want 2", len(aggregate.Projects))
+	}
+	if totalMessages != 5 {
+		t.Errorf("总消息数=%d, want 5", totalMessages)
+	}
+	if len(aggregate.DailyActivityList) != 3 {
+		t.Errorf("天数=%d, want 3", len(aggregate.DailyActivityList))
+	}
+	if len(aggregate.ModelUsageList) != 2 {
+		t.Errorf("模型数=%d, want 2", len(aggregate.ModelUsageList))
+	}
 }
 
 // TestAssistantMessageThinkingType 测试 AssistantMessage 支持 thinking 类型内容
@@ -294,3 +301,866 @@ func TestParseSessionStatsWithFilter_UseAggregate(t *testing.T) {
 
 	t.Logf("✅ ParseSessionStatsWithFilter 正确使用 aggregate: sessions=%d", stats.TotalSessions)
 }
+
+// TestParseHistoryWithArgsBreakdown 测试按「命令 + 首个参数」拆分 slash command 统计
+func TestParseHistoryWithArgsBreakdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	displays := []string{
+		"/model opus",
+		"/model opus",
+		"/model sonnet",
+		"/model",
+		"/compact keep the failing test output and the plan",
+		"/compact",
+		"plain prompt",
+	}
+	var content string
+	for i, display := range displays {
+		content += fmt.Sprintf(`{"display":%q,"timestamp":%d,"project":"demo"}`+"\n", display, 1767225600000+int64(i)*1000)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "history.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入 history.jsonl 失败: %v", err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = tmpDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	parsers := map[string]func(TimeFilter) ([]CommandStats, map[string]int, error){
+		"sequential": ParseHistoryWithArgs,
+		"concurrent": ParseHistoryConcurrentWithArgs,
+	}
+	for name, parse := range parsers {
+		stats, _, err := parse(TimeFilter{})
+		if err != nil {
+			t.Fatalf("%s: 解析失败: %v", name, err)
+		}
+		got := map[string]int{}
+		for _, item := range stats {
+			got[item.Command+"|"+item.SubCommand] = item.Count
+		}
+		want := map[string]int{
+			"/model|opus":   2,
+			"/model|sonnet": 1,
+			"/model|":       1,
+			"/compact|":     2, // prose 参数不拆分
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: rows=%v, want %v", name, got, want)
+		}
+		for key, count := range want {
+			if got[key] != count {
+				t.Fatalf("%s: %s=%d, want %d (all=%v)", name, key, got[key], count, got)
+			}
+		}
+	}
+
+	// 默认解析仍按命令名聚合
+	stats, _, err := ParseHistoryWithFilter(TimeFilter{})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	for _, item := range stats {
+		if item.SubCommand != "" || (item.Command == "/model" && item.Count != 4) {
+			t.Fatalf("默认解析不应拆分参数: %+v", stats)
+		}
+	}
+}
+
+func TestParseHistoryCountsPastedContents(t *testing.T) {
+	tmpDir := t.TempDir()
+	// 第一条含两段粘贴（5 + 7 = 12 字节），第二条没有粘贴，第三条含一段（3 字节，"中" 占 3 字节）
+	content := `{"display":"look [Pasted text #1] [Pasted text #2]","pastedContents":{"1":"hello","2":"goodbye"},"timestamp":1767225600000,"project":"demo"}` + "\n" +
+		`{"display":"/help","timestamp":1767225601000,"project":"demo"}` + "\n" +
+		`{"display":"again","pastedContents":{"1":"中"},"timestamp":1767225602000,"project":"demo"}` + "\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "history.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入 history.jsonl 失败: %v", err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = tmpDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	stats, _, pastes, err := ParseHistoryConcurrentWithPastes(TimeFilter{})
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if want := (PasteStats{RecordsWithPaste: 2, TotalPastes: 3, TotalBytes: 15}); pastes != want {
+		t.Fatalf("paste stats = %+v, want %+v", pastes, want)
+	}
+	if len(stats) != 1 || stats[0].Command != "/help" {
+		t.Fatalf("命令统计不应受粘贴统计影响: %+v", stats)
+	}
+}
+
+func TestParseHistoryMergesRotatedShards(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"history-2025-12.jsonl": `{"display":"/help","timestamp":1764748800000,"project":"demo"}` + "\n" +
+			`{"display":"/model opus","timestamp":1764748801000,"project":"demo"}` + "\n",
+		"history.jsonl": `{"display":"/help","timestamp":1767225600000,"project":"demo"}` + "\n" +
+			`{"display":"/help","timestamp":1767225601000,"project":"demo"}` + "\n" +
+			`{"display":"plain prompt","timestamp":1767225602000,"project":"demo"}` + "\n",
+		// 不匹配 history*.jsonl 的文件不参与统计
+		"history.jsonl.bak": `{"display":"/clear","timestamp":1767225603000,"project":"demo"}` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = tmpDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	counts := func(stats []CommandStats) map[string]int {
+		result := make(map[string]int)
+		for _, item := range stats {
+			result[item.Command] += item.Count
+		}
+		return result
+	}
+	want := map[string]int{"/help": 3, "/model": 1}
+
+	stats, hourly, err := ParseHistoryWithFilter(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryWithFilter() error = %v", err)
+	}
+	if got := counts(stats); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands=%v, want %v", got, want)
+	}
+	total := 0
+	for _, count := range hourly {
+		total += count
+	}
+	if total != 5 {
+		t.Fatalf("hourly total=%d, want 5（两个分片的全部记录）", total)
+	}
+
+	concurrentStats, _, err := ParseHistoryConcurrent(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryConcurrent() error = %v", err)
+	}
+	if got := counts(concurrentStats); !reflect.DeepEqual(got, want) {
+		t.Fatalf("concurrent commands=%v, want %v", got, want)
+	}
+
+	// 原始记录样例、提问长度与数据目录概况同样覆盖全部分片
+	samples, err := collectRecordSamples(AnalysisFilter{RecordSamples: 10, SampleMetric: SampleMetricCommand, SampleKey: "/help"})
+	if err != nil {
+		t.Fatalf("collectRecordSamples() error = %v", err)
+	}
+	if len(samples) != 1 || len(samples[0].Records) != 3 {
+		t.Fatalf("/help samples=%+v, want 3 条（两个分片）", samples)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "projects"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lengths, err := ParseMessageLengthStats(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseMessageLengthStats() error = %v", err)
+	}
+	if lengths.UserPrompts.Count != 5 {
+		t.Fatalf("user prompts=%d, want 5", lengths.UserPrompts.Count)
+	}
+	summary, err := ValidateDataDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ValidateDataDir() error = %v", err)
+	}
+	if summary.HistoryFiles != 2 {
+		t.Fatalf("history files=%d, want 2", summary.HistoryFiles)
+	}
+}
+
+func TestParseJSONLToleratesBOMAndCRLF(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "synced")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	bom := "\uFEFF"
+	project := bom + projectRecordJSON("/tmp/synced", "s1", base) + "\r\n" +
+		projectRecordJSON("/tmp/synced", "s1", base.Add(time.Minute)) + "\r\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := bom + `{"display":"/help","timestamp":1767603600000,"project":"/tmp/synced"}` + "\r\n" +
+		`{"display":"/model","timestamp":1767603601000,"project":"/tmp/synced"}` + "\r\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	// 带 BOM 的首条记录也应计入
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if agg.DailyActivity["2026-01-05"] != 2 || agg.ParseStats.RecordsSkipped != 0 {
+		t.Fatalf("messages=%d skipped=%d, want 2/0", agg.DailyActivity["2026-01-05"], agg.ParseStats.RecordsSkipped)
+	}
+
+	stats, _, err := ParseHistoryWithFilter(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseHistoryWithFilter() error = %v", err)
+	}
+	got := make(map[string]int)
+	for _, item := range stats {
+		got[item.Command] += item.Count
+	}
+	if want := map[string]int{"/help": 1, "/model": 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands=%v, want %v", got, want)
+	}
+}
+
+func TestProjectActiveDaysAndLongestStreak(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectDir := filepath.Join(dataDir, "projects", "active-days")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	// 3/1（两条）、3/2、3/5：3 个不同活跃日，最长连续 2 天，首末活跃日 3/1、3/5
+	content := projectRecordJSON("/tmp/active-days", "s1", day(1)) + "\n" +
+		projectRecordJSON("/tmp/active-days", "s1", day(1).Add(time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/active-days", "s2", day(2)) + "\n" +
+		projectRecordJSON("/tmp/active-days", "s3", day(5)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(source string, projects []ProjectStatItem) {
+		t.Helper()
+		if len(projects) != 1 {
+			t.Fatalf("%s: projects=%+v, want 1", source, projects)
+		}
+		if projects[0].ActiveDays != 3 || projects[0].LongestStreak != 2 {
+			t.Fatalf("%s: active_days=%d longest_streak=%d, want 3/2", source, projects[0].ActiveDays, projects[0].LongestStreak)
+		}
+		if projects[0].FirstSeen != "2026-03-01" || projects[0].LastSeen != "2026-03-05" {
+			t.Fatalf("%s: first_seen=%q last_seen=%q, want 2026-03-01/2026-03-05", source, projects[0].FirstSeen, projects[0].LastSeen)
+		}
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	check("aggregate", agg.Projects)
+
+	cachePath := filepath.Join(tmpDir, "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	originalCache, originalDataDir := loadGlobalCache(), cfg.DataDir
+	cfg.DataDir = dataDir
+	storeGlobalCache(cache)
+	defer func() {
+		cfg.DataDir = originalDataDir
+		storeGlobalCache(originalCache)
+	}()
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	check("cache", data.ProjectStats.Projects)
+}
+
+func TestWorkHoursStatsReportsTiedPeakHours(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "peaks")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 2, hour, minute, 0, 0, time.Local) }
+	// 10 点与 15 点各 2 条并列峰值，8 点 1 条
+	content := projectRecordJSON("/tmp/peaks", "s1", at(8, 0)) + "\n" +
+		projectRecordJSON("/tmp/peaks", "s1", at(10, 0)) + "\n" +
+		projectRecordJSON("/tmp/peaks", "s1", at(10, 30)) + "\n" +
+		projectRecordJSON("/tmp/peaks", "s2", at(15, 0)) + "\n" +
+		projectRecordJSON("/tmp/peaks", "s2", at(15, 30)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(source string, stats *WorkHoursStats) {
+		t.Helper()
+		if stats == nil || stats.PeakHour != 10 || stats.PeakHourCount != 2 || !reflect.DeepEqual(stats.PeakHours, []int{10, 15}) {
+			t.Fatalf("%s: work hours stats = %+v, want peak 10×2 and peak_hours [10 15]", source, stats)
+		}
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	check("aggregate", agg.WorkHoursStats)
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	origCache := loadGlobalCache()
+	storeGlobalCache(cache)
+	defer func() { storeGlobalCache(origCache) }()
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	check("cache", data.WorkHoursStats)
+}
+
+func TestProjectPrimaryModel(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectDir := filepath.Join(dataDir, "projects", "primary-model")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now().Add(-2 * time.Hour)
+	record := func(cwd, model string, i int) string {
+		return strings.Replace(projectRecordJSON(cwd, "s-"+cwd, base.Add(time.Duration(i)*time.Minute)), `"model":"claude-sonnet-4.5"`, `"model":"`+model+`"`, 1)
+	}
+	var lines []string
+	// /tmp/main：sonnet 3 条、opus 2 条；/tmp/tie：各 1 条，按模型名取 opus
+	for i, model := range []string{"sonnet", "opus", "sonnet", "opus", "sonnet"} {
+		lines = append(lines, record("/tmp/main", model, i))
+	}
+	lines = append(lines, record("/tmp/tie", "sonnet", 10), record("/tmp/tie", "opus", 11))
+	if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(source string, projects []ProjectStatItem) {
+		t.Helper()
+		got := make(map[string]string)
+		for _, project := range projects {
+			got[project.Project] = project.PrimaryModel
+		}
+		if got["/tmp/main"] != "sonnet" || got["/tmp/tie"] != "opus" {
+			t.Fatalf("%s: primary_model=%v, want main=sonnet tie=opus", source, got)
+		}
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	check("aggregate", agg.Projects)
+
+	cachePath := filepath.Join(tmpDir, "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	originalCache, originalDataDir := loadGlobalCache(), cfg.DataDir
+	cfg.DataDir = dataDir
+	storeGlobalCache(cache)
+	defer func() {
+		cfg.DataDir = originalDataDir
+		storeGlobalCache(originalCache)
+	}()
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	check("cache", data.ProjectStats.Projects)
+}
+
+func TestParseProjectFileSkipsMalformedLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "projects", "p")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Hour)
+	// 坏行夹在两条合法记录之间：json.Decoder 会卡在坏行上，逐行解析则只跳过这一行
+	content := projectRecordJSON("/tmp/p", "s1", now) + "\n" +
+		`{"type":"assistant","cwd":"/tmp/p",garbage` + "\n" +
+		projectRecordJSON("/tmp/p", "s1", now.Add(time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "s.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, tmpDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	if agg.ParseStats.RecordsParsed != 2 || agg.ParseStats.RecordsSkipped != 1 {
+		t.Fatalf("ParseStats=%+v, want parsed=2 skipped=1", agg.ParseStats)
+	}
+	if len(agg.Projects) != 1 || agg.Projects[0].MessageCount != 2 {
+		t.Fatalf("坏行之后的记录也应被统计: %+v", agg.Projects)
+	}
+
+	cachePath := filepath.Join(tmpDir, "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: tmpDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	originalCache, originalDataDir := loadGlobalCache(), cfg.DataDir
+	cfg.DataDir = tmpDir
+	storeGlobalCache(cache)
+	defer func() {
+		cfg.DataDir = originalDataDir
+		storeGlobalCache(originalCache)
+	}()
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	if data.DataQuality == nil || data.DataQuality.RecordsSkipped != 1 || data.DataQuality.RecordsParsed != 2 {
+		t.Fatalf("data_quality=%+v, want parsed=2 skipped=1", data.DataQuality)
+	}
+}
+
+func TestPreset24HoursUsesRollingWindow(t *testing.T) {
+	tf := NewTimeFilterFromPreset(Range24Hours)
+	now := time.Now()
+	if tf.Start == nil || tf.End == nil {
+		t.Fatalf("24h 预设应限定起止，got %+v", tf)
+	}
+	if !tf.Contains(now.Add(-time.Hour)) || tf.Contains(now.Add(-48*time.Hour)) {
+		t.Fatalf("24h 窗口 [%v, %v] 应包含 1 小时前、排除 2 天前", tf.Start, tf.End)
+	}
+
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "rolling")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := projectRecordJSON("/tmp/rolling", "recent", now.Add(-time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/rolling", "old", now.Add(-48*time.Hour)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agg, err := ParseProjectsConcurrentOnceFromDir(tf, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	if len(agg.Projects) != 1 || agg.Projects[0].MessageCount != 1 {
+		t.Fatalf("24h 预设应只统计 1 小时前的记录: %+v", agg.Projects)
+	}
+}
+
+func TestUserTypeFilterCountsOnlyMatchingRecords(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "projects", "p")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(-time.Hour)
+	withUserType := func(userType string, ts time.Time) string {
+		return strings.Replace(projectRecordJSON("/tmp/p", "s1", ts), `"type":"assistant"`, `"type":"assistant","userType":"`+userType+`"`, 1)
+	}
+	content := withUserType("external", now) + "\n" +
+		withUserType("external", now.Add(time.Minute)) + "\n" +
+		withUserType("internal", now.Add(2*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/p", "s1", now.Add(3*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "s.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		userType string
+		want     int
+	}{{"", 4}, {"external", 2}, {"internal", 1}, {"bot", 0}} {
+		agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{UserType: tc.userType}, tmpDir)
+		if err != nil {
+			t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+		}
+		messages := 0
+		for _, project := range agg.Projects {
+			messages += project.MessageCount
+		}
+		if messages != tc.want {
+			t.Fatalf("userType=%q messages=%d, want %d", tc.userType, messages, tc.want)
+		}
+	}
+
+	// userType 过滤绕过不区分 userType 的缓存，走实时解析
+	originalCache, originalDataDir := loadGlobalCache(), cfg.DataDir
+	cfg.DataDir = tmpDir
+	storeGlobalCache(&CacheFile{})
+	defer func() {
+		cfg.DataDir = originalDataDir
+		storeGlobalCache(originalCache)
+	}()
+	filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?preset=all&userType=external", nil))
+	if err != nil {
+		t.Fatalf("parseAnalysisFilter failed: %v", err)
+	}
+	data, source, err := buildDashboardDataWithFilter(filter)
+	if err != nil {
+		t.Fatalf("buildDashboardDataWithFilter failed: %v", err)
+	}
+	if source != "parsing" || data.ProjectStats.TotalMessages != 2 {
+		t.Fatalf("source=%s total_messages=%d, want parsing/2", source, data.ProjectStats.TotalMessages)
+	}
+}
+
+func TestMCPPatternConfigMatchesHyphenatedServer(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "debug"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "2026-01-05T10:00:00.000Z [DEBUG] tool call mcp__my-server__do.thing\n" +
+		"2026-01-05T10:01:00.000Z [DEBUG] tool call mcp__github__get_file_contents\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "debug", "s.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origPattern, origRe := cfg.MCPPattern, mcpPattern
+	defer func() { cfg.MCPPattern, mcpPattern = origPattern, origRe }()
+
+	signals := func() map[string]int {
+		t.Helper()
+		items, err := ParseDebugLogsConcurrentFromDir(TimeFilter{}, dataDir)
+		if err != nil {
+			t.Fatalf("ParseDebugLogsConcurrentFromDir failed: %v", err)
+		}
+		got := make(map[string]int)
+		for _, item := range items {
+			got[item.Server+"::"+item.Tool] += item.Count
+		}
+		return got
+	}
+
+	// 默认正则：\w 不含连字符，my-server 被漏掉
+	cfg.MCPPattern = ""
+	if err := applyMCPPatternConfig(); err != nil {
+		t.Fatalf("applyMCPPatternConfig failed: %v", err)
+	}
+	if got := signals(); !reflect.DeepEqual(got, map[string]int{"github::get_file_contents": 1}) {
+		t.Fatalf("默认正则 signals=%v", got)
+	}
+	if key := mcpPatternCacheKey(); key != "" {
+		t.Fatalf("默认正则的缓存标识=%q, want empty", key)
+	}
+
+	cfg.MCPPattern = `mcp__([\w.-]+?)__([\w.-]+)`
+	if err := applyMCPPatternConfig(); err != nil {
+		t.Fatalf("applyMCPPatternConfig failed: %v", err)
+	}
+	want := map[string]int{"my-server::do.thing": 1, "github::get_file_contents": 1}
+	if got := signals(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("自定义正则 signals=%v, want %v", got, want)
+	}
+	if key := mcpPatternCacheKey(); key != cfg.MCPPattern {
+		t.Fatalf("自定义正则的缓存标识=%q", key)
+	}
+
+	cfg.MCPPattern = `mcp__([\w-]+)`
+	if err := applyMCPPatternConfig(); err == nil {
+		t.Fatal("只有一个捕获组的正则应报错")
+	}
+}
+
+func TestSymlinkedProjectDirIsCounted(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectsDir := filepath.Join(dataDir, "projects")
+	externalDir := filepath.Join(tmpDir, "external", "linked")
+	for _, dir := range []string{filepath.Join(projectsDir, "local"), externalDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	files := map[string]string{
+		filepath.Join(projectsDir, "local", "a.jsonl"): projectRecordJSON("/tmp/local", "s1", base) + "\n",
+		filepath.Join(externalDir, "b.jsonl"): projectRecordJSON("/tmp/linked", "s2", base) + "\n" +
+			projectRecordJSON("/tmp/linked", "s2", base.Add(time.Minute)) + "\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// linked 指向外部存储；dup 与 local 是同一目录；loop 指回 projects 自身
+	links := map[string]string{"linked": externalDir, "dup": filepath.Join(projectsDir, "local"), "loop": projectsDir}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(projectsDir, name)); err != nil {
+			t.Skipf("当前系统无法创建符号链接: %v", err)
+		}
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	counts := map[string]int{}
+	for _, item := range agg.Projects {
+		counts[item.Project] = item.MessageCount
+	}
+	if want := map[string]int{"/tmp/local": 1, "/tmp/linked": 2}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("project counts = %v, want %v", counts, want)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	if cache.TotalMessages != 3 || cache.TotalSessions != 2 {
+		t.Fatalf("cache messages=%d sessions=%d, want 3/2", cache.TotalMessages, cache.TotalSessions)
+	}
+}
+
+func TestTopLevelProjectJSONLIsCounted(t *testing.T) {
+	dataDir := t.TempDir()
+	projectsDir := filepath.Join(dataDir, "projects")
+	if err := os.MkdirAll(filepath.Join(projectsDir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	// nested 按常规放在项目目录下；loose.jsonl 直接放在 projects 下（手工拷贝）
+	files := map[string]string{
+		filepath.Join(projectsDir, "nested", "a.jsonl"): projectRecordJSON("/tmp/nested", "s1", base) + "\n",
+		filepath.Join(projectsDir, "loose.jsonl"): projectRecordJSON("/tmp/loose", "s2", base) + "\n" +
+			projectRecordJSON("/tmp/loose", "s2", base.Add(time.Minute)) + "\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	counts := map[string]int{}
+	for _, item := range agg.Projects {
+		counts[item.Project] = item.MessageCount
+	}
+	if want := map[string]int{"/tmp/nested": 1, "/tmp/loose": 2}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("project counts = %v, want %v", counts, want)
+	}
+
+	summary, err := ValidateDataDir(dataDir)
+	if err != nil || summary.ProjectFiles != 2 {
+		t.Fatalf("ValidateDataDir project_files=%d err=%v, want 2", summary.ProjectFiles, err)
+	}
+	sessions, err := ParseSessionList(TimeFilter{})
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ParseSessionList = %d 个会话 err=%v, want 2", len(sessions), err)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	if cache.TotalMessages != 3 || cache.TotalSessions != 2 {
+		t.Fatalf("cache messages=%d sessions=%d, want 3/2", cache.TotalMessages, cache.TotalSessions)
+	}
+}
+
+func TestWorkerCountDoesNotChangeResults(t *testing.T) {
+	dataDir := useSyntheticDataDir(t, 9, 40)
+	origWorkers := cfg.Workers
+	defer func() { cfg.Workers = origWorkers }()
+
+	// snapshot 把各解析器结果整理为与顺序无关的 map，便于不同并发度之间直接比较
+	type snapshot struct {
+		Projects map[string][2]int
+		Models   map[string][2]int
+		Daily    map[string]int
+		Tools    map[string]int
+		Commands map[string]int
+		Signals  map[string]int
+		Cached   [2]int
+	}
+	collect := func() snapshot {
+		t.Helper()
+		s := snapshot{
+			Projects: map[string][2]int{}, Models: map[string][2]int{}, Daily: map[string]int{},
+			Tools: map[string]int{}, Commands: map[string]int{}, Signals: map[string]int{},
+		}
+		agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+		if err != nil {
+			t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+		}
+		for _, item := range agg.Projects {
+			s.Projects[item.Project] = [2]int{item.MessageCount, item.Tokens}
+		}
+		for _, item := range agg.ModelUsageList {
+			s.Models[item.Model] = [2]int{item.Count, item.Tokens}
+		}
+		for _, item := range agg.DailyActivityList {
+			s.Daily[item.Date] = item.MessageCount
+		}
+		for _, item := range agg.ToolAnalysis.Tools {
+			s.Tools[item.Tool] = item.CallCount
+		}
+		commands, _, err := ParseHistoryConcurrent(TimeFilter{})
+		if err != nil {
+			t.Fatalf("ParseHistoryConcurrent failed: %v", err)
+		}
+		for _, item := range commands {
+			s.Commands[item.Command] = item.Count
+		}
+		signals, err := ParseDebugLogsConcurrent(TimeFilter{})
+		if err != nil {
+			t.Fatalf("ParseDebugLogsConcurrent failed: %v", err)
+		}
+		for _, item := range signals {
+			s.Signals[item.Server+"::"+item.Tool] = item.Count
+		}
+		cachePath := filepath.Join(t.TempDir(), "cache.db")
+		if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+			t.Fatalf("BuildFullCache failed: %v", err)
+		}
+		cache, err := LoadCacheFile(cachePath)
+		if err != nil {
+			t.Fatalf("LoadCacheFile failed: %v", err)
+		}
+		s.Cached = [2]int{cache.TotalMessages, cache.TotalSessions}
+		return s
+	}
+
+	cfg.Workers = 0
+	want := collect()
+	if len(want.Projects) == 0 || len(want.Commands) == 0 || len(want.Signals) == 0 || want.Cached[0] == 0 {
+		t.Fatalf("合成数据统计不完整: %+v", want)
+	}
+	for _, workers := range []int{1, 3, 16} {
+		cfg.Workers = workers
+		if got := getWorkerCount(); got != workers {
+			t.Fatalf("getWorkerCount()=%d, want %d", got, workers)
+		}
+		if got := collect(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("workers=%d 结果与自动并发度不一致:\ngot  %+v\nwant %+v", workers, got, want)
+		}
+	}
+}
+
+func TestHistoryTimestampSecondsAndMillis(t *testing.T) {
+	secondsEra := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	millisEra := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	if got := parseHistoryTimestamp(secondsEra.Unix()); !got.Equal(secondsEra) {
+		t.Fatalf("秒级时间戳解析为 %v, want %v", got, secondsEra)
+	}
+	if got := parseHistoryTimestamp(millisEra.UnixMilli()); !got.Equal(millisEra) {
+		t.Fatalf("毫秒时间戳解析为 %v, want %v", got, millisEra)
+	}
+
+	dataDir := t.TempDir()
+	history := fmt.Sprintf(`{"display":"/old","timestamp":%d,"project":"/tmp/demo"}`+"\n"+
+		`{"display":"/new","timestamp":%d,"project":"/tmp/demo"}`+"\n", secondsEra.Unix(), millisEra.UnixMilli())
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	// 只取秒级记录所在的那一天：两种 history 解析器都应只命中 /old
+	start := secondsEra.Add(-time.Hour)
+	end := secondsEra.Add(time.Hour)
+	tf := TimeFilter{Start: &start, End: &end}
+	for name, parse := range map[string]func(TimeFilter) ([]CommandStats, map[string]int, error){
+		"ParseHistoryWithFilter": ParseHistoryWithFilter,
+		"ParseHistoryConcurrent": ParseHistoryConcurrent,
+	} {
+		commands, _, err := parse(tf)
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if len(commands) != 1 || commands[0].Command != "/old" || commands[0].Count != 1 {
+			t.Fatalf("%s = %+v, want only /old", name, commands)
+		}
+	}
+
+	cache := &CacheFile{DailyStats: make(map[string]*DayAggregate)}
+	if err := (&CacheBuilder{DataDir: dataDir}).buildFromHistory(cache); err != nil {
+		t.Fatalf("buildFromHistory() failed: %v", err)
+	}
+	for _, ts := range []time.Time{secondsEra, millisEra} {
+		date := ts.Local().Format("2006-01-02")
+		if day := cache.DailyStats[date]; day == nil || day.MessageCount != 1 {
+			t.Fatalf("DailyStats[%s]=%+v, want 1 message (all=%v)", date, day, cache.DailyStats)
+		}
+	}
+}
+
+func TestCountModeSelectsRecordTypes(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	userRecord := func(ts time.Time) string {
+		return `{"type":"user","cwd":"/tmp/demo","sessionId":"s1","timestamp":"` + ts.Format(time.RFC3339Nano) + `","message":{"role":"user","content":"hi"}}`
+	}
+	content := userRecord(base) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(2*time.Minute)) + "\n" +
+		userRecord(base.Add(3*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(4*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origCache := cfg.DataDir, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+
+	for query, want := range map[string]int{"": 3, "&count_mode=assistant": 3, "&countMode=user": 2, "&count_mode=all": 5} {
+		filter, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?preset=all"+query, nil))
+		if err != nil {
+			t.Fatalf("%q: parseAnalysisFilter() error = %v", query, err)
+		}
+		data, _, err := buildDashboardDataWithFilter(filter)
+		if err != nil {
+			t.Fatalf("%q: buildDashboardDataWithFilter() error = %v", query, err)
+		}
+		if data.Summary.TotalMessages != want || data.HourlyCounts["09"] != want {
+			t.Fatalf("%q: total=%d hour09=%d, want %d", query, data.Summary.TotalMessages, data.HourlyCounts["09"], want)
+		}
+		// token 始终只来自 assistant 消息
+		if data.Summary.TotalTokens != 45 {
+			t.Fatalf("%q: tokens=%d, want 45", query, data.Summary.TotalTokens)
+		}
+	}
+
+	if _, err := parseAnalysisFilter(httptest.NewRequest("GET", "/api/data?count_mode=bogus", nil)); err == nil {
+		t.Fatal("无效 count_mode 应报错")
+	}
+	if !(TimeFilter{CountMode: CountModeAll}).bypassesCache() {
+		t.Fatal("非 assistant 口径应绕过缓存")
+	}
+}
@@ -3,10 +3,8 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -21,8 +19,7 @@ func ParseProjectsConcurrentOnce(tf TimeFilter) (*ProjectAggregate, error) {
 
 // ParseProjectsConcurrentOnceFromDir 一次遍历并发解析指定数据目录下的项目统计
 func ParseProjectsConcurrentOnceFromDir(tf TimeFilter, dataDir string) (*ProjectAggregate, error) {
-	projectsDir := filepath.Join(dataDir, "projects")
-	entries, err := os.ReadDir(projectsDir)
+	projectDirs, err := listProjectDirs(filepath.Join(dataDir, "projects"))
 	if err != nil {
 		return nil, fmt.Errorf("读取 projects 目录失败: %w", err)
 	}
@@ -30,12 +27,7 @@ func ParseProjectsConcurrentOnceFromDir(tf TimeFilter, dataDir string) (*Project
 	aggregate := newProjectAggregate()
 
 	var files []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		projectDir := filepath.Join(projectsDir, entry.Name())
+	for _, projectDir := range projectDirs {
 		projectFiles, err := projectJSONLFiles(projectDir)
 		if err != nil {
 			continue
@@ -44,7 +36,7 @@ func ParseProjectsConcurrentOnceFromDir(tf TimeFilter, dataDir string) (*Project
 	}
 	sort.Strings(files)
 
-	maxWorkers := runtime.NumCPU()
+	maxWorkers := getWorkerCount()
 	if len(files) < maxWorkers {
 		maxWorkers = len(files)
 	}
@@ -89,9 +81,49 @@ func ParseProjectsConcurrentOnceFromDir(tf TimeFilter, dataDir string) (*Project
 	return aggregate, nil
 }
 
+// listProjectDirs 列出 projects 下的项目目录（完整路径，按名称排序）。
+// 指向目录的符号链接同样视为项目（entry.IsDir() 对符号链接返回 false，需 stat 目标）；
+// 与已列出的目录解析到同一真实路径、或指向 projects 自身及其上级（会形成循环）的链接被跳过。
+// 直接放在 projects 下的 .jsonl 文件（如手工拷贝）也一并列出，projectJSONLFiles 对文件路径返回其自身，
+// 记录照常按 cwd 归属项目。
+func listProjectDirs(projectsDir string) ([]string, error) {
+	entries, err := dataSource.ReadDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+	root := resolveDataPath(projectsDir)
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, entry := range entries {
+		dir := filepath.Join(projectsDir, entry.Name())
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".jsonl") {
+			if info, err := statData(dir); err == nil && !info.IsDir() {
+				dirs = append(dirs, dir)
+			}
+			continue
+		}
+		if !entry.IsDir() {
+			if entry.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			info, err := statData(dir)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+		}
+		real := resolveDataPath(dir)
+		if seen[real] || real == root || strings.HasPrefix(root, real+string(filepath.Separator)) {
+			continue
+		}
+		seen[real] = true
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
 func projectJSONLFiles(projectDir string) ([]string, error) {
 	var files []string
-	err := filepath.WalkDir(projectDir, func(path string, entry os.DirEntry, err error) error {
+	err := walkData(projectDir, func(path string, entry os.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -110,9 +142,43 @@ func projectJSONLFiles(projectDir string) ([]string, error) {
 	return files, nil
 }
 
+// ensureProjectStat 返回项目统计项，不存在时创建
+func ensureProjectStat(agg *ProjectAggregate, projectName string) *ProjectStatItem {
+	if agg.ProjectStats[projectName] == nil {
+		agg.ProjectStats[projectName] = &ProjectStatItem{Project: projectName}
+	}
+	return agg.ProjectStats[projectName]
+}
+
+// recordMessageCountLocked 把一条计入消息数的记录累加到项目、星期、每日与小时分布
+func recordMessageCountLocked(agg *ProjectAggregate, projectName string, timestamp time.Time) {
+	ensureProjectStat(agg, projectName).MessageCount++
+
+	weekday := int(timestamp.Weekday())  // 0=周日, 1=周一...
+	adjustedWeekday := (weekday + 6) % 7 // 转换为0=周一
+	agg.WeekdayData[adjustedWeekday].MessageCount++
+
+	dateKey := timestamp.Format("2006-01-02")
+	agg.DailyActivity[dateKey]++
+	if agg.DailyProjectCounts[dateKey] == nil {
+		agg.DailyProjectCounts[dateKey] = make(map[string]int)
+	}
+	agg.DailyProjectCounts[dateKey][projectName]++
+
+	hour := timestamp.Hour()
+	agg.HourlyCounts[hour]++
+	dailyHourlyCounts := agg.DailyHourlyCounts[dateKey]
+	dailyHourlyCounts[hour]++
+	agg.DailyHourlyCounts[dateKey] = dailyHourlyCounts
+	dailyHalfHourCounts := agg.DailyHalfHourCounts[dateKey]
+	dailyHalfHourCounts[halfHourBin(timestamp)]++
+	agg.DailyHalfHourCounts[dateKey] = dailyHalfHourCounts
+	recordDayBounds(agg.DailyBounds, dateKey, secondOfDay(timestamp))
+}
+
 // parseProjectFileAggregate 解析单个项目文件并更新聚合数据
 func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggregate) {
-	f, err := os.Open(filePath)
+	f, err := openDataFile(filePath)
 	if err != nil {
 		return
 	}
@@ -121,15 +187,18 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 	pendingTools := make(map[string]pendingToolCall)
 	sessionActiveSkills := make(map[string][]string)
 	lastMsgTs := make(map[string]time.Time) // sessionID -> 上一条带 timestamp 的 message 行时间，用于算单请求 round-trip
-	decoder := json.NewDecoder(f)
+	lines := newJSONLReader(f)
 	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
 		var record ProjectRecord
-		if err := decoder.Decode(&record); err != nil {
-			if err == io.EOF {
-				break
-			}
+		if err := json.Unmarshal(line, &record); err != nil {
+			agg.ParseStats.RecordsSkipped++
 			continue
 		}
+		agg.ParseStats.RecordsParsed++
 
 		timestamp, hasTimestamp := parseProjectRecordTimestamp(record.Timestamp)
 		if hasTimestamp && !tf.Contains(timestamp) {
@@ -138,12 +207,12 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 		if !hasTimestamp && hasTimeFilter(tf) {
 			continue
 		}
-
-		projectName := record.Cwd
-		if projectName == "" {
-			projectName = "Unknown"
+		if !tf.MatchesUserType(record.UserType) || isExcludedProject(record.Cwd) {
+			continue
 		}
 
+		projectName := projectNameFromCwd(record.Cwd)
+
 		recordRuntimeEventLocked(agg, record, timestamp, projectName)
 		if hasTimestamp {
 			recordRuntimeEventLocked(ensureDailyRuntimeAggregate(agg, timestamp.Format("2006-01-02")), record, timestamp, projectName)
@@ -173,6 +242,9 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 		}
 
 		if record.Type == "user" {
+			if tf.countsRecordType(record.Type) {
+				recordMessageCountLocked(agg, projectName, timestamp)
+			}
 			parseToolResults(record, timestamp, projectName, pendingTools, agg)
 			if hasTimestamp && record.SessionID != "" {
 				lastMsgTs[record.SessionID] = timestamp
@@ -190,26 +262,35 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 			continue
 		}
 
-		// 1. 项目统计
-		if agg.ProjectStats[projectName] == nil {
-			agg.ProjectStats[projectName] = &ProjectStatItem{
-				Project: projectName,
-			}
+		// 1. 消息计数（项目、星期、每日、小时），countMode 为 user 时不计 assistant
+		if tf.countsRecordType(record.Type) {
+			recordMessageCountLocked(agg, projectName, timestamp)
 		}
-		agg.ProjectStats[projectName].MessageCount++
 
-		// 2. 星期统计
-		weekday := int(timestamp.Weekday())  // 0=周日, 1=周一...
-		adjustedWeekday := (weekday + 6) % 7 // 转换为0=周一
-		agg.WeekdayData[adjustedWeekday].MessageCount++
+		// 2. 项目 token：count_mode=user 时不为只有 assistant 记录的项目新建 0 消息的行
+		messageTokens := msg.Usage.InputTokens + msg.Usage.OutputTokens
+		if projectStats := agg.ProjectStats[projectName]; projectStats != nil {
+			projectStats.Tokens += messageTokens
+		} else if tf.countsRecordType(record.Type) {
+			ensureProjectStat(agg, projectName).Tokens += messageTokens
+		}
 
-		// 3. 每日活动
+		// 3. 每日 token、版本与 stop_reason
 		dateKey := timestamp.Format("2006-01-02")
-		agg.DailyActivity[dateKey]++
-		if agg.DailyProjectCounts[dateKey] == nil {
-			agg.DailyProjectCounts[dateKey] = make(map[string]int)
+		if agg.DailyProjectTokens[dateKey] == nil {
+			agg.DailyProjectTokens[dateKey] = make(map[string]int)
+		}
+		agg.DailyProjectTokens[dateKey][projectName] += messageTokens
+		if agg.DailyVersionCounts[dateKey] == nil {
+			agg.DailyVersionCounts[dateKey] = make(map[string]int)
 		}
-		agg.DailyProjectCounts[dateKey][projectName]++
+		agg.DailyVersionCounts[dateKey][versionKey(record.Version)]++
+		if agg.DailyStopReasonCounts[dateKey] == nil {
+			agg.DailyStopReasonCounts[dateKey] = make(map[string]int)
+		}
+		agg.DailyStopReasonCounts[dateKey][stopReasonKey(msg.StopReason)]++
+		agg.DailyOutputTokens[dateKey] += msg.Usage.OutputTokens
+		agg.DailyAssistantMessages[dateKey]++
 
 		// 3.5 每日会话去重（同一 sessionID 同天只计一次）
 		if record.SessionID != "" {
@@ -219,16 +300,10 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 			if !agg.DailySessions[dateKey][record.SessionID] {
 				agg.DailySessions[dateKey][record.SessionID] = true
 			}
+			recordSessionSpan(agg.DailySessionSpans, dateKey, record.SessionID, timestamp.UnixMilli(), sessionGap())
 		}
 
-		// 4. 小时统计
-		hour := timestamp.Hour()
-		agg.HourlyCounts[hour]++
-		dailyHourlyCounts := agg.DailyHourlyCounts[dateKey]
-		dailyHourlyCounts[hour]++
-		agg.DailyHourlyCounts[dateKey] = dailyHourlyCounts
-
-		// 5. 模型使用统计
+		// 4. 模型使用统计
 		dailyRuntimeAgg := ensureDailyRuntimeAggregate(agg, dateKey)
 		dailyProjectRuntimeAgg := ensureDailyProjectRuntimeAggregate(agg, dateKey, projectName)
 		dailySessionRuntimeAgg := ensureDailySessionRuntimeAggregate(agg, dateKey, record.SessionID)
@@ -253,7 +328,7 @@ func parseProjectFileAggregate(filePath string, tf TimeFilter, agg *ProjectAggre
 			recordCostUsageLocked(dailySessionRuntimeAgg, msg, record, projectName, roundTripMs)
 		}
 
-		// 6. 工具调用统计
+		// 5. 工具调用统计
 		// 更新 lastMsgTs：作为同一 session 下一条 assistant 的 round-trip 起点（在 cost 统计之后更新）。
 		if hasTimestamp && record.SessionID != "" {
 			lastMsgTs[record.SessionID] = timestamp
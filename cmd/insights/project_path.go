@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// unknownProject 缺少 cwd 的记录归入的项目名
+const unknownProject = "Unknown"
+
+// caseInsensitivePaths 默认文件系统大小写不敏感的平台（macOS、Windows），
+// 同一目录可能以不同大小写出现在 cwd 中；测试可替换
+var caseInsensitivePaths = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// userHomeDir 家目录查询，测试可替换
+var userHomeDir = os.UserHomeDir
+
+// projectDisplayNames 项目分组 key → 该 key 首次出现时的写法；同一目录的各种写法都显示为这一个名字
+var projectDisplayNames sync.Map
+
+// normalizeProjectPath 归一化会话记录的 cwd，使同一目录的不同写法归入同一项目：
+// 去掉末尾的路径分隔符，--collapse-home 时把家目录前缀替换为 ~；
+// 大小写不敏感的平台上只按小写分组，返回值保留该项目首次出现时的大小写
+func normalizeProjectPath(cwd string) string {
+	cwd = strings.TrimSpace(cwd)
+	if cwd == "" {
+		return ""
+	}
+	if trimmed := strings.TrimRight(cwd, `/\`); trimmed != "" {
+		cwd = trimmed
+	} else {
+		cwd = cwd[:1] // 根目录本身
+	}
+	if cfg.CollapseHome {
+		cwd = collapseHomeDir(cwd)
+	}
+	return canonicalProjectName(cwd)
+}
+
+// canonicalProjectName 大小写不敏感的平台上返回同一项目已登记的写法（首次登记者为准），其它平台原样返回。
+// 合并聚合时也经过这里：缓存中复用的文件级快照保留着之前进程选定的写法，
+// 先合并的快照（按文件路径排序）登记其写法，之后解析或合并到的其它写法都归入它
+func canonicalProjectName(project string) string {
+	if !caseInsensitivePaths || project == "" || project == unknownProject {
+		return project
+	}
+	display, _ := projectDisplayNames.LoadOrStore(strings.ToLower(project), project)
+	return display.(string)
+}
+
+// collapseHomeDir 把以家目录开头的路径改写为 ~ 开头；家目录未知时原样返回
+func collapseHomeDir(path string) string {
+	home, err := userHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	home = strings.TrimRight(home, `/\`)
+	samePath := func(a, b string) bool {
+		if caseInsensitivePaths {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+	if samePath(path, home) {
+		return "~"
+	}
+	for _, sep := range []string{string(filepath.Separator), "/"} {
+		if len(path) >= len(home)+len(sep) && samePath(path[:len(home)], home) && path[len(home):len(home)+len(sep)] == sep {
+			return "~" + sep + path[len(home)+len(sep):]
+		}
+	}
+	return path
+}
+
+// projectNameFromCwd 项目统计使用的项目名：归一化后的 cwd，缺失时为 Unknown
+func projectNameFromCwd(cwd string) string {
+	if name := normalizeProjectPath(cwd); name != "" {
+		return name
+	}
+	return unknownProject
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeProjectPathMergesVariants(t *testing.T) {
+	origCfg, origCase, origHome := cfg, caseInsensitivePaths, userHomeDir
+	defer func() { cfg, caseInsensitivePaths, userHomeDir = origCfg, origCase, origHome }()
+	caseInsensitivePaths = true
+	userHomeDir = func() (string, error) { return "/Users/Me", nil }
+	resetProjectDisplayNames(t)
+
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "foo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := projectRecordJSON("/Users/Me/work/foo", "s1", base) + "\n" +
+		projectRecordJSON("/Users/Me/work/foo/", "s1", base.Add(time.Minute)) + "\n" +
+		projectRecordJSON("/users/me/Work/FOO", "s2", base.Add(time.Hour)) + "\n" +
+		projectRecordJSON("", "s3", base.Add(2*time.Hour)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.DataDir = dataDir
+
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if len(agg.ProjectStats) != 2 {
+		t.Fatalf("projects = %v, want 2（同一目录的变体合并 + Unknown）", agg.ProjectStats)
+	}
+	// 分组忽略大小写，显示保留首次出现的写法
+	if stat := agg.ProjectStats["/Users/Me/work/foo"]; stat == nil || stat.MessageCount != 3 {
+		t.Fatalf("合并后的项目 = %+v, want 3 条消息", agg.ProjectStats)
+	}
+	if stat := agg.ProjectStats[unknownProject]; stat == nil || stat.MessageCount != 1 {
+		t.Fatalf("Unknown 项目 = %+v, want 1 条消息", stat)
+	}
+
+	cfg.CollapseHome = true
+	resetProjectDisplayNames(t)
+	for input, want := range map[string]string{
+		"/Users/Me/work/foo/": "~/work/foo",
+		"/Users/Me":           "~",
+		"/users/me/Work/Bar":  "~/Work/Bar",
+		"/Users/Meta/x":       "/Users/Meta/x",
+		"/":                   "/",
+		"":                    "",
+	} {
+		if got := normalizeProjectPath(input); got != want {
+			t.Fatalf("normalizeProjectPath(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	// 大小写敏感的平台保留原始大小写
+	caseInsensitivePaths = false
+	cfg.CollapseHome = false
+	if got := normalizeProjectPath("/Users/Me/Work/"); got != "/Users/Me/Work" {
+		t.Fatalf("大小写敏感平台 normalizeProjectPath = %q", got)
+	}
+}
+
+func TestProjectCasingStableAcrossIncrementalCacheBuilds(t *testing.T) {
+	origCfg, origCase := cfg, caseInsensitivePaths
+	defer func() { cfg, caseInsensitivePaths = origCfg, origCase }()
+	caseInsensitivePaths = true
+	resetProjectDisplayNames(t)
+
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "foo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.DataDir = dataDir
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	writeFile := func(name, cwd, session string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(projectRecordJSON(cwd, session, base)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	builder := &CacheBuilder{CachePath: filepath.Join(t.TempDir(), "cache.db"), DataDir: dataDir}
+	build := func(wantReused int) *CacheFile {
+		t.Helper()
+		if err := builder.BuildFullCache(); err != nil {
+			t.Fatalf("BuildFullCache() failed: %v", err)
+		}
+		cache, err := LoadCacheFile(builder.CachePath)
+		if err != nil {
+			t.Fatalf("LoadCacheFile() failed: %v", err)
+		}
+		if cache.BuildStats.ReusedFiles != wantReused {
+			t.Fatalf("ReusedFiles = %d, want %d", cache.BuildStats.ReusedFiles, wantReused)
+		}
+		return cache
+	}
+	assertSingleProject := func(cache *CacheFile, want int) {
+		t.Helper()
+		if stat := cache.ProjectStats["/Users/me/Foo"]; len(cache.ProjectStats) != 1 || stat == nil || stat.MessageCount != want {
+			t.Fatalf("projects = %+v, want only /Users/me/Foo with %d messages", cache.ProjectStats, want)
+		}
+	}
+
+	// 第一次运行只见过 /Users/me/Foo
+	writeFile("a.jsonl", "/Users/me/Foo", "s1")
+	build(0)
+
+	// 新进程（登记表为空）增量构建：复用的快照先登记原写法，新文件的 /Users/me/foo 归入它
+	resetProjectDisplayNames(t)
+	writeFile("b.jsonl", "/Users/me/foo", "s2")
+	assertSingleProject(build(1), 2)
+
+	// 旧版本按大小写区分写入的快照：合并时同样按忽略大小写的 key 归并
+	caseInsensitivePaths = false
+	writeFile("c.jsonl", "/USERS/me/FOO", "s3")
+	if cache := build(2); len(cache.ProjectStats) != 2 {
+		t.Fatalf("大小写敏感构建 projects = %+v, want 2", cache.ProjectStats)
+	}
+	caseInsensitivePaths = true
+	resetProjectDisplayNames(t)
+	assertSingleProject(build(3), 3)
+}
+
+// resetProjectDisplayNames 清空项目显示名的首次写法记录，测试结束后恢复
+func resetProjectDisplayNames(t *testing.T) {
+	t.Helper()
+	var saved []any
+	projectDisplayNames.Range(func(key, value any) bool {
+		saved = append(saved, key, value)
+		return true
+	})
+	reset := func() {
+		projectDisplayNames.Range(func(key, _ any) bool {
+			projectDisplayNames.Delete(key)
+			return true
+		})
+	}
+	reset()
+	t.Cleanup(func() {
+		reset()
+		for i := 0; i < len(saved); i += 2 {
+			projectDisplayNames.Store(saved[i], saved[i+1])
+		}
+	})
+}
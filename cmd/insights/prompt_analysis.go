@@ -4,10 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
-	"os"
 	"path/filepath"
-	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -128,17 +125,13 @@ func newPromptAggregate() *promptAggregate {
 }
 
 func scanPromptRecords(dataDir string, tf TimeFilter, opts cliOptions, agg *promptAggregate) error {
-	projectsDir := filepath.Join(dataDir, "projects")
-	entries, err := os.ReadDir(projectsDir)
+	projectDirs, err := listProjectDirs(filepath.Join(dataDir, "projects"))
 	if err != nil {
 		return fmt.Errorf("读取 projects 目录失败: %w", err)
 	}
 	files := []string{}
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		projectFiles, err := projectJSONLFiles(filepath.Join(projectsDir, entry.Name()))
+	for _, projectDir := range projectDirs {
+		projectFiles, err := projectJSONLFiles(projectDir)
 		if err != nil {
 			continue
 		}
@@ -154,7 +147,7 @@ func scanPromptRecords(dataDir string, tf TimeFilter, opts cliOptions, agg *prom
 		return nil
 	}
 
-	maxWorkers := runtime.NumCPU()
+	maxWorkers := getWorkerCount()
 	if len(files) < maxWorkers {
 		maxWorkers = len(files)
 	}
@@ -190,7 +183,7 @@ func shouldSkipPromptFile(path string, tf TimeFilter) bool {
 	if tf.Start == nil {
 		return false
 	}
-	info, err := os.Stat(path)
+	info, err := statData(path)
 	if err != nil {
 		return false
 	}
@@ -290,19 +283,20 @@ func promptExampleScore(sample promptSample) int {
 }
 
 func scanPromptFile(path string, tf TimeFilter, opts cliOptions, agg *promptAggregate) {
-	f, err := os.Open(path)
+	f, err := dataSource.Open(path)
 	if err != nil {
 		return
 	}
 	defer f.Close()
 
-	decoder := json.NewDecoder(f)
+	lines := newJSONLReader(f)
 	for {
+		line, ok := lines.Next()
+		if !ok {
+			break
+		}
 		var record ProjectRecord
-		if err := decoder.Decode(&record); err != nil {
-			if err == io.EOF {
-				break
-			}
+		if err := json.Unmarshal(line, &record); err != nil {
 			continue
 		}
 		if record.Type != "user" {
@@ -315,8 +309,8 @@ func scanPromptFile(path string, tf TimeFilter, opts cliOptions, agg *promptAggr
 		if !hasTimestamp && hasTimeFilter(tf) {
 			continue
 		}
-		project := nonEmpty(record.Cwd, "Unknown")
-		if opts.Project != "" && !strings.Contains(project, opts.Project) {
+		project := projectNameFromCwd(record.Cwd)
+		if !matchContains(opts.Project, project) {
 			continue
 		}
 		if opts.Session != "" && record.SessionID != opts.Session {
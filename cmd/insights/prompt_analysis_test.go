@@ -81,6 +81,15 @@ func TestBuildCLIPromptReportProjectFilter(t *testing.T) {
 	if report.Filter.Project != "demo" {
 		t.Fatalf("Filter.Project = %q, want demo", report.Filter.Project)
 	}
+
+	// --project 与其他子命令一致，不区分大小写
+	report, err = buildCLIPromptReport(TimeFilter{}, "all", cliOptions{Limit: 10, Project: "DEMO"})
+	if err != nil {
+		t.Fatalf("buildCLIPromptReport: %v", err)
+	}
+	if report.CleanPrompts != 1 {
+		t.Fatalf("--project DEMO CleanPrompts = %d, want 1", report.CleanPrompts)
+	}
 }
 
 func TestPromptPreviewExtractsCommandArgsAndKeepsRunes(t *testing.T) {
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 进程内结果缓存：同一组时间范围参数在 Config.ResultTTL 内重复请求（前端轮询、多个面板同时加载）
+// 直接复用上次构建的 DashboardData，不再重新解析。缓存的是维度过滤之前的数据，
+// project/model 等过滤在副本上进行，因此 key 只包含决定解析结果的参数。
+// 同一 key 并发请求时只有一个在构建，其余等待其结果（single-flight）。
+
+// resultMemoMaxEntries 最多保留的结果数，超出时淘汰最早过期的一项
+const resultMemoMaxEntries = 32
+
+// resultMemoEntry 一份已构建的结果；以 JSON 保存，每次命中解码出独立副本，避免过滤时改到缓存本身
+type resultMemoEntry struct {
+	payload []byte
+	source  string
+	expires time.Time
+}
+
+// resultMemoCall 正在构建中的请求
+type resultMemoCall struct {
+	done    chan struct{}
+	payload []byte
+	source  string
+	err     error
+}
+
+type resultMemo struct {
+	mu       sync.Mutex
+	entries  map[string]resultMemoEntry
+	inflight map[string]*resultMemoCall
+	// generation 每次失效加一；失效前开始构建的结果不再写入缓存
+	generation uint64
+}
+
+func newResultMemo() *resultMemo {
+	return &resultMemo{
+		entries:  make(map[string]resultMemoEntry),
+		inflight: make(map[string]*resultMemoCall),
+	}
+}
+
+// dashboardResultMemo 全局结果缓存，缓存刷新（/api/reload 等）时清空
+var dashboardResultMemo = newResultMemo()
+
+// dashboardMemoKey 由时间范围参数与影响解析结果的数据源配置组成
+func dashboardMemoKey(filter AnalysisFilter) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%t|%s|%s|%p|%t",
+		filter.Preset, filter.Start, filter.End, filter.TimeFilter.UserType, filter.TimeFilter.CountMode, filter.TimeFilter.Exact,
+		cfg.DataDir, cfg.ArchivePath, loadGlobalCache(), cfg.UnknownModelBucket)
+}
+
+// get 返回 key 对应的结果：未过期时解码缓存副本，否则调用 build（同 key 并发只构建一次）。
+// ttl<=0 时直接构建、不缓存
+func (m *resultMemo) get(key string, ttl time.Duration, build func() (*DashboardData, string, error)) (*DashboardData, string, error) {
+	if ttl <= 0 {
+		return build()
+	}
+
+	m.mu.Lock()
+	if entry, ok := m.entries[key]; ok {
+		if time.Now().Before(entry.expires) {
+			m.mu.Unlock()
+			return decodeMemoPayload(entry.payload, entry.source)
+		}
+		delete(m.entries, key)
+	}
+	if call, ok := m.inflight[key]; ok {
+		m.mu.Unlock()
+		<-call.done
+		if call.err != nil {
+			return nil, call.source, call.err
+		}
+		return decodeMemoPayload(call.payload, call.source)
+	}
+	call := &resultMemoCall{done: make(chan struct{})}
+	m.inflight[key] = call
+	generation := m.generation
+	m.mu.Unlock()
+
+	data, source, err := build()
+	call.source, call.err = source, err
+	if err == nil {
+		call.payload, call.err = json.Marshal(data)
+	}
+
+	m.mu.Lock()
+	delete(m.inflight, key)
+	if call.err == nil && generation == m.generation {
+		m.store(key, resultMemoEntry{payload: call.payload, source: source, expires: time.Now().Add(ttl)})
+	}
+	m.mu.Unlock()
+	close(call.done)
+
+	if err != nil {
+		return nil, source, err
+	}
+	return data, source, nil
+}
+
+// store 写入一项，超出容量时淘汰最早过期的一项；调用方持有 m.mu
+func (m *resultMemo) store(key string, entry resultMemoEntry) {
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= resultMemoMaxEntries {
+		oldestKey := ""
+		var oldest time.Time
+		for k, e := range m.entries {
+			if oldestKey == "" || e.expires.Before(oldest) {
+				oldestKey, oldest = k, e.expires
+			}
+		}
+		delete(m.entries, oldestKey)
+	}
+	m.entries[key] = entry
+}
+
+// invalidate 清空全部结果；正在构建的结果完成后也不会写入
+func (m *resultMemo) invalidate() {
+	m.mu.Lock()
+	m.entries = make(map[string]resultMemoEntry)
+	m.generation++
+	m.mu.Unlock()
+}
+
+func decodeMemoPayload(payload []byte, source string) (*DashboardData, string, error) {
+	var data DashboardData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, source, fmt.Errorf("解码缓存结果失败: %w", err)
+	}
+	return &data, source, nil
+}
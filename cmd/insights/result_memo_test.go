@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResultMemoReusesResultWithinTTL(t *testing.T) {
+	memo := newResultMemo()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	build := func() (*DashboardData, string, error) {
+		calls.Add(1)
+		<-release
+		return &DashboardData{Commands: []CommandStats{{Command: "/help", Count: 2}}}, "parsing", nil
+	}
+
+	// 同一 key 的并发请求只构建一次，其余等待结果
+	var wg sync.WaitGroup
+	results := make([]*DashboardData, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, source, err := memo.get("7d", time.Minute, build)
+			if err != nil || source != "parsing" {
+				t.Errorf("get() source=%s err=%v", source, err)
+			}
+			results[i] = data
+		}(i)
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("并发请求构建了 %d 次, want 1", n)
+	}
+
+	// TTL 内再次请求不重新解析，且拿到的是独立副本
+	results[0].Commands[0].Count = 99
+	data, _, err := memo.get("7d", time.Minute, build)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("TTL 内重复请求构建了 %d 次, want 1", n)
+	}
+	if data.Commands[0].Count != 2 {
+		t.Fatalf("缓存结果被调用方修改: %+v", data.Commands)
+	}
+
+	// 失效后重新构建
+	memo.invalidate()
+	if _, _, err := memo.get("7d", time.Minute, build); err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("invalidate 后构建次数 = %d, want 2", n)
+	}
+}
+
+func TestDashboardMemoKeyDistinguishesExactFilter(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 30, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+	// 按天聚合（可读缓存）与精确到时刻（实时解析）的结果不同，不能共用缓存条目
+	daily := AnalysisFilter{Preset: sinceLastRunPreset, TimeFilter: TimeFilter{Start: &start, End: &end}}
+	exact := daily
+	exact.TimeFilter.Exact = true
+	if dashboardMemoKey(daily) == dashboardMemoKey(exact) {
+		t.Fatalf("Exact 不同的过滤条件得到相同 key: %q", dashboardMemoKey(daily))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 汇总粒度
+const (
+	RollupWeek  = "week"
+	RollupMonth = "month"
+)
+
+// RollupBucket 一个 ISO 周或自然月的汇总；Start/End 为该周期的首尾日期（不受查询范围截断）
+type RollupBucket struct {
+	Key        string `json:"key"`
+	Label      string `json:"label"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Messages   int    `json:"messages"`
+	Sessions   int    `json:"sessions"`
+	ActiveDays int    `json:"active_days"`
+}
+
+// rollupData /api/rollup 返回的按周或按月汇总
+type rollupData struct {
+	By      string         `json:"by"`
+	Buckets []RollupBucket `json:"buckets"`
+}
+
+// normalizeRollupBy 校验 by 参数，缺省按周
+func normalizeRollupBy(value string) (string, error) {
+	switch by := strings.ToLower(strings.TrimSpace(value)); by {
+	case "", RollupWeek:
+		return RollupWeek, nil
+	case RollupMonth:
+		return RollupMonth, nil
+	default:
+		return "", fmt.Errorf("无效的 by: %s（可选 week|month）", value)
+	}
+}
+
+// rollupPeriod 日期所属周期：按周时取 ISO 周（周一开始，跨年的周归入 ISO 年，
+// 如 2026-01-01 属于 2026-W01，2027-01-01 属于 2026-W53），按月时取自然月
+func rollupPeriod(day time.Time, by string) (key, label string, start, end time.Time) {
+	if by == RollupMonth {
+		start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		key = start.Format("2006-01")
+		return key, start.Format("2006年1月"), start, start.AddDate(0, 1, -1)
+	}
+	year, week := day.ISOWeek()
+	offset := (int(day.Weekday()) + 6) % 7 // 周一为 0
+	start = time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, time.UTC)
+	key = fmt.Sprintf("%04d-W%02d", year, week)
+	return key, key, start, start.AddDate(0, 0, 6)
+}
+
+// buildRollup 把每日消息数与会话数按周期汇总；会话数为各日会话数之和（跨天的会话按天重复计入）。
+// 只包含有消息的周期，按 key 升序
+func buildRollup(trend DailyTrendData, dailySessions map[string]int, by string) []RollupBucket {
+	buckets := make(map[string]*RollupBucket)
+	for i, date := range trend.Dates {
+		if i >= len(trend.Counts) || trend.Counts[i] <= 0 {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		key, label, start, end := rollupPeriod(day, by)
+		bucket := buckets[key]
+		if bucket == nil {
+			bucket = &RollupBucket{Key: key, Label: label, Start: start.Format("2006-01-02"), End: end.Format("2006-01-02")}
+			buckets[key] = bucket
+		}
+		bucket.Messages += trend.Counts[i]
+		bucket.Sessions += dailySessions[date]
+		bucket.ActiveDays++
+	}
+
+	result := make([]RollupBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, *bucket)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// handleRollupAPI /api/rollup?by=week|month：把每日趋势按 ISO 周或自然月汇总，长范围下比逐日更易读
+func handleRollupAPI(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAnalysisFilter(r)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	by, err := normalizeRollupBy(r.URL.Query().Get("by"))
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	startedAt := time.Now()
+	data, source, err := buildDashboardDataWithFilter(filter)
+	if err != nil {
+		sendInteractiveError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var dailySessions map[string]int
+	if data.Sessions != nil {
+		dailySessions = data.Sessions.DailySessionMap
+	}
+	payload := rollupData{By: by, Buckets: buildRollup(data.DailyTrend, dailySessions, by)}
+	sendInteractiveJSON(w, payload, source, data.TimeRange, filter, startedAt)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildRollupGroupsByISOWeekAcrossYearBoundary(t *testing.T) {
+	trend := DailyTrendData{
+		Dates:  []string{"2025-12-28", "2025-12-29", "2026-01-01", "2026-01-04", "2026-01-05", "2026-01-06", "2026-01-20"},
+		Counts: []int{1, 2, 3, 4, 5, 0, 7},
+	}
+	sessions := map[string]int{"2025-12-28": 1, "2025-12-29": 1, "2026-01-01": 2, "2026-01-04": 1, "2026-01-05": 3, "2026-01-20": 1}
+
+	// 2025-12-29（周一）到 2026-01-04 属于 ISO 2026-W01；2025-12-28（周日）仍属 2025-W52
+	want := []RollupBucket{
+		{Key: "2025-W52", Label: "2025-W52", Start: "2025-12-22", End: "2025-12-28", Messages: 1, Sessions: 1, ActiveDays: 1},
+		{Key: "2026-W01", Label: "2026-W01", Start: "2025-12-29", End: "2026-01-04", Messages: 9, Sessions: 4, ActiveDays: 3},
+		{Key: "2026-W02", Label: "2026-W02", Start: "2026-01-05", End: "2026-01-11", Messages: 5, Sessions: 3, ActiveDays: 1},
+		{Key: "2026-W04", Label: "2026-W04", Start: "2026-01-19", End: "2026-01-25", Messages: 7, Sessions: 1, ActiveDays: 1},
+	}
+	if got := buildRollup(trend, sessions, RollupWeek); !reflect.DeepEqual(got, want) {
+		t.Fatalf("weekly rollup=%+v\nwant %+v", got, want)
+	}
+
+	wantMonths := []RollupBucket{
+		{Key: "2025-12", Label: "2025年12月", Start: "2025-12-01", End: "2025-12-31", Messages: 3, Sessions: 2, ActiveDays: 2},
+		{Key: "2026-01", Label: "2026年1月", Start: "2026-01-01", End: "2026-01-31", Messages: 19, Sessions: 7, ActiveDays: 4},
+	}
+	if got := buildRollup(trend, sessions, RollupMonth); !reflect.DeepEqual(got, wantMonths) {
+		t.Fatalf("monthly rollup=%+v\nwant %+v", got, wantMonths)
+	}
+
+	// 2027-01-01 是周五，ISO 周仍属上一年的第 53 周
+	if key, _, _, _ := rollupPeriod(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), RollupWeek); key != "2026-W53" {
+		t.Fatalf("2027-01-01 key=%s, want 2026-W53", key)
+	}
+}
+
+func TestRollupAPI(t *testing.T) {
+	useFixtureDataDir(t)
+
+	w := httptest.NewRecorder()
+	handleRollupAPI(w, httptest.NewRequest("GET", "/api/rollup?preset=all&by=week", nil))
+	var resp struct {
+		Success bool       `json:"success"`
+		Data    rollupData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	// 固定数据集 2026-01-05 ~ 01-07 均在 2026-W02，共 5 条 assistant 消息
+	if !resp.Success || resp.Data.By != RollupWeek || len(resp.Data.Buckets) != 1 ||
+		resp.Data.Buckets[0].Key != "2026-W02" || resp.Data.Buckets[0].Messages != 5 {
+		t.Fatalf("/api/rollup 结果不符: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handleRollupAPI(w, httptest.NewRequest("GET", "/api/rollup?by=year", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("无效 by status=%d, want 400", w.Code)
+	}
+}
@@ -90,6 +90,12 @@ func mergeSessionStat(dst, src *SessionAnalysisItem) {
 		}
 		dst.ModelsUsed[model] += count
 	}
+	for tool, count := range src.ToolsUsed {
+		if dst.ToolsUsed == nil {
+			dst.ToolsUsed = make(map[string]int)
+		}
+		dst.ToolsUsed[tool] += count
+	}
 	for name, h := range src.HookBreakdown {
 		if dst.HookBreakdown == nil {
 			dst.HookBreakdown = make(map[string]*SessionHookStat)
@@ -311,6 +317,12 @@ func addSessionToolCallLocked(agg *ProjectAggregate, call pendingToolCall) {
 		stat.ModelsUsed = make(map[string]int)
 	}
 	stat.ModelsUsed[call.Model]++
+	if call.Tool != "" {
+		if stat.ToolsUsed == nil {
+			stat.ToolsUsed = make(map[string]int)
+		}
+		stat.ToolsUsed[call.Tool]++
+	}
 }
 
 func addSessionToolResultLocked(agg *ProjectAggregate, call pendingToolCall, failed bool, missing bool) {
@@ -344,11 +356,16 @@ func (agg *ProjectAggregate) finalizeSessionAnalysis() {
 	}
 	outcomes := make(map[string]int)
 	titles := make(map[string]int)
+	var distinctToolTotal, sessionsWithTools int
 
 	for _, stat := range agg.SessionStatsMap {
 		statCopy := *stat
 		finalizeSessionItem(&statCopy)
 		analysis.Sessions = append(analysis.Sessions, statCopy)
+		if statCopy.DistinctToolCount > 0 {
+			distinctToolTotal += statCopy.DistinctToolCount
+			sessionsWithTools++
+		}
 		outcomes[statCopy.Outcome]++
 		if statCopy.TitleSource != "" {
 			titles[statCopy.TitleSource]++
@@ -396,10 +413,28 @@ func (agg *ProjectAggregate) finalizeSessionAnalysis() {
 	sort.Slice(analysis.Titles, func(i, j int) bool {
 		return analysis.Titles[i].Count > analysis.Titles[j].Count
 	})
+	if sessionsWithTools > 0 {
+		analysis.AvgDistinctTools = float64(distinctToolTotal) / float64(sessionsWithTools)
+	}
+	analysis.Summaries = make([]SessionAnalysisItem, 0, len(analysis.Sessions))
+	for _, item := range analysis.Sessions {
+		analysis.Summaries = append(analysis.Summaries, sessionSummary(item))
+	}
 	limitSessionAnalysis(analysis)
 	agg.SessionAnalysis = analysis
 }
 
+// sessionSummary 只保留 session 过滤（项目、模型）与会话合计所需的字段
+func sessionSummary(item SessionAnalysisItem) SessionAnalysisItem {
+	return SessionAnalysisItem{
+		SessionID:             item.SessionID,
+		Project:               item.Project,
+		PrimaryModel:          item.PrimaryModel,
+		ModelsUsed:            item.ModelsUsed,
+		AssistantMessageCount: item.AssistantMessageCount,
+	}
+}
+
 func finalizeSessionItem(stat *SessionAnalysisItem) {
 	for _, h := range stat.HookBreakdown {
 		if h.TotalCount > 0 {
@@ -435,6 +470,7 @@ func finalizeSessionItem(stat *SessionAnalysisItem) {
 	if len(stat.ModelsUsed) > 0 {
 		stat.PrimaryModel = primarySessionModel(stat.ModelsUsed)
 	}
+	stat.DistinctToolCount = len(stat.ToolsUsed)
 }
 
 // primarySessionModel 返回 session 内使用次数最多的模型（平手按模型名排序）。
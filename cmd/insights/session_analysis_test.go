@@ -78,6 +78,44 @@ func TestParseProjectsConcurrentOnce_SessionAnalysis(t *testing.T) {
 	}
 }
 
+func TestParseProjectsConcurrentOnce_SessionDistinctTools(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	projectDir := filepath.Join(dataDir, "projects", "distinct-tools")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("创建测试项目目录失败: %v", err)
+	}
+
+	// 同一 session 内调用 4 次、3 种不同工具（Bash 重复一次）
+	base := time.Date(2026, 6, 11, 9, 0, 0, 0, time.UTC)
+	content := toolUseRecordWithUsage("/tmp/distinct-tools", "session-tools", base, "call-1", "Bash", "claude-sonnet-4.5", `{"command":"ls"}`, 1, 1) + "\n" +
+		toolUseRecordWithUsage("/tmp/distinct-tools", "session-tools", base.Add(time.Second), "call-2", "Read", "claude-sonnet-4.5", `{"file_path":"/tmp/a.go"}`, 1, 1) + "\n" +
+		toolUseRecordWithUsage("/tmp/distinct-tools", "session-tools", base.Add(2*time.Second), "call-3", "Edit", "claude-sonnet-4.5", `{"file_path":"/tmp/a.go"}`, 1, 1) + "\n" +
+		toolUseRecordWithUsage("/tmp/distinct-tools", "session-tools", base.Add(3*time.Second), "call-4", "Bash", "claude-sonnet-4.5", `{"command":"go test"}`, 1, 1) + "\n"
+
+	if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入测试 jsonl 失败: %v", err)
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	if agg.SessionAnalysis == nil || len(agg.SessionAnalysis.Sessions) != 1 {
+		t.Fatalf("SessionAnalysis=%+v, want one session", agg.SessionAnalysis)
+	}
+	session := agg.SessionAnalysis.Sessions[0]
+	if session.ToolCallCount != 4 {
+		t.Fatalf("ToolCallCount=%d, want 4", session.ToolCallCount)
+	}
+	if session.DistinctToolCount != 3 {
+		t.Fatalf("DistinctToolCount=%d, want 3 (tools=%v)", session.DistinctToolCount, session.ToolsUsed)
+	}
+	if agg.SessionAnalysis.AvgDistinctTools != 3 {
+		t.Fatalf("AvgDistinctTools=%v, want 3", agg.SessionAnalysis.AvgDistinctTools)
+	}
+}
+
 func modeRecord(cwd, sessionID string, ts time.Time, mode string) string {
 	return `{"type":"mode","mode":"` + mode + `","cwd":"` + cwd + `","sessionId":"` + sessionID + `","timestamp":"` + ts.Format(time.RFC3339Nano) + `"}`
 }
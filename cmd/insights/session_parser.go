@@ -3,8 +3,9 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
 // ParseSessionIndex 解析 sessions-index.json 文件
@@ -15,7 +16,7 @@ func ParseSessionIndex(projectPath string) (*SessionIndexResult, error) {
 	}
 
 	indexPath := filepath.Join(projectPath, "sessions-index.json")
-	f, err := os.Open(indexPath)
+	f, err := dataSource.Open(indexPath)
 	if err != nil {
 		return nil, fmt.Errorf("open sessions-index.json: %w", err)
 	}
@@ -39,3 +40,107 @@ func ParseSessionStatsWithFilter(tf TimeFilter) (*SessionStats, error) {
 	}
 	return extractSessionStatsFromAggregate(agg)
 }
+
+// ParseSessionList 遍历 projects 下全部 JSONL，按 sessionId 列出会话的起止时间与消息数。
+// 起止时间取时间范围内带时间戳的 user/assistant 记录；消息数与项目统计一致，只计 assistant 消息。
+// 结果按开始时间倒序。
+func ParseSessionList(tf TimeFilter) ([]SessionInfo, error) {
+	var list []SessionInfo
+	err := WalkSessionList(tf, func(info SessionInfo) error {
+		list = append(list, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []SessionInfo{}
+	}
+	return list, nil
+}
+
+// WalkSessionList 与 ParseSessionList 口径、顺序相同，但逐条交给 emit 而不返回完整切片，
+// 供流式导出使用。同一会话可能跨多个文件，因此仍需先扫描全部文件再输出；
+// emit 返回错误时立即停止并返回该错误。
+func WalkSessionList(tf TimeFilter, emit func(SessionInfo) error) error {
+	projectDirs, err := listProjectDirs(GetDataPath("projects"))
+	if err != nil {
+		return fmt.Errorf("读取 projects 目录失败: %w", err)
+	}
+
+	type sessionRange struct {
+		info       SessionInfo
+		start, end time.Time
+	}
+	sessions := make(map[string]*sessionRange)
+	for _, projectDir := range projectDirs {
+		files, err := projectJSONLFiles(projectDir)
+		if err != nil {
+			continue
+		}
+		for _, path := range files {
+			f, err := dataSource.Open(path)
+			if err != nil {
+				continue
+			}
+			lines := newJSONLReader(f)
+			for {
+				line, ok := lines.Next()
+				if !ok {
+					break
+				}
+				var record ProjectRecord
+				if err := json.Unmarshal(line, &record); err != nil {
+					continue
+				}
+				if record.SessionID == "" || (record.Type != "user" && record.Type != "assistant") {
+					continue
+				}
+				timestamp, ok := parseProjectRecordTimestamp(record.Timestamp)
+				if !ok || !tf.Contains(timestamp) || !tf.MatchesUserType(record.UserType) || isExcludedProject(record.Cwd) {
+					continue
+				}
+				session := sessions[record.SessionID]
+				if session == nil {
+					session = &sessionRange{info: SessionInfo{SessionID: record.SessionID}, start: timestamp, end: timestamp}
+					sessions[record.SessionID] = session
+				}
+				if session.info.Project == "" {
+					session.info.Project = normalizeProjectPath(record.Cwd)
+				}
+				if timestamp.Before(session.start) {
+					session.start = timestamp
+				}
+				if timestamp.After(session.end) {
+					session.end = timestamp
+				}
+				if record.Type == "assistant" {
+					session.info.MessageCount++
+				}
+			}
+			f.Close()
+		}
+	}
+
+	ordered := make([]*sessionRange, 0, len(sessions))
+	for _, session := range sessions {
+		ordered = append(ordered, session)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if !ordered[i].start.Equal(ordered[j].start) {
+			return ordered[i].start.After(ordered[j].start)
+		}
+		return ordered[i].info.SessionID < ordered[j].info.SessionID
+	})
+	for _, session := range ordered {
+		if session.info.Project == "" {
+			session.info.Project = "Unknown"
+		}
+		session.info.StartTime = session.start.Format(time.RFC3339)
+		session.info.EndTime = session.end.Format(time.RFC3339)
+		if err := emit(session.info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
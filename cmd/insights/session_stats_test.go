@@ -1,7 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -60,3 +66,299 @@ func TestSessionStatsTypes(t *testing.T) {
 		t.Errorf("DailySessionMap type mismatch: got %v, want %v", actualType, expectedType)
 	}
 }
+
+func TestAvgMessagesPerSession(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	// 10 个会话 × 10 条 assistant 消息
+	var content strings.Builder
+	for session := 0; session < 10; session++ {
+		for i := 0; i < 10; i++ {
+			content.WriteString(projectRecordJSON("/tmp/demo", fmt.Sprintf("s%d", session), base.Add(time.Duration(session*10+i)*time.Minute)) + "\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agg, err := ParseProjectsConcurrentOnceFromDir(TimeFilter{}, dataDir)
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnceFromDir failed: %v", err)
+	}
+	stats, _ := extractSessionStatsFromAggregate(agg)
+	if stats.TotalSessions != 10 || stats.AvgMessagesPerSession != 10.0 {
+		t.Fatalf("sessions=%d avg=%v, want 10/10.0", stats.TotalSessions, stats.AvgMessagesPerSession)
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile failed: %v", err)
+	}
+	origCache, origDataDir := loadGlobalCache(), cfg.DataDir
+	cfg.DataDir = dataDir
+	storeGlobalCache(cache)
+	defer func() {
+		cfg.DataDir = origDataDir
+		storeGlobalCache(origCache)
+	}()
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache failed: %v", err)
+	}
+	if data.Sessions.AvgMessagesPerSession != 10.0 {
+		t.Fatalf("cache avg=%v, want 10.0", data.Sessions.AvgMessagesPerSession)
+	}
+
+	if avg := avgMessagesPerSession(5, 0); avg != 0 {
+		t.Fatalf("无会话时 avg=%v, want 0", avg)
+	}
+}
+
+func TestParseSessionListMergesSessionAcrossFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	userRecord := `{"type":"user","cwd":"/tmp/demo","sessionId":"s1","timestamp":"` + base.Add(time.Hour).Format(time.RFC3339) + `","message":{"role":"user","content":"hi"}}`
+	// s1 跨 a.jsonl 与 b.jsonl（如 resume 后写入新文件）；s2 只在 a.jsonl
+	files := map[string]string{
+		"a.jsonl": projectRecordJSON("/tmp/demo", "s1", base) + "\n" +
+			projectRecordJSON("/tmp/demo", "s2", base.Add(30*time.Minute)) + "\n",
+		"b.jsonl": userRecord + "\n" +
+			projectRecordJSON("/tmp/demo", "s1", base.Add(2*time.Hour)) + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	sessions, err := ParseSessionList(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseSessionList() error = %v", err)
+	}
+	want := []SessionInfo{
+		{SessionID: "s2", Project: "/tmp/demo", StartTime: base.Add(30 * time.Minute).Format(time.RFC3339), EndTime: base.Add(30 * time.Minute).Format(time.RFC3339), MessageCount: 1},
+		{SessionID: "s1", Project: "/tmp/demo", StartTime: base.Format(time.RFC3339), EndTime: base.Add(2 * time.Hour).Format(time.RFC3339), MessageCount: 2},
+	}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("sessions = %+v\nwant %+v", sessions, want)
+	}
+
+	req := httptest.NewRequest("GET", "/api/sessions?preset=all&offset=1&limit=1", nil)
+	w := httptest.NewRecorder()
+	handleSessionsAPI(w, req)
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    sessionListData `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.TotalSessions != 2 || len(resp.Data.Sessions) != 1 || resp.Data.Sessions[0].SessionID != "s1" {
+		t.Fatalf("分页结果不符: %s", w.Body.String())
+	}
+}
+
+func TestLogicalSessionsSplitOnGap(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 同一 sessionId：9:00、9:10 之后停了 45 分钟，9:55 再继续
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := projectRecordJSON("/tmp/demo", "s1", base) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(10*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(55*time.Minute)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origGap, origCache := cfg.DataDir, cfg.SessionGapMinutes, loadGlobalCache()
+	cfg.DataDir = dataDir
+	cfg.SessionGapMinutes = 30
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir, cfg.SessionGapMinutes = origDataDir, origGap
+		storeGlobalCache(origCache)
+	}()
+
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	stats, _ := extractSessionStatsFromAggregate(agg)
+	if stats.TotalSessions != 1 || stats.LogicalSessions != 2 || stats.SessionGapMinutes != 30 {
+		t.Fatalf("total=%d logical=%d gap=%d, want 1/2/30", stats.TotalSessions, stats.LogicalSessions, stats.SessionGapMinutes)
+	}
+
+	// 缓存路径与实时解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	storeGlobalCache(cache)
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache() error = %v", err)
+	}
+	if data.Sessions.LogicalSessions != 2 {
+		t.Fatalf("缓存路径 logical_sessions=%d, want 2", data.Sessions.LogicalSessions)
+	}
+
+	// 间隔阈值放宽到 60 分钟后只算一段（切分次数在解析时按当前间隔计算，需重新解析）
+	cfg.SessionGapMinutes = 60
+	if agg, err = ParseProjectsConcurrentOnce(TimeFilter{}); err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	if stats, _ := extractSessionStatsFromAggregate(agg); stats.LogicalSessions != 1 {
+		t.Fatalf("gap=60 时 logical=%d, want 1", stats.LogicalSessions)
+	}
+
+	// 缓存路径：间隔变化后不能复用按旧间隔切分的文件级快照
+	builder := &CacheBuilder{CachePath: cachePath, DataDir: dataDir}
+	if !builder.NeedsRebuild() {
+		t.Fatal("间隔变化后应重建缓存")
+	}
+	if err := builder.BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	if cache, err = LoadCacheFile(cachePath); err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	if cache.BuildStats.ReusedFiles != 0 || cache.SessionGapMinutes != 60 {
+		t.Fatalf("reused=%d gap=%d, want 0/60", cache.BuildStats.ReusedFiles, cache.SessionGapMinutes)
+	}
+	storeGlobalCache(cache)
+	if data, err = buildDataFromCache(TimeFilter{}, "all"); err != nil {
+		t.Fatalf("buildDataFromCache() error = %v", err)
+	}
+	if data.Sessions.LogicalSessions != 1 {
+		t.Fatalf("gap=60 重建缓存后 logical_sessions=%d, want 1", data.Sessions.LogicalSessions)
+	}
+}
+
+func TestSessionSpansMergeAcrossFilesWithoutPerMessageTimes(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 同一会话写在两个文件里：9:00、9:10 与 10:00、10:05，两段之间停了 50 分钟
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	first := projectRecordJSON("/tmp/demo", "s1", base) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(10*time.Minute)) + "\n"
+	second := projectRecordJSON("/tmp/demo", "s1", base.Add(time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", base.Add(65*time.Minute)) + "\n"
+	for name, content := range map[string]string{"a.jsonl": first, "b.jsonl": second} {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir, origGap := cfg.DataDir, cfg.SessionGapMinutes
+	cfg.DataDir = dataDir
+	cfg.SessionGapMinutes = 30
+	defer func() { cfg.DataDir, cfg.SessionGapMinutes = origDataDir, origGap }()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	if day := cache.DailyStats["2026-01-05"]; day == nil || day.LogicalSessions != 2 {
+		t.Fatalf("DailyStats=%+v, want 2 段逻辑会话", day)
+	}
+	// 每个文件只落盘一条摘要，而不是逐条消息的时间
+	for path, file := range cache.ProjectFiles {
+		span := file.Aggregate.DailySessionSpans["2026-01-05"]["s1"]
+		if span.Count != 2 || span.Splits != 0 {
+			t.Fatalf("%s span=%+v, want 2 条记录、无切分", path, span)
+		}
+	}
+	raw, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "daily_session_times") {
+		t.Fatal("缓存不应再保存逐条消息时间")
+	}
+}
+
+func TestMinSessionMessagesExcludesShortSessions(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// short 只有 1 条消息（误开后即关闭），long 有 5 条
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := projectRecordJSON("/tmp/demo", "short", base) + "\n"
+	for i := 0; i < 5; i++ {
+		content += projectRecordJSON("/tmp/demo", "long", base.Add(time.Duration(i+1)*time.Minute)) + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir, origMin, origCache := cfg.DataDir, cfg.MinSessionMessages, loadGlobalCache()
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	defer func() {
+		cfg.DataDir, cfg.MinSessionMessages = origDataDir, origMin
+		storeGlobalCache(origCache)
+	}()
+
+	agg, err := ParseProjectsConcurrentOnce(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseProjectsConcurrentOnce() error = %v", err)
+	}
+	cfg.MinSessionMessages = 1
+	if stats, _ := extractSessionStatsFromAggregate(agg); stats.TotalSessions != 2 {
+		t.Fatalf("默认阈值 total=%d, want 2", stats.TotalSessions)
+	}
+	cfg.MinSessionMessages = 2
+	stats, _ := extractSessionStatsFromAggregate(agg)
+	if stats.TotalSessions != 1 || stats.LogicalSessions != 1 || stats.DailySessionMap["2026-01-05"] != 1 {
+		t.Fatalf("阈值 2 时 total=%d logical=%d daily=%v, want 1/1/1", stats.TotalSessions, stats.LogicalSessions, stats.DailySessionMap)
+	}
+
+	// 缓存路径与实时解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	if cache.MinSessionMessages != 2 {
+		t.Fatalf("缓存记录的阈值 = %d, want 2", cache.MinSessionMessages)
+	}
+	storeGlobalCache(cache)
+	data, err := buildDataFromCache(TimeFilter{}, "all")
+	if err != nil {
+		t.Fatalf("buildDataFromCache() error = %v", err)
+	}
+	if data.Sessions.TotalSessions != 1 {
+		t.Fatalf("缓存路径 total_sessions=%d, want 1", data.Sessions.TotalSessions)
+	}
+}
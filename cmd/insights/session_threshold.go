@@ -0,0 +1,50 @@
+package main
+
+// 活跃会话阈值：assistant 消息少于 Config.MinSessionMessages 条的会话（如误开后只发了一条）不计入会话数。
+// 按会话在本次统计数据中的消息总数判定（跨天的会话各天合计），判定后仍与 TotalSessions 一样按天计数。
+
+// defaultMinSessionMessages 默认阈值：有一条消息即计为会话
+const defaultMinSessionMessages = 1
+
+// minSessionMessagesCacheKey 缓存中记录的非默认阈值（<=1 记为 0），变更后需要重建
+func minSessionMessagesCacheKey() int {
+	if cfg.MinSessionMessages <= defaultMinSessionMessages {
+		return 0
+	}
+	return cfg.MinSessionMessages
+}
+
+// shortSessions 消息总数低于 minMessages 的会话集合；dailySpans 为 date→sessionID→会话摘要，
+// Count 为当天的 assistant 消息数。阈值 <=1 时返回 nil
+func shortSessions(dailySpans map[string]map[string]SessionSpan, minMessages int) map[string]bool {
+	if minMessages <= defaultMinSessionMessages {
+		return nil
+	}
+	totals := make(map[string]int)
+	for _, sessions := range dailySpans {
+		for sessionID, span := range sessions {
+			totals[sessionID] += span.Count
+		}
+	}
+	short := make(map[string]bool)
+	for sessionID, count := range totals {
+		if count < minMessages {
+			short[sessionID] = true
+		}
+	}
+	return short
+}
+
+// withoutSessions 去掉 excluded 中的会话；excluded 为空时原样返回
+func withoutSessions(spans map[string]SessionSpan, excluded map[string]bool) map[string]SessionSpan {
+	if len(excluded) == 0 {
+		return spans
+	}
+	out := make(map[string]SessionSpan, len(spans))
+	for sessionID, span := range spans {
+		if !excluded[sessionID] {
+			out[sessionID] = span
+		}
+	}
+	return out
+}
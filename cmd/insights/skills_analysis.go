@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -591,7 +590,7 @@ func normalizeSkillName(name string) string {
 
 func scanInstalledSkillsFromDir(dataDir string) []InstalledSkillItem {
 	skillsDir := filepath.Join(dataDir, "skills")
-	entries, err := os.ReadDir(skillsDir)
+	entries, err := dataSource.ReadDir(skillsDir)
 	if err != nil {
 		return nil
 	}
@@ -602,7 +601,7 @@ func scanInstalledSkillsFromDir(dataDir string) []InstalledSkillItem {
 		}
 		item := InstalledSkillItem{Name: entry.Name(), Path: filepath.ToSlash(filepath.Join("skills", entry.Name()))}
 		skillDir := filepath.Join(skillsDir, entry.Name())
-		files, err := os.ReadDir(skillDir)
+		files, err := dataSource.ReadDir(skillDir)
 		if err == nil {
 			item.FileCount = len(files)
 			for _, file := range files {
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stats-cache.json 有两种布局：
+//   - 旧布局（未声明版本或 version=1）：hourCounts 为 {"9": n} 形式的对象，
+//     dailyModelTokens 为 [{"date", "tokensByModel"}] 数组
+//   - 新布局（schemaVersion>=2）：hourCounts 可能为 24 项数组或 {"09:00": n} 形式的对象，
+//     dailyModelTokens 可能为 {"2026-01-05": {"model": n}} 形式的对象
+// 两个字段都按实际形状解析，不依赖版本号判断，未声明版本但形状不同的文件也能正确读取。
+
+// rawStatsCache 读取时保留需要按形状解析的字段
+type rawStatsCache struct {
+	Version          int             `json:"version"`
+	SchemaVersion    int             `json:"schemaVersion"`
+	DailyActivity    []DailyActivity `json:"dailyActivity"`
+	DailyModelTokens json.RawMessage `json:"dailyModelTokens"`
+	ModelUsage       map[string]struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"modelUsage"`
+	HourCounts json.RawMessage `json:"hourCounts"`
+}
+
+// decodeStatsCache 解析 stats-cache.json，把新旧两种布局映射到 StatsCache
+func decodeStatsCache(data []byte) (*StatsCache, error) {
+	var raw rawStatsCache
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	cache := &StatsCache{
+		SchemaVersion: raw.SchemaVersion,
+		DailyActivity: raw.DailyActivity,
+		ModelUsage:    raw.ModelUsage,
+	}
+	if cache.SchemaVersion == 0 {
+		cache.SchemaVersion = raw.Version
+	}
+
+	hourCounts, err := decodeStatsHourCounts(raw.HourCounts)
+	if err != nil {
+		return nil, fmt.Errorf("hourCounts: %w", err)
+	}
+	cache.HourCounts = hourCounts
+
+	dailyTokens, err := decodeStatsDailyModelTokens(raw.DailyModelTokens)
+	if err != nil {
+		return nil, fmt.Errorf("dailyModelTokens: %w", err)
+	}
+	cache.DailyModelTokens = dailyTokens
+	return cache, nil
+}
+
+// decodeStatsHourCounts 兼容对象（键为 "9"、"09" 或 "09:00"）与 24 项数组两种形状，统一为不补零的小时键
+func decodeStatsHourCounts(raw json.RawMessage) (map[string]int, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	counts := make(map[string]int)
+	if raw[0] == '[' {
+		var list []int
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for hour, count := range list {
+			if hour < 24 && count != 0 {
+				counts[strconv.Itoa(hour)] += count
+			}
+		}
+		return counts, nil
+	}
+	var keyed map[string]int
+	if err := json.Unmarshal(raw, &keyed); err != nil {
+		return nil, err
+	}
+	for key, count := range keyed {
+		hour, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(key), ":00"))
+		if err != nil || hour < 0 || hour > 23 {
+			continue
+		}
+		counts[strconv.Itoa(hour)] += count
+	}
+	return counts, nil
+}
+
+// decodeStatsDailyModelTokens 兼容 [{"date","tokensByModel"}] 数组与按日期为键的对象两种形状，结果按日期升序
+func decodeStatsDailyModelTokens(raw json.RawMessage) ([]DailyModelTokens, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	var days []DailyModelTokens
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &days); err != nil {
+			return nil, err
+		}
+	} else {
+		var byDate map[string]map[string]int
+		if err := json.Unmarshal(raw, &byDate); err != nil {
+			return nil, err
+		}
+		for date, models := range byDate {
+			days = append(days, DailyModelTokens{Date: date, TokensByModel: models})
+		}
+	}
+	sort.SliceStable(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days, nil
+}
+
+// filterDailyModelTokens 按时间范围过滤每日 token（同 FilterDailyActivity）
+func filterDailyModelTokens(days []DailyModelTokens, tf TimeFilter) []DailyModelTokens {
+	if tf.Start == nil && tf.End == nil {
+		return days
+	}
+	result := make([]DailyModelTokens, 0)
+	for _, day := range days {
+		t, err := time.Parse("2006-01-02", day.Date)
+		if err != nil {
+			continue
+		}
+		if tf.Contains(t) {
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+// DailyTokenTrend 由 DailyModelTokens 汇总每日 token 合计（各模型相加），dates 与 totals 一一对应、按日期升序
+func DailyTokenTrend(cache *StatsCache) ([]string, []int) {
+	dates := make([]string, 0, len(cache.DailyModelTokens))
+	totals := make([]int, 0, len(cache.DailyModelTokens))
+	for _, day := range cache.DailyModelTokens {
+		total := 0
+		for _, tokens := range day.TokensByModel {
+			total += tokens
+		}
+		dates = append(dates, day.Date)
+		totals = append(totals, total)
+	}
+	return dates, totals
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatsCacheLegacyLayout(t *testing.T) {
+	dataDir := t.TempDir()
+	content := `{
+  "version": 1,
+  "dailyActivity": [{"date": "2026-01-05", "messageCount": 3, "sessionCount": 1, "toolCallCount": 0}],
+  "dailyModelTokens": [
+    {"date": "2026-01-06", "tokensByModel": {"claude-opus-4-6": 500}},
+    {"date": "2026-01-05", "tokensByModel": {"claude-opus-4-6": 100, "claude-sonnet-4-6": 50}}
+  ],
+  "modelUsage": {"claude-opus-4-6": {"inputTokens": 400, "outputTokens": 200}},
+  "hourCounts": {"9": 2, "14": 1}
+}`
+	if err := os.WriteFile(filepath.Join(dataDir, "stats-cache.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	cache, err := ParseStatsCache()
+	if err != nil {
+		t.Fatalf("ParseStatsCache() error = %v", err)
+	}
+	if cache.SchemaVersion != 1 || len(cache.DailyActivity) != 1 || cache.ModelUsage["claude-opus-4-6"].InputTokens != 400 {
+		t.Fatalf("cache=%+v", cache)
+	}
+	if want := map[string]int{"9": 2, "14": 1}; !reflect.DeepEqual(cache.HourCounts, want) {
+		t.Fatalf("hourCounts=%v, want %v", cache.HourCounts, want)
+	}
+	dates, totals := DailyTokenTrend(cache)
+	if !reflect.DeepEqual(dates, []string{"2026-01-05", "2026-01-06"}) || !reflect.DeepEqual(totals, []int{150, 500}) {
+		t.Fatalf("token trend dates=%v totals=%v", dates, totals)
+	}
+
+	tf, err := NewTimeFilterCustom("2026-01-06", "2026-01-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := ParseStatsCacheWithFilter(tf)
+	if err != nil {
+		t.Fatalf("ParseStatsCacheWithFilter() error = %v", err)
+	}
+	if len(filtered.DailyModelTokens) != 1 || filtered.DailyModelTokens[0].Date != "2026-01-06" {
+		t.Fatalf("filtered dailyModelTokens=%+v", filtered.DailyModelTokens)
+	}
+}
+
+func TestDecodeStatsCacheNewLayout(t *testing.T) {
+	content := `{
+  "schemaVersion": 2,
+  "lastComputedDate": "2026-01-06",
+  "totalSessions": 7,
+  "dailyActivity": [],
+  "dailyModelTokens": {
+    "2026-01-06": {"claude-opus-4-6": 30},
+    "2026-01-05": {"claude-opus-4-6": 10, "claude-haiku-4-5": 5}
+  },
+  "hourCounts": {"09:00": 4, "23:00": 1}
+}`
+	cache, err := decodeStatsCache([]byte(content))
+	if err != nil {
+		t.Fatalf("decodeStatsCache() error = %v", err)
+	}
+	if cache.SchemaVersion != 2 {
+		t.Fatalf("schemaVersion=%d, want 2", cache.SchemaVersion)
+	}
+	want := map[string]int{"9": 4, "23": 1}
+	if !reflect.DeepEqual(cache.HourCounts, want) {
+		t.Fatalf("hourCounts=%v, want %v", cache.HourCounts, want)
+	}
+	dates, totals := DailyTokenTrend(cache)
+	if !reflect.DeepEqual(dates, []string{"2026-01-05", "2026-01-06"}) || !reflect.DeepEqual(totals, []int{15, 30}) {
+		t.Fatalf("token trend dates=%v totals=%v", dates, totals)
+	}
+
+	// hourCounts 也可能是 24 项数组
+	arrayCache, err := decodeStatsCache([]byte(`{"schemaVersion": 2, "hourCounts": [0,0,0,0,0,0,0,0,0,4,0,0,0,0,0,0,0,0,0,0,0,0,0,1]}`))
+	if err != nil {
+		t.Fatalf("decodeStatsCache(array) error = %v", err)
+	}
+	if !reflect.DeepEqual(arrayCache.HourCounts, want) {
+		t.Fatalf("array hourCounts=%v, want %v", arrayCache.HourCounts, want)
+	}
+}
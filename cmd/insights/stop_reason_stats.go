@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// unknownStopReason 记录缺少 stop_reason（字段缺失或为 null）时的归类
+const unknownStopReason = "unknown"
+
+// StopReasonStat 单个 stop_reason 的 assistant 消息数；max_tokens 偏多说明回复常被截断
+type StopReasonStat struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// stopReasonKey 归一化 AssistantMessage.StopReason，空值归入 unknown
+func stopReasonKey(reason string) string {
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return unknownStopReason
+	}
+	return reason
+}
+
+// buildStopReasonStats 汇总每日 stop_reason 计数，按消息数降序、原因名升序排列
+func buildStopReasonStats(dailyReasons map[string]map[string]int) []StopReasonStat {
+	totals := make(map[string]int)
+	for _, reasons := range dailyReasons {
+		for reason, count := range reasons {
+			totals[reason] += count
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	stats := make([]StopReasonStat, 0, len(totals))
+	for reason, count := range totals {
+		stats = append(stats, StopReasonStat{Reason: reason, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Reason < stats[j].Reason
+	})
+	return stats
+}
+
+// ParseStopReasonStats 统计时间范围内各 stop_reason 的 assistant 消息数
+func ParseStopReasonStats(tf TimeFilter) ([]StopReasonStat, error) {
+	agg, err := ParseProjectsConcurrentOnce(tf)
+	if err != nil {
+		return nil, err
+	}
+	return buildStopReasonStats(agg.DailyStopReasonCounts), nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stopReasonRecordJSON 带 message.stop_reason 的 assistant 记录
+func stopReasonRecordJSON(sessionID, reason string, ts time.Time) string {
+	return strings.Replace(projectRecordJSON("/tmp/demo", sessionID, ts), `"message":{`, `"message":{"stop_reason":"`+reason+`",`, 1)
+}
+
+func TestParseStopReasonStatsCountsPerReason(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := stopReasonRecordJSON("s1", "end_turn", base) + "\n" +
+		stopReasonRecordJSON("s1", "max_tokens", base.Add(time.Minute)) + "\n" +
+		stopReasonRecordJSON("s2", "end_turn", base.AddDate(0, 0, 1)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s3", base.AddDate(0, 0, 2)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	want := []StopReasonStat{{Reason: "end_turn", Count: 2}, {Reason: "max_tokens", Count: 1}, {Reason: unknownStopReason, Count: 1}}
+	stats, err := ParseStopReasonStats(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseStopReasonStats() error = %v", err)
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("stop reason stats = %+v, want %+v", stats, want)
+	}
+
+	// 缓存路径：按日落盘后再汇总，结果应与直接解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	daily := make(map[string]map[string]int)
+	for date, day := range cache.DailyStats {
+		daily[date] = day.StopReasons
+	}
+	if got := buildStopReasonStats(daily); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached stop reason stats = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// noExtensionType 没有扩展名的文件在 byType 中的归类
+const noExtensionType = "(none)"
+
+// StorageStats /api/storage 返回的数据目录占用
+type StorageStats struct {
+	DataDir    string           `json:"data_dir"`
+	FileCount  int              `json:"file_count"`
+	TotalBytes int64            `json:"total_bytes"`
+	ByType     map[string]int64 `json:"by_type"` // 扩展名（小写，如 .jsonl）-> 字节数
+}
+
+// DataDirStats 递归统计数据目录下的文件数与总字节数，并按扩展名汇总字节数。
+// 与缓存新鲜度检查共用 walkDataFiles：不跟随符号链接，只计普通文件，读不到的目录与文件跳过
+func DataDirStats() (fileCount int, totalBytes int64, byType map[string]int64) {
+	byType = make(map[string]int64)
+	walkDataFiles(cfg.DataDir, func(path string, info fs.FileInfo) {
+		if !info.Mode().IsRegular() {
+			return
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == "" {
+			ext = noExtensionType
+		}
+		fileCount++
+		totalBytes += info.Size()
+		byType[ext] += info.Size()
+	})
+	return fileCount, totalBytes, byType
+}
+
+// handleStorageAPI /api/storage：数据目录的磁盘占用（文件数、总字节数、按扩展名汇总）
+func handleStorageAPI(w http.ResponseWriter, r *http.Request) {
+	fileCount, totalBytes, byType := DataDirStats()
+	sendJSON(w, APIResponse{Success: true, Data: StorageStats{
+		DataDir:    cfg.DataDir,
+		FileCount:  fileCount,
+		TotalBytes: totalBytes,
+		ByType:     byType,
+	}})
+}
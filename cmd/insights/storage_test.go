@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataDirStatsSumsFileSizes(t *testing.T) {
+	dataDir := t.TempDir()
+	files := map[string]int{
+		"history.jsonl":                100,
+		"projects/demo/a.jsonl":        250,
+		"projects/demo/nested/b.JSONL": 50,
+		"debug/x.txt":                  30,
+		"statsig/cache":                7,
+	}
+	for name, size := range files {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	count, total, byType := DataDirStats()
+	if count != 5 || total != 437 {
+		t.Fatalf("count=%d total=%d, want 5 437", count, total)
+	}
+	if byType[".jsonl"] != 400 || byType[".txt"] != 30 || byType["(none)"] != 7 || len(byType) != 3 {
+		t.Fatalf("byType=%v，扩展名应按小写汇总", byType)
+	}
+
+	w := httptest.NewRecorder()
+	handleStorageAPI(w, httptest.NewRequest("GET", "/api/storage", nil))
+	var resp struct {
+		Success bool         `json:"success"`
+		Data    StorageStats `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("无法解析响应 JSON: %v", err)
+	}
+	if !resp.Success || resp.Data.FileCount != 5 || resp.Data.TotalBytes != 437 || resp.Data.DataDir != dataDir {
+		t.Fatalf("/api/storage 结果不符: %s", w.Body.String())
+	}
+}
+
+func TestWalkDataFilesReportsSizeAndModTime(t *testing.T) {
+	dataDir := t.TempDir()
+	mtime := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	for name, size := range map[string]int{"history.jsonl": 3, "projects/demo/a.jsonl": 5} {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sizes := make(map[string]int64)
+	err := walkDataFiles(dataDir, func(path string, info fs.FileInfo) {
+		rel, _ := filepath.Rel(dataDir, path)
+		sizes[filepath.ToSlash(rel)] = info.Size()
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("%s ModTime = %v, want %v", rel, info.ModTime(), mtime)
+		}
+	})
+	if err != nil {
+		t.Fatalf("walkDataFiles() error = %v", err)
+	}
+	if want := map[string]int64{"history.jsonl": 3, "projects/demo/a.jsonl": 5}; !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("sizes = %v, want %v", sizes, want)
+	}
+	if err := walkDataFiles(filepath.Join(dataDir, "missing"), func(string, fs.FileInfo) {}); !os.IsNotExist(err) {
+		t.Fatalf("目录不存在时 err = %v, want not exist", err)
+	}
+
+	// 缓存新鲜度检查经同一遍历取得最后修改时间
+	lastMod, err := (&CacheBuilder{DataDir: dataDir}).GetLastDataModified()
+	if err != nil || !lastMod.Equal(mtime) {
+		t.Fatalf("GetLastDataModified() = %v, %v, want %v", lastMod, err, mtime)
+	}
+}
@@ -19,7 +19,7 @@ func ParseTasksConcurrent(tf TimeFilter) (*TaskAnalysisData, error) {
 // ParseTasksConcurrentFromDir scans tasks directory under specified dataDir
 func ParseTasksConcurrentFromDir(tf TimeFilter, dataDir string) (*TaskAnalysisData, error) {
 	tasksDir := filepath.Join(dataDir, "tasks")
-	entries, err := os.ReadDir(tasksDir)
+	entries, err := dataSource.ReadDir(tasksDir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return &TaskAnalysisData{}, nil
@@ -82,7 +82,7 @@ func ParseTasksConcurrentFromDir(tf TimeFilter, dataDir string) (*TaskAnalysisDa
 func parseTaskSessionDir(sessionDirPath string, tf TimeFilter) *SessionTaskAgg {
 	dirName := filepath.Base(sessionDirPath)
 
-	dirInfo, err := os.Stat(sessionDirPath)
+	dirInfo, err := statData(sessionDirPath)
 	if err != nil {
 		return nil
 	}
@@ -90,7 +90,7 @@ func parseTaskSessionDir(sessionDirPath string, tf TimeFilter) *SessionTaskAgg {
 		return nil
 	}
 
-	entries, err := os.ReadDir(sessionDirPath)
+	entries, err := dataSource.ReadDir(sessionDirPath)
 	if err != nil {
 		return nil
 	}
@@ -141,7 +141,7 @@ func parseTaskSessionDir(sessionDirPath string, tf TimeFilter) *SessionTaskAgg {
 
 // parseTaskJSONFile reads and parses a single task JSON file
 func parseTaskJSONFile(filePath string) (*TaskRaw, error) {
-	data, err := os.ReadFile(filePath)
+	data, err := readDataFile(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -59,3 +59,28 @@ func appendProjectRecord(t *testing.T, dataDir string, cwd string, sessionID str
 func projectRecordJSON(cwd string, sessionID string, ts time.Time) string {
 	return `{"type":"assistant","cwd":"` + cwd + `","sessionId":"` + sessionID + `","timestamp":"` + ts.UTC().Format(time.RFC3339Nano) + `","message":{"model":"claude-sonnet-4.5","usage":{"input_tokens":10,"output_tokens":5}}}`
 }
+
+// fixtureDataDir 提交在 testdata/fixture 下的固定数据集（时间戳固定为 2026-01-05 ~ 2026-01-07 UTC）：
+//   - projects：/tmp/alpha 1 个会话 2 条 assistant（sonnet，Bash 1 次），/tmp/beta 2 个会话 3 条 assistant（opus，Read 1 次）
+//   - history.jsonl：/help×2、/model×1、/compact×1 与 1 条普通提示
+//   - debug：github::get_file_contents×2、crawl::extract_url×1
+//   - stats-cache.json：与上述项目数据一致的每日活动
+const fixtureDataDir = "testdata/fixture"
+
+// useFixtureDataDir 把 cfg.DataDir 指向固定数据集并清空全局缓存，测试结束后恢复
+func useFixtureDataDir(t *testing.T) string {
+	t.Helper()
+	dataDir, err := filepath.Abs(fixtureDataDir)
+	if err != nil {
+		t.Fatalf("定位 fixture 目录失败: %v", err)
+	}
+	origDataDir, origCache, origSource := cfg.DataDir, loadGlobalCache(), dataSource
+	cfg.DataDir = dataDir
+	storeGlobalCache(nil)
+	dataSource = osDataSource{}
+	t.Cleanup(func() {
+		cfg.DataDir, dataSource = origDataDir, origSource
+		storeGlobalCache(origCache)
+	})
+	return dataDir
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// TodayStats 从本地时区零点到当前时刻的概况，不受页面选择的时间范围影响
+type TodayStats struct {
+	Date            string `json:"date"`
+	Since           string `json:"since"`
+	Until           string `json:"until"`
+	Messages        int    `json:"messages"`
+	Sessions        int    `json:"sessions"`
+	Tokens          int    `json:"tokens"`
+	TopCommand      string `json:"top_command,omitempty"`
+	TopCommandCount int    `json:"top_command_count,omitempty"`
+}
+
+// todayTimeFilter 返回 now 所在时区当天零点到 now 的过滤器（零点按日历计算，夏令时切换日也正确）
+func todayTimeFilter(now time.Time) TimeFilter {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return TimeFilter{Start: &midnight, End: &now}
+}
+
+// ParseTodayStats 统计今天（本地时区零点起）的消息数、会话数、token 与最常用命令
+func ParseTodayStats() (*TodayStats, error) {
+	return parseTodayStats(time.Now())
+}
+
+func parseTodayStats(now time.Time) (*TodayStats, error) {
+	tf := todayTimeFilter(now)
+	agg, err := ParseProjectsConcurrentOnce(tf)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TodayStats{
+		Date:  tf.Start.Format("2006-01-02"),
+		Since: tf.Start.Format(time.RFC3339),
+		Until: now.Format(time.RFC3339),
+	}
+	for _, project := range agg.Projects {
+		stats.Messages += project.MessageCount
+		stats.Tokens += project.Tokens
+	}
+	if sessions, _ := extractSessionStatsFromAggregate(agg); sessions != nil {
+		stats.Sessions = sessions.TotalSessions
+	}
+
+	// history.jsonl 缺失时只是没有命令数据
+	cmdStats, _, _ := safeParseHistoryConcurrent(tf)
+	for _, item := range cmdStats {
+		if item.Count > stats.TopCommandCount || (item.Count == stats.TopCommandCount && item.Command < stats.TopCommand) {
+			stats.TopCommand, stats.TopCommandCount = item.Command, item.Count
+		}
+	}
+	return stats, nil
+}
+
+// handleTodayAPI /api/today：今日概况，前端可定时轮询刷新
+func handleTodayAPI(w http.ResponseWriter, r *http.Request) {
+	stats, err := ParseTodayStats()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSON(w, APIResponse{Success: false, Error: err.Error()})
+		return
+	}
+	sendJSON(w, APIResponse{Success: true, Data: stats})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTodayStatsCountsSinceLocalMidnight(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local)
+	midnight := time.Date(2026, 1, 6, 0, 0, 0, 0, time.Local)
+	// 零点前 30 分钟与 now 之后的记录都不计入
+	projects := projectRecordJSON("/tmp/demo", "yesterday", midnight.Add(-30*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s1", midnight.Add(30*time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s2", now.Add(-time.Hour)) + "\n" +
+		projectRecordJSON("/tmp/demo", "later", now.Add(time.Hour)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(projects), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := `{"display":"/help","timestamp":` + formatUnixMilli(midnight.Add(-time.Minute)) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"/help","timestamp":` + formatUnixMilli(midnight.Add(-2*time.Minute)) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"/model opus","timestamp":` + formatUnixMilli(midnight.Add(time.Minute)) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"/model","timestamp":` + formatUnixMilli(now.Add(-time.Minute)) + `,"project":"/tmp/demo"}` + "\n" +
+		`{"display":"/compact","timestamp":` + formatUnixMilli(now.Add(-time.Minute)) + `,"project":"/tmp/demo"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "history.jsonl"), []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	stats, err := parseTodayStats(now)
+	if err != nil {
+		t.Fatalf("parseTodayStats() error = %v", err)
+	}
+	if stats.Date != "2026-01-06" || stats.Messages != 2 || stats.Sessions != 2 || stats.Tokens != 30 {
+		t.Fatalf("today stats = %+v, want date=2026-01-06 messages=2 sessions=2 tokens=30", stats)
+	}
+	if stats.TopCommand != "/model" || stats.TopCommandCount != 2 {
+		t.Fatalf("top command = %s×%d, want /model×2", stats.TopCommand, stats.TopCommandCount)
+	}
+	if stats.Since != midnight.Format(time.RFC3339) {
+		t.Fatalf("since = %s, want %s", stats.Since, midnight.Format(time.RFC3339))
+	}
+}
@@ -14,25 +14,63 @@ type HistoryRecord struct {
 	Project        string            `json:"project"`
 }
 
+// PasteStats history.jsonl 中粘贴内容（pastedContents）的使用情况
+type PasteStats struct {
+	RecordsWithPaste int `json:"records_with_paste"` // 含至少一段粘贴的提问数
+	TotalPastes      int `json:"total_pastes"`       // 粘贴段数合计
+	TotalBytes       int `json:"total_bytes"`        // 粘贴内容字节数合计
+}
+
+// add 累加一条 history 记录的粘贴内容
+func (s *PasteStats) add(pasted map[string]string) {
+	if len(pasted) == 0 {
+		return
+	}
+	s.RecordsWithPaste++
+	s.TotalPastes += len(pasted)
+	for _, content := range pasted {
+		s.TotalBytes += len(content)
+	}
+}
+
+// merge 合并另一份粘贴统计
+func (s *PasteStats) merge(other PasteStats) {
+	s.RecordsWithPaste += other.RecordsWithPaste
+	s.TotalPastes += other.TotalPastes
+	s.TotalBytes += other.TotalBytes
+}
+
 // DailyActivity 每日活动统计
 type DailyActivity struct {
 	Date          string `json:"date"`
 	MessageCount  int    `json:"messageCount"`
 	SessionCount  int    `json:"sessionCount"`
 	ToolCallCount int    `json:"toolCallCount"`
+	// FirstTime/LastTime 当天首条与末条消息的时刻（HH:MM），仅由会话记录统计时填写
+	FirstTime string `json:"firstTime,omitempty"`
+	LastTime  string `json:"lastTime,omitempty"`
 }
 
-// StatsCache stats-cache.json 结构
+// StatsCache stats-cache.json 结构（新旧两种布局由 decodeStatsCache 统一映射到这里）
 type StatsCache struct {
-	DailyActivity    []DailyActivity          `json:"dailyActivity"`
-	DailyModelTokens []map[string]interface{} `json:"dailyModelTokens"`
+	// SchemaVersion 文件声明的 version/schemaVersion，旧布局未声明时为 0
+	SchemaVersion    int                `json:"schemaVersion,omitempty"`
+	DailyActivity    []DailyActivity    `json:"dailyActivity"`
+	DailyModelTokens []DailyModelTokens `json:"dailyModelTokens"`
 	ModelUsage       map[string]struct {
 		InputTokens  int `json:"inputTokens"`
 		OutputTokens int `json:"outputTokens"`
 	} `json:"modelUsage"`
+	// HourCounts 键为不补零的小时 "0".."23"
 	HourCounts map[string]int `json:"hourCounts"`
 }
 
+// DailyModelTokens stats-cache.json 中单日按模型的 token 数
+type DailyModelTokens struct {
+	Date          string         `json:"date"`
+	TokensByModel map[string]int `json:"tokensByModel"`
+}
+
 // SessionStats 会话统计数据
 type SessionStats struct {
 	TotalSessions   int            `json:"total_sessions"`
@@ -41,12 +79,28 @@ type SessionStats struct {
 	ValleyDate      string         `json:"valley_date"`
 	ValleyCount     int            `json:"valley_count"`
 	DailySessionMap map[string]int `json:"daily_session_map"`
+	// LogicalSessions 按 session_gap_minutes 切分后的逻辑会话数：同一 sessionId 内间隔超过阈值的记录算作新会话（>= total_sessions）
+	LogicalSessions   int `json:"logical_sessions"`
+	SessionGapMinutes int `json:"session_gap_minutes"`
+	// AvgMessagesPerSession 平均每个会话的 assistant 消息数（消息总数 / 会话总数，无会话时为 0）
+	AvgMessagesPerSession float64 `json:"avg_messages_per_session"`
+}
+
+// SessionInfo 单个会话的原始范围（跨多个 JSONL 文件的同一 sessionId 合并为一条）
+type SessionInfo struct {
+	SessionID    string `json:"session_id"`
+	Project      string `json:"project"`
+	StartTime    string `json:"start_time"`
+	EndTime      string `json:"end_time"`
+	MessageCount int    `json:"message_count"`
 }
 
 // CommandStats 命令统计
 type CommandStats struct {
 	Command string `json:"command"`
-	Count   int    `json:"count"`
+	// SubCommand 首个参数（仅 breakdown=args 时填充，如 /model opus 的 opus）
+	SubCommand string `json:"sub_command,omitempty"`
+	Count      int    `json:"count"`
 }
 
 // ProjectStats 项目统计
@@ -64,9 +118,27 @@ type ProjectStatsData struct {
 
 // ProjectStatItem 单个项目统计
 type ProjectStatItem struct {
-	Project      string `json:"project"`
-	SessionCount int    `json:"session_count"`
-	MessageCount int    `json:"message_count"`
+	Project       string `json:"project"`
+	SessionCount  int    `json:"session_count"`
+	MessageCount  int    `json:"message_count"`
+	Tokens        int    `json:"tokens"`                  // assistant 消息 input+output token 合计
+	ActiveDays    int    `json:"active_days"`             // 有活动的不同日期数
+	LongestStreak int    `json:"longest_streak"`          // 最长连续活跃天数
+	FirstSeen     string `json:"first_seen,omitempty"`    // 最早有活动的日期（YYYY-MM-DD）
+	LastSeen      string `json:"last_seen,omitempty"`     // 最近有活动的日期（YYYY-MM-DD）
+	PrimaryModel  string `json:"primary_model,omitempty"` // 该项目 assistant 消息最多的模型
+}
+
+// ParseStats 解析 JSONL 时成功/跳过（无法解析）的记录数，用于提示数据质量问题
+type ParseStats struct {
+	RecordsParsed  int `json:"records_parsed"`
+	RecordsSkipped int `json:"records_skipped"`
+}
+
+// Add 累加另一份解析统计
+func (s *ParseStats) Add(other ParseStats) {
+	s.RecordsParsed += other.RecordsParsed
+	s.RecordsSkipped += other.RecordsSkipped
 }
 
 // WeekdayStats 星期统计
@@ -83,9 +155,10 @@ type WeekdayItem struct {
 
 // ModelUsageItem 单个模型使用统计
 type ModelUsageItem struct {
-	Model  string `json:"model"`
-	Count  int    `json:"count"`
-	Tokens int    `json:"tokens"`
+	Model   string  `json:"model"`
+	Count   int     `json:"count"`
+	Tokens  int     `json:"tokens"`
+	CostCNY float64 `json:"cost_cny,omitempty"`
 }
 
 // ToolAnalysisData 工具调用分析结果
@@ -300,6 +373,10 @@ type SessionAnalysisData struct {
 	Outcomes        []SessionOutcomeStat  `json:"outcomes"`
 	QueueOperations []QueueOperationStat  `json:"queue_operations"`
 	Titles          []SessionTitleStat    `json:"titles"`
+	// AvgDistinctTools 有工具调用的 session 平均使用的不同工具数
+	AvgDistinctTools float64 `json:"avg_distinct_tools"`
+	// Summaries 截断前的全部 session，只保留过滤与计数用到的字段；按模型、项目过滤后据此重算会话合计
+	Summaries []SessionAnalysisItem `json:"-"`
 }
 
 // SessionAnalysisItem 单个 session 摘要
@@ -340,6 +417,8 @@ type SessionAnalysisItem struct {
 	QueueOperationsSample string                      `json:"queue_operations_sample,omitempty"`
 	PrimaryModel          string                      `json:"primary_model,omitempty"`
 	ModelsUsed            map[string]int              `json:"models_used,omitempty"`
+	ToolsUsed             map[string]int              `json:"tools_used,omitempty"`
+	DistinctToolCount     int                         `json:"distinct_tool_count"`
 }
 
 // SessionOutcomeStat session outcome 聚合
@@ -989,12 +1068,13 @@ type ToolPerfAgg struct {
 
 // WorkHoursStats 工作时段统计
 type WorkHoursStats struct {
-	HourlyData     []HourlyItem `json:"hourly_data"` // 每小时数据
-	WorkHoursCount int          `json:"work_hours"`  // 工作时段(9-18点)总次数
-	OffHoursCount  int          `json:"off_hours"`   // 非工作时段总次数
-	WorkHoursRatio float64      `json:"work_ratio"`  // 工作时段占比
-	PeakHour       int          `json:"peak_hour"`   // 峰值小时
-	PeakHourCount  int          `json:"peak_count"`  // 峰值小时次数
+	HourlyData     []HourlyItem `json:"hourly_data"`          // 每小时数据
+	WorkHoursCount int          `json:"work_hours"`           // 工作时段(9-18点)总次数
+	OffHoursCount  int          `json:"off_hours"`            // 非工作时段总次数
+	WorkHoursRatio float64      `json:"work_ratio"`           // 工作时段占比
+	PeakHour       int          `json:"peak_hour"`            // 峰值小时（并列时取最早）
+	PeakHourCount  int          `json:"peak_count"`           // 峰值小时次数
+	PeakHours      []int        `json:"peak_hours,omitempty"` // 次数等于峰值的全部小时（升序）
 }
 
 // HourlyItem 单小时数据
@@ -1014,10 +1094,19 @@ type ProjectAggregate struct {
 	DailyActivity           map[string]int                          `json:"-"`                // 每日消息数（map）
 	DailyActivityList       []DailyActivity                         `json:"daily"`            // 每日活动（输出格式）
 	DailySessions           map[string]map[string]bool              `json:"-"`                // 每日会话集 date→sessionID→true（用于提取SessionStats，避免重复解析）
+	DailySessionSpans       map[string]map[string]SessionSpan       `json:"-"`                // 每日会话 assistant 记录摘要 date→sessionID→首末时间/条数/切分次数（按间隔切分逻辑会话）
 	DailyProjectCounts      map[string]map[string]int               `json:"-"`                // 每日项目消息数 date→project→count
 	DailyModelCounts        map[string]map[string]int               `json:"-"`                // 每日模型请求数 date→model→count
+	DailyVersionCounts      map[string]map[string]int               `json:"-"`                // 每日 Claude Code 版本消息数 date→version→count
+	DailyStopReasonCounts   map[string]map[string]int               `json:"-"`                // 每日 stop_reason 消息数 date→reason→count
+	DailyOutputTokens       map[string]int                          `json:"-"`                // 每日 assistant 消息 output token 数 date→tokens
+	DailyAssistantMessages  map[string]int                          `json:"-"`                // 每日 assistant 消息数（不受 count_mode 影响）date→count
 	DailyModelTokens        map[string]map[string]int               `json:"-"`                // 每日模型 token 数 date→model→tokens
+	DailyProjectTokens      map[string]map[string]int               `json:"-"`                // 每日项目 token 数 date→project→tokens
+	ParseStats              ParseStats                              `json:"-"`                // JSONL 解析成功/跳过的记录数
 	DailyHourlyCounts       map[string][24]int                      `json:"-"`                // 每日小时消息数 date→hour→count
+	DailyHalfHourCounts     map[string][48]int                      `json:"-"`                // 每日半小时消息数 date→(hour*2+minute/30)→count
+	DailyBounds             map[string][2]int                       `json:"-"`                // 每日首条/末条消息的当天秒数 date→[first, last]
 	DailyRuntime            map[string]*ProjectAggregate            `json:"-"`                // 每日运行时聚合（工具/成本/失败/性能等）
 	DailyProjectRuntime     map[string]map[string]*ProjectAggregate `json:"-"`                // 每日项目运行时聚合 date→project→aggregate
 	DailySessionRuntime     map[string]map[string]*ProjectAggregate `json:"-"`                // 每日 Session 运行时聚合 date→session→aggregate
@@ -1135,7 +1224,9 @@ type AssistantMessage struct {
 	Role    string             `json:"role"`
 	Model   string             `json:"model"`
 	Content []AssistantContent `json:"content"`
-	Usage   struct {
+	// StopReason 结束原因：end_turn、max_tokens、tool_use 等；流式中间记录为 null
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
 		InputTokens              int `json:"input_tokens"`
 		OutputTokens             int `json:"output_tokens"`
 		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
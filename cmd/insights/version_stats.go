@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// unknownVersion 记录缺少 version 字段时的归类
+const unknownVersion = "unknown"
+
+// VersionStat 单个 Claude Code 版本的 assistant 消息数
+type VersionStat struct {
+	Version string `json:"version"`
+	Count   int    `json:"count"`
+}
+
+// versionKey 归一化 ProjectRecord.Version，空值归入 unknown
+func versionKey(version string) string {
+	if version = strings.TrimSpace(version); version == "" {
+		return unknownVersion
+	}
+	return version
+}
+
+// buildVersionStats 汇总每日版本计数，按消息数降序、版本号升序排列
+func buildVersionStats(dailyVersions map[string]map[string]int) []VersionStat {
+	totals := make(map[string]int)
+	for _, versions := range dailyVersions {
+		for version, count := range versions {
+			totals[version] += count
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	stats := make([]VersionStat, 0, len(totals))
+	for version, count := range totals {
+		stats = append(stats, VersionStat{Version: version, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Version < stats[j].Version
+	})
+	return stats
+}
+
+// ParseVersionStats 统计时间范围内各 Claude Code 版本的 assistant 消息数
+func ParseVersionStats(tf TimeFilter) ([]VersionStat, error) {
+	agg, err := ParseProjectsConcurrentOnce(tf)
+	if err != nil {
+		return nil, err
+	}
+	return buildVersionStats(agg.DailyVersionCounts), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// versionedRecordJSON 带 version 字段的 assistant 记录
+func versionedRecordJSON(sessionID, version string, ts time.Time) string {
+	return strings.Replace(projectRecordJSON("/tmp/demo", sessionID, ts), `"type":"assistant"`, `"type":"assistant","version":"`+version+`"`, 1)
+}
+
+func TestParseVersionStatsCountsPerVersion(t *testing.T) {
+	dataDir := t.TempDir()
+	projectDir := filepath.Join(dataDir, "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	content := versionedRecordJSON("s1", "2.1.0", base) + "\n" +
+		versionedRecordJSON("s1", "2.1.0", base.Add(time.Minute)) + "\n" +
+		versionedRecordJSON("s2", "2.1.0", base.AddDate(0, 0, 1)) + "\n" +
+		versionedRecordJSON("s2", "2.0.5", base.AddDate(0, 0, 1).Add(time.Minute)) + "\n" +
+		projectRecordJSON("/tmp/demo", "s3", base.AddDate(0, 0, 2)) + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "a.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origDataDir := cfg.DataDir
+	cfg.DataDir = dataDir
+	defer func() { cfg.DataDir = origDataDir }()
+
+	want := []VersionStat{{Version: "2.1.0", Count: 3}, {Version: "2.0.5", Count: 1}, {Version: unknownVersion, Count: 1}}
+	stats, err := ParseVersionStats(TimeFilter{})
+	if err != nil {
+		t.Fatalf("ParseVersionStats() error = %v", err)
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("version stats = %+v, want %+v", stats, want)
+	}
+
+	// 缓存路径：按日落盘后再汇总，结果应与直接解析一致
+	cachePath := filepath.Join(t.TempDir(), "cache.db")
+	if err := (&CacheBuilder{CachePath: cachePath, DataDir: dataDir}).BuildFullCache(); err != nil {
+		t.Fatalf("BuildFullCache() failed: %v", err)
+	}
+	cache, err := LoadCacheFile(cachePath)
+	if err != nil {
+		t.Fatalf("LoadCacheFile() failed: %v", err)
+	}
+	daily := make(map[string]map[string]int)
+	for date, day := range cache.DailyStats {
+		daily[date] = day.VersionCounts
+	}
+	if got := buildVersionStats(daily); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached version stats = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// --watch 自动刷新：按固定间隔轮询数据目录（不引入 fsnotify 依赖），数据比缓存新或规则变更等
+// NeedsRebuild 条件成立时走一次 refreshGlobalCache：增量构建后替换 globalCache，并推送给 /ws 连接。
+// 刷新与 /api/reload 共用 refreshGlobalCache：同一时间只有一次刷新，并发调用等待并共享其结果。
+
+// defaultWatchInterval --watch-interval 未设置或非法时的轮询间隔
+const defaultWatchInterval = 30 * time.Second
+
+// watchCacheOnce 检查一次数据是否变更，变更时刷新缓存；返回是否触发了刷新
+func watchCacheOnce() (bool, error) {
+	if cfg.Demo {
+		return false, nil
+	}
+	builder := &CacheBuilder{CachePath: cacheFilePath(), DataDir: cfg.DataDir}
+	if !builder.NeedsRebuild() {
+		return false, nil
+	}
+	Info("检测到数据变更，自动刷新缓存", "data_dir", cfg.DataDir)
+	return true, refreshGlobalCache(false)
+}
+
+// startCacheWatcher 按 interval 周期调用 watchCacheOnce；返回的函数停止轮询，
+// 并等待进行中的刷新结束后才返回
+func startCacheWatcher(interval time.Duration) func() {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := watchCacheOnce(); err != nil {
+					Warn("自动刷新缓存失败，继续使用现有缓存", "error", err.Error())
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
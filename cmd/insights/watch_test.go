@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheWatcherSwapsInUpdatedData(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "data", "projects", "demo")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sessionPath := filepath.Join(projectDir, "a.jsonl")
+	base := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	if err := os.WriteFile(sessionPath, []byte(projectRecordJSON("/tmp/demo", "s1", base)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	origCfg, origCache := cfg, loadGlobalCache()
+	defer func() {
+		cfg = origCfg
+		storeGlobalCache(origCache)
+	}()
+	cfg.DataDir = filepath.Join(tmpDir, "data")
+	cfg.CacheDir = filepath.Join(tmpDir, "cache")
+
+	if err := refreshGlobalCache(true); err != nil {
+		t.Fatalf("refreshGlobalCache() failed: %v", err)
+	}
+	if loadGlobalCache().TotalMessages != 1 {
+		t.Fatalf("初始 messages=%d, want 1", loadGlobalCache().TotalMessages)
+	}
+	if refreshed, err := watchCacheOnce(); err != nil || refreshed {
+		t.Fatalf("数据未变更时 refreshed=%v err=%v, want false", refreshed, err)
+	}
+
+	interval := 20 * time.Millisecond
+	stop := startCacheWatcher(interval)
+	defer stop()
+
+	// 追加一条记录；mtime 拨到缓存构建之后，避免文件系统时间精度导致判断不到变更
+	f, err := os.OpenFile(sessionPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(projectRecordJSON("/tmp/demo", "s1", base.Add(time.Minute)) + "\n")
+	f.Close()
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(sessionPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for loadGlobalCache().TotalMessages != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watcher 未在期限内换入新数据: messages=%d", loadGlobalCache().TotalMessages)
+		}
+		time.Sleep(interval)
+	}
+}
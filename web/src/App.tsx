@@ -129,7 +129,11 @@ function App() {
             loading={dashboard.isLoading}
           />
           <WeekdayChart stats={dashboard.data?.weekday_stats} loading={dashboard.isLoading} />
-          <ModelChart data={dashboard.data?.model_usage} loading={dashboard.isLoading} />
+          <ModelChart
+            data={dashboard.data?.model_usage}
+            weight={dashboard.data?.model_weight}
+            loading={dashboard.isLoading}
+          />
           <div className="xl:col-span-2">
             <WorkHoursChart
               stats={dashboard.data?.work_hours_stats}
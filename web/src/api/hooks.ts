@@ -14,6 +14,9 @@ import type {
 
 const BASE = '/api'
 
+// 服务端以 --token 启动时，用 /dashboard?token=<token> 打开页面，API 请求带上 Bearer 头
+const TOKEN = new URLSearchParams(window.location.search).get('token')
+
 // filter → query string（只带非空字段）
 function toQuery(filters: Filters): string {
   const p = new URLSearchParams()
@@ -26,7 +29,7 @@ function toQuery(filters: Filters): string {
 }
 
 async function getJSON<T>(url: string): Promise<T> {
-  const res = await fetch(url)
+  const res = await fetch(url, TOKEN ? { headers: { Authorization: `Bearer ${TOKEN}` } } : undefined)
   if (!res.ok) throw new Error(`${res.status} ${res.statusText}`)
   return (await res.json()) as T
 }
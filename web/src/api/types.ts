@@ -5,6 +5,8 @@ export interface TimeRange {
   preset: string
   start?: string
   end?: string
+  since?: string
+  clamped?: boolean
 }
 
 export interface ApiMeta {
@@ -18,6 +20,8 @@ export interface ApiMeta {
 
 // 交互式 API（/api/overview、/api/diagnostics、/api/detail/*）统一外层
 export interface InteractiveResponse<T> {
+  api_version?: string
+  last_update?: string
   success: boolean
   meta?: ApiMeta
   data?: T
@@ -26,6 +30,10 @@ export interface InteractiveResponse<T> {
 
 // /api/data 外层（APIResponse）
 export interface ApiResponse<T> {
+  // 响应结构版本，不兼容变化时递增
+  api_version?: string
+  // 数据新鲜度（RFC3339）：缓存构建时间，未加载缓存时为实时解析时间
+  last_update?: string
   success: boolean
   data?: T
   error?: string
@@ -66,6 +74,149 @@ export interface DailyTrend {
   counts: number[]
 }
 
+export interface DailyIntensityItem {
+  date: string
+  messages: number
+  peak_hour: number
+  peak_hour_count: number
+  intensity: number
+  bursty: boolean
+}
+
+// Claude Code 版本分布：各版本的 assistant 消息数，缺版本号归入 unknown
+export interface VersionStat {
+  version: string
+  count: number
+}
+
+// 每日模型构成，models[i].counts 与 dates 下标对应
+export interface DailyModelSeries {
+  model: string
+  counts: number[]
+}
+
+export interface DailyModelMix {
+  dates: string[]
+  models: DailyModelSeries[]
+}
+
+// history.jsonl 粘贴内容统计
+export interface PasteStats {
+  records_with_paste: number
+  total_pastes: number
+  total_bytes: number
+}
+
+// /api/today —— 本地零点至今的概况
+export interface TodayStats {
+  date: string
+  since: string
+  until: string
+  messages: number
+  sessions: number
+  tokens: number
+  top_command?: string
+  top_command_count?: number
+}
+
+// /api/hourly —— 单日 24 小时分布（bin=30m 时为 48 个半小时桶）
+export interface HourlyForDate {
+  date: string
+  source: 'cache' | 'parsing'
+  bin: '1h' | '30m'
+  hours: number[]
+  labels?: string[]
+  total: number
+}
+
+// /api/storage —— 数据目录磁盘占用
+export interface StorageStats {
+  data_dir: string
+  file_count: number
+  total_bytes: number
+  by_type: Record<string, number>
+}
+
+// /api/debug-patterns —— debug 日志各模式命中行数
+export interface DebugPatternData {
+  debug_pattern_stats: Record<string, number>
+}
+
+// /api/message-lengths —— 提问与回复的字符长度分布
+export interface LengthSummary {
+  count: number
+  avg_chars: number
+  median_chars: number
+  p95_chars: number
+}
+export interface MessageLengthStats {
+  user_prompts: LengthSummary
+  assistant_text: LengthSummary
+}
+
+// Dashboard 顶部大数字，由各明细数组汇总
+export interface DashboardSummary {
+  total_messages: number
+  total_sessions: number
+  total_tokens: number
+  total_cost_cny: number
+  total_commands: number
+  total_tool_calls: number
+  avg_output_tokens_per_message: number
+}
+
+// 单日 output token 合计与平均每条消息的 output token 数
+export interface DailyOutputTokenItem {
+  date: string
+  messages: number
+  output_tokens: number
+  avg_output_tokens: number
+}
+
+export interface DailyActiveHoursItem {
+  date: string
+  first: string
+  last: string
+  first_hour: number
+  last_hour: number
+}
+
+export interface DashboardFacets {
+  models: string[]
+  commands: string[]
+  tools: string[]
+}
+
+// /api/compare —— 两个时间范围对比，百分比为 (a-b)/b*100，b 为 0 时为 null
+export interface CommandDelta {
+  command: string
+  a: number
+  b: number
+  change: number
+}
+
+export interface CompareDeltas {
+  messages_pct: number | null
+  sessions_pct: number | null
+  tokens_pct: number | null
+  messages: number
+  sessions: number
+  tokens: number
+  commands: CommandDelta[]
+}
+
+export interface CompareResult {
+  a: DashboardData
+  b: DashboardData
+  deltas: CompareDeltas
+}
+
+// stop_reason 分布：各结束原因的 assistant 消息数，缺失归入 unknown；max_tokens 表示回复被截断
+export interface StopReasonStat {
+  reason: string
+  count: number
+}
+
 // /api/version —— 构建版本（由 Go -ldflags -X main.version 注入）
 export interface VersionInfo {
   version?: string
@@ -75,12 +226,33 @@ export interface VersionInfo {
 
 export interface CommandStat {
   command: string
+  sub_command?: string
+  count: number
+}
+// /api/commands —— 不截断的 slash command 统计与分类汇总
+export interface CategoryStat {
+  category: string
   count: number
+  commands: string[]
+}
+export interface CommandListData {
+  total_calls: number
+  total_commands: number
+  offset: number
+  limit: number
+  commands: CommandStat[]
+  command_categories: CategoryStat[]
 }
 export interface ProjectStatItem {
   project: string
   session_count: number
   message_count: number
+  tokens?: number
+  active_days?: number
+  longest_streak?: number
+  first_seen?: string
+  last_seen?: string
+  primary_model?: string
 }
 export interface ProjectStatsData {
   projects: ProjectStatItem[]
@@ -90,7 +262,9 @@ export interface ModelUsageItem {
   model: string
   count: number
   tokens: number
+  cost_cny?: number
 }
+export type ModelWeight = 'count' | 'tokens' | 'cost'
 export interface WeekdayItem {
   weekday: number
   weekday_name: string
@@ -108,6 +282,7 @@ export interface WorkHourItem {
 }
 export interface WorkHoursStats {
   hourly_data: WorkHourItem[]
+  peak_hours?: number[]
   [key: string]: unknown
 }
 
@@ -187,6 +362,20 @@ export interface ToolPerformanceData {
   [key: string]: unknown
 }
 
+// /api/data?samples=N 的原始记录样例
+export interface RecordSample {
+  file: string
+  line: number
+  timestamp?: string
+  project?: string
+  session_id?: string
+}
+export interface MetricSamples {
+  metric: 'model' | 'command'
+  key: string
+  records: RecordSample[]
+}
+
 // cost + runtime 组子结构（Phase 3c）
 export interface CostByModel {
   model: string
@@ -214,7 +403,10 @@ export interface SessionStats {
   peak_count?: number
   valley_date?: string
   valley_count?: number
+  avg_messages_per_session?: number
   daily_session_map?: Record<string, number>
+  logical_sessions?: number
+  session_gap_minutes?: number
   [key: string]: unknown
 }
 export interface SessionOutcome {
@@ -310,10 +502,21 @@ export interface RuntimeToolSignal {
 export interface DashboardData {
   timestamp?: string
   time_range?: TimeRange
+  summary?: DashboardSummary
   daily_trend: DailyTrend
+  daily_intensity?: DailyIntensityItem[]
+  version_stats?: VersionStat[]
+  daily_model_mix?: DailyModelMix
+  stop_reason_stats?: StopReasonStat[]
+  daily_output_tokens?: DailyOutputTokenItem[]
+  daily_active_hours?: DailyActiveHoursItem[]
+  paste_stats?: PasteStats
+  parse_timings?: Record<string, number>
+  facets?: DashboardFacets
   commands?: CommandStat[]
   project_stats?: ProjectStatsData
   model_usage?: ModelUsageItem[]
+  model_weight?: ModelWeight
   weekday_stats?: WeekdayStats
   work_hours_stats?: WorkHoursStats
   tool_analysis?: ToolAnalysisData
@@ -321,6 +524,8 @@ export interface DashboardData {
   command_analysis?: CommandAnalysisData
   file_analysis?: FileAnalysisData
   tool_performance?: ToolPerformanceData
+  data_quality?: { records_parsed: number; records_skipped: number }
+  samples?: MetricSamples[]
   cost_analysis?: CostAnalysisData
   sessions?: SessionStats
   session_analysis?: SessionAnalysisData
@@ -348,6 +553,22 @@ export interface TimelineData {
   days: TimelineDay[]
 }
 
+// /api/rollup
+export interface RollupBucket {
+  key: string
+  label: string
+  start: string
+  end: string
+  messages: number
+  sessions: number
+  active_days: number
+}
+
+export interface RollupData {
+  by: 'week' | 'month'
+  buckets: RollupBucket[]
+}
+
 // 前端 filter（驱动所有 Query，见 hooks/useFilters.ts）
 export interface Filters {
   preset: string
@@ -357,11 +578,20 @@ export interface Filters {
   tool?: string
   model?: string
   reason?: string
+  model_weight?: string
 }
 
 export const PRESETS = ['24h', '7d', '30d', '90d', 'all'] as const
 export type Preset = (typeof PRESETS)[number]
 
+// /api/presets —— 后端支持的预设及按当前时间解析出的范围
+export interface PresetInfo {
+  key: Preset
+  start?: string
+  end?: string
+  default?: boolean
+}
+
 // /api/diagnostics —— 诊断建议（对应 cli.go diagnosticFinding）
 export interface DiagnosticEvidence {
   label: string
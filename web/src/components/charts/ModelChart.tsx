@@ -7,7 +7,7 @@ import {
   XAxis,
   YAxis,
 } from 'recharts'
-import type { ModelUsageItem } from '@/api/types'
+import type { ModelUsageItem, ModelWeight } from '@/api/types'
 import { ChartCard, ChartEmpty } from './ChartCard'
 
 const tooltipStyle = {
@@ -22,10 +22,36 @@ function shortModel(m: string): string {
   return tail.length > 16 ? tail.slice(0, 15) + '…' : tail
 }
 
-export function ModelChart({ data, loading }: { data?: ModelUsageItem[]; loading?: boolean }) {
+// 按权重口径取柱高：请求次数 / Token / 估算费用
+const weightMeta: Record<ModelWeight, { label: string; unit: string }> = {
+  count: { label: '请求', unit: '次请求' },
+  tokens: { label: 'Token', unit: ' Token' },
+  cost: { label: '费用 (¥)', unit: ' 元估算费用' },
+}
+
+function weightValue(item: ModelUsageItem, weight: ModelWeight): number {
+  if (weight === 'tokens') return item.tokens
+  if (weight === 'cost') return item.cost_cny ?? 0
+  return item.count
+}
+
+export function ModelChart({
+  data,
+  weight = 'count',
+  loading,
+}: {
+  data?: ModelUsageItem[]
+  weight?: ModelWeight
+  loading?: boolean
+}) {
   const has = !!data && data.length > 0
-  const rows = has ? [...data].sort((a, b) => b.count - a.count) : []
-  const total = rows.reduce((s, x) => s + x.count, 0)
+  const rows = has
+    ? [...data]
+        .map((x) => ({ ...x, value: weightValue(x, weight) }))
+        .sort((a, b) => b.value - a.value)
+    : []
+  const total = rows.reduce((s, x) => s + x.value, 0)
+  const meta = weightMeta[weight]
 
   return (
     <ChartCard
@@ -34,7 +60,8 @@ export function ModelChart({ data, loading }: { data?: ModelUsageItem[]; loading
       insight={
         has ? (
           <>
-            共 <strong>{total.toLocaleString()}</strong> 次请求，主力模型{' '}
+            共 <strong>{total.toLocaleString()}</strong>
+            {meta.unit}，主力模型{' '}
             <strong className="font-mono">{shortModel(rows[0].model)}</strong>。
           </>
         ) : loading ? (
@@ -62,11 +89,9 @@ export function ModelChart({ data, loading }: { data?: ModelUsageItem[]; loading
             <Tooltip
               contentStyle={tooltipStyle}
               cursor={{ fill: 'rgb(var(--accent) / 0.3)' }}
-              formatter={(v: number, n: string) =>
-                n === 'tokens' ? [v.toLocaleString(), 'Token'] : [v.toLocaleString(), '请求']
-              }
+              formatter={(v: number) => [v.toLocaleString(), meta.label]}
             />
-            <Bar dataKey="count" name="count" fill="rgb(var(--primary))" radius={[4, 4, 0, 0]} />
+            <Bar dataKey="value" name={weight} fill="rgb(var(--primary))" radius={[4, 4, 0, 0]} />
           </BarChart>
         </ResponsiveContainer>
       ) : (
@@ -17,6 +17,7 @@ function readFromURL(): Filters {
     tool: p.get('tool') || undefined,
     model: p.get('model') || undefined,
     reason: p.get('reason') || undefined,
+    model_weight: p.get('model_weight') || undefined,
   }
 }
 